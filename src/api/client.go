@@ -651,6 +651,8 @@ type CreateWalletOptions struct {
 	Encrypt               bool
 	Bip44Coin             *bip44.CoinType
 	CollectionPrivateKeys string
+	CryptoType            string
+	ScryptParams          *wallet.ScryptParams
 }
 
 // CreateWallet makes a request to POST /api/v1/wallet/create and creates a wallet.
@@ -686,6 +688,16 @@ func (c *Client) CreateWallet(o CreateWalletOptions) (*WalletResponse, error) {
 		v.Add("private-keys", o.CollectionPrivateKeys)
 	}
 
+	if o.CryptoType != "" {
+		v.Add("crypto-type", o.CryptoType)
+	}
+
+	if o.ScryptParams != nil {
+		v.Add("scrypt-n", fmt.Sprint(o.ScryptParams.N))
+		v.Add("scrypt-r", fmt.Sprint(o.ScryptParams.R))
+		v.Add("scrypt-p", fmt.Sprint(o.ScryptParams.P))
+	}
+
 	var w WalletResponse
 	if err := c.PostForm("/api/v1/wallet/create", strings.NewReader(v.Encode()), &w); err != nil {
 		return nil, err
@@ -893,6 +905,16 @@ func (c *Client) UpdateWallet(id, label string) error {
 	return c.PostForm("/api/v1/wallet/update", strings.NewReader(v.Encode()), nil)
 }
 
+// SetAddressLabel makes a request to POST /api/v1/wallet/addressLabel
+func (c *Client) SetAddressLabel(id, addr, label string) error {
+	v := url.Values{}
+	v.Add("id", id)
+	v.Add("address", addr)
+	v.Add("label", label)
+
+	return c.PostForm("/api/v1/wallet/addressLabel", strings.NewReader(v.Encode()), nil)
+}
+
 // WalletFolderName makes a request to GET /api/v1/wallets/folderName
 func (c *Client) WalletFolderName() (*WalletFolder, error) {
 	var w WalletFolder
@@ -1322,9 +1344,18 @@ func (c *Client) Health() (*HealthResponse, error) {
 
 // EncryptWallet makes a request to POST /api/v1/wallet/encrypt to encrypt a specific wallet with the given password
 func (c *Client) EncryptWallet(id, password string) (*WalletResponse, error) {
+	return c.EncryptWalletWithCryptoType(id, password, "")
+}
+
+// EncryptWalletWithCryptoType makes a request to POST /api/v1/wallet/encrypt to encrypt a wallet
+// with the given crypto type. If cryptoType is empty, the wallet's crypto type is used.
+func (c *Client) EncryptWalletWithCryptoType(id, password, cryptoType string) (*WalletResponse, error) {
 	v := url.Values{}
 	v.Add("id", id)
 	v.Add("password", password)
+	if cryptoType != "" {
+		v.Add("crypto-type", cryptoType)
+	}
 	var wlt WalletResponse
 	if err := c.PostForm("/api/v1/wallet/encrypt", strings.NewReader(v.Encode()), &wlt); err != nil {
 		return nil, err
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/crypto"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/daemon"
 	"github.com/skycoin/skycoin/src/kvstorage"
@@ -101,6 +102,7 @@ type Visorer interface {
 type Walleter interface {
 	UnloadWallet(wltID string) error
 	EncryptWallet(wltID string, password []byte) (wallet.Wallet, error)
+	EncryptWalletWithCryptoType(wltID string, password []byte, cryptoType crypto.CryptoType) (wallet.Wallet, error)
 	DecryptWallet(wltID string, password []byte) (wallet.Wallet, error)
 	GetWalletSeed(wltID string, password []byte) (string, string, error)
 	CreateWallet(wltName string, options wallet.Options) (wallet.Wallet, error)
@@ -110,6 +112,7 @@ type Walleter interface {
 	GetWallet(wltID string) (wallet.Wallet, error)
 	GetWallets() (wallet.Wallets, error)
 	UpdateWalletLabel(wltID, label string) error
+	SetAddressLabel(wltID string, addr cipher.Address, label string) error
 	WalletDir() (string, error)
 }
 
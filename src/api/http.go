@@ -504,6 +504,9 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 	webHandlerV1("/wallet/update", walletUpdateHandler(gateway), map[string][]string{
 		http.MethodPost: {EndpointsWallet},
 	})
+	webHandlerV1("/wallet/addressLabel", walletAddressLabelHandler(gateway), map[string][]string{
+		http.MethodPost: {EndpointsWallet},
+	})
 	webHandlerV1("/wallets", walletsHandler(gateway), map[string][]string{
 		http.MethodGet: {EndpointsWallet},
 	})
@@ -120,6 +120,9 @@ var endpointsMethods = map[string][]string{
 	"/api/v1/wallet": []string{
 		http.MethodGet,
 	},
+	"/api/v1/wallet/addressLabel": []string{
+		http.MethodPost,
+	},
 	"/api/v1/wallet/balance": []string{
 		http.MethodGet,
 	},
@@ -776,11 +776,8 @@ func TestDecryptWallet(t *testing.T) {
 }
 
 func TestRecoverWallet(t *testing.T) {
-	if !doLiveOrStable(t) {
-		return
-	}
-
-	if skipWalletIfLive(t) {
+	// Recovering a wallet requires the seed API
+	if !doEnableSeedAPI(t) {
 		return
 	}
 
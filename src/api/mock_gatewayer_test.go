@@ -6,6 +6,8 @@ import (
 	cipher "github.com/skycoin/skycoin/src/cipher"
 	coin "github.com/skycoin/skycoin/src/coin"
 
+	crypto "github.com/skycoin/skycoin/src/cipher/crypto"
+
 	daemon "github.com/skycoin/skycoin/src/daemon"
 
 	historydb "github.com/skycoin/skycoin/src/visor/historydb"
@@ -192,6 +194,29 @@ func (_m *MockGatewayer) EncryptWallet(wltID string, password []byte) (wallet.Wa
 	return r0, r1
 }
 
+// EncryptWalletWithCryptoType provides a mock function with given fields: wltID, password, cryptoType
+func (_m *MockGatewayer) EncryptWalletWithCryptoType(wltID string, password []byte, cryptoType crypto.CryptoType) (wallet.Wallet, error) {
+	ret := _m.Called(wltID, password, cryptoType)
+
+	var r0 wallet.Wallet
+	if rf, ok := ret.Get(0).(func(string, []byte, crypto.CryptoType) wallet.Wallet); ok {
+		r0 = rf(wltID, password, cryptoType)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(wallet.Wallet)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, []byte, crypto.CryptoType) error); ok {
+		r1 = rf(wltID, password, cryptoType)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetAllStorageValues provides a mock function with given fields: storageType
 func (_m *MockGatewayer) GetAllStorageValues(storageType kvstorage.Type) (map[string]string, error) {
 	ret := _m.Called(storageType)
@@ -1324,6 +1349,20 @@ func (_m *MockGatewayer) ScanWalletAddresses(wltID string, password []byte, num
 	return r0, r1
 }
 
+// SetAddressLabel provides a mock function with given fields: wltID, addr, label
+func (_m *MockGatewayer) SetAddressLabel(wltID string, addr cipher.Address, label string) error {
+	ret := _m.Called(wltID, addr, label)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, cipher.Address, string) error); ok {
+		r0 = rf(wltID, addr, label)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // StartedAt provides a mock function with given fields:
 func (_m *MockGatewayer) StartedAt() time.Time {
 	ret := _m.Called()
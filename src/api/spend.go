@@ -602,7 +602,7 @@ func walletCreateTransactionHandler(gateway Gatewayer) http.HandlerFunc {
 			switch err.(type) {
 			case wallet.Error:
 				switch err {
-				case wallet.ErrWalletAPIDisabled:
+				case wallet.ErrWalletAPIDisabled, wallet.ErrWalletReadOnly:
 					wh.Error403(w, "")
 				case wallet.ErrWalletNotExist:
 					wh.Error404(w, err.Error())
@@ -717,7 +717,7 @@ func walletSignTransactionHandler(gateway Gatewayer) http.HandlerFunc {
 				switch err {
 				case wallet.ErrWalletNotExist:
 					resp = NewHTTPErrorResponse(http.StatusNotFound, err.Error())
-				case wallet.ErrWalletAPIDisabled:
+				case wallet.ErrWalletAPIDisabled, wallet.ErrWalletReadOnly:
 					resp = NewHTTPErrorResponse(http.StatusForbidden, err.Error())
 				default:
 					resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
@@ -13,6 +13,7 @@ import (
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/cipher/bip39"
 	"github.com/skycoin/skycoin/src/cipher/bip44"
+	"github.com/skycoin/skycoin/src/cipher/crypto"
 	"github.com/skycoin/skycoin/src/readable"
 	wh "github.com/skycoin/skycoin/src/util/http"
 	"github.com/skycoin/skycoin/src/wallet"
@@ -47,6 +48,7 @@ func NewWalletResponse(w wallet.Wallet) (*WalletResponse, error) {
 	wr.Meta.Coin = w.Coin()
 	wr.Meta.Filename = w.Filename()
 	wr.Meta.Label = w.Label()
+	wr.Meta.Notes = w.Notes()
 	wr.Meta.Type = w.Type()
 	wr.Meta.Version = w.Version()
 	wr.Meta.CryptoType = w.CryptoType()
@@ -79,6 +81,7 @@ func NewWalletResponse(w wallet.Wallet) (*WalletResponse, error) {
 		wr.Entries[i] = readable.WalletEntry{
 			Address: e.Address.String(),
 			Public:  e.Public.Hex(),
+			Label:   e.Label,
 		}
 
 		switch w.Type() {
@@ -124,7 +127,7 @@ func walletBalanceHandler(gateway Gatewayer) http.HandlerFunc {
 			switch err {
 			case wallet.ErrWalletNotExist:
 				wh.Error404(w, "")
-			case wallet.ErrWalletAPIDisabled:
+			case wallet.ErrWalletAPIDisabled, wallet.ErrWalletReadOnly:
 				wh.Error403(w, "")
 			default:
 				wh.Error500(w, err.Error())
@@ -215,6 +218,8 @@ func balanceHandler(gateway Gatewayer) http.HandlerFunc {
 //     encrypt: bool value, whether encrypt the wallet [optional]
 //     password: password for encrypting wallet [optional, must be provided if "encrypt" is set]
 //     private-keys: private keys for generating addresses for collection wallets.[optional, multiple keys must be joined with commas]
+//     crypto-type: the encryption method [optional, defaults to the node's configured crypto type, only valid if "encrypt" is set]
+//     scrypt-n, scrypt-r, scrypt-p: scrypt parameters for encrypting the wallet [optional, must be set together, only valid if "encrypt" is set]
 func walletCreateHandler(gateway Gatewayer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -286,6 +291,31 @@ func walletCreateHandler(gateway Gatewayer) http.HandlerFunc {
 			bip44Coin = &c
 		}
 
+		scryptParams, err := parseScryptParams(r)
+		if err != nil {
+			wh.Error400(w, err.Error())
+			return
+		}
+
+		if scryptParams != nil && !encrypt {
+			wh.Error400(w, "scrypt parameters are only valid if encrypt is true")
+			return
+		}
+
+		var cryptoType crypto.CryptoType
+		if ct := r.FormValue("crypto-type"); ct != "" {
+			if !encrypt {
+				wh.Error400(w, "crypto-type is only valid if encrypt is true")
+				return
+			}
+
+			cryptoType, err = crypto.CryptoTypeFromString(ct)
+			if err != nil {
+				wh.Error400(w, fmt.Sprintf("invalid crypto-type: %v", err))
+				return
+			}
+		}
+
 		secKeys, err := wallet.ParsePrivateKeys(r.FormValue("private-keys"))
 		if err != nil {
 			wh.Error400(w, "invalid collection private keys")
@@ -302,6 +332,7 @@ func walletCreateHandler(gateway Gatewayer) http.HandlerFunc {
 			Label:                 label,
 			Encrypt:               encrypt,
 			Password:              []byte(password),
+			CryptoType:            cryptoType,
 			ScanN:                 scanN,
 			Type:                  walletType,
 			SeedPassphrase:        r.FormValue("seed-passphrase"),
@@ -309,12 +340,13 @@ func walletCreateHandler(gateway Gatewayer) http.HandlerFunc {
 			XPub:                  r.FormValue("xpub"),
 			TF:                    gateway.TransactionsFinder(),
 			CollectionPrivateKeys: secKeys,
+			ScryptParams:          scryptParams,
 		})
 		if err != nil {
 			switch err.(type) {
 			case wallet.Error:
 				switch err {
-				case wallet.ErrWalletAPIDisabled:
+				case wallet.ErrWalletAPIDisabled, wallet.ErrWalletReadOnly:
 					wh.Error403(w, "")
 					return
 				default:
@@ -336,6 +368,35 @@ func walletCreateHandler(gateway Gatewayer) http.HandlerFunc {
 	}
 }
 
+// parseScryptParams parses the scrypt-n, scrypt-r and scrypt-p form values.
+// Returns nil if none of them are set.
+func parseScryptParams(r *http.Request) (*wallet.ScryptParams, error) {
+	nStr := r.FormValue("scrypt-n")
+	rStr := r.FormValue("scrypt-r")
+	pStr := r.FormValue("scrypt-p")
+	if nStr == "" && rStr == "" && pStr == "" {
+		return nil, nil
+	}
+
+	if nStr == "" || rStr == "" || pStr == "" {
+		return nil, errors.New("scrypt-n, scrypt-r and scrypt-p must be set together")
+	}
+
+	var p wallet.ScryptParams
+	var err error
+	if p.N, err = strconv.Atoi(nStr); err != nil {
+		return nil, errors.New("invalid scrypt-n value")
+	}
+	if p.R, err = strconv.Atoi(rStr); err != nil {
+		return nil, errors.New("invalid scrypt-r value")
+	}
+	if p.P, err = strconv.Atoi(pStr); err != nil {
+		return nil, errors.New("invalid scrypt-p value")
+	}
+
+	return &p, nil
+}
+
 // Note: The wallet will not be saved to disk
 // Loads wallet from seed temporary in memory, will scan ahead N address and
 // load addresses till the last one that have coins.
@@ -420,7 +481,7 @@ func walletCreateTempHandler(gateway Gatewayer) http.HandlerFunc {
 			switch err.(type) {
 			case wallet.Error:
 				switch err {
-				case wallet.ErrWalletAPIDisabled:
+				case wallet.ErrWalletAPIDisabled, wallet.ErrWalletReadOnly:
 					wh.Error403(w, "")
 					return
 				default:
@@ -491,7 +552,7 @@ func walletNewAddressesHandler(gateway Gatewayer) http.HandlerFunc {
 		addrs, err := gateway.NewAddresses(wltID, []byte(password), opts...)
 		if err != nil {
 			switch err {
-			case wallet.ErrWalletAPIDisabled:
+			case wallet.ErrWalletAPIDisabled, wallet.ErrWalletReadOnly:
 				wh.Error403(w, "")
 			default:
 				wh.Error400(w, err.Error())
@@ -555,7 +616,7 @@ func walletScanAddressesHandler(gateway Gatewayer) http.HandlerFunc {
 		addrs, err := gateway.ScanWalletAddresses(wltID, []byte(password), n)
 		if err != nil {
 			switch err {
-			case wallet.ErrWalletAPIDisabled:
+			case wallet.ErrWalletAPIDisabled, wallet.ErrWalletReadOnly:
 				wh.Error403(w, "")
 			default:
 				wh.Error400(w, err.Error())
@@ -607,7 +668,59 @@ func walletUpdateHandler(gateway Gatewayer) http.HandlerFunc {
 			switch err {
 			case wallet.ErrWalletNotExist:
 				wh.Error404(w, "")
-			case wallet.ErrWalletAPIDisabled:
+			case wallet.ErrWalletAPIDisabled, wallet.ErrWalletReadOnly:
+				wh.Error403(w, "")
+			default:
+				wh.Error500(w, err.Error())
+			}
+			return
+		}
+
+		wh.SendJSONOr500(logger, w, "success")
+	}
+}
+
+// Set the label of a wallet address
+// URI: /api/v1/wallet/addressLabel
+// Method: POST
+// Args:
+//     id: wallet id [required]
+//     address: the wallet address to label [required]
+//     label: the label of the address [optional, an empty label clears it]
+func walletAddressLabelHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			wh.Error405(w)
+			return
+		}
+
+		wltID := r.FormValue("id")
+		if wltID == "" {
+			wh.Error400(w, "missing wallet id")
+			return
+		}
+
+		addrStr := r.FormValue("address")
+		if addrStr == "" {
+			wh.Error400(w, "missing address")
+			return
+		}
+
+		addr, err := cipher.DecodeBase58Address(addrStr)
+		if err != nil {
+			wh.Error400(w, fmt.Sprintf("invalid address: %v", err))
+			return
+		}
+
+		if err := gateway.SetAddressLabel(wltID, addr, r.FormValue("label")); err != nil {
+			logger.Errorf("set address label failed: %v", err)
+
+			switch err {
+			case wallet.ErrWalletNotExist:
+				wh.Error404(w, "")
+			case wallet.ErrEntryNotFound:
+				wh.Error400(w, "address not found in wallet")
+			case wallet.ErrWalletAPIDisabled, wallet.ErrWalletReadOnly:
 				wh.Error403(w, "")
 			default:
 				wh.Error500(w, err.Error())
@@ -640,7 +753,7 @@ func walletHandler(gateway Gatewayer) http.HandlerFunc {
 		wlt, err := gateway.GetWallet(wltID)
 		if err != nil {
 			switch err {
-			case wallet.ErrWalletAPIDisabled:
+			case wallet.ErrWalletAPIDisabled, wallet.ErrWalletReadOnly:
 				wh.Error403(w, "")
 			default:
 				wh.Error400(w, err.Error())
@@ -686,7 +799,7 @@ func walletTransactionsHandler(gateway Gatewayer) http.HandlerFunc {
 			case nil:
 			case wallet.ErrWalletNotExist:
 				wh.Error404(w, "")
-			case wallet.ErrWalletAPIDisabled:
+			case wallet.ErrWalletAPIDisabled, wallet.ErrWalletReadOnly:
 				wh.Error403(w, "")
 			default:
 				wh.Error500(w, err.Error())
@@ -748,7 +861,7 @@ func walletsHandler(gateway Gatewayer) http.HandlerFunc {
 		wlts, err := gateway.GetWallets()
 		if err != nil {
 			switch err {
-			case wallet.ErrWalletAPIDisabled:
+			case wallet.ErrWalletAPIDisabled, wallet.ErrWalletReadOnly:
 				wh.Error403(w, "")
 			default:
 				wh.Error500(w, err.Error())
@@ -793,7 +906,7 @@ func walletFolderHandler(s Walleter) http.HandlerFunc {
 		addr, err := s.WalletDir()
 		if err != nil {
 			switch err {
-			case wallet.ErrWalletAPIDisabled:
+			case wallet.ErrWalletAPIDisabled, wallet.ErrWalletReadOnly:
 				wh.Error403(w, "")
 			default:
 				wh.Error500(w, err.Error())
@@ -894,7 +1007,8 @@ func walletSeedHandler(gateway Gatewayer) http.HandlerFunc {
 			switch err {
 			case wallet.ErrMissingPassword,
 				wallet.ErrWalletNotEncrypted,
-				wallet.ErrInvalidPassword:
+				wallet.ErrInvalidPassword,
+				wallet.ErrWalletLocked:
 				wh.Error400(w, err.Error())
 			case wallet.ErrWalletAPIDisabled, wallet.ErrSeedAPIDisabled:
 				wh.Error403(w, "")
@@ -972,7 +1086,7 @@ func walletUnloadHandler(gateway Gatewayer) http.HandlerFunc {
 
 		if err := gateway.UnloadWallet(id); err != nil {
 			switch err {
-			case wallet.ErrWalletAPIDisabled:
+			case wallet.ErrWalletAPIDisabled, wallet.ErrWalletReadOnly:
 				wh.Error403(w, "")
 			default:
 				wh.Error500(w, err.Error())
@@ -987,6 +1101,7 @@ func walletUnloadHandler(gateway Gatewayer) http.HandlerFunc {
 // Args:
 //     id: wallet id
 //     password: wallet password
+//     crypto-type: [optional] encryption method, one of "sha256-xor", "scrypt-chacha20poly1305", "argon2id-chacha20poly1305"
 func walletEncryptHandler(gateway Gatewayer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -1005,15 +1120,29 @@ func walletEncryptHandler(gateway Gatewayer) http.HandlerFunc {
 			password = ""
 		}()
 
-		wlt, err := gateway.EncryptWallet(id, []byte(password))
+		var wlt wallet.Wallet
+		var err error
+		if ct := r.FormValue("crypto-type"); ct != "" {
+			var cryptoType crypto.CryptoType
+			cryptoType, err = crypto.CryptoTypeFromString(ct)
+			if err != nil {
+				wh.Error400(w, fmt.Sprintf("invalid crypto-type: %v", err))
+				return
+			}
+			wlt, err = gateway.EncryptWalletWithCryptoType(id, []byte(password), cryptoType)
+		} else {
+			wlt, err = gateway.EncryptWallet(id, []byte(password))
+		}
 		if err != nil {
 			switch err {
 			case wallet.ErrWalletEncrypted,
 				wallet.ErrMissingPassword,
+				wallet.ErrWeakPassword,
 				wallet.ErrEncryptTempWallet,
-				wallet.ErrInvalidPassword:
+				wallet.ErrInvalidPassword,
+				wallet.ErrWalletLocked:
 				wh.Error400(w, err.Error())
-			case wallet.ErrWalletAPIDisabled:
+			case wallet.ErrWalletAPIDisabled, wallet.ErrWalletReadOnly:
 				wh.Error403(w, "")
 			case wallet.ErrWalletNotExist:
 				wh.Error404(w, "")
@@ -1062,9 +1191,10 @@ func walletDecryptHandler(gateway Gatewayer) http.HandlerFunc {
 			switch err {
 			case wallet.ErrMissingPassword,
 				wallet.ErrWalletNotEncrypted,
-				wallet.ErrInvalidPassword:
+				wallet.ErrInvalidPassword,
+				wallet.ErrWalletLocked:
 				wh.Error400(w, err.Error())
-			case wallet.ErrWalletAPIDisabled:
+			case wallet.ErrWalletAPIDisabled, wallet.ErrWalletReadOnly:
 				wh.Error403(w, "")
 			case wallet.ErrWalletNotExist:
 				wh.Error404(w, "")
@@ -1149,7 +1279,7 @@ func walletRecoverHandler(gateway Gatewayer) http.HandlerFunc {
 				switch err {
 				case wallet.ErrWalletNotExist:
 					resp = NewHTTPErrorResponse(http.StatusNotFound, "")
-				case wallet.ErrWalletAPIDisabled:
+				case wallet.ErrWalletAPIDisabled, wallet.ErrSeedAPIDisabled, wallet.ErrWalletReadOnly:
 					resp = NewHTTPErrorResponse(http.StatusForbidden, "")
 				default:
 					resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
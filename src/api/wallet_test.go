@@ -300,7 +300,7 @@ func TestWalletGet(t *testing.T) {
 					Filename:   "test.wlt",
 					Type:       "deterministic",
 					Label:      "test",
-					Version:    "0.4",
+					Version:    "0.5",
 					CryptoType: "scrypt-chacha20poly1305",
 					Encrypted:  false,
 				},
@@ -641,6 +641,166 @@ func TestUpdateWalletLabelHandler(t *testing.T) {
 	}
 }
 
+func TestWalletAddressLabelHandler(t *testing.T) {
+	addr := testutil.MakeAddress()
+
+	type httpBody struct {
+		WalletID string
+		Address  string
+		Label    string
+	}
+
+	tt := []struct {
+		name                      string
+		method                    string
+		body                      *httpBody
+		status                    int
+		err                       string
+		gatewaySetAddressLabelErr error
+		responseBody              string
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			body:   &httpBody{},
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:   "400 - missing wallet id",
+			method: http.MethodPost,
+			body:   &httpBody{},
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - missing wallet id",
+		},
+		{
+			name:   "400 - missing address",
+			method: http.MethodPost,
+			body: &httpBody{
+				WalletID: "foo",
+			},
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - missing address",
+		},
+		{
+			name:   "400 - invalid address",
+			method: http.MethodPost,
+			body: &httpBody{
+				WalletID: "foo",
+				Address:  "bad",
+			},
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - invalid address: Invalid address length",
+		},
+		{
+			name:   "404 - gateway.SetAddressLabel ErrWalletNotExist",
+			method: http.MethodPost,
+			body: &httpBody{
+				WalletID: "foo",
+				Address:  addr.String(),
+				Label:    "label",
+			},
+			status:                    http.StatusNotFound,
+			err:                       "404 Not Found",
+			gatewaySetAddressLabelErr: wallet.ErrWalletNotExist,
+		},
+		{
+			name:   "400 - gateway.SetAddressLabel ErrEntryNotFound",
+			method: http.MethodPost,
+			body: &httpBody{
+				WalletID: "foo",
+				Address:  addr.String(),
+				Label:    "label",
+			},
+			status:                    http.StatusBadRequest,
+			err:                       "400 Bad Request - address not found in wallet",
+			gatewaySetAddressLabelErr: wallet.ErrEntryNotFound,
+		},
+		{
+			name:   "403 Forbidden - wallet API disabled",
+			method: http.MethodPost,
+			body: &httpBody{
+				WalletID: "foo",
+				Address:  addr.String(),
+				Label:    "label",
+			},
+			status:                    http.StatusForbidden,
+			err:                       "403 Forbidden",
+			gatewaySetAddressLabelErr: wallet.ErrWalletAPIDisabled,
+		},
+		{
+			name:   "500 - gateway.SetAddressLabel error",
+			method: http.MethodPost,
+			body: &httpBody{
+				WalletID: "foo",
+				Address:  addr.String(),
+				Label:    "label",
+			},
+			status:                    http.StatusInternalServerError,
+			err:                       "500 Internal Server Error - gateway.SetAddressLabel error",
+			gatewaySetAddressLabelErr: errors.New("gateway.SetAddressLabel error"),
+		},
+		{
+			name:   "200 OK",
+			method: http.MethodPost,
+			body: &httpBody{
+				WalletID: "foo",
+				Address:  addr.String(),
+				Label:    "label",
+			},
+			status:       http.StatusOK,
+			responseBody: "\"success\"",
+		},
+		{
+			name:   "200 OK - empty label",
+			method: http.MethodPost,
+			body: &httpBody{
+				WalletID: "foo",
+				Address:  addr.String(),
+			},
+			status:       http.StatusOK,
+			responseBody: "\"success\"",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("SetAddressLabel", tc.body.WalletID, addr, tc.body.Label).Return(tc.gatewaySetAddressLabelErr)
+
+			endpoint := "/api/v1/wallet/addressLabel"
+
+			v := url.Values{}
+			if tc.body.WalletID != "" {
+				v.Add("id", tc.body.WalletID)
+			}
+			if tc.body.Address != "" {
+				v.Add("address", tc.body.Address)
+			}
+			if tc.body.Label != "" {
+				v.Add("label", tc.body.Label)
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, strings.NewReader(v.Encode()))
+			require.NoError(t, err)
+			req.Header.Add("Content-Type", ContentTypeForm)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()))
+			} else {
+				require.Equal(t, tc.responseBody, rr.Body.String())
+			}
+		})
+	}
+}
+
 func TestWalletTransactionsHandler(t *testing.T) {
 	type httpBody struct {
 		walletID string
@@ -883,6 +1043,7 @@ func TestWalletCreateHandler(t *testing.T) {
 		SeedPassphrase string
 		Bip44Coin      string
 		XPub           string
+		CryptoType     string
 	}
 	tt := []struct {
 		name                      string
@@ -1121,7 +1282,7 @@ func TestWalletCreateHandler(t *testing.T) {
 					Label:      "test",
 					Filename:   "filename",
 					Type:       "deterministic",
-					Version:    "0.4",
+					Version:    "0.5",
 					CryptoType: "scrypt-chacha20poly1305",
 				},
 				Entries: responseEntries[:],
@@ -1165,7 +1326,7 @@ func TestWalletCreateHandler(t *testing.T) {
 					Label:      "test",
 					Filename:   "filename",
 					Type:       "deterministic",
-					Version:    "0.4",
+					Version:    "0.5",
 					CryptoType: "scrypt-chacha20poly1305",
 				},
 				Entries: responseEntries[:],
@@ -1207,7 +1368,7 @@ func TestWalletCreateHandler(t *testing.T) {
 					Label:      "test",
 					Filename:   "filename",
 					Type:       "deterministic",
-					Version:    "0.4",
+					Version:    "0.5",
 					CryptoType: "scrypt-chacha20poly1305",
 				},
 				Entries: responseEntries[:],
@@ -1301,6 +1462,77 @@ func TestWalletCreateHandler(t *testing.T) {
 			status: http.StatusBadRequest,
 			err:    "400 Bad Request - missing password",
 		},
+		{
+			name:   "200 - OK - Encrypted with crypto type",
+			method: http.MethodPost,
+			body: &httpBody{
+				Type:       wallet.WalletTypeDeterministic,
+				Seed:       "foo",
+				Label:      "bar",
+				Encrypt:    true,
+				Password:   "pwd",
+				ScanN:      "2",
+				CryptoType: string(crypto.CryptoTypeSha256Xor),
+			},
+			status:  http.StatusOK,
+			err:     "",
+			wltName: "filename",
+			options: wallet.Options{
+				Type:       wallet.WalletTypeDeterministic,
+				Label:      "bar",
+				Seed:       "foo",
+				Encrypt:    true,
+				Password:   []byte("pwd"),
+				CryptoType: crypto.CryptoTypeSha256Xor,
+				ScanN:      2,
+			},
+			gatewayCreateWalletResult: func(_ string, _ wallet.Options) wallet.Wallet {
+				return &deterministic.Wallet{
+					Meta: wallet.Meta{
+						"filename":   "filename",
+						"label":      "bar",
+						"encrypted":  "true",
+						"cryptoType": string(crypto.CryptoTypeSha256Xor),
+						"secrets":    "secrets",
+					},
+				}
+			},
+			responseBody: WalletResponse{
+				Meta: readable.WalletMeta{
+					Filename:   "filename",
+					Label:      "bar",
+					Encrypted:  true,
+					CryptoType: crypto.CryptoTypeSha256Xor,
+				},
+				Entries: []readable.WalletEntry{},
+			},
+		},
+		{
+			name:   "400 Bad request - crypto type without encrypt",
+			method: http.MethodPost,
+			body: &httpBody{
+				Type:       wallet.WalletTypeDeterministic,
+				Seed:       "foo",
+				Label:      "bar",
+				CryptoType: string(crypto.CryptoTypeSha256Xor),
+			},
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - crypto-type is only valid if encrypt is true",
+		},
+		{
+			name:   "400 Bad request - invalid crypto type",
+			method: http.MethodPost,
+			body: &httpBody{
+				Type:       wallet.WalletTypeDeterministic,
+				Seed:       "foo",
+				Label:      "bar",
+				Encrypt:    true,
+				Password:   "pwd",
+				CryptoType: "foo",
+			},
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - invalid crypto-type: unknown crypto type",
+		},
 	}
 
 	for _, tc := range tt {
@@ -1347,6 +1579,10 @@ func TestWalletCreateHandler(t *testing.T) {
 				if tc.body.XPub != "" {
 					v.Add("xpub", tc.body.XPub)
 				}
+
+				if tc.body.CryptoType != "" {
+					v.Add("crypto-type", tc.body.CryptoType)
+				}
 			}
 
 			req, err := http.NewRequest(tc.method, endpoint, strings.NewReader(v.Encode()))
@@ -2628,6 +2864,7 @@ func TestEncryptWallet(t *testing.T) {
 		method        string
 		wltID         string
 		password      string
+		cryptoType    string
 		gatewayReturn gatewayReturnPair
 		status        int
 		expectWallet  WalletResponse
@@ -2659,13 +2896,57 @@ func TestEncryptWallet(t *testing.T) {
 					Filename:   "wallet.wlt",
 					Label:      "test",
 					Type:       "deterministic",
-					Version:    "0.4",
+					Version:    "0.5",
 					CryptoType: "scrypt-chacha20poly1305",
 					Encrypted:  true,
 				},
 				Entries: responseEntries,
 			},
 		},
+		{
+			name:       "200 - OK with crypto type",
+			method:     http.MethodPost,
+			wltID:      "wallet.wlt",
+			password:   "pwd",
+			cryptoType: "argon2id-chacha20poly1305",
+			gatewayReturn: gatewayReturnPair{
+				w: func() wallet.Wallet {
+					wlt, err := deterministic.NewWallet(
+						"wallet.wlt",
+						"test",
+						"seed",
+						wallet.OptionPassword([]byte("pwd")),
+						wallet.OptionCryptoType(crypto.CryptoTypeArgon2idChacha20poly1305),
+						wallet.OptionGenerateN(5),
+						wallet.OptionEncrypt(true))
+					require.NoError(t, err)
+					wlt.SetTimestamp(0)
+					return wlt
+				}(),
+			},
+			status: http.StatusOK,
+			expectWallet: WalletResponse{
+				Meta: readable.WalletMeta{
+					Coin:       "skycoin",
+					Filename:   "wallet.wlt",
+					Label:      "test",
+					Type:       "deterministic",
+					Version:    "0.5",
+					CryptoType: "argon2id-chacha20poly1305",
+					Encrypted:  true,
+				},
+				Entries: responseEntries,
+			},
+		},
+		{
+			name:       "400 - Invalid Crypto Type",
+			method:     http.MethodPost,
+			wltID:      "wallet.wlt",
+			password:   "pwd",
+			cryptoType: "foo",
+			status:     http.StatusBadRequest,
+			expectErr:  "400 Bad Request - invalid crypto-type: unknown crypto type",
+		},
 		{
 			name:     "403 Forbidden",
 			method:   http.MethodPost,
@@ -2736,17 +3017,69 @@ func TestEncryptWallet(t *testing.T) {
 			status:    http.StatusBadRequest,
 			expectErr: "400 Bad Request - wallet is encrypted",
 		},
+		{
+			name:       "400 Bad Request - Invalid Password With Crypto Type",
+			method:     http.MethodPost,
+			wltID:      "wallet.wlt",
+			password:   "pwd",
+			cryptoType: "argon2id-chacha20poly1305",
+			gatewayReturn: gatewayReturnPair{
+				err: wallet.ErrInvalidPassword,
+			},
+			status:    http.StatusBadRequest,
+			expectErr: "400 Bad Request - invalid password",
+		},
+		{
+			name:       "400 - Wallet Is Encrypted With Crypto Type",
+			method:     http.MethodPost,
+			wltID:      "wallet.wlt",
+			password:   "pwd",
+			cryptoType: "argon2id-chacha20poly1305",
+			gatewayReturn: gatewayReturnPair{
+				err: wallet.ErrWalletEncrypted,
+			},
+			status:    http.StatusBadRequest,
+			expectErr: "400 Bad Request - wallet is encrypted",
+		},
+		{
+			name:       "404 - Wallet Not Found With Crypto Type",
+			method:     http.MethodPost,
+			wltID:      "wallet.wlt",
+			password:   "pwd",
+			cryptoType: "scrypt-chacha20poly1305",
+			gatewayReturn: gatewayReturnPair{
+				err: wallet.ErrWalletNotExist,
+			},
+			status:    http.StatusNotFound,
+			expectErr: "404 Not Found",
+		},
+		{
+			name:       "403 Forbidden With Crypto Type",
+			method:     http.MethodPost,
+			wltID:      "wallet.wlt",
+			password:   "pwd",
+			cryptoType: "scrypt-chacha20poly1305",
+			gatewayReturn: gatewayReturnPair{
+				err: wallet.ErrWalletAPIDisabled,
+			},
+			status:    http.StatusForbidden,
+			expectErr: "403 Forbidden",
+		},
 	}
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
 			gateway := &MockGatewayer{}
 			gateway.On("EncryptWallet", tc.wltID, []byte(tc.password)).Return(tc.gatewayReturn.w, tc.gatewayReturn.err)
+			gateway.On("EncryptWalletWithCryptoType", tc.wltID, []byte(tc.password), crypto.CryptoType(tc.cryptoType)).Return(tc.gatewayReturn.w, tc.gatewayReturn.err)
 
 			endpoint := "/api/v1/wallet/encrypt"
 			v := url.Values{}
 			v.Add("id", tc.wltID)
 			v.Add("password", tc.password)
+			if tc.cryptoType != "" {
+				v.Add("crypto-type", tc.cryptoType)
+			}
 
 			req, err := http.NewRequest(tc.method, endpoint, strings.NewReader(v.Encode()))
 			require.NoError(t, err)
@@ -2822,7 +3155,7 @@ func TestDecryptWallet(t *testing.T) {
 					Filename:   "wallet",
 					Label:      "filename",
 					Type:       "deterministic",
-					Version:    "0.4",
+					Version:    "0.5",
 					CryptoType: "scrypt-chacha20poly1305",
 					Encrypted:  false,
 				},
@@ -2844,7 +3177,7 @@ func TestDecryptWallet(t *testing.T) {
 					Filename:   "wallet",
 					Label:      "filename",
 					Type:       "deterministic",
-					Version:    "0.4",
+					Version:    "0.5",
 					CryptoType: "scrypt-chacha20poly1305",
 					Encrypted:  false,
 				},
@@ -3140,6 +3473,20 @@ func TestWalletRecover(t *testing.T) {
 			},
 			httpResponse: NewHTTPErrorResponse(http.StatusForbidden, ""),
 		},
+		{
+			name:        "wallet seed api disabled",
+			method:      http.MethodPost,
+			status:      http.StatusForbidden,
+			contentType: ContentTypeJSON,
+			req: &WalletRecoverRequest{
+				ID:   "foo",
+				Seed: "fooseed",
+			},
+			gatewayReturn: gatewayReturnPair{
+				err: wallet.ErrSeedAPIDisabled,
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusForbidden, ""),
+		},
 		{
 			name:        "wallet other error",
 			method:      http.MethodPost,
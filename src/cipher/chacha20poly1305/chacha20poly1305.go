@@ -51,14 +51,15 @@ func (c *chacha20poly1305) Seal(dst, nonce, plaintext, additionalData []byte) []
 	return c.seal(dst, nonce, plaintext, additionalData)
 }
 
-var errOpen = errors.New("chacha20poly1305: message authentication failed")
+// ErrOpen is returned by Open if the message authentication fails, e.g. because of a wrong key
+var ErrOpen = errors.New("chacha20poly1305: message authentication failed")
 
 func (c *chacha20poly1305) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
 	if len(nonce) != NonceSize {
 		panic("chacha20poly1305: bad nonce length passed to Open")
 	}
 	if len(ciphertext) < 16 {
-		return nil, errOpen
+		return nil, ErrOpen
 	}
 	if uint64(len(ciphertext)) > (1<<38)-48 {
 		panic("chacha20poly1305: ciphertext too large")
@@ -120,7 +120,7 @@ func (c *chacha20poly1305) open(dst, nonce, ciphertext, additionalData []byte) (
 		for i := range out {
 			out[i] = 0
 		}
-		return nil, errOpen
+		return nil, ErrOpen
 	}
 
 	return ret, nil
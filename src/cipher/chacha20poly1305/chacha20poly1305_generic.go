@@ -61,7 +61,7 @@ func (c *chacha20poly1305) openGeneric(dst, nonce, ciphertext, additionalData []
 		for i := range out {
 			out[i] = 0
 		}
-		return nil, errOpen
+		return nil, ErrOpen
 	}
 
 	counter[0] = 1
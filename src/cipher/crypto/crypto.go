@@ -25,6 +25,8 @@ func CryptoTypeFromString(s string) (CryptoType, error) {
 		return CryptoTypeScryptChacha20poly1305, nil
 	case CryptoTypeScryptChacha20poly1305Insecure:
 		return CryptoTypeScryptChacha20poly1305Insecure, nil
+	case CryptoTypeArgon2idChacha20poly1305:
+		return CryptoTypeArgon2idChacha20poly1305, nil
 	default:
 		return "", errors.New("unknown crypto type")
 	}
@@ -38,6 +40,8 @@ const (
 	CryptoTypeScryptChacha20poly1305 = CryptoType("scrypt-chacha20poly1305")
 	// CryptoTypeScryptChacha20poly1305Insecure uses chacha20poly1305 + scrypt key derivation with a weak work factor (unsafe)
 	CryptoTypeScryptChacha20poly1305Insecure = CryptoType("scrypt-chacha20poly1305-insecure")
+	// CryptoTypeArgon2idChacha20poly1305 uses chacha20poly1305 + argon2id key derivation
+	CryptoTypeArgon2idChacha20poly1305 = CryptoType("argon2id-chacha20poly1305")
 
 	// DefaultCryptoType is the default CryptoType used
 	DefaultCryptoType = CryptoTypeScryptChacha20poly1305
@@ -54,6 +58,7 @@ var cryptoTable = map[CryptoType]Cryptor{
 		P:      encrypt.ScryptP,
 		KeyLen: encrypt.ScryptKeyLen,
 	},
+	CryptoTypeArgon2idChacha20poly1305: encrypt.DefaultArgon2idChacha20poly1305,
 }
 
 // GetCrypto gets crypto of given type
@@ -72,6 +77,7 @@ func Types() []CryptoType {
 		CryptoTypeSha256Xor,
 		CryptoTypeScryptChacha20poly1305,
 		CryptoTypeScryptChacha20poly1305Insecure,
+		CryptoTypeArgon2idChacha20poly1305,
 		DefaultCryptoType,
 	}
 }
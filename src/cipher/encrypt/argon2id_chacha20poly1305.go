@@ -0,0 +1,198 @@
+package encrypt
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+
+	"golang.org/x/crypto/argon2"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/chacha20poly1305"
+)
+
+const (
+	argon2idChacha20MetaLengthSize = 2  // meta data length field size in bytes
+	argon2idChacha20SaltSize       = 32 // salt bytes number
+)
+
+// Default argon2id paramenters
+const (
+	// Argon2idTime: argon2id number of passes over the memory. 1 is recommended when the memory is large.
+	Argon2idTime = 1
+	// Argon2idMemory: argon2id memory size in KiB. 64 MiB is the recommended value for argon2id.
+	Argon2idMemory = 64 * 1024
+	// Argon2idThreads: argon2id number of threads.
+	Argon2idThreads = 4
+	// Argon2idKeyLen: The length of returned byte slice that can be used as cryptographic key.
+	Argon2idKeyLen = 32
+)
+
+// Maximum argon2id parameters. The parameters of the data to decrypt are read from its metadata,
+// so they are bounded to keep crafted data from making argon2id use unbounded memory or time.
+const (
+	// Argon2idMaxTime: maximum number of passes over the memory
+	Argon2idMaxTime = 10
+	// Argon2idMaxMemory: maximum memory size in KiB, 256 MiB
+	Argon2idMaxMemory = 256 * 1024
+	// Argon2idMaxThreads: maximum number of threads
+	Argon2idMaxThreads = 16
+)
+
+// DefaultArgon2idChacha20poly1305 default Argon2idChacha20poly1305 encryptor
+var DefaultArgon2idChacha20poly1305 = Argon2idChacha20poly1305{
+	Time:    Argon2idTime,
+	Memory:  Argon2idMemory,
+	Threads: Argon2idThreads,
+	KeyLen:  Argon2idKeyLen,
+}
+
+// Argon2idChacha20poly1305 provides methods for encryption/decryption with argon2id and chacha20poly1305
+type Argon2idChacha20poly1305 struct {
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+	KeyLen  uint32
+}
+
+type argon2idMeta struct {
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+	KeyLen  uint32 `json:"keyLen"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+}
+
+// validateArgon2idParams checks that the argon2id parameters are set, not above the maximums,
+// and derive a chacha20poly1305 key
+func validateArgon2idParams(time, memory uint32, threads uint8, keyLen uint32) error {
+	if time == 0 || threads == 0 || keyLen == 0 {
+		return errors.New("invalid argon2id paramenters")
+	}
+
+	if time > Argon2idMaxTime || memory > Argon2idMaxMemory || threads > Argon2idMaxThreads {
+		return errors.New("argon2id paramenters exceed the maximums")
+	}
+
+	if keyLen != chacha20poly1305.KeySize {
+		return errors.New("invalid argon2id key length")
+	}
+
+	return nil
+}
+
+// Encrypt encrypts data with password,
+// 1. Argon2id derives the key from password
+// 2. Chacha20poly1305 generates AEAD from the derived key
+// 3. Puts argon2id paramenters, salt and nonce into metadata, json serialize it and get the serialized metadata length
+// 4. AEAD.Seal encrypts the data, and use [length][metadata] as additional data
+// 5. Final format: base64([[length][metadata]][ciphertext]), length is 2 bytes.
+func (a Argon2idChacha20poly1305) Encrypt(data, password []byte) ([]byte, error) {
+	if len(password) == 0 {
+		return nil, errors.New("missing password")
+	}
+
+	if err := validateArgon2idParams(a.Time, a.Memory, a.Threads, a.KeyLen); err != nil {
+		return nil, err
+	}
+
+	// Argon2id derives key from password
+	salt := cipher.RandByte(argon2idChacha20SaltSize)
+	dk := argon2.IDKey(password, salt, a.Time, a.Memory, a.Threads, a.KeyLen)
+
+	// Prepare metadata
+	m := argon2idMeta{
+		Time:    a.Time,
+		Memory:  a.Memory,
+		Threads: a.Threads,
+		KeyLen:  a.KeyLen,
+		Salt:    salt,
+		Nonce:   cipher.RandByte(chacha20poly1305.NonceSize),
+	}
+	// json serialize the metadata
+	ms, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ms) > math.MaxUint16 {
+		return nil, errors.New("metadata length beyond the math.MaxUint16")
+	}
+
+	length := make([]byte, argon2idChacha20MetaLengthSize)
+	binary.LittleEndian.PutUint16(length, uint16(len(ms)))
+
+	// Additional data for AEAD
+	ad := append(length, ms...)
+	aead, err := chacha20poly1305.New(dk)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext := aead.Seal(nil, m.Nonce, data, ad)
+
+	// Base64 encode the [[length][metadata]][ciphertext]
+	rawData := append(ad, ciphertext...)
+	enc := base64.StdEncoding
+	buf := make([]byte, enc.EncodedLen(len(rawData)))
+	enc.Encode(buf, rawData)
+	return buf, nil
+}
+
+// Decrypt decrypts the data with password
+// 1. Base64 decodes the data
+// 2. Reads the first [metaLengthSize] bytes data to get the metadata length, and reads out the metadata.
+// 3. Argon2id derives key from password and paramenters in metadata
+// 4. Chacha20poly1305 geneates AEAD
+// 5. AEAD decrypts ciphertext with nonce in metadata and [length][metadata] as additional data.
+func (a Argon2idChacha20poly1305) Decrypt(data, password []byte) ([]byte, error) {
+	if len(password) == 0 {
+		return nil, errors.New("missing password")
+	}
+
+	enc := base64.StdEncoding
+	encData := make([]byte, enc.DecodedLen(len(data)))
+	n, err := enc.Decode(encData, data)
+	if err != nil {
+		return nil, err
+	}
+	encData = encData[:n]
+
+	if len(encData) < argon2idChacha20MetaLengthSize {
+		return nil, errors.New("invalid data length")
+	}
+
+	// The length is converted to int before the addition, which could overflow uint16
+	metaEnd := argon2idChacha20MetaLengthSize + int(binary.LittleEndian.Uint16(encData[:argon2idChacha20MetaLengthSize]))
+	if metaEnd > len(encData) {
+		return nil, errors.New("invalid metadata length")
+	}
+
+	var m argon2idMeta
+	if err := json.Unmarshal(encData[argon2idChacha20MetaLengthSize:metaEnd], &m); err != nil {
+		return nil, err
+	}
+
+	if err := validateArgon2idParams(m.Time, m.Memory, m.Threads, m.KeyLen); err != nil {
+		return nil, err
+	}
+
+	if len(m.Nonce) != chacha20poly1305.NonceSize {
+		return nil, errors.New("invalid nonce length")
+	}
+
+	ad := encData[:metaEnd]
+	// Argon2id derives key
+	dk := argon2.IDKey(password, m.Salt, m.Time, m.Memory, m.Threads, m.KeyLen)
+
+	// Geneates AEAD
+	aead, err := chacha20poly1305.New(dk)
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Open(nil, m.Nonce, encData[metaEnd:], ad)
+}
@@ -0,0 +1,163 @@
+package encrypt
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestArgon2idChacha20poly1305EncryptDecrypt(t *testing.T) {
+	crypto := Argon2idChacha20poly1305{Time: 1, Memory: 64, Threads: 1, KeyLen: 32}
+	encData, err := crypto.Encrypt([]byte("plaintext"), []byte("password"))
+	require.NoError(t, err)
+
+	data, err := base64.StdEncoding.DecodeString(string(encData))
+	require.NoError(t, err)
+	// Checks the prefix
+	ml := binary.LittleEndian.Uint16(data[:argon2idChacha20MetaLengthSize])
+	require.True(t, int(argon2idChacha20MetaLengthSize+ml) <= len(data))
+	var m argon2idMeta
+	require.NoError(t, json.Unmarshal(data[argon2idChacha20MetaLengthSize:argon2idChacha20MetaLengthSize+ml], &m))
+	require.Equal(t, uint32(1), m.Time)
+	require.Equal(t, uint32(64), m.Memory)
+	require.Equal(t, uint8(1), m.Threads)
+	require.Equal(t, uint32(32), m.KeyLen)
+	require.Len(t, m.Salt, argon2idChacha20SaltSize)
+
+	// The paramenters are read from the metadata, not from the decryptor
+	plain, err := DefaultArgon2idChacha20poly1305.Decrypt(encData, []byte("password"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("plaintext"), plain)
+
+	_, err = crypto.Decrypt(encData, []byte("wrong password"))
+	require.Error(t, err)
+
+	_, err = crypto.Decrypt(encData, nil)
+	require.EqualError(t, err, "missing password")
+
+	_, err = crypto.Encrypt([]byte("plaintext"), nil)
+	require.EqualError(t, err, "missing password")
+
+	_, err = Argon2idChacha20poly1305{}.Encrypt([]byte("plaintext"), []byte("password"))
+	require.EqualError(t, err, "invalid argon2id paramenters")
+
+	// Data encrypted with scrypt can't be decrypted with argon2id
+	scryptData, err := ScryptChacha20poly1305{N: 1 << 2, R: 8, P: 1, KeyLen: 32}.Encrypt([]byte("plaintext"), []byte("password"))
+	require.NoError(t, err)
+	_, err = crypto.Decrypt(scryptData, []byte("password"))
+	require.Error(t, err)
+}
+
+func TestArgon2idChacha20poly1305DecryptInvalidData(t *testing.T) {
+	encode := func(length uint16, meta, ciphertext []byte) []byte {
+		data := make([]byte, argon2idChacha20MetaLengthSize)
+		binary.LittleEndian.PutUint16(data, length)
+		data = append(data, meta...)
+		data = append(data, ciphertext...)
+		return []byte(base64.StdEncoding.EncodeToString(data))
+	}
+
+	makeMeta := func(m argon2idMeta) []byte {
+		ms, err := json.Marshal(m)
+		require.NoError(t, err)
+		return ms
+	}
+
+	validMeta := argon2idMeta{
+		Time:    1,
+		Memory:  64,
+		Threads: 1,
+		KeyLen:  32,
+		Salt:    make([]byte, argon2idChacha20SaltSize),
+		Nonce:   make([]byte, 12),
+	}
+
+	tt := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{
+			name: "metadata length overflows uint16",
+			data: encode(65535, []byte("{}"), nil),
+			err:  "invalid metadata length",
+		},
+		{
+			name: "metadata length beyond the data",
+			data: encode(65534, []byte("{}"), nil),
+			err:  "invalid metadata length",
+		},
+		{
+			name: "memory above the maximum",
+			data: func() []byte {
+				m := validMeta
+				m.Memory = Argon2idMaxMemory + 1
+				ms := makeMeta(m)
+				return encode(uint16(len(ms)), ms, make([]byte, 32))
+			}(),
+			err: "argon2id paramenters exceed the maximums",
+		},
+		{
+			name: "time above the maximum",
+			data: func() []byte {
+				m := validMeta
+				m.Time = Argon2idMaxTime + 1
+				ms := makeMeta(m)
+				return encode(uint16(len(ms)), ms, make([]byte, 32))
+			}(),
+			err: "argon2id paramenters exceed the maximums",
+		},
+		{
+			name: "threads above the maximum",
+			data: func() []byte {
+				m := validMeta
+				m.Threads = Argon2idMaxThreads + 1
+				ms := makeMeta(m)
+				return encode(uint16(len(ms)), ms, make([]byte, 32))
+			}(),
+			err: "argon2id paramenters exceed the maximums",
+		},
+		{
+			name: "invalid key length",
+			data: func() []byte {
+				m := validMeta
+				m.KeyLen = 1 << 30
+				ms := makeMeta(m)
+				return encode(uint16(len(ms)), ms, make([]byte, 32))
+			}(),
+			err: "invalid argon2id key length",
+		},
+		{
+			name: "invalid nonce length",
+			data: func() []byte {
+				m := validMeta
+				m.Nonce = []byte{1}
+				ms := makeMeta(m)
+				return encode(uint16(len(ms)), ms, make([]byte, 32))
+			}(),
+			err: "invalid nonce length",
+		},
+		{
+			name: "authentication fails",
+			data: func() []byte {
+				ms := makeMeta(validMeta)
+				return encode(uint16(len(ms)), ms, make([]byte, 32))
+			}(),
+			err: "chacha20poly1305: message authentication failed",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := DefaultArgon2idChacha20poly1305.Decrypt(tc.data, []byte("password"))
+			require.EqualError(t, err, tc.err)
+		})
+	}
+
+	// Encrypt rejects the parameters that Decrypt would reject
+	_, err := Argon2idChacha20poly1305{Time: 1, Memory: Argon2idMaxMemory + 1, Threads: 1, KeyLen: 32}.Encrypt([]byte("plaintext"), []byte("password"))
+	require.EqualError(t, err, "argon2id paramenters exceed the maximums")
+}
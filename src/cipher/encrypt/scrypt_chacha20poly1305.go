@@ -4,6 +4,7 @@ Package encrypt provides encryption methods.
 Encryption methods provided:
 
 * chacha20-poly1305 with scrypt key derivation
+* chacha20-poly1305 with argon2id key derivation
 * sha256xor with sha256 key derivation
 
 The latter is insecure due to the insecure key derivation so should not be used.
@@ -43,6 +44,15 @@ const (
 	ScryptKeyLen = 32
 )
 
+// Maximum scrypt parameters. The parameters of the data to decrypt are read from its metadata,
+// so they are bounded to keep crafted data from making scrypt use unbounded memory or time.
+const (
+	// ScryptMaxNR: maximum product of N and r, the memory used by scrypt is 128*N*r bytes
+	ScryptMaxNR = ScryptN * ScryptR
+	// ScryptMaxP: maximum p paramenter
+	ScryptMaxP = 16
+)
+
 // DefaultScryptChacha20poly1305 default ScryptChacha20poly1305 encryptor
 var DefaultScryptChacha20poly1305 = ScryptChacha20poly1305{
 	N:      ScryptN,
@@ -68,6 +78,20 @@ type meta struct {
 	Nonce  []byte `json:"nonce"`
 }
 
+// validateScryptParams checks that the scrypt parameters are not above the maximums
+// and derive a chacha20poly1305 key. The other checks are left to scrypt.Key
+func validateScryptParams(n, r, p, keyLen int) error {
+	if n > 0 && r > 0 && (n > ScryptMaxNR || r > ScryptMaxNR/n) || p > ScryptMaxP {
+		return errors.New("scrypt paramenters exceed the maximums")
+	}
+
+	if keyLen != chacha20poly1305.KeySize {
+		return errors.New("invalid scrypt key length")
+	}
+
+	return nil
+}
+
 // Encrypt encrypts data with password,
 // 1. Scrypt derives the key from password
 // 2. Chacha20poly1305 generates AEAD from the derived key
@@ -144,17 +168,30 @@ func (s ScryptChacha20poly1305) Decrypt(data, password []byte) ([]byte, error) {
 	}
 	encData = encData[:n]
 
-	length := binary.LittleEndian.Uint16(encData[:scryptChacha20MetaLengthSize])
-	if int(scryptChacha20MetaLengthSize+length) > len(encData) {
+	if len(encData) < scryptChacha20MetaLengthSize {
+		return nil, errors.New("invalid data length")
+	}
+
+	// The length is converted to int before the addition, which could overflow uint16
+	metaEnd := scryptChacha20MetaLengthSize + int(binary.LittleEndian.Uint16(encData[:scryptChacha20MetaLengthSize]))
+	if metaEnd > len(encData) {
 		return nil, errors.New("invalid metadata length")
 	}
 
 	var m meta
-	if err := json.Unmarshal(encData[scryptChacha20MetaLengthSize:scryptChacha20MetaLengthSize+length], &m); err != nil {
+	if err := json.Unmarshal(encData[scryptChacha20MetaLengthSize:metaEnd], &m); err != nil {
+		return nil, err
+	}
+
+	if err := validateScryptParams(m.N, m.R, m.P, m.KeyLen); err != nil {
 		return nil, err
 	}
 
-	ad := encData[:scryptChacha20MetaLengthSize+length]
+	if len(m.Nonce) != chacha20poly1305.NonceSize {
+		return nil, errors.New("invalid nonce length")
+	}
+
+	ad := encData[:metaEnd]
 	// Scrypt derives key
 	dk, err := scrypt.Key(password, m.Salt, m.N, m.R, m.P, m.KeyLen)
 	if err != nil {
@@ -167,5 +204,5 @@ func (s ScryptChacha20poly1305) Decrypt(data, password []byte) ([]byte, error) {
 		return nil, err
 	}
 
-	return aead.Open(nil, m.Nonce, encData[scryptChacha20MetaLengthSize+length:], ad)
+	return aead.Open(nil, m.Nonce, encData[metaEnd:], ad)
 }
@@ -83,3 +83,68 @@ func TestScryptChacha20poly1305Decrypt(t *testing.T) {
 		})
 	}
 }
+
+func TestScryptChacha20poly1305DecryptInvalidData(t *testing.T) {
+	encode := func(length uint16, meta []byte) []byte {
+		data := make([]byte, scryptChacha20MetaLengthSize)
+		binary.LittleEndian.PutUint16(data, length)
+		data = append(data, meta...)
+		data = append(data, make([]byte, 32)...)
+		return []byte(base64.StdEncoding.EncodeToString(data))
+	}
+
+	encodeMeta := func(n, r, p, keyLen int) []byte {
+		ms, err := json.Marshal(meta{N: n, R: r, P: p, KeyLen: keyLen, Nonce: make([]byte, 12)})
+		require.NoError(t, err)
+		return encode(uint16(len(ms)), ms)
+	}
+
+	tt := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{
+			name: "too short",
+			data: []byte(base64.StdEncoding.EncodeToString([]byte{1})),
+			err:  "invalid data length",
+		},
+		{
+			name: "metadata length overflows uint16",
+			data: encode(65535, []byte("{}")),
+			err:  "invalid metadata length",
+		},
+		{
+			name: "memory above the maximum",
+			data: encodeMeta(1<<21, 8, 1, 32),
+			err:  "scrypt paramenters exceed the maximums",
+		},
+		{
+			name: "r above the maximum",
+			data: encodeMeta(1<<2, ScryptMaxNR, 1, 32),
+			err:  "scrypt paramenters exceed the maximums",
+		},
+		{
+			name: "p above the maximum",
+			data: encodeMeta(1<<2, 8, ScryptMaxP+1, 32),
+			err:  "scrypt paramenters exceed the maximums",
+		},
+		{
+			name: "invalid key length",
+			data: encodeMeta(1<<2, 8, 1, 1<<30),
+			err:  "invalid scrypt key length",
+		},
+		{
+			name: "authentication fails",
+			data: encodeMeta(1<<2, 8, 1, 32),
+			err:  "chacha20poly1305: message authentication failed",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ScryptChacha20poly1305{}.Decrypt(tc.data, []byte("password"))
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
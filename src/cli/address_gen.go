@@ -80,10 +80,14 @@ func addressGenCmd() *cobra.Command {
 				}
 
 				var err error
-				password, err = PasswordFromTerm{}.Password()
+				password, err = PasswordFromTermWithConfirm{}.Password()
 				if err != nil {
 					return err
 				}
+
+				if err := wallet.CheckPasswordStrength(password, cliConfig.MinPasswordLength); err != nil {
+					return err
+				}
 			}
 
 			w, err := wallet.NewWallet(wallet.NewWalletFilename(), label, seed, wallet.Options{
@@ -7,12 +7,14 @@ REST API to query a skycoin node's status.
 package cli
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"net/url"
 	"path/filepath"
+	"strconv"
 	"syscall"
 
 	"os"
@@ -44,7 +46,8 @@ var (
     RPC_USER: Username for RPC API, if enabled in the RPC.
     RPC_PASS: Password for RPC API, if enabled in the RPC.
     COIN: Name of the coin. Default "%s"
-    DATA_DIR: Directory where everything is stored. Default "%s"`, defaultRPCAddress, defaultCoin, defaultDataDir)
+    DATA_DIR: Directory where everything is stored. Default "%s"
+    MIN_PASSWORD_LENGTH: Minimum length of new wallet passwords. Disabled if not set`, defaultRPCAddress, defaultCoin, defaultDataDir)
 
 	helpTemplate = fmt.Sprintf(`USAGE:{{if .Runnable}}
   {{.UseLine}}{{end}}{{if .HasAvailableSubCommands}}
@@ -79,6 +82,8 @@ Use "{{.CommandPath}} [command] --help" for more information about a command.{{e
 	ErrAddress = errors.New("invalid address")
 	// ErrJSONMarshal is returned if JSON marshaling failed
 	ErrJSONMarshal = errors.New("json marshal failed")
+	// ErrPasswordMismatch is returned if the password and its confirmation differ
+	ErrPasswordMismatch = errors.New("passwords do not match")
 )
 
 var (
@@ -94,6 +99,8 @@ type Config struct {
 	RPCAddress  string `json:"rpc_address"`
 	RPCUsername string `json:"-"`
 	RPCPassword string `json:"-"`
+	// MinPasswordLength is the minimum length of new wallet passwords, disabled if 0
+	MinPasswordLength int `json:"min_password_length"`
 }
 
 // LoadConfig loads config from environment, prior to parsing CLI flags
@@ -132,12 +139,22 @@ func LoadConfig() (Config, error) {
 		return Config{}, errors.New("the envvar WALLET_NAME is no longer recognized by the CLI tool. Please review the updated CLI docs to learn how to specify the wallet file for your desired action")
 	}
 
+	var minPasswordLength int
+	if v := os.Getenv("MIN_PASSWORD_LENGTH"); v != "" {
+		var err error
+		minPasswordLength, err = strconv.Atoi(v)
+		if err != nil || minPasswordLength < 0 {
+			return Config{}, errors.New("MIN_PASSWORD_LENGTH must be a non-negative integer")
+		}
+	}
+
 	return Config{
-		DataDir:     dataDir,
-		Coin:        coin,
-		RPCAddress:  rpcAddr,
-		RPCUsername: rpcUser,
-		RPCPassword: rpcPass,
+		DataDir:           dataDir,
+		Coin:              coin,
+		RPCAddress:        rpcAddr,
+		RPCUsername:       rpcUser,
+		RPCPassword:       rpcPass,
+		MinPasswordLength: minPasswordLength,
 	}, nil
 }
 
@@ -193,11 +210,13 @@ func NewCLI(cfg Config) (*cobra.Command, error) {
 		decryptWalletCmd(),
 		encryptWalletCmd(),
 		lastBlocksCmd(),
+		importAddressLabelsCmd(),
 		listAddressesCmd(),
 		listWalletsCmd(),
 		sendCmd(),
 		showConfigCmd(),
 		showSeedCmd(),
+		showWalletCmd(),
 		statusCmd(),
 		transactionCmd(),
 		verifyTransactionCmd(),
@@ -255,8 +274,12 @@ func printJSON(obj interface{}) error {
 
 // readPasswordFromTerminal promotes user to enter password and read it.
 func readPasswordFromTerminal() ([]byte, error) {
-	// Promotes to enter the wallet password
-	fmt.Fprint(os.Stdout, "enter password:")
+	return readPasswordWithPrompt("enter password:")
+}
+
+// readPasswordWithPrompt prints the prompt and reads the password from terminal
+func readPasswordWithPrompt(prompt string) ([]byte, error) {
+	fmt.Fprint(os.Stdout, prompt)
 	bp, err := terminal.ReadPassword(int(syscall.Stdin)) //nolint:unconvert
 	if err != nil {
 		return nil, err
@@ -312,6 +335,29 @@ func (p PasswordFromTerm) Password() ([]byte, error) {
 	return v, nil
 }
 
+// PasswordFromTermWithConfirm reads a new password from terminal twice,
+// so that a typo can't produce a wallet that can't be decrypted
+type PasswordFromTermWithConfirm struct{}
+
+// Password implements the PasswordReader's Password method
+func (p PasswordFromTermWithConfirm) Password() ([]byte, error) {
+	v, err := readPasswordFromTerminal()
+	if err != nil {
+		return nil, err
+	}
+
+	confirm, err := readPasswordWithPrompt("confirm password:")
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(v, confirm) {
+		return nil, ErrPasswordMismatch
+	}
+
+	return v, nil
+}
+
 // NewPasswordReader creats a PasswordReader instance,
 // reads password from the input bytes first, if it's empty, then read from terminal.
 func NewPasswordReader(p []byte) PasswordReader {
@@ -321,3 +367,13 @@ func NewPasswordReader(p []byte) PasswordReader {
 
 	return PasswordFromTerm{}
 }
+
+// NewConfirmedPasswordReader creates a PasswordReader for a new password,
+// reads password from the input bytes first, if it's empty, then read it twice from terminal.
+func NewConfirmedPasswordReader(p []byte) PasswordReader {
+	if len(p) != 0 {
+		return PasswordFromBytes(p)
+	}
+
+	return PasswordFromTermWithConfirm{}
+}
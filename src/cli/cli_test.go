@@ -69,4 +69,21 @@ func TestLoadConfig(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, cfg.DataDir, val)
 	})
+
+	t.Run("set MIN_PASSWORD_LENGTH", func(t *testing.T) {
+		os.Setenv("MIN_PASSWORD_LENGTH", "8")
+		defer os.Unsetenv("MIN_PASSWORD_LENGTH")
+
+		cfg, err := LoadConfig()
+		require.NoError(t, err)
+		require.Equal(t, 8, cfg.MinPasswordLength)
+	})
+
+	t.Run("set MIN_PASSWORD_LENGTH invalid", func(t *testing.T) {
+		os.Setenv("MIN_PASSWORD_LENGTH", "-1")
+		defer os.Unsetenv("MIN_PASSWORD_LENGTH")
+
+		_, err := LoadConfig()
+		testutil.RequireError(t, err, "MIN_PASSWORD_LENGTH must be a non-negative integer")
+	})
 }
@@ -1,8 +1,11 @@
 package cli
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
 
+	"github.com/skycoin/skycoin/src/cipher/crypto"
 	"github.com/skycoin/skycoin/src/wallet"
 )
 
@@ -17,21 +20,33 @@ func encryptWalletCmd() *cobra.Command {
     Use caution when using the "-p" command. If you have command history enabled
     your wallet encryption password can be recovered from the history log. If you
     do not include the "-p" option you will be prompted to enter your password
-    after you enter your command.`,
+    after you enter your command, and asked to enter it again to confirm it.
+
+    Use the "-x" option to choose the encryption method, one of
+    "sha256-xor", "scrypt-chacha20poly1305" or "argon2id-chacha20poly1305".
+    If not set, the wallet's current encryption method is used.`,
 		SilenceUsage: true,
 		RunE: func(c *cobra.Command, args []string) error {
 			w := args[0]
-			pr := NewPasswordReader([]byte(c.Flag("password").Value.String()))
+			pr := NewConfirmedPasswordReader([]byte(c.Flag("password").Value.String()))
+
+			cryptoType := c.Flag("crypto-type").Value.String()
+			if cryptoType != "" {
+				if _, err := crypto.CryptoTypeFromString(cryptoType); err != nil {
+					return fmt.Errorf("invalid crypto type %q: %v", cryptoType, err)
+				}
+			}
 
-			return encryptWallet(w, pr)
+			return encryptWallet(w, pr, cryptoType)
 		},
 	}
 
 	encryptWalletCmd.Flags().StringP("password", "p", "", "wallet password")
+	encryptWalletCmd.Flags().StringP("crypto-type", "x", "", "encryption method, one of sha256-xor, scrypt-chacha20poly1305, argon2id-chacha20poly1305")
 	return encryptWalletCmd
 }
 
-func encryptWallet(id string, pr PasswordReader) error {
+func encryptWallet(id string, pr PasswordReader, cryptoType string) error {
 	wlt, err := apiClient.Wallet(id)
 	if err != nil {
 		return err
@@ -54,7 +69,11 @@ func encryptWallet(id string, pr PasswordReader) error {
 		return err
 	}
 
-	wlt, err = apiClient.EncryptWallet(id, string(pwd))
+	if err := wallet.CheckPasswordStrength(pwd, cliConfig.MinPasswordLength); err != nil {
+		return err
+	}
+
+	wlt, err = apiClient.EncryptWalletWithCryptoType(id, string(pwd), cryptoType)
 	if err != nil {
 		return err
 	}
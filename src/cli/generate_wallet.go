@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/cipher/bip39"
 	"github.com/skycoin/skycoin/src/cipher/bip44"
+	"github.com/skycoin/skycoin/src/cipher/crypto"
 	secp256k1 "github.com/skycoin/skycoin/src/cipher/secp256k1-go"
 	"github.com/skycoin/skycoin/src/wallet"
 )
@@ -29,7 +31,12 @@ func walletCreateCmd() *cobra.Command {
     Use caution when using the "-p" command. If you have command
     history enabled your wallet encryption password can be recovered
     from the history log. If you do not include the "-p" option you will
-    be prompted to enter your password after you enter your command.
+    be prompted to enter your password after you enter your command,
+    and asked to enter it again to confirm it.
+
+    The wallet is encrypted by the node before it is written to disk, so the
+    plaintext seed is never saved. Use "--print-seed" to print the seed once,
+    along with the wallet, so that it can be written down.
 
     All results are returned in JSON format in addition to being written to the specified filename.`,
 		SilenceUsage: true,
@@ -48,8 +55,13 @@ func walletCreateCmd() *cobra.Command {
 	walletCreateCmd.Flags().StringP("type", "t", wallet.WalletTypeDeterministic, "Wallet type. Types are \"collection\", \"deterministic\", \"bip44\" or \"xpub\"")
 	walletCreateCmd.Flags().BoolP("encrypt", "e", true, "Create encrypted wallet.")
 	walletCreateCmd.Flags().StringP("password", "p", "", "Wallet password")
+	walletCreateCmd.Flags().StringP("crypto-type", "x", "", "encryption method, one of sha256-xor, scrypt-chacha20poly1305, argon2id-chacha20poly1305. Defaults to the node's configured method")
+	walletCreateCmd.Flags().BoolP("print-seed", "", false, "Print the seed with the created wallet, so that it can be written down")
 	walletCreateCmd.Flags().StringP("xpub", "", "", "xpub key for \"xpub\" type wallets")
 	walletCreateCmd.Flags().StringP("private-keys", "", "", "Collection private keys")
+	walletCreateCmd.Flags().IntP("scrypt-n", "", 0, "scrypt N parameter for wallet encryption. If set, --scrypt-r and --scrypt-p must also be set.")
+	walletCreateCmd.Flags().IntP("scrypt-r", "", 0, "scrypt r parameter for wallet encryption")
+	walletCreateCmd.Flags().IntP("scrypt-p", "", 0, "scrypt p parameter for wallet encryption")
 
 	return walletCreateCmd
 }
@@ -193,12 +205,45 @@ func generateWalletHandler(c *cobra.Command, _ []string) error {
 
 	var password []byte
 	if encrypt {
-		pr := NewPasswordReader([]byte(c.Flag("password").Value.String()))
+		pr := NewConfirmedPasswordReader([]byte(c.Flag("password").Value.String()))
 		var err error
 		password, err = pr.Password()
 		if err != nil {
 			return err
 		}
+
+		if err := wallet.CheckPasswordStrength(password, cliConfig.MinPasswordLength); err != nil {
+			return err
+		}
+	}
+
+	scryptParams, err := parseScryptParamsFlags(c)
+	if err != nil {
+		return err
+	}
+	if scryptParams != nil && !encrypt {
+		return errors.New("scrypt parameters are only valid for encrypted wallets")
+	}
+
+	cryptoType, err := c.Flags().GetString("crypto-type")
+	if err != nil {
+		return err
+	}
+	if cryptoType != "" {
+		if !encrypt {
+			return errors.New("crypto type is only valid for encrypted wallets")
+		}
+		if _, err := crypto.CryptoTypeFromString(cryptoType); err != nil {
+			return err
+		}
+	}
+
+	printSeed, err := c.Flags().GetBool("print-seed")
+	if err != nil {
+		return err
+	}
+	if printSeed && sd == "" {
+		return fmt.Errorf("%q type wallets do not use seeds", walletType)
 	}
 
 	opts := api.CreateWalletOptions{
@@ -207,11 +252,13 @@ func generateWalletHandler(c *cobra.Command, _ []string) error {
 		SeedPassphrase:        seedPassphrase,
 		Encrypt:               encrypt,
 		Password:              string(password),
+		CryptoType:            cryptoType,
 		Type:                  walletType,
 		Bip44Coin:             bip44Coin,
 		ScanN:                 scan,
 		XPub:                  xpub,
 		CollectionPrivateKeys: collectionPrivateKeys,
+		ScryptParams:          scryptParams,
 	}
 
 	wlt, err := apiClient.CreateWallet(opts)
@@ -244,9 +291,24 @@ func generateWalletHandler(c *cobra.Command, _ []string) error {
 		return err
 	}
 
+	if printSeed {
+		return printJSON(walletWithSeed{
+			WalletResponse: wlt,
+			Seed:           sd,
+			SeedPassphrase: seedPassphrase,
+		})
+	}
+
 	return printJSON(wlt)
 }
 
+// walletWithSeed is printed by walletCreate when the seed is requested with "--print-seed"
+type walletWithSeed struct {
+	*api.WalletResponse
+	Seed           string `json:"seed"`
+	SeedPassphrase string `json:"seed_passphrase,omitempty"`
+}
+
 func walletCreateTempCmd() *cobra.Command {
 	walletCreateTempCmd := &cobra.Command{
 		Use:   "walletCreateTemp",
@@ -497,6 +559,7 @@ func parseDeterministicWalletSeedOptions(s string, r, m bool, wc uint64) (string
 			return "", errors.New("seed already specified, must not use -r or -m again")
 		}
 		// 100
+		warnWeakSeed(s)
 		return s, nil
 	}
 
@@ -514,6 +577,15 @@ func parseDeterministicWalletSeedOptions(s string, r, m bool, wc uint64) (string
 	return newMnemomic(wc)
 }
 
+// warnWeakSeed prints a warning to stderr if the user supplied seed is weak enough to be guessed
+func warnWeakSeed(seed string) {
+	if err := wallet.CheckSeedStrength(seed); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: the seed has an estimated entropy of %.0f bits, less than %d bits. "+
+			"Wallets created from it could be taken by anyone who guesses it, use -r or -m to generate a seed instead\n",
+			wallet.SeedEntropy(seed), wallet.MinSeedEntropyBits)
+	}
+}
+
 // PUBLIC
 
 // MakeAlphanumericSeed creates a random seed with AlphaNumericSeedLength bytes and hex encodes it
@@ -521,3 +593,36 @@ func MakeAlphanumericSeed() string {
 	seedRaw := cipher.SumSHA256(secp256k1.RandByte(AlphaNumericSeedLength))
 	return hex.EncodeToString(seedRaw[:])
 }
+
+// parseScryptParamsFlags parses the --scrypt-n, --scrypt-r and --scrypt-p flags.
+// Returns nil if none of them are set.
+func parseScryptParamsFlags(c *cobra.Command) (*wallet.ScryptParams, error) {
+	nChanged := c.Flags().Changed("scrypt-n")
+	rChanged := c.Flags().Changed("scrypt-r")
+	pChanged := c.Flags().Changed("scrypt-p")
+	if !nChanged && !rChanged && !pChanged {
+		return nil, nil
+	}
+
+	if !nChanged || !rChanged || !pChanged {
+		return nil, errors.New("--scrypt-n, --scrypt-r and --scrypt-p must be set together")
+	}
+
+	var p wallet.ScryptParams
+	var err error
+	if p.N, err = c.Flags().GetInt("scrypt-n"); err != nil {
+		return nil, err
+	}
+	if p.R, err = c.Flags().GetInt("scrypt-r"); err != nil {
+		return nil, err
+	}
+	if p.P, err = c.Flags().GetInt("scrypt-p"); err != nil {
+		return nil, err
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/readable"
+)
+
+func importAddressLabelsCmd() *cobra.Command {
+	importAddressLabelsCmd := &cobra.Command{
+		Short: "Imports the labels of wallet addresses from a CSV file",
+		Use:   "importAddressLabels [wallet]",
+		Long: `Imports the labels of wallet addresses from a CSV file.
+
+    The CSV file must have a header row with the columns address and label,
+    in any order. Other columns, like the index column printed by
+    "listAddresses --csv", are ignored. An empty label clears the label of the address.
+
+    No label is imported if any row has an invalid address or an address
+    that is not in the wallet.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE:         importAddressLabels,
+	}
+
+	importAddressLabelsCmd.Flags().String("csv", "", "CSV file containing addresses and labels")
+
+	return importAddressLabelsCmd
+}
+
+func importAddressLabels(c *cobra.Command, args []string) error {
+	csvFile, err := c.Flags().GetString("csv")
+	if err != nil {
+		return err
+	}
+
+	if csvFile == "" {
+		return errors.New("missing --csv file")
+	}
+
+	fields, err := openCSV(csvFile)
+	if err != nil {
+		return err
+	}
+
+	wlt, err := apiClient.Wallet(args[0])
+	if err != nil {
+		return err
+	}
+
+	labels, err := parseAddressLabelsFromCSV(fields, wlt.Entries)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range labels {
+		if err := apiClient.SetAddressLabel(args[0], l.Address, l.Label); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addressLabel is the label of a wallet address
+type addressLabel struct {
+	Address string
+	Label   string
+}
+
+// parseAddressLabelsFromCSV parses the address labels from CSV fields with a header row,
+// which must have the columns address and label. Returns an error listing the rows
+// whose address is invalid or not one of the wallet entries.
+func parseAddressLabelsFromCSV(fields [][]string, entries []readable.WalletEntry) ([]addressLabel, error) {
+	if len(fields) == 0 {
+		return nil, errors.New("missing CSV header row")
+	}
+
+	addrCol, labelCol := -1, -1
+	for i, name := range fields[0] {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "address":
+			addrCol = i
+		case "label":
+			labelCol = i
+		}
+	}
+
+	if addrCol == -1 || labelCol == -1 {
+		return nil, errors.New("CSV header row must have the columns address and label")
+	}
+
+	wltAddrs := make(map[string]struct{}, len(entries))
+	for _, e := range entries {
+		wltAddrs[e.Address] = struct{}{}
+	}
+
+	var labels []addressLabel
+	var errs []string
+	for i, f := range fields[1:] {
+		row := i + 1
+		addr := strings.TrimSpace(f[addrCol])
+
+		if _, err := cipher.DecodeBase58Address(addr); err != nil {
+			errs = append(errs, fmt.Sprintf("[row %d] Invalid address %s: %v", row, addr, err))
+			continue
+		}
+
+		if _, ok := wltAddrs[addr]; !ok {
+			errs = append(errs, fmt.Sprintf("[row %d] Address %s is not in the wallet", row, addr))
+			continue
+		}
+
+		labels = append(labels, addressLabel{
+			Address: addr,
+			Label:   f[labelCol],
+		})
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "\n"))
+	}
+
+	return labels, nil
+}
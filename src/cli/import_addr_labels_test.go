@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/readable"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+func TestParseAddressLabelsFromCSV(t *testing.T) {
+	entries := []readable.WalletEntry{
+		{Address: testutil.MakeAddress().String()},
+		{Address: testutil.MakeAddress().String()},
+	}
+	other := testutil.MakeAddress().String()
+
+	readCSV := func(s string) [][]string {
+		fields, err := csv.NewReader(strings.NewReader(s)).ReadAll()
+		require.NoError(t, err)
+		return fields
+	}
+
+	// The output of listAddresses --csv, with quoted labels
+	fields := readCSV("index,address,label\n" +
+		"0," + entries[0].Address + ",\"exchange, deposit\"\n" +
+		"1," + entries[1].Address + ",\"say \"\"hi\"\"\"\n")
+	labels, err := parseAddressLabelsFromCSV(fields, entries)
+	require.NoError(t, err)
+	require.Equal(t, []addressLabel{
+		{Address: entries[0].Address, Label: "exchange, deposit"},
+		{Address: entries[1].Address, Label: `say "hi"`},
+	}, labels)
+
+	// The columns can be in any order, and an empty label clears it
+	fields = readCSV("Label, Address\n,  " + entries[1].Address + "\n")
+	labels, err = parseAddressLabelsFromCSV(fields, entries)
+	require.NoError(t, err)
+	require.Equal(t, []addressLabel{
+		{Address: entries[1].Address, Label: ""},
+	}, labels)
+
+	// Rows whose address is invalid or not in the wallet are rejected
+	fields = readCSV("address,label\n" +
+		entries[0].Address + ",a\n" +
+		"bad,b\n" +
+		other + ",c\n")
+	_, err = parseAddressLabelsFromCSV(fields, entries)
+	require.Equal(t, "[row 2] Invalid address bad: Invalid address length\n"+
+		"[row 3] Address "+other+" is not in the wallet", err.Error())
+
+	_, err = parseAddressLabelsFromCSV(readCSV("index,address\n0,"+entries[0].Address+"\n"), entries)
+	require.Equal(t, "CSV header row must have the columns address and label", err.Error())
+
+	_, err = parseAddressLabelsFromCSV(nil, entries)
+	require.Equal(t, "missing CSV header row", err.Error())
+}
@@ -1471,11 +1471,17 @@ func TestStableListWallets(t *testing.T) {
 			{
 				Name:       w1.Meta.Filename,
 				Label:      l1,
+				Type:       w1.Meta.Type,
+				Encrypted:  w1.Meta.Encrypted,
+				CryptoType: w1.Meta.CryptoType,
 				AddressNum: 2,
 			},
 			{
 				Name:       w2.Meta.Filename,
 				Label:      l2,
+				Type:       w2.Meta.Type,
+				Encrypted:  w2.Meta.Encrypted,
+				CryptoType: w2.Meta.CryptoType,
 				AddressNum: 3,
 			},
 		},
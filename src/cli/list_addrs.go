@@ -1,23 +1,50 @@
 package cli
 
 import (
+	"encoding/csv"
 	"fmt"
+	"io"
+	"os"
+	"strconv"
 
 	"github.com/spf13/cobra"
+
+	"github.com/skycoin/skycoin/src/readable"
 )
 
 func listAddressesCmd() *cobra.Command {
-	return &cobra.Command{
-		Short:                 "Lists all addresses in a given wallet",
-		Use:                   "listAddresses [wallet]",
-		Args:                  cobra.ExactArgs(1),
-		DisableFlagsInUseLine: true,
-		SilenceUsage:          true,
-		RunE:                  listAddresses,
+	listAddressesCmd := &cobra.Command{
+		Short: "Lists all addresses in a given wallet",
+		Use:   "listAddresses [wallet]",
+		Long: `Lists all addresses in a given wallet.
+
+    Use the "--csv" option to print the addresses as CSV, with a header row
+    and the columns index, address and label.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE:         listAddresses,
 	}
+
+	listAddressesCmd.Flags().Bool("csv", false, "Print the addresses as CSV")
+
+	return listAddressesCmd
 }
 
-func listAddresses(_ *cobra.Command, args []string) error {
+func listAddresses(c *cobra.Command, args []string) error {
+	csvFmt, err := c.Flags().GetBool("csv")
+	if err != nil {
+		return err
+	}
+
+	if csvFmt {
+		wlt, err := apiClient.Wallet(args[0])
+		if err != nil {
+			return err
+		}
+
+		return writeAddressesCSV(os.Stdout, wlt.Entries)
+	}
+
 	addrs, err := getWalletAddresses(args[0])
 	if err != nil {
 		return err
@@ -33,6 +60,26 @@ func listAddresses(_ *cobra.Command, args []string) error {
 	return nil
 }
 
+// addressesCSVHeader is the header row of the addresses CSV
+var addressesCSVHeader = []string{"index", "address", "label"}
+
+// writeAddressesCSV writes the wallet entries as CSV, with a header row and the columns index, address and label.
+func writeAddressesCSV(w io.Writer, entries []readable.WalletEntry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(addressesCSVHeader); err != nil {
+		return err
+	}
+
+	for i, e := range entries {
+		if err := cw.Write([]string{strconv.Itoa(i), e.Address, e.Label}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
 func getWalletAddresses(id string) ([]string, error) {
 	wlt, err := apiClient.Wallet(id)
 	if err != nil {
@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/readable"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+func TestWriteAddressesCSV(t *testing.T) {
+	entries := []readable.WalletEntry{
+		{Address: testutil.MakeAddress().String(), Label: "savings"},
+		{Address: testutil.MakeAddress().String()},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeAddressesCSV(&buf, entries))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		{"index", "address", "label"},
+		{"0", entries[0].Address, "savings"},
+		{"1", entries[1].Address, ""},
+	}, records)
+
+	// No entries prints the header only
+	buf.Reset()
+	require.NoError(t, writeAddressesCSV(&buf, nil))
+	require.Equal(t, "index,address,label\n", buf.String())
+}
@@ -2,20 +2,27 @@ package cli
 
 import (
 	"github.com/spf13/cobra"
+
+	"github.com/skycoin/skycoin/src/cipher/crypto"
 )
 
 // WalletEntry represents an entry in a wallet file
 type WalletEntry struct {
-	Name       string `json:"name"`
-	Label      string `json:"label"`
-	AddressNum int    `json:"address_num"`
+	Name       string            `json:"name"`
+	Label      string            `json:"label"`
+	Type       string            `json:"type"`
+	Encrypted  bool              `json:"encrypted"`
+	CryptoType crypto.CryptoType `json:"crypto_type"`
+	AddressNum int               `json:"address_num"`
 }
 
 func listWalletsCmd() *cobra.Command {
 	return &cobra.Command{
 		Short: "Lists all wallets stored in the wallet directory",
 		Use:   "listWallets",
-		Long: `Lists all wallets stored in the wallet directory.
+		Long: `Lists all wallets stored in the wallet directory, with their label, type,
+    encryption status, encryption method and number of addresses.
+    No password is needed, encrypted wallets are listed without being decrypted.
 
     The [wallet dir] argument is optional. If not provided, defaults to $DATA_DIR/wallets`,
 		DisableFlagsInUseLine: true,
@@ -48,6 +55,9 @@ func listWallets(_ *cobra.Command, _ []string) error {
 		wlts.Wallets = append(wlts.Wallets, WalletEntry{
 			Name:       w.Meta.Filename,
 			Label:      w.Meta.Label,
+			Type:       w.Meta.Type,
+			Encrypted:  w.Meta.Encrypted,
+			CryptoType: w.Meta.CryptoType,
 			AddressNum: len(w.Entries),
 		})
 	}
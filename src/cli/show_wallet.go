@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func showWalletCmd() *cobra.Command {
+	showWalletCmd := &cobra.Command{
+		Args:  cobra.ExactArgs(1),
+		Use:   "showWallet [wallet file]",
+		Short: "Show a wallet file, decrypting it in memory",
+		Long: `Print a wallet file with its addresses and secret keys.
+    An encrypted wallet is decrypted in memory only, nothing is written
+    back to the wallet file or anywhere else on the filesystem.
+
+    Use caution when using the "-p" command. If you have command history enabled
+    your wallet encryption password can be recovered from the history log. If you
+    do not include the "-p" option you will be prompted to enter your password
+    after you enter your command.`,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			password, err := c.Flags().GetString("password")
+			if err != nil {
+				return err
+			}
+
+			w, err := loadDecryptedWallet(args[0], NewPasswordReader([]byte(password)))
+			switch err.(type) {
+			case nil:
+			case WalletLoadError:
+				printHelp(c)
+				return err
+			default:
+				return err
+			}
+
+			data, err := w.Serialize()
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(string(data))
+			return nil
+		},
+	}
+
+	showWalletCmd.Flags().StringP("password", "p", "", "Wallet password")
+
+	return showWalletCmd
+}
+
+// loadDecryptedWallet loads the wallet file and returns a decrypted copy of it if it is encrypted.
+// The wallet file is only read.
+func loadDecryptedWallet(walletFile string, pr PasswordReader) (wallet.Wallet, error) {
+	w, err := wallet.Load(walletFile)
+	if err != nil {
+		return nil, WalletLoadError{err}
+	}
+
+	if !w.IsEncrypted() {
+		return w, nil
+	}
+
+	password, err := pr.Password()
+	if err != nil {
+		return nil, err
+	}
+
+	// Unlock returns a decrypted copy, the loaded wallet stays encrypted
+	return w.Unlock(password)
+}
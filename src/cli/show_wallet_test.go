@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher/crypto"
+	"github.com/skycoin/skycoin/src/wallet"
+	_ "github.com/skycoin/skycoin/src/wallet/deterministic"
+)
+
+func TestLoadDecryptedWallet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "show-wallet")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wallet.NewWallet("test.wlt", "test", "seed", wallet.Options{
+		Type:       wallet.WalletTypeDeterministic,
+		GenerateN:  2,
+		Encrypt:    true,
+		Password:   []byte("pwd"),
+		CryptoType: crypto.CryptoTypeSha256Xor,
+	})
+	require.NoError(t, err)
+	require.NoError(t, wallet.Save(w, dir))
+
+	walletFile := filepath.Join(dir, "test.wlt")
+	data, err := ioutil.ReadFile(walletFile)
+	require.NoError(t, err)
+
+	_, err = loadDecryptedWallet(walletFile, PasswordFromBytes("wrong"))
+	require.Equal(t, wallet.ErrInvalidPassword, err)
+
+	_, err = loadDecryptedWallet(filepath.Join(dir, "none.wlt"), PasswordFromBytes("pwd"))
+	require.IsType(t, WalletLoadError{}, err)
+
+	dw, err := loadDecryptedWallet(walletFile, PasswordFromBytes("pwd"))
+	require.NoError(t, err)
+	require.False(t, dw.IsEncrypted())
+	require.Equal(t, "seed", dw.Seed())
+	entries, err := dw.GetEntries()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	for _, e := range entries {
+		require.NoError(t, e.Verify())
+	}
+
+	// The wallet file is left as it was, and nothing else is written
+	after, err := ioutil.ReadFile(walletFile)
+	require.NoError(t, err)
+	require.Equal(t, data, after)
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+}
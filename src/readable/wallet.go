@@ -52,6 +52,7 @@ type WalletEntry struct {
 	Public      string  `json:"public_key"`
 	ChildNumber *uint32 `json:"child_number,omitempty"` // For bip32/44
 	Change      *uint32 `json:"change,omitempty"`       // For bip44
+	Label       string  `json:"label,omitempty"`
 }
 
 // WalletMeta the wallet meta struct
@@ -59,6 +60,7 @@ type WalletMeta struct {
 	Coin       wallet.CoinType   `json:"coin"`
 	Filename   string            `json:"filename"`
 	Label      string            `json:"label"`
+	Notes      string            `json:"notes,omitempty"`
 	Type       string            `json:"type"`
 	Version    string            `json:"version"`
 	CryptoType crypto.CryptoType `json:"crypto_type"`
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -25,6 +26,7 @@ import (
 	"github.com/skycoin/skycoin/src/util/droplet"
 	"github.com/skycoin/skycoin/src/util/file"
 	"github.com/skycoin/skycoin/src/util/useragent"
+	"github.com/skycoin/skycoin/src/wallet"
 )
 
 var (
@@ -168,6 +170,33 @@ type NodeConfig struct {
 	WalletDirectory string
 	// Wallet crypto type
 	WalletCryptoType string
+	// Load the wallets read-only, disabling wallet changes and spending
+	WalletReadOnly bool
+	// Skip the invalid wallet files on startup instead of failing
+	WalletSkipInvalid bool
+	// Load the wallet files on first access instead of on startup
+	WalletLazyLoad bool
+	// Number of timestamped backups kept for each wallet file, disabled if 0
+	WalletMaxBackups int
+	// Maximum number of wallets, unlimited if 0
+	WalletMaxCount int
+	// Prefix of the generated wallet filenames
+	WalletFilenamePrefix string
+	// Label template of the wallets created without a label
+	WalletDefaultLabel string
+	// Minimum length of the wallet encryption passwords, disabled if 0
+	WalletMinPasswordLength int
+	// Number of consecutive wrong passwords after which a wallet is locked, disabled if 0
+	WalletMaxUnlockAttempts int
+	// How long a wallet stays locked after WalletMaxUnlockAttempts wrong passwords
+	WalletUnlockLockout time.Duration
+	// Minimum time between two balance queries of a wallet address scan, disabled if 0
+	WalletScanRateLimit time.Duration
+	// Octal permission modes of the wallet directory and wallet files
+	WalletDirPerm  string
+	walletDirPerm  os.FileMode
+	WalletFilePerm string
+	walletFilePerm os.FileMode
 
 	// Key-value storage
 	// Default to ${DataDirectory}/data
@@ -309,8 +338,12 @@ func NewNodeConfig(mode string, node fiber.NodeConfig) NodeConfig {
 		MaxBlockTransactionsSize: node.MaxBlockTransactionsSize,
 
 		// Wallets
-		WalletDirectory:  "",
-		WalletCryptoType: string(crypto.DefaultCryptoType),
+		WalletDirectory:     "",
+		WalletCryptoType:    string(crypto.DefaultCryptoType),
+		WalletMaxBackups:    wallet.DefaultMaxBackups,
+		WalletDirPerm:       fmt.Sprintf("%#o", wallet.DefaultDirPerm),
+		WalletFilePerm:      fmt.Sprintf("%#o", wallet.DefaultFilePerm),
+		WalletUnlockLockout: wallet.DefaultUnlockLockout,
 
 		// Key-value storage
 		KVStorageDirectory: "",
@@ -413,6 +446,15 @@ func (c *Config) postProcess() error {
 		c.Node.WalletDirectory = replaceHome(c.Node.WalletDirectory, home)
 	}
 
+	c.Node.walletDirPerm, err = parseFilePerm(c.Node.WalletDirPerm)
+	if err != nil {
+		return fmt.Errorf("invalid -wallet-dir-perm: %v", err)
+	}
+	c.Node.walletFilePerm, err = parseFilePerm(c.Node.WalletFilePerm)
+	if err != nil {
+		return fmt.Errorf("invalid -wallet-file-perm: %v", err)
+	}
+
 	if c.Node.KVStorageDirectory == "" {
 		c.Node.KVStorageDirectory = filepath.Join(c.Node.DataDirectory, "data")
 	} else {
@@ -735,6 +777,19 @@ func (c *NodeConfig) RegisterFlags() {
 	flag.IntVar(&c.MaxIncomingMessageLength, "max-in-msg-len", c.MaxIncomingMessageLength, "Maximum length of incoming wire messages")
 	flag.BoolVar(&c.LocalhostOnly, "localhost-only", c.LocalhostOnly, "Run on localhost and only connect to localhost peers")
 	flag.StringVar(&c.WalletCryptoType, "wallet-crypto-type", c.WalletCryptoType, "wallet crypto type. Can be sha256-xor or scrypt-chacha20poly1305")
+	flag.IntVar(&c.WalletMinPasswordLength, "wallet-min-password-length", c.WalletMinPasswordLength, "minimum length of the wallet encryption passwords. Disabled if 0")
+	flag.BoolVar(&c.WalletReadOnly, "wallet-read-only", c.WalletReadOnly, "load the wallets read-only. Wallets can be viewed but not changed or spent from")
+	flag.IntVar(&c.WalletMaxBackups, "wallet-max-backups", c.WalletMaxBackups, "number of timestamped backups kept for each wallet file, a backup is made before each save. Disabled if 0")
+	flag.StringVar(&c.WalletDirPerm, "wallet-dir-perm", c.WalletDirPerm, "octal permission mode of the wallet directory, e.g. 0750")
+	flag.StringVar(&c.WalletFilePerm, "wallet-file-perm", c.WalletFilePerm, "octal permission mode of the wallet files, e.g. 0640")
+	flag.IntVar(&c.WalletMaxUnlockAttempts, "wallet-max-unlock-attempts", c.WalletMaxUnlockAttempts, "number of consecutive wrong passwords after which a wallet can't be decrypted for wallet-unlock-lockout. Disabled if 0")
+	flag.DurationVar(&c.WalletUnlockLockout, "wallet-unlock-lockout", c.WalletUnlockLockout, "how long a wallet stays locked after wallet-max-unlock-attempts wrong passwords")
+	flag.DurationVar(&c.WalletScanRateLimit, "wallet-scan-rate-limit", c.WalletScanRateLimit, "minimum time between two balance queries when scanning the addresses of a wallet, e.g. 100ms. Disabled if 0")
+	flag.IntVar(&c.WalletMaxCount, "wallet-max-count", c.WalletMaxCount, "maximum number of wallets, no more wallets can be created or imported once reached. Unlimited if 0")
+	flag.StringVar(&c.WalletFilenamePrefix, "wallet-filename-prefix", c.WalletFilenamePrefix, "prefix of the generated wallet filenames, e.g. to namespace the wallets of a tenant")
+	flag.StringVar(&c.WalletDefaultLabel, "wallet-default-label", c.WalletDefaultLabel, "label template of the wallets created without a label. {prefix}, {date} and {filename} are replaced with the filename prefix, the creation date and the wallet filename")
+	flag.BoolVar(&c.WalletLazyLoad, "wallet-lazy-load", c.WalletLazyLoad, "read only the headers of the wallet files on startup, and load each wallet on first access")
+	flag.BoolVar(&c.WalletSkipInvalid, "wallet-skip-invalid", c.WalletSkipInvalid, "skip unreadable, duplicate or empty wallet files on startup instead of failing. The skipped files are logged")
 	flag.BoolVar(&c.Version, "version", false, "show node version")
 }
 
@@ -771,3 +826,15 @@ func panicIfError(err error, msg string, args ...interface{}) { //nolint:unparam
 func replaceHome(path, home string) string {
 	return strings.Replace(path, "$HOME", home, 1)
 }
+
+// parseFilePerm parses an octal permission mode such as "0700"
+func parseFilePerm(s string) (os.FileMode, error) {
+	perm, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return 0, err
+	}
+	if os.FileMode(perm)&^os.ModePerm != 0 {
+		return 0, fmt.Errorf("%s is not a permission mode", s)
+	}
+	return os.FileMode(perm), nil
+}
@@ -200,6 +200,9 @@ func (c *Coin) Run() error {
 		c.logger.WithError(err).Error("wallet.NewService failed")
 		return err
 	}
+	for _, err := range w.InvalidWallets() {
+		c.logger.WithError(err).Warning("Skipped invalid wallet file")
+	}
 
 	c.logger.Info("visor.New")
 	v, err = visor.New(vconf, db, w)
@@ -304,6 +307,11 @@ func (c *Coin) Run() error {
 	c.logger.Info("Waiting for goroutines to finish")
 	wg.Wait()
 
+	c.logger.Info("Closing wallet service")
+	if err := w.Close(); err != nil {
+		c.logger.WithError(err).Error("wallet.Service.Close failed")
+	}
+
 	return retErr
 }
 
@@ -369,6 +377,19 @@ func (c *Coin) ConfigureWallet() wallet.Config {
 	wc.WalletDir = c.config.Node.WalletDirectory
 	_, wc.EnableWalletAPI = c.config.Node.enabledAPISets[api.EndpointsWallet]
 	_, wc.EnableSeedAPI = c.config.Node.enabledAPISets[api.EndpointsInsecureWalletSeed]
+	wc.ReadOnly = c.config.Node.WalletReadOnly
+	wc.SkipInvalidWallets = c.config.Node.WalletSkipInvalid
+	wc.LazyLoad = c.config.Node.WalletLazyLoad
+	wc.MaxBackups = c.config.Node.WalletMaxBackups
+	wc.MaxWallets = c.config.Node.WalletMaxCount
+	wc.FilenamePrefix = c.config.Node.WalletFilenamePrefix
+	wc.DefaultLabel = c.config.Node.WalletDefaultLabel
+	wc.MinPasswordLength = c.config.Node.WalletMinPasswordLength
+	wc.MaxUnlockAttempts = c.config.Node.WalletMaxUnlockAttempts
+	wc.UnlockLockout = c.config.Node.WalletUnlockLockout
+	wc.ScanRateLimit = c.config.Node.WalletScanRateLimit
+	wc.DirPerm = c.config.Node.walletDirPerm
+	wc.FilePerm = c.config.Node.walletFilePerm
 
 	// Initialize wallet default crypto type
 	cryptoType, err := crypto.CryptoTypeFromString(c.config.Node.WalletCryptoType)
@@ -117,6 +117,40 @@ func sortSpendsCoinsLowToHigh(uxa []UxBalance) {
 	}))
 }
 
+// ChooseSpendsMinimizeHours chooses uxout spends to satisfy an amount, using the uxouts with the least hours first.
+// This preserves the uxouts holding the most coin hours for later spends.
+func ChooseSpendsMinimizeHours(uxa []UxBalance, coins, hours uint64) ([]UxBalance, error) {
+	return ChooseSpends(uxa, coins, hours, sortSpendsHoursLowToHigh)
+}
+
+// ChooseSpendsWithStrategy chooses uxout spends to satisfy an amount using the named selection strategy.
+// An empty strategy is treated as SelectionStrategyMinimizeInputs.
+// Every strategy guarantees that the requested coins are met and that the remaining hours
+// after the fee burn satisfy the requested hours.
+func ChooseSpendsWithStrategy(uxa []UxBalance, coins, hours uint64, strategy string) ([]UxBalance, error) {
+	return chooseSpendsWithStrategy(uxa, coins, hours, strategy, remainingHoursAfterFee)
+}
+
+// chooseSpendsWithStrategy is ChooseSpendsWithStrategy, with the hours of the spends that are
+// available to satisfy the requested hours given by remainingHours
+func chooseSpendsWithStrategy(uxa []UxBalance, coins, hours uint64, strategy string, remainingHours func(uint64) uint64) ([]UxBalance, error) {
+	switch strategy {
+	case "", SelectionStrategyMinimizeInputs:
+		return chooseSpends(uxa, coins, hours, sortSpendsCoinsHighToLow, remainingHours)
+	case SelectionStrategyMaximizeInputs:
+		return chooseSpends(uxa, coins, hours, sortSpendsCoinsLowToHigh, remainingHours)
+	case SelectionStrategyMinimizeHours:
+		return chooseSpends(uxa, coins, hours, sortSpendsHoursLowToHigh, remainingHours)
+	default:
+		return chooseSpendsByStrategy(uxa, coins, hours, strategy, remainingHours)
+	}
+}
+
+// remainingHoursAfterFee returns the hours left after burning the required fee
+func remainingHoursAfterFee(hours uint64) uint64 {
+	return fee.RemainingHours(hours, params.UserVerifyTxn.BurnFactor)
+}
+
 // sortSpendsHoursLowToHigh sorts uxout spends with lowest hours to highest
 func sortSpendsHoursLowToHigh(uxa []UxBalance) {
 	sort.Slice(uxa, makeCmpUxOutByHours(uxa, func(a, b uint64) bool {
@@ -124,6 +158,39 @@ func sortSpendsHoursLowToHigh(uxa []UxBalance) {
 	}))
 }
 
+// sortSpendsOldestFirst sorts uxout spends with the earliest block first
+func sortSpendsOldestFirst(uxa []UxBalance) {
+	sort.Slice(uxa, makeCmpUxOutByAge(uxa, func(a, b uint64) bool {
+		return a < b
+	}))
+}
+
+// sortSpendsNewestFirst sorts uxout spends with the most recent block first
+func sortSpendsNewestFirst(uxa []UxBalance) {
+	sort.Slice(uxa, makeCmpUxOutByAge(uxa, func(a, b uint64) bool {
+		return a > b
+	}))
+}
+
+func makeCmpUxOutByAge(uxa []UxBalance, bkSeqCmp func(a, b uint64) bool) func(i, j int) bool {
+	// Sort by:
+	// block seq oldest or newest depending on bkSeqCmp
+	//  block time, in the same direction
+	//   tie break with hash comparison
+	return func(i, j int) bool {
+		a := uxa[i]
+		b := uxa[j]
+
+		if a.BkSeq == b.BkSeq {
+			if a.Time == b.Time {
+				return cmpUxBalanceByUxID(a, b)
+			}
+			return bkSeqCmp(a.Time, b.Time)
+		}
+		return bkSeqCmp(a.BkSeq, b.BkSeq)
+	}
+}
+
 func makeCmpUxOutByCoins(uxa []UxBalance, coinsCmp func(a, b uint64) bool) func(i, j int) bool {
 	// Sort by:
 	// coins highest or lowest depending on coinsCmp
@@ -183,6 +250,10 @@ func cmpUxBalanceByUxID(a, b UxBalance) bool {
 // It then chooses uxouts with zero coinhours, ordered by sortStrategy
 // It then chooses remaining uxouts with nonzero coinhours, ordered by sortStrategy
 func ChooseSpends(uxa []UxBalance, coins, hours uint64, sortStrategy func([]UxBalance)) ([]UxBalance, error) {
+	return chooseSpends(uxa, coins, hours, sortStrategy, remainingHoursAfterFee)
+}
+
+func chooseSpends(uxa []UxBalance, coins, hours uint64, sortStrategy func([]UxBalance), remainingHours func(uint64) uint64) ([]UxBalance, error) {
 	if coins == 0 {
 		return nil, ErrZeroSpend
 	}
@@ -234,7 +305,7 @@ func ChooseSpends(uxa []UxBalance, coins, hours uint64, sortStrategy func([]UxBa
 	haveCoins += firstNonzero.Coins
 	haveHours += firstNonzero.Hours
 
-	if haveCoins >= coins && fee.RemainingHours(haveHours, params.UserVerifyTxn.BurnFactor) >= hours {
+	if haveCoins >= coins && remainingHours(haveHours) >= hours {
 		return spending, nil
 	}
 
@@ -252,7 +323,7 @@ func ChooseSpends(uxa []UxBalance, coins, hours uint64, sortStrategy func([]UxBa
 		}
 	}
 
-	if haveCoins >= coins && fee.RemainingHours(haveHours, params.UserVerifyTxn.BurnFactor) >= hours {
+	if haveCoins >= coins && remainingHours(haveHours) >= hours {
 		return spending, nil
 	}
 
@@ -265,7 +336,82 @@ func ChooseSpends(uxa []UxBalance, coins, hours uint64, sortStrategy func([]UxBa
 		haveCoins += ux.Coins
 		haveHours += ux.Hours
 
-		if haveCoins >= coins && fee.RemainingHours(haveHours, params.UserVerifyTxn.BurnFactor) >= hours {
+		if haveCoins >= coins && remainingHours(haveHours) >= hours {
+			return spending, nil
+		}
+	}
+
+	if haveCoins < coins {
+		return nil, ErrInsufficientBalance
+	}
+
+	return nil, ErrInsufficientHours
+}
+
+// ChooseSpendsByStrategy chooses uxouts from a list of uxouts, in the order defined by strategy.
+// Unlike ChooseSpends, the uxouts are consumed strictly in strategy order, so that the
+// returned spends preserve that order.
+// Selection stops once the requested coins are met and the remaining hours after
+// the fee burn satisfy the requested hours.
+func ChooseSpendsByStrategy(uxa []UxBalance, coins, hours uint64, strategy string) ([]UxBalance, error) {
+	return chooseSpendsByStrategy(uxa, coins, hours, strategy, remainingHoursAfterFee)
+}
+
+func chooseSpendsByStrategy(uxa []UxBalance, coins, hours uint64, strategy string, remainingHours func(uint64) uint64) ([]UxBalance, error) {
+	var sortStrategy func([]UxBalance)
+	switch strategy {
+	case SelectionStrategyOldestFirst:
+		sortStrategy = sortSpendsOldestFirst
+	case SelectionStrategyNewestFirst:
+		sortStrategy = sortSpendsNewestFirst
+	case SelectionStrategyLargestFirst:
+		sortStrategy = sortSpendsCoinsHighToLow
+	case SelectionStrategySmallestFirst:
+		sortStrategy = sortSpendsCoinsLowToHigh
+	default:
+		return nil, ErrInvalidSelectionStrategy
+	}
+
+	if coins == 0 {
+		return nil, ErrZeroSpend
+	}
+
+	if len(uxa) == 0 {
+		return nil, ErrNoUnspents
+	}
+
+	var haveAnyHours bool
+	for _, ux := range uxa {
+		if ux.Coins == 0 {
+			logger.Panic("UxOut coins are 0, can't spend")
+			return nil, errors.New("UxOut coins are 0, can't spend")
+		}
+		if ux.Hours != 0 {
+			haveAnyHours = true
+		}
+	}
+
+	// Abort if there are no uxouts with non-zero coinhours, they can't be spent yet
+	if !haveAnyHours {
+		return nil, fee.ErrTxnNoFee
+	}
+
+	sorted := make([]UxBalance, len(uxa))
+	copy(sorted, uxa)
+	sortStrategy(sorted)
+
+	var haveCoins uint64
+	var haveHours uint64
+	var spending []UxBalance
+
+	for _, ux := range sorted {
+		spending = append(spending, ux)
+
+		haveCoins += ux.Coins
+		haveHours += ux.Hours
+
+		// The fee can only be paid if at least one of the spends has hours
+		if haveHours != 0 && haveCoins >= coins && remainingHours(haveHours) >= hours {
 			return spending, nil
 		}
 	}
@@ -2,6 +2,7 @@ package transaction
 
 import (
 	"bytes"
+	"fmt"
 	"math"
 	"math/rand"
 	"reflect"
@@ -10,6 +11,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/testutil"
 	"github.com/skycoin/skycoin/src/util/fee"
 )
@@ -532,3 +534,214 @@ func verifySortedHoursLowToHigh(t *testing.T, uxb []UxBalance) {
 		return a.Hours <= b.Hours
 	})
 }
+
+func TestChooseSpendsByStrategy(t *testing.T) {
+	oldestSmall := UxBalance{
+		Hash:  testutil.RandSHA256(t),
+		BkSeq: 1,
+		Time:  100,
+		Coins: 2e6,
+		Hours: 10,
+	}
+	middleLarge := UxBalance{
+		Hash:  testutil.RandSHA256(t),
+		BkSeq: 2,
+		Time:  200,
+		Coins: 10e6,
+		Hours: 10,
+	}
+	newestMedium := UxBalance{
+		Hash:  testutil.RandSHA256(t),
+		BkSeq: 3,
+		Time:  300,
+		Coins: 5e6,
+		Hours: 10,
+	}
+	uxb := []UxBalance{middleLarge, newestMedium, oldestSmall}
+
+	cases := []struct {
+		strategy string
+		coins    uint64
+		hours    uint64
+		expect   []UxBalance
+		err      error
+	}{
+		{
+			strategy: SelectionStrategyOldestFirst,
+			coins:    3e6,
+			expect:   []UxBalance{oldestSmall, middleLarge},
+		},
+		{
+			strategy: SelectionStrategyNewestFirst,
+			coins:    6e6,
+			expect:   []UxBalance{newestMedium, middleLarge},
+		},
+		{
+			strategy: SelectionStrategyLargestFirst,
+			coins:    12e6,
+			expect:   []UxBalance{middleLarge, newestMedium},
+		},
+		{
+			strategy: SelectionStrategySmallestFirst,
+			coins:    6e6,
+			expect:   []UxBalance{oldestSmall, newestMedium},
+		},
+		{
+			strategy: SelectionStrategyOldestFirst,
+			coins:    18e6,
+			err:      ErrInsufficientBalance,
+		},
+		{
+			strategy: SelectionStrategyNewestFirst,
+			coins:    1e6,
+			hours:    100,
+			err:      ErrInsufficientHours,
+		},
+		{
+			strategy: "foo",
+			coins:    1e6,
+			err:      ErrInvalidSelectionStrategy,
+		},
+	}
+
+	for _, tc := range cases {
+		name := fmt.Sprintf("strategy=%s coins=%d hours=%d", tc.strategy, tc.coins, tc.hours)
+		t.Run(name, func(t *testing.T) {
+			input := make([]UxBalance, len(uxb))
+			copy(input, uxb)
+
+			spends, err := ChooseSpendsByStrategy(input, tc.coins, tc.hours, tc.strategy)
+			require.Equal(t, tc.err, err)
+			require.Equal(t, tc.expect, spends)
+
+			// The input slice must not be reordered
+			require.Equal(t, uxb, input)
+		})
+	}
+
+	// No uxouts with hours (error)
+	_, err := ChooseSpendsByStrategy([]UxBalance{{
+		Hash:  testutil.RandSHA256(t),
+		Coins: 1e6,
+	}}, 1e6, 0, SelectionStrategyOldestFirst)
+	require.Equal(t, fee.ErrTxnNoFee, err)
+}
+
+func TestChooseSpendsWithStrategy(t *testing.T) {
+	a := UxBalance{
+		Hash:  testutil.RandSHA256(t),
+		BkSeq: 1,
+		Coins: 5e6,
+		Hours: 100,
+	}
+	b := UxBalance{
+		Hash:  testutil.RandSHA256(t),
+		BkSeq: 2,
+		Coins: 1e6,
+		Hours: 60,
+	}
+	c := UxBalance{
+		Hash:  testutil.RandSHA256(t),
+		BkSeq: 3,
+		Coins: 2e6,
+		Hours: 50,
+	}
+	d := UxBalance{
+		Hash:  testutil.RandSHA256(t),
+		BkSeq: 4,
+		Coins: 3e6,
+		Hours: 80,
+	}
+	uxb := []UxBalance{b, d, a, c}
+
+	cases := []struct {
+		strategy string
+		coins    uint64
+		hours    uint64
+		expect   []UxBalance
+		err      error
+	}{
+		{
+			strategy: "",
+			coins:    7e6,
+			expect:   []UxBalance{a, d},
+		},
+		{
+			strategy: SelectionStrategyMinimizeInputs,
+			coins:    7e6,
+			expect:   []UxBalance{a, d},
+		},
+		{
+			strategy: SelectionStrategyMaximizeInputs,
+			coins:    7e6,
+			expect:   []UxBalance{a, b, c},
+		},
+		{
+			strategy: SelectionStrategyMinimizeHours,
+			coins:    7e6,
+			expect:   []UxBalance{a, c},
+		},
+		{
+			strategy: SelectionStrategyOldestFirst,
+			coins:    7e6,
+			expect:   []UxBalance{a, b, c},
+		},
+		{
+			strategy: SelectionStrategyMinimizeHours,
+			coins:    1e6,
+			hours:    300,
+			err:      ErrInsufficientHours,
+		},
+		{
+			strategy: SelectionStrategyMaximizeInputs,
+			coins:    12e6,
+			err:      ErrInsufficientBalance,
+		},
+		{
+			strategy: "foo",
+			coins:    1e6,
+			err:      ErrInvalidSelectionStrategy,
+		},
+	}
+
+	for _, tc := range cases {
+		name := fmt.Sprintf("strategy=%s coins=%d hours=%d", tc.strategy, tc.coins, tc.hours)
+		t.Run(name, func(t *testing.T) {
+			input := make([]UxBalance, len(uxb))
+			copy(input, uxb)
+
+			spends, err := ChooseSpendsWithStrategy(input, tc.coins, tc.hours, tc.strategy)
+			require.Equal(t, tc.err, err)
+			if err != nil {
+				return
+			}
+			require.Equal(t, tc.expect, spends)
+		})
+	}
+
+	// Every strategy must satisfy the requested coins and leave enough hours for the fee
+	strategies := []string{
+		SelectionStrategyMinimizeInputs,
+		SelectionStrategyMaximizeInputs,
+		SelectionStrategyMinimizeHours,
+	}
+	for _, strategy := range strategies {
+		for i := uint64(1); i <= 11; i++ {
+			coins := i * 1e6
+			hours := i * 10
+			spends, err := ChooseSpendsWithStrategy(uxb, coins, hours, strategy)
+			if err != nil {
+				require.Equal(t, ErrInsufficientHours, err)
+				continue
+			}
+
+			var haveCoins, haveHours uint64
+			for _, s := range spends {
+				haveCoins += s.Coins
+				haveHours += s.Hours
+			}
+			require.True(t, haveCoins >= coins)
+			require.True(t, fee.RemainingHours(haveHours, params.UserVerifyTxn.BurnFactor) >= hours)
+		}
+	}
+}
@@ -26,6 +26,28 @@ var (
 	logger = logging.MustGetLogger("txn")
 )
 
+const (
+	// encodedTransactionHeaderSize is the size of the length, type and inner hash of an encoded transaction,
+	// plus the length prefixes of its signatures, inputs and outputs
+	encodedTransactionHeaderSize = 4 + 1 + 32 + 4 + 4 + 4
+	// encodedTransactionInputSize is the size of an input hash and its signature in an encoded transaction
+	encodedTransactionInputSize = 32 + 65
+	// encodedTransactionOutputSize is the size of an output address, coins and hours in an encoded transaction
+	encodedTransactionOutputSize = 21 + 8 + 8
+)
+
+// EstimateTransactionSize returns the size in bytes of a signed transaction with the given number of inputs and outputs.
+// The size of a transaction only depends on these numbers, so the estimate is exact
+func EstimateTransactionSize(numInputs, numOutputs int) int {
+	return encodedTransactionHeaderSize + numInputs*encodedTransactionInputSize + numOutputs*encodedTransactionOutputSize
+}
+
+// fitsMaxTransactionSize reports whether a transaction with the given number of inputs and outputs
+// is not larger than maxSize, which is unlimited if 0
+func fitsMaxTransactionSize(maxSize uint32, numInputs, numOutputs int) bool {
+	return maxSize == 0 || EstimateTransactionSize(numInputs, numOutputs) <= int(maxSize)
+}
+
 // Create creates an unsigned transaction based upon Params.
 // NOTE: Caller must ensure that auxs correspond to params.UxOuts options
 // Outputs to spend are chosen from the pool of outputs provided.
@@ -36,8 +58,12 @@ var (
 //   - If the total amount of coins in the chosen outputs is exactly equal to the requested amount of coins,
 //     such that there would be no change output but hours remain as change, another output will be chosen to create change,
 //     if the coinhour cost of adding that output is less than the coinhours that would be lost as change
+// If p.SelectionStrategy is set, outputs are instead chosen according to that strategy (see ChooseSpendsWithStrategy).
 // If receiving hours are not explicitly specified, hours are allocated amongst the receiving outputs proportional to the number of coins being sent to them.
 // If the change address is not specified, the address whose bytes are lexically sorted first is chosen from the owners of the outputs being spent.
+// The created transaction is deterministic: every selection strategy breaks ties by the uxout hash, and the outputs are
+// always ordered as p.To followed by the change output, so identical params, auxs and headTime produce the same inputs,
+// outputs and inner hash regardless of the ordering of auxs.
 func Create(p Params, auxs coin.AddressUxOuts, headTime uint64) (*coin.Transaction, []UxBalance, error) {
 	return create(p, auxs, headTime, 0)
 }
@@ -88,14 +114,42 @@ func create(p Params, auxs coin.AddressUxOuts, headTime uint64, callCount int) (
 		}
 	}
 
-	// Use the MinimizeUxOuts strategy, to use least possible uxouts
+	// Use the MinimizeUxOuts strategy by default, to use least possible uxouts
 	// this will allow more frequent spending
 	// we don't need to check whether we have sufficient balance beforehand as ChooseSpends already checks that
-	spends, err := ChooseSpendsMinimizeUxOuts(uxb, totalOutCoins, requestedHours)
+	// If BurnHours is set, the burn is the fee, so the spends must cover the requested hours plus BurnHours
+	// without another fee deducted. BurnHours is checked against the required fee once the spends are chosen
+	chooseHours := requestedHours
+	spendableHours := remainingHoursAfterFee
+	if p.BurnHours > 0 {
+		chooseHours, err = mathutil.AddUint64(requestedHours, p.BurnHours)
+		if err != nil {
+			return nil, nil, NewError(fmt.Errorf("total output hours error: %v", err))
+		}
+		spendableHours = func(hours uint64) uint64 {
+			return hours
+		}
+	}
+
+	spends, err := chooseSpendsWithStrategy(uxb, totalOutCoins, chooseHours, p.SelectionStrategy, spendableHours)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	// Prefer fewer inputs if the spends chosen by the strategy would make the transaction too large.
+	// The change output is not known yet, so the size of the transaction is checked again once it is built
+	if !fitsMaxTransactionSize(p.MaxTransactionSize, len(spends), len(p.To)) {
+		logger.WithFields(logrus.Fields{
+			"nSpends":            len(spends),
+			"maxTransactionSize": p.MaxTransactionSize,
+		}).Info("Chosen spends exceed the max transaction size, choosing the fewest spends instead")
+
+		spends, err = chooseSpends(uxb, totalOutCoins, chooseHours, sortSpendsCoinsHighToLow, spendableHours)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// Calculate total coins and hours in spends
 	var totalInputCoins uint64
 	var totalInputHours uint64
@@ -124,6 +178,16 @@ func create(p Params, auxs coin.AddressUxOuts, headTime uint64, callCount int) (
 		logger.Critical().WithError(err).WithField("totalInputHours", totalInputHours).Error()
 		return nil, nil, err
 	}
+
+	if p.BurnHours > 0 {
+		if p.BurnHours < feeHours {
+			return nil, nil, ErrBurnHoursBelowRequiredFee
+		}
+		if p.BurnHours > totalInputHours {
+			return nil, nil, fee.ErrTxnInsufficientCoinHours
+		}
+		feeHours = p.BurnHours
+	}
 	remainingHours := totalInputHours - feeHours
 
 	switch p.HoursSelection.Type {
@@ -222,7 +286,7 @@ func create(p Params, auxs coin.AddressUxOuts, headTime uint64, callCount int) (
 	// This chooses an available input with the least number of coin hours;
 	// if the extra coin hour fee incurred by this additional input is less than
 	// the remaining coin hours, the input is added.
-	if changeCoins == 0 && changeHours > 0 {
+	if changeCoins == 0 && changeHours > 0 && fitsMaxTransactionSize(p.MaxTransactionSize, len(spends)+1, len(p.To)+1) {
 		logger.Info("Trying to recover change hours by forcing an extra input")
 		// Find the output with the least coin hours
 		// If size of the fee for this output is less than the changeHours, add it
@@ -241,6 +305,9 @@ func create(p Params, auxs coin.AddressUxOuts, headTime uint64, callCount int) (
 
 			// Calculate the new fee for this new amount of hours
 			newFee := fee.RequiredFee(newTotalHours, params.UserVerifyTxn.BurnFactor)
+			if newFee < p.BurnHours {
+				newFee = p.BurnHours
+			}
 			if newFee < feeHours {
 				err := errors.New("updated fee after adding extra input for change is unexpectedly less than it was initially")
 				logger.WithError(err).Error()
@@ -315,6 +382,11 @@ func create(p Params, auxs coin.AddressUxOuts, headTime uint64, callCount int) (
 		return create(p, auxs, headTime, 1)
 	}
 
+	// The leftover hours of manual hours selection would be burned in addition to BurnHours
+	if changeCoins == 0 && changeHours > 0 && p.BurnHours > 0 {
+		return nil, nil, ErrBurnHoursNotExact
+	}
+
 	if changeCoins > 0 {
 		var changeAddress cipher.Address
 		if p.ChangeAddress != nil {
@@ -358,6 +430,10 @@ func create(p Params, auxs coin.AddressUxOuts, headTime uint64, callCount int) (
 		}
 	}
 
+	if !fitsMaxTransactionSize(p.MaxTransactionSize, len(txn.In), len(txn.Out)) {
+		return nil, nil, ErrTransactionTooLarge
+	}
+
 	// Initialize unsigned transaction
 	txn.Sigs = make([]cipher.Sig, len(txn.In))
 
@@ -500,5 +576,9 @@ func VerifyCreatedInvariants(p Params, txn *coin.Transaction, inputs []UxBalance
 		return errors.New("Transaction will not satisfy required fee")
 	}
 
+	if p.BurnHours != 0 && inputHours-outputHours != p.BurnHours {
+		return errors.New("Transaction does not burn the requested hours")
+	}
+
 	return nil
 }
@@ -14,6 +14,7 @@ import (
 
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/testutil"
 	"github.com/skycoin/skycoin/src/util/fee"
 )
@@ -763,6 +764,282 @@ func TestCreate(t *testing.T) {
 	}
 }
 
+func TestCreateBurnHours(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+	_, secKeys := cipher.MustGenerateDeterministicKeyPairsSeed([]byte("seed"), 1)
+	secKey := secKeys[0]
+
+	makeAuxs := func(hours uint64) coin.AddressUxOuts {
+		uxout := makeUxOut(t, secKey, 2e6, hours)
+		uxout.Head.Time = headTime
+		uxout.Head.BkSeq = 1
+		return coin.NewAddressUxOuts([]coin.UxOut{uxout})
+	}
+	auxs := makeAuxs(1000)
+
+	requiredFee := fee.RequiredFee(1000, params.UserVerifyTxn.BurnFactor)
+	require.True(t, requiredFee > 1)
+
+	one := decimal.New(1, 0)
+	to := testutil.MakeAddress()
+
+	cases := []struct {
+		name        string
+		p           Params
+		auxs        coin.AddressUxOuts
+		err         error
+		outputHours []uint64
+	}{
+		{
+			name: "auto, burn more than the required fee",
+			p: Params{
+				HoursSelection: HoursSelection{
+					Type:        HoursSelectionTypeAuto,
+					Mode:        HoursSelectionModeShare,
+					ShareFactor: &one,
+				},
+				To:        []coin.TransactionOutput{{Address: to, Coins: 1e6}},
+				BurnHours: requiredFee + 100,
+			},
+			outputHours: []uint64{1000 - requiredFee - 100, 0},
+		},
+		{
+			name: "auto, burn the required fee",
+			p: Params{
+				HoursSelection: HoursSelection{
+					Type:        HoursSelectionTypeAuto,
+					Mode:        HoursSelectionModeShare,
+					ShareFactor: &one,
+				},
+				To:        []coin.TransactionOutput{{Address: to, Coins: 1e6}},
+				BurnHours: requiredFee,
+			},
+			outputHours: []uint64{1000 - requiredFee, 0},
+		},
+		{
+			name: "manual, change gets the remaining hours",
+			p: Params{
+				HoursSelection: HoursSelection{
+					Type: HoursSelectionTypeManual,
+				},
+				To:        []coin.TransactionOutput{{Address: to, Coins: 1e6, Hours: 10}},
+				BurnHours: 500,
+			},
+			outputHours: []uint64{10, 1000 - 500 - 10},
+		},
+		{
+			name: "burn less than the required fee",
+			p: Params{
+				HoursSelection: HoursSelection{
+					Type: HoursSelectionTypeManual,
+				},
+				To:        []coin.TransactionOutput{{Address: to, Coins: 1e6, Hours: 10}},
+				BurnHours: requiredFee - 1,
+			},
+			err: ErrBurnHoursBelowRequiredFee,
+		},
+		{
+			name: "burn more than the available hours",
+			p: Params{
+				HoursSelection: HoursSelection{
+					Type: HoursSelectionTypeManual,
+				},
+				To:        []coin.TransactionOutput{{Address: to, Coins: 1e6, Hours: 10}},
+				BurnHours: 1000,
+			},
+			err: ErrInsufficientHours,
+		},
+		{
+			name: "manual, no change output for the leftover hours",
+			p: Params{
+				HoursSelection: HoursSelection{
+					Type: HoursSelectionTypeManual,
+				},
+				To:        []coin.TransactionOutput{{Address: to, Coins: 2e6, Hours: 10}},
+				BurnHours: 500,
+			},
+			err: ErrBurnHoursNotExact,
+		},
+		{
+			name: "manual, requested and burned hours use all input hours",
+			p: Params{
+				HoursSelection: HoursSelection{
+					Type: HoursSelectionTypeManual,
+				},
+				To:        []coin.TransactionOutput{{Address: to, Coins: 1e6, Hours: 50}},
+				BurnHours: 50,
+			},
+			auxs:        makeAuxs(100),
+			outputHours: []uint64{50, 0},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tcAuxs := auxs
+			if tc.auxs != nil {
+				tcAuxs = tc.auxs
+			}
+
+			txn, inputs, err := Create(tc.p, tcAuxs, headTime)
+			if tc.err != nil {
+				require.Equal(t, tc.err, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, inputs, 1)
+
+			var hours []uint64
+			for _, o := range txn.Out {
+				hours = append(hours, o.Hours)
+			}
+			require.Equal(t, tc.outputHours, hours)
+
+			outputHours, err := txn.OutputHours()
+			require.NoError(t, err)
+			require.Equal(t, tc.p.BurnHours, inputs[0].Hours-outputHours)
+		})
+	}
+}
+
+func TestCreateDeterministic(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+	_, secKeys := cipher.MustGenerateDeterministicKeyPairsSeed([]byte("seed"), 3)
+
+	// Create unspent outputs with equal coins, hours and ages spread over several
+	// addresses, so that the selection relies on the hash tiebreaker
+	var uxouts []coin.UxOut
+	for i := 0; i < 12; i++ {
+		uxout := makeUxOut(t, secKeys[i%len(secKeys)], 1e6, 100)
+		uxout.Head.Time = headTime
+		uxout.Head.BkSeq = 1
+		uxouts = append(uxouts, uxout)
+	}
+
+	one := decimal.New(1, 0)
+	p := Params{
+		HoursSelection: HoursSelection{
+			Type:        HoursSelectionTypeAuto,
+			Mode:        HoursSelectionModeShare,
+			ShareFactor: &one,
+		},
+		To: []coin.TransactionOutput{
+			{Address: testutil.MakeAddress(), Coins: 3e6},
+			{Address: testutil.MakeAddress(), Coins: 2e6},
+		},
+	}
+
+	strategies := []string{
+		"",
+		SelectionStrategyMinimizeInputs,
+		SelectionStrategyMaximizeInputs,
+		SelectionStrategyMinimizeHours,
+		SelectionStrategyOldestFirst,
+		SelectionStrategyNewestFirst,
+		SelectionStrategyLargestFirst,
+		SelectionStrategySmallestFirst,
+	}
+
+	for _, strategy := range strategies {
+		t.Run(strategy, func(t *testing.T) {
+			p := p
+			p.SelectionStrategy = strategy
+
+			var expected *coin.Transaction
+			for i := 0; i < 5; i++ {
+				shuffled := make([]coin.UxOut, len(uxouts))
+				copy(shuffled, uxouts)
+				rand.Shuffle(len(shuffled), func(i, j int) {
+					shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+				})
+
+				txn, _, err := Create(p, coin.NewAddressUxOuts(shuffled), headTime)
+				require.NoError(t, err)
+
+				if expected == nil {
+					expected = txn
+					continue
+				}
+
+				require.Equal(t, expected.In, txn.In)
+				require.Equal(t, expected.Out, txn.Out)
+				require.Equal(t, expected.InnerHash, txn.InnerHash)
+			}
+		})
+	}
+}
+
+func TestEstimateTransactionSize(t *testing.T) {
+	for _, n := range []struct {
+		inputs, outputs int
+	}{
+		{0, 0},
+		{1, 1},
+		{1, 2},
+		{5, 3},
+		{20, 10},
+	} {
+		var txn coin.Transaction
+		for i := 0; i < n.inputs; i++ {
+			txn.In = append(txn.In, testutil.RandSHA256(t))
+			txn.Sigs = append(txn.Sigs, cipher.Sig{})
+		}
+		for i := 0; i < n.outputs; i++ {
+			txn.Out = append(txn.Out, coin.TransactionOutput{Address: testutil.MakeAddress(), Coins: 1e6, Hours: 1})
+		}
+
+		size, err := txn.Size()
+		require.NoError(t, err)
+		require.Equal(t, int(size), EstimateTransactionSize(n.inputs, n.outputs))
+	}
+}
+
+func TestCreateMaxTransactionSize(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+	_, secKeys := cipher.MustGenerateDeterministicKeyPairsSeed([]byte("seed"), 1)
+
+	// One large output and many small outputs, which smallest_first spends first
+	uxouts := []coin.UxOut{makeUxOut(t, secKeys[0], 5e6, 100)}
+	for i := 0; i < 10; i++ {
+		uxouts = append(uxouts, makeUxOut(t, secKeys[0], 1e6, 100))
+	}
+	for i := range uxouts {
+		uxouts[i].Head.Time = headTime
+		uxouts[i].Head.BkSeq = 1
+	}
+	auxs := coin.NewAddressUxOuts(uxouts)
+
+	one := decimal.New(1, 0)
+	p := Params{
+		HoursSelection: HoursSelection{
+			Type:        HoursSelectionTypeAuto,
+			Mode:        HoursSelectionModeShare,
+			ShareFactor: &one,
+		},
+		To:                []coin.TransactionOutput{{Address: testutil.MakeAddress(), Coins: 4e6}},
+		SelectionStrategy: SelectionStrategySmallestFirst,
+	}
+
+	txn, _, err := Create(p, auxs, headTime)
+	require.NoError(t, err)
+	require.True(t, len(txn.In) >= 4)
+
+	// The fewest inputs are chosen instead if the chosen ones would exceed the limit
+	p.MaxTransactionSize = uint32(EstimateTransactionSize(2, 2))
+	txn, _, err = Create(p, auxs, headTime)
+	require.NoError(t, err)
+	require.Len(t, txn.In, 1)
+	require.Len(t, txn.Out, 2)
+	size, err := txn.Size()
+	require.NoError(t, err)
+	require.True(t, size <= p.MaxTransactionSize)
+
+	// The limit is too small for any transaction with these outputs
+	p.MaxTransactionSize = uint32(EstimateTransactionSize(1, 1))
+	_, _, err = Create(p, auxs, headTime)
+	require.Equal(t, ErrTransactionTooLarge, err)
+}
+
 func makeUxOut(t *testing.T, s cipher.SecKey, coins, hours uint64) coin.UxOut { //nolint:unparam
 	body := makeUxBody(t, s, coins, hours)
 	tm := rand.Int31n(1000)
@@ -0,0 +1,67 @@
+package transaction
+
+import (
+	"errors"
+
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/util/mathutil"
+)
+
+// FeeEstimate summarizes the coins and coin hours spent by a transaction created from Params,
+// so that the fee can be previewed before the transaction is signed
+type FeeEstimate struct {
+	InputCoins  uint64
+	InputHours  uint64
+	OutputCoins uint64
+	OutputHours uint64
+	// Fee is the number of coin hours burned by the transaction
+	Fee uint64
+	// ChangeCoins and ChangeHours are the coins and hours of the change output, if any
+	ChangeCoins uint64
+	ChangeHours uint64
+}
+
+// NewFeeEstimate creates a FeeEstimate for a transaction created by Create from p, with the given inputs.
+// The change output is the output following the outputs requested by p.To, if present.
+func NewFeeEstimate(p Params, txn *coin.Transaction, inputs []UxBalance) (*FeeEstimate, error) {
+	if len(txn.Out) != len(p.To) && len(txn.Out) != len(p.To)+1 {
+		return nil, errors.New("Transaction has unexpected number of outputs")
+	}
+
+	var e FeeEstimate
+	var err error
+	for _, in := range inputs {
+		e.InputCoins, err = mathutil.AddUint64(e.InputCoins, in.Coins)
+		if err != nil {
+			return nil, err
+		}
+		e.InputHours, err = mathutil.AddUint64(e.InputHours, in.Hours)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, o := range txn.Out {
+		e.OutputCoins, err = mathutil.AddUint64(e.OutputCoins, o.Coins)
+		if err != nil {
+			return nil, err
+		}
+		e.OutputHours, err = mathutil.AddUint64(e.OutputHours, o.Hours)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if e.OutputHours > e.InputHours {
+		return nil, errors.New("Transaction output hours exceed input hours")
+	}
+	e.Fee = e.InputHours - e.OutputHours
+
+	if len(txn.Out) == len(p.To)+1 {
+		change := txn.Out[len(txn.Out)-1]
+		e.ChangeCoins = change.Coins
+		e.ChangeHours = change.Hours
+	}
+
+	return &e, nil
+}
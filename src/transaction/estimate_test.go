@@ -0,0 +1,80 @@
+package transaction
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+func TestNewFeeEstimate(t *testing.T) {
+	to := testutil.MakeAddress()
+	change := testutil.MakeAddress()
+
+	p := Params{
+		To: []coin.TransactionOutput{
+			{
+				Address: to,
+				Coins:   2e6,
+				Hours:   10,
+			},
+		},
+	}
+
+	inputs := []UxBalance{
+		{
+			Hash:  testutil.RandSHA256(t),
+			Coins: 2e6,
+			Hours: 40,
+		},
+		{
+			Hash:  testutil.RandSHA256(t),
+			Coins: 1e6,
+			Hours: 60,
+		},
+	}
+
+	txn := &coin.Transaction{
+		In: []cipher.SHA256{inputs[0].Hash, inputs[1].Hash},
+		Out: []coin.TransactionOutput{
+			p.To[0],
+			{
+				Address: change,
+				Coins:   1e6,
+				Hours:   40,
+			},
+		},
+	}
+
+	e, err := NewFeeEstimate(p, txn, inputs)
+	require.NoError(t, err)
+	require.Equal(t, FeeEstimate{
+		InputCoins:  3e6,
+		InputHours:  100,
+		OutputCoins: 3e6,
+		OutputHours: 50,
+		Fee:         50,
+		ChangeCoins: 1e6,
+		ChangeHours: 40,
+	}, *e)
+
+	// No change output
+	txn.Out = txn.Out[:1]
+	e, err = NewFeeEstimate(p, txn, inputs[:1])
+	require.NoError(t, err)
+	require.Equal(t, FeeEstimate{
+		InputCoins:  2e6,
+		InputHours:  40,
+		OutputCoins: 2e6,
+		OutputHours: 10,
+		Fee:         30,
+	}, *e)
+
+	// Unexpected number of outputs
+	txn.Out = nil
+	_, err = NewFeeEstimate(p, txn, inputs)
+	require.Error(t, err)
+}
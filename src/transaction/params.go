@@ -2,6 +2,7 @@ package transaction
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/shopspring/decimal"
 
@@ -23,6 +24,30 @@ func NewError(err error) error {
 	return Error{err}
 }
 
+// Unwrap returns the wrapped error
+func (e Error) Unwrap() error {
+	return e.error
+}
+
+// ReceiverError is returned by Params.Validate, wrapped in an Error, if the address of an output of Params.To is invalid
+type ReceiverError struct {
+	// Index is the index of the output in Params.To
+	Index int
+	// Address is the invalid address
+	Address cipher.Address
+	// Err is the reason the address is invalid
+	Err error
+}
+
+func (e ReceiverError) Error() string {
+	return fmt.Sprintf("To[%d].Address %s is invalid: %v", e.Index, e.Address, e.Err)
+}
+
+// Unwrap returns the reason the address is invalid
+func (e ReceiverError) Unwrap() error {
+	return e.Err
+}
+
 const (
 	// HoursSelectionTypeManual is used to specify manual hours selection in advanced spend
 	HoursSelectionTypeManual = "manual"
@@ -31,6 +56,23 @@ const (
 
 	// HoursSelectionModeShare will distribute coin hours equally amongst destinations
 	HoursSelectionModeShare = "share"
+
+	// SelectionStrategyOldestFirst spends the outputs created in the earliest blocks first
+	SelectionStrategyOldestFirst = "oldest_first"
+	// SelectionStrategyNewestFirst spends the outputs created in the most recent blocks first
+	SelectionStrategyNewestFirst = "newest_first"
+	// SelectionStrategyLargestFirst spends the outputs with the most coins first
+	SelectionStrategyLargestFirst = "largest_first"
+	// SelectionStrategySmallestFirst spends the outputs with the least coins first
+	SelectionStrategySmallestFirst = "smallest_first"
+	// SelectionStrategyMinimizeInputs spends as few outputs as possible, choosing the outputs with the most coins first.
+	// This is the default strategy, used when Params.SelectionStrategy is empty.
+	SelectionStrategyMinimizeInputs = "minimize_inputs"
+	// SelectionStrategyMaximizeInputs spends as many outputs as possible, choosing the outputs with the least coins first
+	SelectionStrategyMaximizeInputs = "maximize_inputs"
+	// SelectionStrategyMinimizeHours spends the outputs with the least coin hours first,
+	// preserving the outputs with the most coin hours
+	SelectionStrategyMinimizeHours = "minimize_hours"
 )
 
 var (
@@ -60,6 +102,15 @@ var (
 	ErrInvalidShareFactor = NewError(errors.New("HoursSelection.ShareFactor can only be used for share mode"))
 	// ErrShareFactorOutOfRange HoursSelection.ShareFactor must be >= 0 and <= 1
 	ErrShareFactorOutOfRange = NewError(errors.New("HoursSelection.ShareFactor must be >= 0 and <= 1"))
+	// ErrInvalidSelectionStrategy Invalid SelectionStrategy
+	ErrInvalidSelectionStrategy = NewError(errors.New("Invalid SelectionStrategy"))
+	// ErrBurnHoursBelowRequiredFee BurnHours is less than the fee required by the chosen spends
+	ErrBurnHoursBelowRequiredFee = NewError(errors.New("BurnHours is less than the required fee"))
+	// ErrBurnHoursNotExact BurnHours can't be burned exactly, because the hours left over by manual hours
+	// selection can't be sent to a change output when there are no change coins
+	ErrBurnHoursNotExact = NewError(errors.New("BurnHours can't be burned exactly, the leftover hours have no change output"))
+	// ErrTransactionTooLarge the transaction would be larger than MaxTransactionSize
+	ErrTransactionTooLarge = NewError(errors.New("Transaction would be larger than MaxTransactionSize"))
 )
 
 // HoursSelection defines options for hours distribution
@@ -74,6 +125,20 @@ type Params struct {
 	HoursSelection HoursSelection
 	To             []coin.TransactionOutput
 	ChangeAddress  *cipher.Address
+	// SelectionStrategy controls the order in which unspent outputs are chosen.
+	// If empty, SelectionStrategyMinimizeInputs is used.
+	SelectionStrategy string
+	// BurnHours if set, is the exact number of coin hours burned by the transaction as the fee,
+	// instead of the minimum required fee. It must not be less than the fee required by the spends chosen,
+	// which depends on their coin hours, so it is checked by Create.
+	// The hours left after the burn are distributed as usual.
+	BurnHours uint64
+	// MaxTransactionSize if set, is the maximum size in bytes of the created transaction once signed,
+	// see EstimateTransactionSize. If the spends chosen by SelectionStrategy would exceed it, the spends
+	// are chosen again with SelectionStrategyMinimizeInputs, and no extra input is added to recover change hours.
+	// ErrTransactionTooLarge is returned if the transaction still exceeds it.
+	// The limit enforced by the network is params.UserVerifyTxn.MaxTransactionSize
+	MaxTransactionSize uint32
 }
 
 // Validate validates Params
@@ -86,7 +151,7 @@ func (c Params) Validate() error {
 		return ErrMissingReceivers
 	}
 
-	for _, to := range c.To {
+	for i, to := range c.To {
 		if to.Coins == 0 {
 			return ErrZeroCoinsReceiver
 		}
@@ -94,6 +159,14 @@ func (c Params) Validate() error {
 		if to.Address.Null() {
 			return ErrNullAddressReceiver
 		}
+
+		if to.Address.Version != 0 {
+			return NewError(ReceiverError{
+				Index:   i,
+				Address: to.Address,
+				Err:     cipher.ErrAddressInvalidVersion,
+			})
+		}
 	}
 
 	// Check for duplicate outputs, a transaction can't have outputs with
@@ -135,6 +208,19 @@ func (c Params) Validate() error {
 		return ErrInvalidHoursSelectionType
 	}
 
+	switch c.SelectionStrategy {
+	case "",
+		SelectionStrategyOldestFirst,
+		SelectionStrategyNewestFirst,
+		SelectionStrategyLargestFirst,
+		SelectionStrategySmallestFirst,
+		SelectionStrategyMinimizeInputs,
+		SelectionStrategyMaximizeInputs,
+		SelectionStrategyMinimizeHours:
+	default:
+		return ErrInvalidSelectionStrategy
+	}
+
 	if c.HoursSelection.ShareFactor == nil {
 		if c.HoursSelection.Mode == HoursSelectionModeShare {
 			return ErrMissingShareFactor
@@ -260,6 +260,31 @@ func TestCreateWalletParamsVerify(t *testing.T) {
 			},
 		},
 
+		{
+			name: "invalid selection strategy",
+			params: Params{
+				ChangeAddress: &changeAddress,
+				To:            toManual,
+				HoursSelection: HoursSelection{
+					Type: HoursSelectionTypeManual,
+				},
+				SelectionStrategy: "foo",
+			},
+			err: "Invalid SelectionStrategy",
+		},
+
+		{
+			name: "valid manual oldest first",
+			params: Params{
+				ChangeAddress: &changeAddress,
+				To:            toManual,
+				HoursSelection: HoursSelection{
+					Type: HoursSelectionTypeManual,
+				},
+				SelectionStrategy: SelectionStrategyOldestFirst,
+			},
+		},
+
 		{
 			name: "valid manual",
 			params: Params{
@@ -283,3 +308,36 @@ func TestCreateWalletParamsVerify(t *testing.T) {
 		})
 	}
 }
+
+func TestParamsValidateReceiverAddressVersion(t *testing.T) {
+	badAddr := testutil.MakeAddress()
+	badAddr.Version = 1
+
+	p := Params{
+		HoursSelection: HoursSelection{
+			Type: HoursSelectionTypeManual,
+		},
+		To: []coin.TransactionOutput{
+			{
+				Address: testutil.MakeAddress(),
+				Coins:   1e6,
+			},
+			{
+				Address: badAddr,
+				Coins:   1e6,
+			},
+		},
+	}
+
+	err := p.Validate()
+	require.Equal(t, NewError(ReceiverError{
+		Index:   1,
+		Address: badAddr,
+		Err:     cipher.ErrAddressInvalidVersion,
+	}), err)
+
+	var rerr ReceiverError
+	require.True(t, errors.As(err, &rerr))
+	require.Equal(t, 1, rerr.Index)
+	require.True(t, errors.Is(err, cipher.ErrAddressInvalidVersion))
+}
@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -151,23 +150,64 @@ func SaveJSONSafe(filename string, thing interface{}, mode os.FileMode) error {
 	return f.Sync()
 }
 
-// SaveBinary persists data into given file in binary,
-// backup the data to `tmp` wallet file and then write data
-// to target wallet file. Remove the tmp file in the end of
-// this function. In this way, the wallet data would not be lost
+// SaveBinary persists data into given file in binary.
+// The data is written to a `tmp` file in the same directory and synced to disk,
+// then the tmp file is renamed over the target file. The rename is atomic, so
+// a crash leaves either the old or the new file in place, never a truncated one.
+// A crash before the rename may leave the tmp file behind, see TempFilePrefix.
 func SaveBinary(filename string, data []byte, mode os.FileMode) error {
 	// Write the new file to a temporary
 	dataHash := cipher.SumSHA256(data)
-	tmpname := filename + ".tmp." + dataHash.Hex()[:8]
-	if err := ioutil.WriteFile(tmpname, data, mode); err != nil {
+	tmpname := TempFilePrefix(filename) + dataHash.Hex()[:8]
+	if err := writeFileSync(tmpname, data, mode); err != nil {
+		removeTempFile(tmpname)
 		return err
 	}
 
-	if err := ioutil.WriteFile(filename, data, mode); err != nil {
+	if err := os.Rename(tmpname, filename); err != nil {
+		removeTempFile(tmpname)
 		return err
 	}
 
-	return os.Remove(tmpname)
+	// Sync the directory so that the rename itself is persisted
+	if d, err := os.Open(filepath.Dir(filename)); err == nil {
+		if err := d.Sync(); err != nil {
+			logger.WithError(err).Warningf("Sync of directory of %s failed", filename)
+		}
+		d.Close()
+	}
+
+	return nil
+}
+
+// TempFilePrefix returns the prefix of the temporary files written by SaveBinary for filename
+func TempFilePrefix(filename string) string {
+	return filename + ".tmp."
+}
+
+func writeFileSync(filename string, data []byte, mode os.FileMode) error {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func removeTempFile(filename string) {
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		logger.WithError(err).Warningf("os.Remove(%s) failed", filename)
+	}
 }
 
 //TODO: require file named after application and then hashcode, in static directory
@@ -311,11 +351,11 @@ func Exists(fn string) (bool, error) {
 	return true, nil
 }
 
-// IsWritable checks if the file is writable
+// IsWritable checks if the file is writable. The file is not modified, and is not created if it does not exist
 func IsWritable(name string) bool {
-	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil && os.IsPermission(err) {
-		return false
+	f, err := os.OpenFile(name, os.O_WRONLY, 0)
+	if err != nil {
+		return !os.IsPermission(err)
 	}
 	f.Close()
 	return true
@@ -264,4 +264,14 @@ func TestIsWritable(t *testing.T) {
 	err = os.Chmod(fn, 0600)
 	require.NoError(t, err)
 	require.True(t, IsWritable(fn))
+
+	// The file is left intact
+	data, err := ioutil.ReadFile(fn)
+	require.NoError(t, err)
+	require.Equal(t, b, data)
+
+	// A missing file is not created
+	require.True(t, IsWritable("missing.bin"))
+	_, err = os.Stat("missing.bin")
+	require.True(t, os.IsNotExist(err))
 }
@@ -1846,6 +1846,28 @@ func (vs *Visor) GetUnspentsOfAddrs(addrs []cipher.Address) (coin.AddressUxOuts,
 	return uxa, nil
 }
 
+// GetSpendableOutputsOfAddresses returns the unspent outputs of multiple addresses,
+// excluding the outputs spent by unconfirmed transactions
+func (vs *Visor) GetSpendableOutputsOfAddresses(addrs []cipher.Address) (coin.AddressUxOuts, error) {
+	var uxa coin.AddressUxOuts
+
+	if err := vs.db.View("GetSpendableOutputsOfAddresses", func(tx *dbutil.Tx) error {
+		var err error
+		uxa, err = vs.getCreateTransactionAuxsAddress(tx, addrs, true)
+		switch err {
+		case transaction.ErrNoUnspents, ErrNoSpendableOutputs:
+			uxa = coin.AddressUxOuts{}
+			return nil
+		default:
+			return err
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	return uxa, nil
+}
+
 // VerifyTxnVerbose verifies a transaction, it returns transaction's input uxouts, whether the
 // transaction is confirmed, and error if any
 func (vs *Visor) VerifyTxnVerbose(txn *coin.Transaction, signed transaction.TxnSignedFlag) ([]TransactionInput, bool, error) {
@@ -225,6 +225,10 @@ type CreateTransactionParams struct {
 	// IgnoreUnconfirmed if true, outputs matching Addresses or UxOuts spent by
 	// an unconfirmed transactions will be ignored, otherwise an error will be returned
 	IgnoreUnconfirmed bool
+
+	// TransactionOptions are applied to the transactions created from a wallet by
+	// WalletCreateTransaction and WalletCreateTransactionSigned, and are ignored by CreateTransaction
+	wallet.TransactionOptions
 }
 
 // Validate validates params
@@ -261,97 +265,69 @@ func (p CreateTransactionParams) Validate() error {
 
 // WalletCreateTransactionSigned creates a signed transaction based upon the parameters in CreateTransactionParams
 func (vs *Visor) WalletCreateTransactionSigned(wltID string, password []byte, p transaction.Params, wp CreateTransactionParams) (*coin.Transaction, []TransactionInput, error) {
-	// Validate params before unlocking wallet
-	if err := p.Validate(); err != nil {
-		return nil, nil, err
-	}
-	if err := wp.Validate(); err != nil {
-		return nil, nil, err
-	}
+	return vs.walletCreateTransaction("WalletCreateTransactionSigned", wltID, password, p, wp, transaction.TxnSigned)
+}
 
-	var txn *coin.Transaction
-	var inputs []TransactionInput
-	w, err := vs.wallets.GetWallet(wltID)
+// WalletEstimateFee previews the fee of the transaction that WalletCreateTransaction would create,
+// without signing it. Returns a summary of the coins and coin hours spent, and the chosen inputs.
+// wp.ChangeWalletID and wp.GenerateChange are ignored, since the change address does not affect the fee
+// and a change address should not be generated for an estimate. wp.Memo is not saved.
+func (vs *Visor) WalletEstimateFee(wltID string, p transaction.Params, wp CreateTransactionParams) (*transaction.FeeEstimate, []TransactionInput, error) {
+	wp.ChangeWalletID = ""
+	wp.ChangeWalletPassword = nil
+	wp.GenerateChange = false
+	wp.Memo = nil
+
+	txn, inputs, err := vs.WalletCreateTransaction(wltID, p, wp)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	if p.ChangeAddress == nil && w.Type() == wallet.WalletTypeBip44 {
-		// TODO: Maybe add the `PeekChangeAddress` to wallet.Wallet interface, and
-		// only bip44 wallet will implement it, all others do nothing. In this way
-		// we don't have to explicitly check the wallet type here.
-		//
-		// For bip44 wallet, peek a change address if p.ChangeAddress is nill
-		if err := vs.wallets.Update(wltID, func(w wallet.Wallet) error {
-			addr, err := w.(*bip44wallet.Wallet).PeekChangeAddress(vs.tf)
-			if err != nil {
-				logger.Critical().WithError(err).Error("PeekChangeAddress failed")
-				return err
-			}
-			skyAddr := addr.(cipher.Address)
-			p.ChangeAddress = &skyAddr
-			return nil
-		}); err != nil {
-			return nil, nil, err
+	uxb := make([]transaction.UxBalance, len(inputs))
+	for i, in := range inputs {
+		uxb[i] = transaction.UxBalance{
+			Hash:           in.UxOut.Hash(),
+			BkSeq:          in.UxOut.Head.BkSeq,
+			Time:           in.UxOut.Head.Time,
+			Address:        in.UxOut.Body.Address,
+			Coins:          in.UxOut.Body.Coins,
+			InitialHours:   in.UxOut.Body.Hours,
+			Hours:          in.CalculatedHours,
+			SrcTransaction: in.UxOut.Body.SrcTransaction,
 		}
 	}
 
-	if err := vs.wallets.ViewSecrets(wltID, password, func(w wallet.Wallet) error {
-		var err error
-		txn, inputs, err = vs.walletCreateTransaction("WalletCreateTransactionSigned", w, p, wp, transaction.TxnSigned)
-		return err
-	}); err != nil {
+	e, err := transaction.NewFeeEstimate(p, txn, uxb)
+	if err != nil {
 		return nil, nil, err
 	}
 
-	return txn, inputs, nil
+	return e, inputs, nil
 }
 
 // WalletCreateTransaction creates a transaction based upon the parameters in CreateTransactionParams
 // TODO: Only referenced by tests, vs.walletCreateTransaction
 func (vs *Visor) WalletCreateTransaction(wltID string, p transaction.Params, wp CreateTransactionParams) (*coin.Transaction, []TransactionInput, error) {
-	// Validate params before opening wallet
+	return vs.walletCreateTransaction("WalletCreateTransaction", wltID, nil, p, wp, transaction.TxnUnsigned)
+}
+
+// walletCreateTransaction creates a transaction spending from the wallet wltID through the wallet service,
+// which applies wp.TransactionOptions. The transaction is verified against the blockchain before
+// the wallet service records anything in the wallet.
+func (vs *Visor) walletCreateTransaction(methodName, wltID string, password []byte, p transaction.Params, wp CreateTransactionParams, signed transaction.TxnSignedFlag) (*coin.Transaction, []TransactionInput, error) {
+	// Validate params before opening the wallet
 	if err := p.Validate(); err != nil {
 		return nil, nil, err
 	}
 	if err := wp.Validate(); err != nil {
 		return nil, nil, err
 	}
-
-	var txn *coin.Transaction
-	var inputs []TransactionInput
-
-	if err := vs.wallets.Update(wltID, func(w wallet.Wallet) error {
-		if p.ChangeAddress == nil && w.Type() == wallet.WalletTypeBip44 {
-			// TODO: Maybe add the `PeekChangeAddress` to wallet.Wallet interface, and
-			// only bip44 wallet will implement it, all others do nothing. In this way
-			// we don't have to explicitly check the wallet type here.
-			//
-			// For bip44 wallet, peek a change address if p.ChangeAddress is nill
-			addr, err := w.(*bip44wallet.Wallet).PeekChangeAddress(vs.tf)
-			if err != nil {
-				logger.Critical().WithError(err).Error("PeekChangeAddress failed")
-				return err
-			}
-			skyAddr := addr.(cipher.Address)
-			p.ChangeAddress = &skyAddr
-		}
-
-		var err error
-		txn, inputs, err = vs.walletCreateTransaction("WalletCreateTransaction", w, p, wp, transaction.TxnUnsigned)
-		return err
-	}); err != nil {
+	if err := wp.TransactionOptions.Validate(p); err != nil {
 		return nil, nil, err
 	}
 
-	return txn, inputs, nil
-}
-
-func (vs *Visor) walletCreateTransaction(methodName string, w wallet.Wallet, p transaction.Params, wp CreateTransactionParams, signed transaction.TxnSignedFlag) (*coin.Transaction, []TransactionInput, error) {
-	if err := p.Validate(); err != nil {
-		return nil, nil, err
-	}
-	if err := wp.Validate(); err != nil {
+	w, err := vs.wallets.GetWallet(wltID)
+	if err != nil {
 		return nil, nil, err
 	}
 
@@ -385,76 +361,92 @@ func (vs *Visor) walletCreateTransaction(methodName string, w wallet.Wallet, p t
 		}
 	}
 
-	var txn *coin.Transaction
-	var uxb []transaction.UxBalance
+	if p.ChangeAddress == nil && wp.ChangeAddress == nil && !wp.GenerateChange && wp.ChangeWalletID == "" &&
+		w.Type() == wallet.WalletTypeBip44 {
+		// TODO: Maybe add the `PeekChangeAddress` to wallet.Wallet interface, and
+		// only bip44 wallet will implement it, all others do nothing. In this way
+		// we don't have to explicitly check the wallet type here.
+		//
+		// For bip44 wallet, peek a change address if p.ChangeAddress is nill
+		if err := vs.wallets.Update(wltID, func(w wallet.Wallet) error {
+			addr, err := w.(*bip44wallet.Wallet).PeekChangeAddress(vs.tf)
+			if err != nil {
+				logger.Critical().WithError(err).Error("PeekChangeAddress failed")
+				return err
+			}
+			skyAddr := addr.(cipher.Address)
+			p.ChangeAddress = &skyAddr
+			return nil
+		}); err != nil {
+			return nil, nil, err
+		}
+	}
 
+	var head *coin.SignedBlock
+	var auxs coin.AddressUxOuts
 	if err := vs.db.View(methodName, func(tx *dbutil.Tx) error {
 		var err error
-		txn, uxb, err = vs.walletCreateTransactionTx(tx, methodName, w, p, wp, signed, addrs, walletAddressesMap)
+		head, err = vs.blockchain.Head(tx)
+		if err != nil {
+			logger.WithError(err).Error("blockchain.Head failed")
+			return err
+		}
+
+		auxs, err = vs.getWalletCreateTransactionAuxs(tx, wp, addrs, walletAddressesMap)
 		return err
 	}); err != nil {
 		return nil, nil, err
 	}
 
+	txn, uxb, err := vs.wallets.CreateVerifiedTransaction(wallet.CreateTransactionParams{
+		WalletID:           wltID,
+		Password:           password,
+		Params:             p,
+		TransactionOptions: wp.TransactionOptions,
+	}, auxs, head.Time(), signed, func(txn *coin.Transaction) error {
+		return vs.db.View(methodName, func(tx *dbutil.Tx) error {
+			return vs.verifyWalletTransactionTx(tx, txn, signed)
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
 	inputs := NewTransactionInputsFromUxBalance(uxb)
 
 	return txn, inputs, nil
 }
 
-func (vs *Visor) walletCreateTransactionTx(tx *dbutil.Tx, methodName string,
-	w wallet.Wallet, p transaction.Params, wp CreateTransactionParams, signed transaction.TxnSignedFlag,
-	addrs []cipher.Address, walletAddressesMap map[cipher.Address]struct{}) (*coin.Transaction, []transaction.UxBalance, error) {
+// getWalletCreateTransactionAuxs returns the outputs to spend from a wallet, chosen from the outputs
+// wp.UxOuts, which must all belong to the wallet, or the outputs of the wallet addresses addrs
+func (vs *Visor) getWalletCreateTransactionAuxs(tx *dbutil.Tx, wp CreateTransactionParams,
+	addrs []cipher.Address, walletAddressesMap map[cipher.Address]struct{}) (coin.AddressUxOuts, error) {
 	// Note: assumes inputs have already been validated by walletCreateTransaction
 
-	head, err := vs.blockchain.Head(tx)
-	if err != nil {
-		logger.WithError(err).Error("blockchain.Head failed")
-		return nil, nil, err
+	if len(wp.UxOuts) == 0 {
+		return vs.getCreateTransactionAuxsAddress(tx, addrs, wp.IgnoreUnconfirmed)
 	}
 
-	// Get mapping of addresses to uxOuts based upon CreateTransactionParams
-	var auxs coin.AddressUxOuts
-	if len(wp.UxOuts) != 0 {
-		var err error
-		auxs, err = vs.getCreateTransactionAuxsUxOut(tx, wp.UxOuts, wp.IgnoreUnconfirmed)
-		if err != nil {
-			return nil, nil, err
-		}
+	auxs, err := vs.getCreateTransactionAuxsUxOut(tx, wp.UxOuts, wp.IgnoreUnconfirmed)
+	if err != nil {
+		return nil, err
+	}
 
-		// Check that UxOut addresses are in the wallet,
-		for a := range auxs {
-			if _, ok := walletAddressesMap[a]; !ok {
-				return nil, nil, wallet.ErrUnknownUxOut
-			}
-		}
-	} else {
-		var err error
-		auxs, err = vs.getCreateTransactionAuxsAddress(tx, addrs, wp.IgnoreUnconfirmed)
-		if err != nil {
-			return nil, nil, err
+	// Check that UxOut addresses are in the wallet,
+	for a := range auxs {
+		if _, ok := walletAddressesMap[a]; !ok {
+			return nil, wallet.ErrUnknownUxOut
 		}
 	}
 
-	// Create and sign transaction
-	var txn *coin.Transaction
-	var uxb []transaction.UxBalance
-
-	switch signed {
-	case transaction.TxnSigned:
-		txn, uxb, err = wallet.CreateTransactionSigned(w, p, auxs, head.Time())
-	case transaction.TxnUnsigned:
-		txn, uxb, err = wallet.CreateTransaction(w, p, auxs, head.Time())
-	default:
-		logger.Panic("Invalid TxnSignedFlag")
-	}
-	if err != nil {
-		logger.Critical().WithError(err).Errorf("%s failed", methodName)
-		return nil, nil, err
-	}
+	return auxs, nil
+}
 
+// verifyWalletTransactionTx checks that a transaction created by the wallet service is valid
+func (vs *Visor) verifyWalletTransactionTx(tx *dbutil.Tx, txn *coin.Transaction, signed transaction.TxnSignedFlag) error {
 	if err := transaction.VerifySingleTxnUserConstraints(*txn); err != nil {
 		logger.WithError(err).Error("Created transaction violates transaction user constraints")
-		return nil, nil, err
+		return err
 	}
 
 	// The wallet can create transactions that would not pass all validation, such as the decimal restriction,
@@ -463,10 +455,10 @@ func (vs *Visor) walletCreateTransactionTx(tx *dbutil.Tx, methodName string,
 	// TODO -- decimal restriction was moved to params/ package so the wallet can verify now. Move visor/verify to new package?
 	if _, _, err := vs.blockchain.VerifySingleTxnSoftHardConstraints(tx, *txn, vs.Config.Distribution, params.UserVerifyTxn, signed); err != nil {
 		logger.WithError(err).Error("Created transaction violates transaction soft/hard constraints")
-		return nil, nil, err
+		return err
 	}
 
-	return txn, uxb, nil
+	return nil
 }
 
 // CreateTransaction creates an unsigned transaction from requested coin.UxOut hashes
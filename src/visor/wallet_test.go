@@ -442,6 +442,18 @@ func TestWalletCreateTransaction(t *testing.T) {
 		},
 	}
 
+	noHoursParams := transaction.Params{
+		HoursSelection: transaction.HoursSelection{
+			Type: transaction.HoursSelectionTypeManual,
+		},
+		To: []coin.TransactionOutput{
+			{
+				Address: testutil.MakeAddress(),
+				Coins:   1e6,
+			},
+		},
+	}
+
 	getArrayRet := coin.UxArray{
 		{
 			Head: coin.UxHead{
@@ -703,6 +715,133 @@ func TestWalletCreateTransaction(t *testing.T) {
 			err:            transaction.ErrNullAddressReceiver,
 		},
 
+		{
+			name: "output below the dust threshold",
+			p:    validParams,
+			wp: CreateTransactionParams{
+				UxOuts: uxOuts,
+				TransactionOptions: wallet.TransactionOptions{
+					DustThreshold: 2e6,
+				},
+			},
+			walletID:       "foo.wlt",
+			walletType:     wallet.WalletTypeCollection,
+			blockchainHead: headBlock,
+			getArrayInputs: uxOuts,
+			getArray:       getArrayRet,
+			err:            wallet.ErrDustOutput,
+		},
+
+		{
+			name: "output without hours",
+			p:    noHoursParams,
+			wp: CreateTransactionParams{
+				UxOuts: uxOuts,
+				TransactionOptions: wallet.TransactionOptions{
+					RequireOutputHours: true,
+				},
+			},
+			walletID:       "foo.wlt",
+			walletType:     wallet.WalletTypeCollection,
+			blockchainHead: headBlock,
+			getArrayInputs: uxOuts,
+			getArray:       getArrayRet,
+			err:            wallet.ErrOutputWithoutHours,
+		},
+
+		{
+			name: "memo",
+			p:    validParams,
+			wp: CreateTransactionParams{
+				UxOuts: uxOuts,
+				TransactionOptions: wallet.TransactionOptions{
+					Memo: []byte("rent"),
+				},
+			},
+			walletID:       "foo.wlt",
+			walletType:     wallet.WalletTypeCollection,
+			blockchainHead: headBlock,
+			getArrayInputs: uxOuts,
+			getArray:       getArrayRet,
+			txn:            txn,
+			inputs:         inputs,
+		},
+
+		{
+			name: "memo not saved on blockchain verify error",
+			p:    validParams,
+			wp: CreateTransactionParams{
+				UxOuts: uxOuts,
+				TransactionOptions: wallet.TransactionOptions{
+					Memo: []byte("rent"),
+				},
+			},
+			walletID:       "foo.wlt",
+			walletType:     wallet.WalletTypeCollection,
+			blockchainHead: headBlock,
+			getArrayInputs: uxOuts,
+			getArray:       getArrayRet,
+			txn:            txn,
+			inputs:         inputs,
+			verifyErr:      transaction.NewErrTxnViolatesSoftConstraint(errors.New("Violates soft constraints")),
+			err:            transaction.NewErrTxnViolatesSoftConstraint(errors.New("Violates soft constraints")),
+		},
+
+		{
+			name: "change wallet",
+			p:    validParams,
+			wp: CreateTransactionParams{
+				UxOuts: uxOuts,
+				TransactionOptions: wallet.TransactionOptions{
+					ChangeWalletID: "change.wlt",
+				},
+			},
+			walletID:       "foo.wlt",
+			walletType:     wallet.WalletTypeCollection,
+			blockchainHead: headBlock,
+			getArrayInputs: uxOuts,
+			getArray:       getArrayRet,
+			txn:            txn,
+			inputs:         inputs,
+		},
+
+		{
+			name: "change wallet, insufficient balance",
+			p:    insufficientBalanceParams,
+			wp: CreateTransactionParams{
+				UxOuts: uxOuts,
+				TransactionOptions: wallet.TransactionOptions{
+					ChangeWalletID: "change.wlt",
+				},
+			},
+			walletID:       "foo.wlt",
+			walletType:     wallet.WalletTypeCollection,
+			blockchainHead: headBlock,
+			getArrayInputs: uxOuts,
+			getArray:       getArrayRet,
+			err:            transaction.ErrInsufficientBalance,
+		},
+
+		{
+			name: "change wallet, blockchain verify error",
+			p:    validParams,
+			wp: CreateTransactionParams{
+				UxOuts: uxOuts,
+				TransactionOptions: wallet.TransactionOptions{
+					ChangeWalletID: "change.wlt",
+				},
+			},
+			walletID:       "foo.wlt",
+			walletType:     wallet.WalletTypeCollection,
+			blockchainHead: headBlock,
+			getArrayInputs: uxOuts,
+			getArray:       getArrayRet,
+			txn:            txn,
+			inputs:         inputs,
+			verifyErr:      transaction.NewErrTxnViolatesSoftConstraint(errors.New("Violates soft constraints")),
+			err:            transaction.NewErrTxnViolatesSoftConstraint(errors.New("Violates soft constraints")),
+		},
+
 		{
 			name:              "Blockchain.Head failed",
 			p:                 validParams,
@@ -917,6 +1056,23 @@ func TestWalletCreateTransaction(t *testing.T) {
 			})
 			require.NoError(t, err)
 
+			var changeAddrs []cipher.Address
+			if tc.wp.ChangeWalletID != "" {
+				changeSeed, err := wallet.NewMnemonic(wallet.MnemonicEntropyBits12Words)
+				require.NoError(t, err)
+
+				_, err = ws.CreateWallet(tc.wp.ChangeWalletID, wallet.Options{
+					Label: "change",
+					Coin:  wallet.CoinTypeSkycoin,
+					Type:  wallet.WalletTypeBip44,
+					Seed:  changeSeed,
+				})
+				require.NoError(t, err)
+
+				changeAddrs, err = ws.GetAddresses(tc.wp.ChangeWalletID, wallet.OptionChange())
+				require.NoError(t, err)
+			}
+
 			err = ws.UpdateSecrets(tc.walletID, tc.password, func(w wallet.Wallet) error {
 				switch w.Type() {
 				case wallet.WalletTypeCollection:
@@ -998,6 +1154,29 @@ func TestWalletCreateTransaction(t *testing.T) {
 				t.Fatal("invalid tc.signed value")
 			}
 			require.Equal(t, tc.err, err, "%v != %v", tc.err, err)
+
+			if tc.wp.ChangeWalletID != "" {
+				// The change wallet address is only saved if the transaction is created and verified
+				addrs, err := ws.GetAddresses(tc.wp.ChangeWalletID, wallet.OptionChange())
+				require.NoError(t, err)
+				if tc.err == nil {
+					require.Len(t, addrs, len(changeAddrs)+1)
+				} else {
+					require.Equal(t, changeAddrs, addrs)
+				}
+			}
+
+			if len(tc.wp.Memo) != 0 && tc.txn != nil {
+				// The memo is only saved if the transaction is created and verified
+				memo, err := ws.GetTransactionMemo(tc.walletID, tc.txn.InnerHash)
+				require.NoError(t, err)
+				if tc.err == nil {
+					require.Equal(t, tc.wp.Memo, memo)
+				} else {
+					require.Empty(t, memo)
+				}
+			}
+
 			if tc.err != nil {
 				return
 			}
@@ -1117,6 +1296,33 @@ func TestWalletCreateTransactionValidation(t *testing.T) {
 			},
 			err: ErrCreateTransactionParamsConflict,
 		},
+		{
+			name: "change wallet and change address",
+			p: func() transaction.Params {
+				p := validParams
+				changeAddr := testutil.MakeAddress()
+				p.ChangeAddress = &changeAddr
+				return p
+			}(),
+			wp: CreateTransactionParams{
+				Addresses: []cipher.Address{testutil.MakeAddress()},
+				TransactionOptions: wallet.TransactionOptions{
+					ChangeWalletID: "change.wlt",
+				},
+			},
+			err: wallet.ErrChangeAddressConflict,
+		},
+		{
+			name: "change wallet password without change wallet id",
+			p:    validParams,
+			wp: CreateTransactionParams{
+				Addresses: []cipher.Address{testutil.MakeAddress()},
+				TransactionOptions: wallet.TransactionOptions{
+					ChangeWalletPassword: []byte("pwd"),
+				},
+			},
+			err: wallet.ErrChangeWalletPasswordWithoutID,
+		},
 	}
 
 	for _, tc := range cases {
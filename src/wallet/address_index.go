@@ -0,0 +1,125 @@
+package wallet
+
+import (
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// addressIndex maps the addresses of the loaded wallets to their wallet, see Service.FindWalletByAddress
+type addressIndex struct {
+	// wallets is the wallet of each indexed address
+	wallets map[cipher.Address]string
+	// addresses is the indexed addresses of each wallet, a wallet is indexed if it has an entry
+	addresses map[string][]cipher.Address
+}
+
+func newAddressIndex() *addressIndex {
+	return &addressIndex{
+		wallets:   make(map[cipher.Address]string),
+		addresses: make(map[string][]cipher.Address),
+	}
+}
+
+// add indexes the addresses of the wallet
+func (idx *addressIndex) add(wltID string, addrs []cipher.Address) {
+	for _, a := range addrs {
+		if _, ok := idx.wallets[a]; !ok {
+			idx.wallets[a] = wltID
+		}
+	}
+	idx.addresses[wltID] = addrs
+}
+
+// remove removes the addresses of the wallet from the index
+func (idx *addressIndex) remove(wltID string) {
+	for _, a := range idx.addresses[wltID] {
+		if idx.wallets[a] == wltID {
+			delete(idx.wallets, a)
+		}
+	}
+	delete(idx.addresses, wltID)
+}
+
+// has returns true if the wallet is indexed
+func (idx *addressIndex) has(wltID string) bool {
+	_, ok := idx.addresses[wltID]
+	return ok
+}
+
+// indexWallet updates the address index with the current addresses of the wallet, or removes
+// the wallet from the index if it is not loaded anymore. A lazily loaded wallet is not loaded to be indexed.
+// The caller must hold the service write lock.
+func (serv *Service) indexWallet(wltID string) {
+	serv.addrIndex.remove(wltID)
+
+	w := serv.wallets.get(wltID)
+	if w == nil {
+		return
+	}
+
+	if lw, ok := w.(*lazyWallet); ok && lw.loaded() == nil {
+		return
+	}
+
+	addrs, err := walletAddresses(w)
+	if err != nil {
+		logger.WithError(err).WithField("wallet", wltID).Warning("indexWallet: walletAddresses failed")
+		return
+	}
+
+	serv.addrIndex.add(wltID, addrs)
+}
+
+// walletAddresses returns the skycoin addresses of all the entries of the wallet,
+// including the change addresses and the addresses of every account of a bip44 wallet
+func walletAddresses(w Wallet) ([]cipher.Address, error) {
+	var addrs []cipher.Address
+	for _, opts := range walletChains(w) {
+		as, err := w.GetAddresses(opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, a := range as {
+			if addr, ok := a.(cipher.Address); ok {
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+
+	return addrs, nil
+}
+
+// FindWalletByAddress returns the id of the wallet that has addr among its entries, including change addresses
+// and the addresses of every bip44 account. Only public data is used, so no password is needed for encrypted wallets.
+// If several wallets have the address, one of them is returned. Returns ErrUnknownAddress if no wallet has it.
+func (serv *Service) FindWalletByAddress(addr cipher.Address) (string, error) {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return "", err
+	}
+
+	if wltID, ok := serv.addrIndex.wallets[addr]; ok {
+		return wltID, nil
+	}
+
+	// The wallets that are not indexed, e.g. lazily loaded wallets, are searched
+	for wltID, w := range serv.wallets {
+		if serv.addrIndex.has(wltID) {
+			continue
+		}
+
+		addrs, err := walletAddresses(w)
+		if err != nil {
+			return "", err
+		}
+
+		for _, a := range addrs {
+			if a == addr {
+				return wltID, nil
+			}
+		}
+	}
+
+	return "", ErrUnknownAddress
+}
@@ -0,0 +1,190 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BackupVersion is the format version of the backup documents written by Service.ExportAll
+const BackupVersion = "1"
+
+// ErrBackupWalletExists is returned by Service.ImportAll if a wallet in the backup
+// would replace an existing wallet and overwrite is not set
+var ErrBackupWalletExists = NewError(errors.New("a wallet in the backup already exists in the wallet directory"))
+
+// serviceBackup is the JSON document written by Service.ExportAll and read by Service.ImportAll
+type serviceBackup struct {
+	Version string `json:"version"`
+	// Created is the unix time of the export
+	Created int64          `json:"created"`
+	Wallets []walletBackup `json:"wallets"`
+}
+
+// walletBackup is a wallet in a backup document
+type walletBackup struct {
+	Filename string `json:"filename"`
+	// Wallet is the serialized wallet, as it is written to the wallet file
+	Wallet json.RawMessage `json:"wallet"`
+}
+
+// ExportAll writes every wallet of the service to w, as a single JSON document.
+// The wallets are written in their serialized form, encrypted wallets are never decrypted.
+func (serv *Service) ExportAll(w io.Writer) error {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(serv.wallets))
+	for id := range serv.wallets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	b := serviceBackup{
+		Version: BackupVersion,
+		Created: time.Now().Unix(),
+		Wallets: make([]walletBackup, len(ids)),
+	}
+
+	for i, id := range ids {
+		data, err := serv.wallets[id].Serialize()
+		if err != nil {
+			return fmt.Errorf("serialize wallet %q failed: %v", id, err)
+		}
+
+		b.Wallets[i] = walletBackup{
+			Filename: id,
+			Wallet:   data,
+		}
+	}
+
+	data, err := json.MarshalIndent(b, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// ImportAll restores the wallets of a backup document written by ExportAll.
+// The wallets keep the filenames they had when exported. A wallet whose filename is already
+// loaded or exists in the wallet directory is only replaced if overwrite is true,
+// otherwise ErrBackupWalletExists is returned.
+// Like NewService, DuplicateWalletError is returned if two wallets would have the same fingerprint
+// and EmptyWalletError if a wallet in the backup has no addresses.
+// Nothing is imported if any of the wallets is rejected. If saving a wallet fails, the wallets
+// saved before it stay imported and SaveWalletError is returned.
+func (serv *Service) ImportAll(r io.Reader, overwrite bool) error {
+	serv.Lock()
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return err
+	}
+	if serv.config.ReadOnly {
+		return ErrWalletReadOnly
+	}
+
+	var b serviceBackup
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return NewError(fmt.Errorf("invalid wallet backup: %v", err))
+	}
+
+	if b.Version != BackupVersion {
+		return NewError(fmt.Errorf("unsupported wallet backup version %q", b.Version))
+	}
+
+	imported := make(Wallets, len(b.Wallets))
+	ids := make([]string, 0, len(b.Wallets))
+	for _, wb := range b.Wallets {
+		id := wb.Filename
+		if filepath.Base(id) != id || !strings.HasSuffix(id, "."+WalletExt) || id == "."+WalletExt {
+			return ErrInvalidWalletFilename
+		}
+
+		if _, ok := imported[id]; ok {
+			return NewError(fmt.Errorf("wallet %q appears more than once in the backup", id))
+		}
+
+		w, err := loadWalletData(wb.Wallet)
+		if err != nil {
+			return LoadWalletError{WalletID: id, Err: err}
+		}
+		if w == nil {
+			return LoadWalletError{WalletID: id, Err: errors.New("unknown wallet type")}
+		}
+
+		if w.Coin() != CoinTypeSkycoin {
+			return LoadWalletError{WalletID: id, Err: fmt.Errorf("only skycoin wallets can be imported, %s is a %s wallet", id, w.Coin())}
+		}
+
+		w.SetFilename(id)
+
+		if !overwrite {
+			if serv.wallets.get(id) != nil {
+				return ErrBackupWalletExists
+			}
+			if _, err := os.Stat(filepath.Join(serv.config.WalletDir, id)); !os.IsNotExist(err) {
+				return ErrBackupWalletExists
+			}
+		}
+
+		imported[id] = w
+		ids = append(ids, id)
+	}
+
+	if wltID, hasEmpty := imported.containsEmpty(); hasEmpty {
+		return EmptyWalletError{WalletID: wltID}
+	}
+
+	// Checks the fingerprints of the wallets the service would have after the import
+	merged := make(Wallets, len(serv.wallets)+len(imported))
+	for id, w := range serv.wallets {
+		merged[id] = w
+	}
+	for id, w := range imported {
+		merged[id] = w
+	}
+
+	if wltID, fp, hasDup := merged.containsDuplicate(); hasDup {
+		return DuplicateWalletError{WalletID: wltID, Fingerprint: fp}
+	}
+
+	if err := serv.checkMaxWallets(len(merged) - len(serv.wallets)); err != nil {
+		return err
+	}
+
+	sort.Strings(ids)
+	for _, id := range ids {
+		w := imported[id]
+		if err := serv.save(w); err != nil {
+			return SaveWalletError{WalletID: id, Err: err}
+		}
+
+		event := WalletEventCreated
+		if old := serv.wallets.get(id); old != nil {
+			event = WalletEventUpdated
+			if fp := old.Fingerprint(); fp != "" {
+				delete(serv.fingerprints, fp)
+			}
+		}
+
+		serv.wallets.set(w)
+		if fp := w.Fingerprint(); fp != "" {
+			serv.fingerprints[fp] = id
+		}
+
+		serv.queueEvent(id, event)
+	}
+
+	return nil
+}
@@ -1,6 +1,9 @@
 package wallet
 
 import (
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/util/mathutil"
 )
@@ -16,6 +19,57 @@ type BalancePair struct {
 	Predicted Balance
 }
 
+// Add adds two BalancePairs
+func (bp BalancePair) Add(other BalancePair) (BalancePair, error) {
+	confirmed, err := bp.Confirmed.Add(other.Confirmed)
+	if err != nil {
+		return BalancePair{}, err
+	}
+
+	predicted, err := bp.Predicted.Add(other.Predicted)
+	if err != nil {
+		return BalancePair{}, err
+	}
+
+	return BalancePair{
+		Confirmed: confirmed,
+		Predicted: predicted,
+	}, nil
+}
+
+// BalanceGetter interface for getting the balances of addresses
+type BalanceGetter interface {
+	GetBalanceOfAddresses(addrs []cipher.Address) ([]BalancePair, error)
+}
+
+// UnspentOutputsGetter interface for getting the unspent outputs of addresses that can be spent,
+// which excludes the outputs spent by unconfirmed transactions
+type UnspentOutputsGetter interface {
+	GetSpendableOutputsOfAddresses(addrs []cipher.Address) (coin.AddressUxOuts, error)
+}
+
+// balanceTransactionsFinder is a TransactionsFinder that reports the addresses with a balance as active
+type balanceTransactionsFinder struct {
+	bg BalanceGetter
+}
+
+func (f balanceTransactionsFinder) AddressesActivity(addrs []cipher.Addresser) ([]bool, error) {
+	bps, err := f.bg.GetBalanceOfAddresses(SkycoinAddresses(addrs))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bps) != len(addrs) {
+		return nil, fmt.Errorf("got %d balances for %d addresses", len(bps), len(addrs))
+	}
+
+	active := make([]bool, len(addrs))
+	for i, bp := range bps {
+		active[i] = bp.Confirmed.Coins > 0 || bp.Predicted.Coins > 0
+	}
+	return active, nil
+}
+
 // AddressBalances represents a map of address balances
 type AddressBalances map[string]BalancePair
 
@@ -0,0 +1,113 @@
+package wallet
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// walletBalanceCache holds the cached balances of the addresses of a wallet
+type walletBalanceCache struct {
+	balances map[cipher.Address]cachedBalance
+}
+
+// cachedBalance is the balance of an address and the time it was fetched at
+type cachedBalance struct {
+	balance   BalancePair
+	fetchedAt time.Time
+}
+
+// GetCachedBalance returns the total balance of the wallet's addresses, like GetBalance, but serves the
+// balance of each address from a cache for Config.BalanceCacheTTL after it was fetched.
+// Only the addresses whose balance is missing or expired are requested from bg.
+// The cache of a wallet is cleared when the wallet changes, e.g. when addresses are added to it,
+// and when a transaction is created from it. Every balance is fetched from bg if Config.BalanceCacheTTL is 0
+func (serv *Service) GetCachedBalance(wltID string, bg BalanceGetter) (BalancePair, error) {
+	var addrs []cipher.Address
+	if err := serv.View(wltID, func(w Wallet) error {
+		as, err := w.GetAddresses()
+		if err != nil {
+			return err
+		}
+		addrs = SkycoinAddresses(as)
+		return nil
+	}); err != nil {
+		return BalancePair{}, err
+	}
+
+	ttl := serv.config.BalanceCacheTTL
+	now := time.Now()
+
+	serv.balanceCacheMu.Lock()
+	cache := serv.balanceCache[wltID]
+	if cache == nil {
+		cache = &walletBalanceCache{
+			balances: make(map[cipher.Address]cachedBalance),
+		}
+		if ttl > 0 {
+			serv.balanceCache[wltID] = cache
+		}
+	}
+
+	balances := make([]BalancePair, len(addrs))
+	var missing []cipher.Address
+	var missingIdx []int
+	for i, a := range addrs {
+		b, ok := cache.balances[a]
+		if ok && now.Sub(b.fetchedAt) < ttl {
+			balances[i] = b.balance
+			continue
+		}
+		missing = append(missing, a)
+		missingIdx = append(missingIdx, i)
+	}
+	serv.balanceCacheMu.Unlock()
+
+	if len(missing) != 0 {
+		fetched, err := bg.GetBalanceOfAddresses(missing)
+		if err != nil {
+			return BalancePair{}, err
+		}
+
+		if len(fetched) != len(missing) {
+			return BalancePair{}, fmt.Errorf("got %d balances for %d addresses", len(fetched), len(missing))
+		}
+
+		for i, b := range fetched {
+			balances[missingIdx[i]] = b
+		}
+
+		// The balances are only cached if the cache was not cleared while they were fetched,
+		// otherwise they could be older than the change that cleared it
+		serv.balanceCacheMu.Lock()
+		if ttl > 0 && serv.balanceCache[wltID] == cache {
+			for i, a := range missing {
+				cache.balances[a] = cachedBalance{
+					balance:   fetched[i],
+					fetchedAt: now,
+				}
+			}
+		}
+		serv.balanceCacheMu.Unlock()
+	}
+
+	var total BalancePair
+	for _, b := range balances {
+		var err error
+		total, err = total.Add(b)
+		if err != nil {
+			return BalancePair{}, err
+		}
+	}
+
+	return total, nil
+}
+
+// InvalidateBalanceCache clears the cached balances of the wallet, see GetCachedBalance.
+// It must be called after a transaction spending from the wallet is created outside of the service methods
+func (serv *Service) InvalidateBalanceCache(wltID string) {
+	serv.balanceCacheMu.Lock()
+	defer serv.balanceCacheMu.Unlock()
+	delete(serv.balanceCache, wltID)
+}
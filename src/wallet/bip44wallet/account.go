@@ -97,9 +97,8 @@ func (a *bip44Account) newAddresses(chainIndex, num uint32) ([]cipher.Addresser,
 // erase wipes sensitive data
 func (a *bip44Account) erase() {
 	if a.Account.PrivateKey != nil {
-		for i := range a.Account.Key {
-			a.Account.Key[i] = 0
-		}
+		wallet.EraseBytes(a.Account.Key)
+		wallet.EraseBytes(a.Account.ChainCode)
 		a.Account.PrivateKey = nil
 		a.Account = bip44.Account{}
 	}
@@ -238,6 +237,16 @@ func (a bip44Account) getEntry(address cipher.Addresser) (wallet.Entry, bool) {
 	return wallet.Entry{}, false
 }
 
+func (a *bip44Account) setEntryLabel(address cipher.Addresser, label string) bool {
+	for i := range a.Chains {
+		if a.Chains[i].Entries.SetLabel(address, label) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Clone clones the bip44Account, it would also hide the
 // bip44.Account.Clone() function so that user would not
 // call it mistakenly.
@@ -516,6 +525,15 @@ func (a bip44Accounts) getEntry(account uint32, address cipher.Addresser) (walle
 	return e, ok, nil
 }
 
+func (a *bip44Accounts) setEntryLabel(account uint32, address cipher.Addresser, label string) (bool, error) {
+	act, err := a.account(account)
+	if err != nil {
+		return false, err
+	}
+
+	return act.setEntryLabel(address, label), nil
+}
+
 func (a *bip44Accounts) syncSecrets(ss wallet.Secrets) error {
 	for _, act := range a.accounts {
 		if err := act.syncSecrets(ss); err != nil {
@@ -199,6 +199,7 @@ func newBip44EntryFromReadable(re readableBip44Entry, d wallet.AddressSecKeyDeco
 		Public:      p,
 		Secret:      secKey,
 		ChildNumber: re.ChildNumber,
+		Label:       re.Label,
 	}, nil
 }
 
@@ -208,6 +209,7 @@ type readableBip44Entry struct {
 	Public      string `json:"public"`
 	Secret      string `json:"secret"`
 	ChildNumber uint32 `json:"child_number"` // For bip32/bip44
+	Label       string `json:"label,omitempty"`
 }
 
 // newReadableBip44Accounts converts bip44Accounts to ReadableBip44Accounts
@@ -259,6 +261,7 @@ func newReadableBip44Chains(cs []bip44Chain, d wallet.SecKeyDecoder) ([]readable
 				Public:      e.Public.Hex(),
 				ChildNumber: e.ChildNumber,
 				Secret:      secret,
+				Label:       e.Label,
 			})
 		}
 		rcs = append(rcs, rc)
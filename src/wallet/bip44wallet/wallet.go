@@ -68,6 +68,8 @@ type accountManager interface {
 	entryAt(account, chain, index uint32) (wallet.Entry, error)
 	// getEntry returns the entry of given address
 	getEntry(account uint32, address cipher.Addresser) (wallet.Entry, bool, error)
+	// setEntryLabel sets the label of the entry with given address
+	setEntryLabel(account uint32, address cipher.Addresser, label string) (bool, error)
 	// len returns the account number
 	len() uint32
 	// clone returns a deep clone accounts manager
@@ -157,17 +159,21 @@ func NewWallet(filename, label, seed, seedPassphrase string, options ...wallet.O
 
 	// Generate addresses if options.GenrateN > 0
 	generateN := advOpts.GenerateN
-	if generateN == 0 {
+	if generateN == 0 && !advOpts.NoDefaultAddresses {
 		generateN = 1
 	}
 
-	if _, err := wlt.GenerateAddresses(wallet.OptionGenerateN(generateN)); err != nil {
-		return nil, err
+	if generateN > 0 {
+		if _, err := wlt.GenerateAddresses(wallet.OptionGenerateN(generateN)); err != nil {
+			return nil, err
+		}
 	}
 
 	// Generate a default change address
-	if _, err := wlt.GenerateAddresses(wallet.OptionGenerateN(1), wallet.OptionChange()); err != nil {
-		return nil, err
+	if !advOpts.NoDefaultAddresses {
+		if _, err := wlt.GenerateAddresses(wallet.OptionGenerateN(1), wallet.OptionChange()); err != nil {
+			return nil, err
+		}
 	}
 
 	scanN := advOpts.ScanN
@@ -186,6 +192,12 @@ func NewWallet(filename, label, seed, seedPassphrase string, options ...wallet.O
 		}
 	}
 
+	if advOpts.GapLimit > 0 {
+		if _, err := wallet.ScanAddressesGapLimit(wlt, advOpts.GapLimit, advOpts.ScanChunkSize, advOpts.TF); err != nil {
+			return nil, err
+		}
+	}
+
 	// encrypts wallet if options.Encrypt is true
 	if advOpts.Encrypt {
 		if wlt.IsTemp() {
@@ -357,13 +369,14 @@ func (w *Wallet) Lock(password []byte) error {
 	if err != nil {
 		return err
 	}
+	defer wallet.EraseBytes(sb)
 
 	cryptoType := wlt.Meta.CryptoType()
 	if cryptoType == "" {
 		cryptoType = crypto.DefaultCryptoType
 	}
 
-	cryptor, err := crypto.GetCrypto(cryptoType)
+	cryptor, err := wallet.NewCryptor(cryptoType, wlt.Meta)
 	if err != nil {
 		return err
 	}
@@ -416,12 +429,8 @@ func (w *Wallet) Unlock(password []byte) (wallet.Wallet, error) {
 		return nil, wallet.ErrInvalidPassword
 	}
 
-	defer func() {
-		// Wipes the data from secrets bytes buffer
-		for i := range sb {
-			sb[i] = 0
-		}
-	}()
+	// Wipes the data from secrets bytes buffer
+	defer wallet.EraseBytes(sb)
 
 	ss := make(wallet.Secrets)
 	defer ss.Erase()
@@ -434,6 +443,7 @@ func (w *Wallet) Unlock(password []byte) (wallet.Wallet, error) {
 	initSSLen := len(ss)
 	// fills secrets for those new generated addresses
 	if err := cw.syncSecrets(ss); err != nil {
+		cw.Erase()
 		return nil, err
 	}
 
@@ -443,6 +453,7 @@ func (w *Wallet) Unlock(password []byte) (wallet.Wallet, error) {
 		if err != nil {
 			return nil, err
 		}
+		defer wallet.EraseBytes(sb)
 
 		encSecret, err := cryptor.Encrypt(sb, password)
 		if err != nil {
@@ -454,6 +465,8 @@ func (w *Wallet) Unlock(password []byte) (wallet.Wallet, error) {
 	}
 
 	if err := cw.unpackSecrets(ss); err != nil {
+		// Wipes the secrets already copied into the partially unlocked wallet
+		cw.Erase()
 		return nil, err
 	}
 	cw.SetDecrypted()
@@ -490,6 +503,12 @@ func (w Wallet) Fingerprint() string {
 	} else {
 		addr = entries[0].Address.String()
 	}
+
+	// The first address of an encrypted wallet without entries can't be derived,
+	// so the wallet can't be identified
+	if addr == "" {
+		return ""
+	}
 	return fmt.Sprintf("%s-%s", cw.Type(), addr)
 }
 
@@ -753,6 +772,22 @@ func (w *Wallet) HasEntry(addr cipher.Addresser, options ...wallet.Option) (bool
 	return ok, nil
 }
 
+// SetEntryLabel sets the label of the entry with given address on selected account,
+// if no options are provided, the account 0 will be searched.
+func (w *Wallet) SetEntryLabel(addr cipher.Addresser, label string, options ...wallet.Option) error {
+	opts := getBip44Options(options...)
+	ok, err := w.setEntryLabel(opts.Account, addr, label)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return wallet.ErrEntryNotFound
+	}
+
+	return nil
+}
+
 // EntriesLen returns the entries length of selected account and chain,
 // if no options are provided, entries length of all chains will
 // be returned.
@@ -880,6 +915,10 @@ func convertOptions(options wallet.Options) []wallet.Option {
 		opts = append(opts, wallet.OptionCryptoType(options.CryptoType))
 	}
 
+	if options.ScryptParams != nil {
+		opts = append(opts, wallet.OptionScryptParams(*options.ScryptParams))
+	}
+
 	if options.Decoder != nil {
 		opts = append(opts, wallet.OptionDecoder(options.Decoder))
 	}
@@ -893,11 +932,21 @@ func convertOptions(options wallet.Options) []wallet.Option {
 		opts = append(opts, wallet.OptionGenerateN(options.GenerateN))
 	}
 
+	if options.NoDefaultAddresses {
+		opts = append(opts, wallet.OptionNoDefaultAddresses(true))
+	}
+
 	if options.ScanN > 0 {
 		opts = append(opts, wallet.OptionScanN(options.ScanN))
 		opts = append(opts, wallet.OptionTransactionsFinder(options.TF))
 	}
 
+	if options.GapLimit > 0 {
+		opts = append(opts, wallet.OptionGapLimit(options.GapLimit))
+		opts = append(opts, wallet.OptionScanChunkSize(options.ScanChunkSize))
+		opts = append(opts, wallet.OptionTransactionsFinder(options.TF))
+	}
+
 	if options.Temp {
 		opts = append(opts, wallet.OptionTemp(true))
 	}
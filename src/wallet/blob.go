@@ -0,0 +1,247 @@
+package wallet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/skycoin/skycoin/src/cipher/chacha20poly1305"
+	"github.com/skycoin/skycoin/src/cipher/crypto"
+	"github.com/skycoin/skycoin/src/cipher/encrypt"
+)
+
+// EncryptedBlobVersion is the format version of the blobs written by Service.ExportEncryptedBlob
+const EncryptedBlobVersion = 1
+
+// encryptedBlobMagic starts every encrypted wallet blob
+const encryptedBlobMagic = "SKYWLT"
+
+// maxEncryptedBlobWalletSize is the maximum size of the serialized wallet of a blob, once decompressed
+const maxEncryptedBlobWalletSize = 64 << 20
+
+// ErrInvalidEncryptedBlob is returned by Service.ImportEncryptedBlob if the blob is malformed or has an unknown version
+var ErrInvalidEncryptedBlob = NewError(errors.New("invalid encrypted wallet blob"))
+
+/*
+An encrypted wallet blob is made of:
+
+	magic        "SKYWLT"
+	version      1 byte, EncryptedBlobVersion
+	crypto type  1 byte length, followed by the crypto type name
+	payload      the serialized decrypted wallet, gzip compressed, then encrypted with the crypto type and the blob password
+*/
+
+// ExportEncryptedBlob returns a compact, self-contained copy of the wallet encrypted with password,
+// e.g. to transfer the wallet to a mobile app with a QR code. The whole wallet, including the seed and the
+// entries, is encrypted with the crypto type of the service, so the blob can only be read with the password.
+// If the wallet is encrypted, password must be its password; otherwise it is the new password of the blob.
+// ImportEncryptedBlob imports the blob.
+func (serv *Service) ExportEncryptedBlob(wltID string, password []byte) ([]byte, error) {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return nil, err
+	}
+	if serv.config.ReadOnly {
+		return nil, ErrWalletReadOnly
+	}
+
+	if len(password) == 0 {
+		return nil, ErrMissingPassword
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !w.IsEncrypted() {
+		if err := CheckPasswordStrength(password, serv.config.MinPasswordLength); err != nil {
+			return nil, err
+		}
+	}
+
+	var blob []byte
+	f := func(w Wallet) error {
+		var err error
+		blob, err = encryptWalletBlob(w, password, serv.config.CryptoType)
+		return err
+	}
+
+	if w.IsEncrypted() {
+		err = serv.guardView(w, password, f)
+	} else {
+		err = f(w)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return blob, nil
+}
+
+// ImportEncryptedBlob adds the wallet of a blob written by ExportEncryptedBlob to the service, as wltName.
+// A unique filename is generated if wltName is empty. Returns ErrInvalidPassword if password does not
+// decrypt the blob, and ErrInvalidEncryptedBlob if the blob is malformed. The blob is untrusted, so its crypto type
+// must be registered, its crypto parameters are bounded by the cryptor and its decompressed size is limited. The wallet is saved encrypted with password, unless it has no secrets, e.g. an xpub wallet.
+// The same checks as ImportWallet are applied.
+func (serv *Service) ImportEncryptedBlob(blob, password []byte, wltName string) (Wallet, error) {
+	serv.Lock()
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return nil, err
+	}
+	if serv.config.ReadOnly {
+		return nil, ErrWalletReadOnly
+	}
+
+	if len(password) == 0 {
+		return nil, ErrMissingPassword
+	}
+
+	if err := CheckPasswordStrength(password, serv.config.MinPasswordLength); err != nil {
+		return nil, err
+	}
+
+	if err := serv.checkMaxWallets(1); err != nil {
+		return nil, err
+	}
+
+	if wltName == "" {
+		wltName = serv.generateUniqueWalletFilename()
+	} else {
+		if filepath.Base(wltName) != wltName || !strings.HasSuffix(wltName, "."+WalletExt) || wltName == "."+WalletExt {
+			return nil, ErrInvalidWalletFilename
+		}
+
+		if serv.wallets.get(wltName) != nil {
+			return nil, ErrWalletNameConflict
+		}
+
+		if !serv.config.InMemory {
+			if _, err := os.Stat(filepath.Join(serv.config.WalletDir, wltName)); !os.IsNotExist(err) {
+				return nil, ErrWalletNameConflict
+			}
+		}
+	}
+
+	w, err := decryptWalletBlob(blob, password)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := serv.checkImportedWallet(w, "blob"); err != nil {
+		return nil, err
+	}
+
+	w.SetFilename(wltName)
+
+	switch w.Type() {
+	case WalletTypeXPub, WalletTypeWatchOnly:
+	default:
+		w.SetCryptoType(serv.config.CryptoType)
+		if err := w.Lock(password); err != nil {
+			return nil, err
+		}
+	}
+
+	return serv.addImportedWallet(w)
+}
+
+// encryptWalletBlob serializes the decrypted wallet w and encrypts it into a blob
+func encryptWalletBlob(w Wallet, password []byte, cryptoType crypto.CryptoType) ([]byte, error) {
+	cryptor, err := crypto.GetCrypto(cryptoType)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := w.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	defer EraseBytes(data)
+
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	defer EraseBytes(buf.Bytes())
+
+	payload, err := cryptor.Encrypt(buf.Bytes(), password)
+	if err != nil {
+		return nil, err
+	}
+
+	blob := make([]byte, 0, len(encryptedBlobMagic)+2+len(cryptoType)+len(payload))
+	blob = append(blob, encryptedBlobMagic...)
+	blob = append(blob, EncryptedBlobVersion, byte(len(cryptoType)))
+	blob = append(blob, cryptoType...)
+	return append(blob, payload...), nil
+}
+
+// decryptWalletBlob decrypts a blob written by encryptWalletBlob and loads its wallet
+func decryptWalletBlob(blob, password []byte) (Wallet, error) {
+	if !bytes.HasPrefix(blob, []byte(encryptedBlobMagic)) {
+		return nil, ErrInvalidEncryptedBlob
+	}
+	blob = blob[len(encryptedBlobMagic):]
+
+	if len(blob) < 2 || blob[0] != EncryptedBlobVersion {
+		return nil, ErrInvalidEncryptedBlob
+	}
+
+	n := int(blob[1])
+	blob = blob[2:]
+	if len(blob) < n {
+		return nil, ErrInvalidEncryptedBlob
+	}
+
+	cryptor, err := crypto.GetCrypto(crypto.CryptoType(blob[:n]))
+	if err != nil {
+		return nil, ErrInvalidEncryptedBlob
+	}
+
+	compressed, err := cryptor.Decrypt(blob[n:], password)
+	switch err {
+	case nil:
+	case chacha20poly1305.ErrOpen, encrypt.ErrInvalidPassword:
+		return nil, ErrInvalidPassword
+	default:
+		return nil, ErrInvalidEncryptedBlob
+	}
+	defer EraseBytes(compressed)
+
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, ErrInvalidEncryptedBlob
+	}
+
+	// The size is limited, so that a small blob can't decompress to an unbounded wallet
+	data, err := ioutil.ReadAll(io.LimitReader(zr, maxEncryptedBlobWalletSize+1))
+	defer EraseBytes(data)
+	if err != nil || len(data) > maxEncryptedBlobWalletSize {
+		return nil, ErrInvalidEncryptedBlob
+	}
+
+	w, err := loadWalletData(data)
+	if err != nil {
+		return nil, err
+	}
+	if w == nil {
+		return nil, ErrInvalidWalletType
+	}
+
+	return w, nil
+}
@@ -191,13 +191,14 @@ func (w *Wallet) Lock(password []byte) error {
 	if err != nil {
 		return err
 	}
+	defer wallet.EraseBytes(sb)
 
 	cryptoType := wlt.CryptoType()
 	if cryptoType == "" {
 		cryptoType = crypto.DefaultCryptoType
 	}
 
-	cryptor, err := crypto.GetCrypto(cryptoType)
+	cryptor, err := wallet.NewCryptor(cryptoType, wlt.Meta)
 	if err != nil {
 		return err
 	}
@@ -245,12 +246,8 @@ func (w *Wallet) Unlock(password []byte) (wallet.Wallet, error) {
 		return nil, wallet.ErrInvalidPassword
 	}
 
-	defer func() {
-		// Wipes the data from secrets bytes buffer
-		for i := range sb {
-			sb[i] = 0
-		}
-	}()
+	// Wipes the data from secrets bytes buffer
+	defer wallet.EraseBytes(sb)
 
 	ss := make(wallet.Secrets)
 	defer ss.Erase()
@@ -260,6 +257,8 @@ func (w *Wallet) Unlock(password []byte) (wallet.Wallet, error) {
 
 	cw := w.Clone().(*Wallet)
 	if err := cw.unpackSecrets(ss); err != nil {
+		// Wipes the secrets already copied into the partially unlocked wallet
+		cw.Erase()
 		return nil, err
 	}
 	cw.SetDecrypted()
@@ -396,6 +395,14 @@ func (w *Wallet) HasEntry(a cipher.Addresser, _ ...wallet.Option) (bool, error)
 	return w.entries.Has(a), nil
 }
 
+// SetEntryLabel sets the label of the entry with given address
+func (w *Wallet) SetEntryLabel(a cipher.Addresser, label string, _ ...wallet.Option) error {
+	if !w.entries.SetLabel(a, label) {
+		return wallet.ErrEntryNotFound
+	}
+	return nil
+}
+
 // EntriesLen returns the number of entries in the wallet
 func (w *Wallet) EntriesLen(_ ...wallet.Option) (int, error) {
 	return len(w.entries), nil
@@ -465,6 +472,10 @@ func convertOptions(options wallet.Options) []wallet.Option {
 		opts = append(opts, wallet.OptionCryptoType(options.CryptoType))
 	}
 
+	if options.ScryptParams != nil {
+		opts = append(opts, wallet.OptionScryptParams(*options.ScryptParams))
+	}
+
 	if options.Decoder != nil {
 		opts = append(opts, wallet.OptionDecoder(options.Decoder))
 	}
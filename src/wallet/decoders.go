@@ -29,6 +29,11 @@ func (a addressSecKeyDecoders) get(coinType CoinType) AddressSecKeyDecoder {
 	return adpt
 }
 
+func (a addressSecKeyDecoders) has(coinType CoinType) bool {
+	_, ok := a.adapters[coinType]
+	return ok
+}
+
 func (a addressSecKeyDecoders) add(coinType CoinType, ca AddressSecKeyDecoder) error {
 	if _, ok := a.adapters[coinType]; ok {
 		return fmt.Errorf("coin adapter for %s already registered", coinType)
@@ -42,6 +47,11 @@ func RegisterAddressSecKeyDecoder(coinType CoinType, d AddressSecKeyDecoder) err
 	return registeredAddressSecKeyDecoders.add(coinType, d)
 }
 
+// IsRegisteredCoinType returns true if an address and seckey decoder is registered for the coin type
+func IsRegisteredCoinType(coinType CoinType) bool {
+	return registeredAddressSecKeyDecoders.has(coinType)
+}
+
 // ResolveAddressSecKeyDecoder returns an address and seckey decoder by coin type,
 // if the corresponding decoder of is not found, returns
 // the skycoin decoder.
@@ -0,0 +1,145 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/transaction"
+	"github.com/skycoin/skycoin/src/util/droplet"
+	"github.com/skycoin/skycoin/src/util/mathutil"
+)
+
+// TransactionSummary is a display-ready summary of a transaction, returned by DescribeTransaction.
+// Coin amounts are decimal strings in coins, e.g. "1.500000", and hours are coin hours.
+type TransactionSummary struct {
+	Inputs  []TransactionSummaryInput
+	Outputs []TransactionSummaryOutput
+
+	InputCoins  string
+	InputHours  uint64
+	OutputCoins string
+	OutputHours uint64
+	// Fee is the number of coin hours burned by the transaction
+	Fee uint64
+	// ChangeCoins and ChangeHours are the totals of the outputs marked as change
+	ChangeCoins string
+	ChangeHours uint64
+	// Memo is the memo recorded in the wallet for the transaction, only set by Service.DescribeTransaction
+	Memo []byte
+}
+
+// TransactionSummaryInput is an output spent by the summarized transaction
+type TransactionSummaryInput struct {
+	Hash    cipher.SHA256
+	Address cipher.Address
+	Coins   string
+	Hours   uint64
+}
+
+// TransactionSummaryOutput is an output created by the summarized transaction
+type TransactionSummaryOutput struct {
+	Address cipher.Address
+	Coins   string
+	Hours   uint64
+	// IsChange is true if the output is sent back to the owner of one of the inputs
+	IsChange bool
+}
+
+// DescribeTransaction summarizes a transaction, such as one returned by CreateTransaction, for display.
+// The inputs must be the outputs spent by the transaction, in the order of the transaction inputs,
+// otherwise ErrTransactionInputsMismatch is returned.
+// An output is detected as change if its address owns one of the inputs. Change sent to a new address,
+// e.g. on the change chain of a bip44 wallet, is not detected.
+func DescribeTransaction(txn *coin.Transaction, inputs []transaction.UxBalance) (TransactionSummary, error) {
+	if len(inputs) != len(txn.In) {
+		return TransactionSummary{}, ErrTransactionInputsMismatch
+	}
+
+	var s TransactionSummary
+	var inputCoins, outputCoins, changeCoins uint64
+	var err error
+	inputAddrs := make(map[cipher.Address]struct{}, len(inputs))
+	s.Inputs = make([]TransactionSummaryInput, len(inputs))
+	for i, in := range inputs {
+		if in.Hash != txn.In[i] {
+			return TransactionSummary{}, ErrTransactionInputsMismatch
+		}
+
+		inputCoins, err = mathutil.AddUint64(inputCoins, in.Coins)
+		if err != nil {
+			return TransactionSummary{}, err
+		}
+		s.InputHours, err = mathutil.AddUint64(s.InputHours, in.Hours)
+		if err != nil {
+			return TransactionSummary{}, err
+		}
+
+		coins, err := droplet.ToString(in.Coins)
+		if err != nil {
+			return TransactionSummary{}, err
+		}
+
+		s.Inputs[i] = TransactionSummaryInput{
+			Hash:    in.Hash,
+			Address: in.Address,
+			Coins:   coins,
+			Hours:   in.Hours,
+		}
+		inputAddrs[in.Address] = struct{}{}
+	}
+
+	s.Outputs = make([]TransactionSummaryOutput, len(txn.Out))
+	for i, o := range txn.Out {
+		outputCoins, err = mathutil.AddUint64(outputCoins, o.Coins)
+		if err != nil {
+			return TransactionSummary{}, err
+		}
+		s.OutputHours, err = mathutil.AddUint64(s.OutputHours, o.Hours)
+		if err != nil {
+			return TransactionSummary{}, err
+		}
+
+		coins, err := droplet.ToString(o.Coins)
+		if err != nil {
+			return TransactionSummary{}, err
+		}
+
+		_, isChange := inputAddrs[o.Address]
+		if isChange {
+			// Can't overflow, the change is part of the output coins and hours
+			changeCoins += o.Coins
+			s.ChangeHours += o.Hours
+		}
+
+		s.Outputs[i] = TransactionSummaryOutput{
+			Address:  o.Address,
+			Coins:    coins,
+			Hours:    o.Hours,
+			IsChange: isChange,
+		}
+	}
+
+	if outputCoins != inputCoins {
+		return TransactionSummary{}, errors.New("Transaction output coins do not match input coins")
+	}
+	if s.OutputHours > s.InputHours {
+		return TransactionSummary{}, errors.New("Transaction output hours exceed input hours")
+	}
+	s.Fee = s.InputHours - s.OutputHours
+
+	s.InputCoins, err = droplet.ToString(inputCoins)
+	if err != nil {
+		return TransactionSummary{}, err
+	}
+	s.OutputCoins, err = droplet.ToString(outputCoins)
+	if err != nil {
+		return TransactionSummary{}, err
+	}
+	s.ChangeCoins, err = droplet.ToString(changeCoins)
+	if err != nil {
+		return TransactionSummary{}, err
+	}
+
+	return s, nil
+}
@@ -32,11 +32,12 @@ type readableEntry struct {
 	Address string `json:"address"`
 	Public  string `json:"public_key"`
 	Secret  string `json:"secret_key"`
+	Label   string `json:"label,omitempty"`
 }
 
 // newReadableEntry creates readable wallet entry
 func newReadableEntry(coinType wallet.CoinType, e wallet.Entry) readableEntry {
-	re := readableEntry{}
+	re := readableEntry{Label: e.Label}
 	if !e.Address.Null() {
 		re.Address = e.Address.String()
 	}
@@ -129,6 +130,7 @@ func newEntryFromReadable(coinType wallet.CoinType, re *readableEntry) (*wallet.
 		Address: a,
 		Public:  p,
 		Secret:  secret,
+		Label:   re.Label,
 	}, nil
 }
 
@@ -49,16 +49,17 @@ func NewWallet(filename, label, seed string, options ...wallet.Option) (*Wallet,
 
 	var wlt = &Wallet{
 		Meta: wallet.Meta{
-			wallet.MetaFilename:   filename,
-			wallet.MetaLabel:      label,
-			wallet.MetaSeed:       seed,
-			wallet.MetaLastSeed:   seed,
-			wallet.MetaEncrypted:  "false",
-			wallet.MetaType:       WalletType,
-			wallet.MetaVersion:    wallet.Version,
-			wallet.MetaCoin:       string(wallet.CoinTypeSkycoin),
-			wallet.MetaCryptoType: string(crypto.DefaultCryptoType),
-			wallet.MetaTimestamp:  strconv.FormatInt(time.Now().Unix(), 10),
+			wallet.MetaFilename:      filename,
+			wallet.MetaLabel:         label,
+			wallet.MetaSeed:          seed,
+			wallet.MetaLastSeed:      seed,
+			wallet.MetaLastSeedIndex: "0",
+			wallet.MetaEncrypted:     "false",
+			wallet.MetaType:          WalletType,
+			wallet.MetaVersion:       wallet.Version,
+			wallet.MetaCoin:          string(wallet.CoinTypeSkycoin),
+			wallet.MetaCryptoType:    string(crypto.DefaultCryptoType),
+			wallet.MetaTimestamp:     strconv.FormatInt(time.Now().Unix(), 10),
 		},
 		entries: wallet.Entries{},
 		decoder: defaultWalletDecoder,
@@ -99,6 +100,12 @@ func NewWallet(filename, label, seed string, options ...wallet.Option) (*Wallet,
 		}
 	}
 
+	if advOpts.GapLimit > 0 {
+		if _, err := wallet.ScanAddressesGapLimit(wlt, advOpts.GapLimit, advOpts.ScanChunkSize, advOpts.TF); err != nil {
+			return nil, err
+		}
+	}
+
 	// encrypts wallet if options.Encrypt is true
 	if advOpts.Encrypt {
 		if wlt.IsTemp() {
@@ -201,13 +208,14 @@ func (w *Wallet) Lock(password []byte) error {
 	if err != nil {
 		return err
 	}
+	defer wallet.EraseBytes(sb)
 
 	cryptoType := wlt.CryptoType()
 	if cryptoType == "" {
 		cryptoType = crypto.DefaultCryptoType
 	}
 
-	cryptor, err := crypto.GetCrypto(cryptoType)
+	cryptor, err := wallet.NewCryptor(cryptoType, wlt.Meta)
 	if err != nil {
 		return err
 	}
@@ -257,12 +265,8 @@ func (w *Wallet) Unlock(password []byte) (wallet.Wallet, error) {
 		return nil, wallet.ErrInvalidPassword
 	}
 
-	defer func() {
-		// Wipes the data from secrets bytes buffer
-		for i := range sb {
-			sb[i] = 0
-		}
-	}()
+	// Wipes the data from secrets bytes buffer
+	defer wallet.EraseBytes(sb)
 
 	ss := make(wallet.Secrets)
 	defer ss.Erase()
@@ -272,6 +276,8 @@ func (w *Wallet) Unlock(password []byte) (wallet.Wallet, error) {
 
 	cw := w.Clone().(*Wallet)
 	if err := cw.unpackSecrets(ss); err != nil {
+		// Wipes the secrets already copied into the partially unlocked wallet
+		cw.Erase()
 		return nil, err
 	}
 	cw.SetDecrypted()
@@ -318,6 +324,12 @@ func (w *Wallet) Fingerprint() string {
 	} else {
 		addr = w.entries[0].Address.String()
 	}
+
+	// The first address of an encrypted wallet without entries can't be derived,
+	// so the wallet can't be identified
+	if addr == "" {
+		return ""
+	}
 	return fmt.Sprintf("%s-%s", w.Type(), addr)
 }
 
@@ -388,8 +400,6 @@ func (w *Wallet) ScanAddresses(scanN uint64, tf wallet.TransactionsFinder) ([]ci
 
 	w2 := w.Clone().(*Wallet)
 
-	nExistingAddrs := uint64(len(w2.entries))
-
 	// Generate the addresses to scan
 	addrs, err := w2.GenerateAddresses(wallet.OptionGenerateN(scanN))
 	if err != nil {
@@ -411,18 +421,14 @@ func (w *Wallet) ScanAddresses(scanN uint64, tf wallet.TransactionsFinder) ([]ci
 		}
 	}
 
-	// Regenerate addresses up to nExistingAddrs + nAddAddrs.
-	// This is necessary to keep the lastSeed updated.
-	w2.reset()
-	//if _, err := w2.GenerateSkycoinAddresses(nExistingAddrs + keepNum); err != nil {
-	//	return nil, err
-	//}
-
-	if _, err := w2.GenerateAddresses(wallet.OptionGenerateN(nExistingAddrs + keepNum)); err != nil {
+	// Generate the kept addresses on a fresh copy, so that the lastSeed and its index
+	// only move past the addresses that are kept
+	w3 := w.Clone().(*Wallet)
+	if _, err := w3.GenerateAddresses(wallet.OptionGenerateN(keepNum)); err != nil {
 		return nil, err
 	}
 
-	*w = *w2
+	*w = *w3
 
 	return addrs[:keepNum], nil
 }
@@ -440,7 +446,8 @@ func (w *Wallet) GenerateAddresses(options ...wallet.Option) ([]cipher.Addresser
 
 	var seckeys []cipher.SecKey
 	var seed []byte
-	if len(w.entries) == 0 {
+	lastSeedIndex := w.lastSeedIndex()
+	if lastSeedIndex == 0 {
 		seed, seckeys = cipher.MustGenerateDeterministicKeyPairsSeed([]byte(w.Meta.Seed()), int(num))
 	} else {
 		sd, err := hex.DecodeString(w.Meta.LastSeed())
@@ -451,6 +458,7 @@ func (w *Wallet) GenerateAddresses(options ...wallet.Option) ([]cipher.Addresser
 	}
 
 	w.Meta.SetLastSeed(hex.EncodeToString(seed))
+	w.Meta.SetLastSeedIndex(lastSeedIndex + num)
 
 	addrs := make([]cipher.Addresser, len(seckeys))
 	makeAddress := wallet.AddressConstructor(w.Meta)
@@ -467,6 +475,15 @@ func (w *Wallet) GenerateAddresses(options ...wallet.Option) ([]cipher.Addresser
 	return addrs, nil
 }
 
+// lastSeedIndex returns the position of the lastSeed in the seed chain.
+// Wallets that don't track it have generated one key per entry.
+func (w *Wallet) lastSeedIndex() uint64 {
+	if _, ok := w.Meta[wallet.MetaLastSeedIndex]; !ok {
+		return uint64(len(w.entries))
+	}
+	return w.Meta.LastSeedIndex()
+}
+
 // GetAddresses returns all addresses in wallet
 func (w *Wallet) GetAddresses(_ ...wallet.Option) ([]cipher.Addresser, error) {
 	return w.entries.GetAddresses(), nil
@@ -500,17 +517,19 @@ func (w *Wallet) HasEntry(a cipher.Addresser, _ ...wallet.Option) (bool, error)
 	return w.entries.Has(a), nil
 }
 
+// SetEntryLabel sets the label of the entry with given address
+func (w *Wallet) SetEntryLabel(a cipher.Addresser, label string, _ ...wallet.Option) error {
+	if !w.entries.SetLabel(a, label) {
+		return wallet.ErrEntryNotFound
+	}
+	return nil
+}
+
 // EntriesLen returns the number of entries in the wallet
 func (w *Wallet) EntriesLen(_ ...wallet.Option) (int, error) {
 	return len(w.entries), nil
 }
 
-// reset resets the wallet entries and move the lastSeed to origin
-func (w *Wallet) reset() {
-	w.entries = wallet.Entries{}
-	w.Meta.SetLastSeed(w.Meta.Seed())
-}
-
 // Loader implements the wallet.Loader interface
 type Loader struct{}
 
@@ -539,10 +558,18 @@ func convertOptions(options wallet.Options) []wallet.Option {
 		opts = append(opts, wallet.OptionCoinType(options.Coin))
 	}
 
+	if options.AddressType != "" {
+		opts = append(opts, wallet.OptionAddressType(options.AddressType))
+	}
+
 	if options.CryptoType != "" {
 		opts = append(opts, wallet.OptionCryptoType(options.CryptoType))
 	}
 
+	if options.ScryptParams != nil {
+		opts = append(opts, wallet.OptionScryptParams(*options.ScryptParams))
+	}
+
 	if options.Decoder != nil {
 		opts = append(opts, wallet.OptionDecoder(options.Decoder))
 	}
@@ -556,11 +583,25 @@ func convertOptions(options wallet.Options) []wallet.Option {
 		opts = append(opts, wallet.OptionGenerateN(options.GenerateN))
 	}
 
+	if options.NoDefaultAddresses {
+		opts = append(opts, wallet.OptionNoDefaultAddresses(true))
+	}
+
+	if options.SeedType != "" {
+		opts = append(opts, wallet.OptionSeedType(options.SeedType))
+	}
+
 	if options.ScanN > 0 {
 		opts = append(opts, wallet.OptionScanN(options.ScanN))
 		opts = append(opts, wallet.OptionTransactionsFinder(options.TF))
 	}
 
+	if options.GapLimit > 0 {
+		opts = append(opts, wallet.OptionGapLimit(options.GapLimit))
+		opts = append(opts, wallet.OptionScanChunkSize(options.ScanChunkSize))
+		opts = append(opts, wallet.OptionTransactionsFinder(options.TF))
+	}
+
 	if options.Temp {
 		opts = append(opts, wallet.OptionTemp(true))
 	}
@@ -15,6 +15,7 @@ type Entry struct {
 	Secret      cipher.SecKey
 	ChildNumber uint32 // For bip32/bip44
 	Change      uint32 // For bip44
+	Label       string // Optional user defined address label
 }
 
 // SkycoinAddress returns the Skycoin address of an entry. Panics if Address is not a Skycoin address
@@ -83,6 +84,18 @@ func (entries Entries) Get(a cipher.Addresser) (Entry, bool) {
 	return Entry{}, false
 }
 
+// SetLabel sets the label of the entry with specified address,
+// returns false if no such entry exists
+func (entries Entries) SetLabel(a cipher.Addresser, label string) bool {
+	for i := range entries {
+		if entries[i].Address == a {
+			entries[i].Label = label
+			return true
+		}
+	}
+	return false
+}
+
 // GetAddresses returns all addresses
 func (entries Entries) GetAddresses() []cipher.Addresser {
 	addrs := make([]cipher.Addresser, len(entries))
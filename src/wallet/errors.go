@@ -0,0 +1,94 @@
+package wallet
+
+import "fmt"
+
+// DuplicateWalletError is returned by NewService if two wallets in the wallet directory
+// have the same fingerprint, i.e. were generated from the same seed
+type DuplicateWalletError struct {
+	// WalletID is the wallet file found to be a duplicate
+	WalletID string
+	// Fingerprint is the shared fingerprint, composed of the wallet type and its first address
+	Fingerprint string
+}
+
+func (e DuplicateWalletError) Error() string {
+	return fmt.Sprintf("duplicate wallet found with fingerprint %s in file %q", e.Fingerprint, e.WalletID)
+}
+
+// EmptyWalletError is returned by NewService if a wallet in the wallet directory has no addresses
+type EmptyWalletError struct {
+	// WalletID is the empty wallet file
+	WalletID string
+}
+
+func (e EmptyWalletError) Error() string {
+	return fmt.Sprintf("empty wallet file found: %q", e.WalletID)
+}
+
+// LoadWalletError is returned by NewService if a wallet file in the wallet directory can't be loaded
+type LoadWalletError struct {
+	// WalletID is the wallet file that failed to load
+	WalletID string
+	// Err is the underlying error
+	Err error
+}
+
+func (e LoadWalletError) Error() string {
+	return fmt.Sprintf("failed to load wallet %q: %v", e.WalletID, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e LoadWalletError) Unwrap() error {
+	return e.Err
+}
+
+// SaveWalletError is returned by Service.NewAddressesMulti if a wallet can't be saved
+type SaveWalletError struct {
+	// WalletID is the wallet that failed to save
+	WalletID string
+	// Err is the underlying error
+	Err error
+}
+
+func (e SaveWalletError) Error() string {
+	return fmt.Sprintf("failed to save wallet %q: %v", e.WalletID, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e SaveWalletError) Unwrap() error {
+	return e.Err
+}
+
+// WalletDirError is returned by NewService if an operation on the wallet directory fails,
+// the underlying error is usually an *os.PathError
+type WalletDirError struct {
+	// Op is the operation that failed
+	Op string
+	// Dir is the wallet directory
+	Dir string
+	// Err is the underlying error
+	Err error
+}
+
+func (e WalletDirError) Error() string {
+	return fmt.Sprintf("%s failed for wallet directory %s: %v", e.Op, e.Dir, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e WalletDirError) Unwrap() error {
+	return e.Err
+}
+
+// SharedAddressError is returned by Service.VerifyIntegrity if two wallets have the same first address
+type SharedAddressError struct {
+	// WalletID is the wallet found to share its first address
+	WalletID string
+	// OtherWalletID is the wallet that has the same first address
+	OtherWalletID string
+	// Address is the shared first address
+	Address string
+}
+
+func (e SharedAddressError) Error() string {
+	return fmt.Sprintf("wallets %q and %q have the same first address %s", e.OtherWalletID, e.WalletID, e.Address)
+}
@@ -0,0 +1,75 @@
+package wallet
+
+// WalletEvent is the kind of change reported to the listeners registered with Service.OnWalletChange
+type WalletEvent string
+
+const (
+	// WalletEventCreated is fired when a wallet is created, imported or duplicated
+	WalletEventCreated WalletEvent = "created"
+	// WalletEventAddressesAdded is fired when new addresses are generated or scanned
+	WalletEventAddressesAdded WalletEvent = "addresses_added"
+	// WalletEventEncrypted is fired when a wallet is encrypted
+	WalletEventEncrypted WalletEvent = "encrypted"
+	// WalletEventDecrypted is fired when a wallet is decrypted
+	WalletEventDecrypted WalletEvent = "decrypted"
+	// WalletEventLabelUpdated is fired when the wallet label changes
+	WalletEventLabelUpdated WalletEvent = "label_updated"
+	// WalletEventNotesUpdated is fired when the wallet notes change
+	WalletEventNotesUpdated WalletEvent = "notes_updated"
+	// WalletEventAddressLabelUpdated is fired when the label of an address changes
+	WalletEventAddressLabelUpdated WalletEvent = "address_label_updated"
+	// WalletEventRenamed is fired with the new wallet ID when a wallet is renamed,
+	// after WalletEventUnloaded is fired with the old one
+	WalletEventRenamed WalletEvent = "renamed"
+	// WalletEventRecovered is fired when a wallet is recovered from its seed
+	WalletEventRecovered WalletEvent = "recovered"
+	// WalletEventUpdated is fired when a wallet is changed by Service.Update or Service.UpdateSecrets
+	WalletEventUpdated WalletEvent = "updated"
+	// WalletEventReloaded is fired when a wallet is read again from its file by Service.ReloadWallet
+	WalletEventReloaded WalletEvent = "reloaded"
+	// WalletEventUnloaded is fired when a wallet is removed from the service
+	WalletEventUnloaded WalletEvent = "unloaded"
+	// WalletEventDeleted is fired when a wallet is removed from the service and its file is deleted
+	WalletEventDeleted WalletEvent = "deleted"
+)
+
+// walletChange is a change waiting to be reported to the listeners
+type walletChange struct {
+	wltID string
+	event WalletEvent
+}
+
+// OnWalletChange registers fn to be called after a wallet is changed and saved.
+// fn is called outside the service lock, so it may call back into the service.
+// Listeners are called in the order they were registered, from the goroutine that made the change.
+func (serv *Service) OnWalletChange(fn func(wltID string, event WalletEvent)) {
+	serv.Lock()
+	defer serv.Unlock()
+	serv.listeners = append(serv.listeners, fn)
+}
+
+// queueEvent records a change to report once the service lock is released.
+// The caller must hold the service write lock and release it with unlockAndNotify.
+// The cached balances of the wallet are cleared and its addresses are indexed again, since the change may affect them.
+func (serv *Service) queueEvent(wltID string, event WalletEvent) {
+	serv.pendingChanges = append(serv.pendingChanges, walletChange{
+		wltID: wltID,
+		event: event,
+	})
+	serv.InvalidateBalanceCache(wltID)
+	serv.indexWallet(wltID)
+}
+
+// unlockAndNotify releases the service write lock, then reports the queued changes to the listeners
+func (serv *Service) unlockAndNotify() {
+	changes := serv.pendingChanges
+	serv.pendingChanges = nil
+	listeners := serv.listeners
+	serv.Unlock()
+
+	for _, c := range changes {
+		for _, fn := range listeners {
+			fn(c.wltID, c.event)
+		}
+	}
+}
@@ -0,0 +1,538 @@
+package wallet
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/bip44"
+	"github.com/skycoin/skycoin/src/cipher/crypto"
+)
+
+// readWalletHeader reads the meta data of a wallet file, without decoding its entries.
+// The meta field is written first by the wallet decoders, so the rest of the file is not read.
+func readWalletHeader(path string) (Meta, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(f))
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := t.(json.Delim); !ok || d != '{' {
+		return nil, errors.New("wallet file is not a JSON object")
+	}
+
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		if k, ok := t.(string); ok && k == "meta" {
+			var m Meta
+			if err := dec.Decode(&m); err != nil {
+				return nil, err
+			}
+			return m, nil
+		}
+
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, errors.New("missing meta field")
+}
+
+// loadWalletIndex returns a lazyWallet for the wallet file if its header can stand in for the wallet
+// until it is accessed, or nil if the wallet must be loaded now. That is the case of wallets saved
+// without a header or by another version, which have to be migrated, and of invalid headers,
+// whose errors are reported by the full load.
+func loadWalletIndex(path string) *lazyWallet {
+	m, err := readWalletHeader(path)
+	if err != nil {
+		return nil
+	}
+
+	if _, _, ok := m.Header(); !ok || m.Version() != Version {
+		return nil
+	}
+
+	if _, ok := getLoader(m.Type()); !ok {
+		return nil
+	}
+
+	m.SetFilename(filepath.Base(path))
+	if err := m.Validate(); err != nil {
+		return nil
+	}
+
+	return &lazyWallet{
+		path:   path,
+		header: m,
+	}
+}
+
+// firstAddress returns the address of the first entry of the wallet, or "" if it has none.
+// For bip44 wallets it is the first external address of the first account that has one.
+func firstAddress(w Wallet) (string, error) {
+	var options [][]Option
+	if w.Type() == WalletTypeBip44 {
+		for _, a := range w.Accounts() {
+			options = append(options, []Option{OptionAccount(a.Index)})
+		}
+	} else {
+		options = append(options, nil)
+	}
+
+	for _, opts := range options {
+		l, err := w.EntriesLen(opts...)
+		if err != nil {
+			return "", err
+		}
+		if l == 0 {
+			continue
+		}
+
+		e, err := w.GetEntryAt(0, opts...)
+		if err != nil {
+			return "", err
+		}
+		return e.Address.String(), nil
+	}
+
+	return "", nil
+}
+
+// setHeader records the fingerprint and first address of the wallet in its meta data,
+// which is enough for NewService to check the wallet without loading it, see Config.LazyLoad
+func setHeader(w Wallet) error {
+	addr, err := firstAddress(w)
+	if err != nil {
+		return err
+	}
+
+	w.SetHeader(w.Fingerprint(), addr)
+	return nil
+}
+
+// lazyWallet stands in for a wallet of a service with Config.LazyLoad set, until the wallet is accessed.
+// The methods answered by the meta data use the header read from the wallet file.
+// The other methods load the wallet file on first use and cache the wallet.
+// A wallet that fails to load is reported by the methods that return an error;
+// the others log the error and return the zero value.
+type lazyWallet struct {
+	path   string
+	header Meta
+
+	mu sync.Mutex
+	w  Wallet
+}
+
+// load returns the loaded wallet, loading it if needed
+func (lw *lazyWallet) load() (Wallet, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	if lw.w != nil {
+		return lw.w, nil
+	}
+
+	w, err := Load(lw.path)
+	if err != nil {
+		return nil, LoadWalletError{WalletID: lw.header.Filename(), Err: err}
+	}
+	if w == nil {
+		return nil, LoadWalletError{WalletID: lw.header.Filename(), Err: errors.New("no loader for the wallet type")}
+	}
+
+	logger.WithField("filename", lw.path).Info("lazyWallet: loaded wallet")
+
+	lw.w = w
+	return w, nil
+}
+
+// mustLoad loads the wallet for the methods that can't return an error, logging the error if any
+func (lw *lazyWallet) mustLoad() Wallet {
+	w, err := lw.load()
+	if err != nil {
+		logger.WithError(err).WithField("filename", lw.path).Error("lazyWallet: load failed")
+		return nil
+	}
+	return w
+}
+
+// isEmpty reports whether the wallet has no entries, without loading it
+func (lw *lazyWallet) isEmpty() bool {
+	if w := lw.loaded(); w != nil {
+		_, empty := (Wallets{lw.header.Filename(): w}).containsEmpty()
+		return empty
+	}
+
+	_, addr, _ := lw.header.Header()
+	return addr == ""
+}
+
+// firstAddress returns the address of the first entry of the wallet, or "" if it has none,
+// without loading it. The header records the same address as firstAddress.
+func (lw *lazyWallet) firstAddress() (string, error) {
+	if w := lw.loaded(); w != nil {
+		return firstAddress(w)
+	}
+
+	_, addr, _ := lw.header.Header()
+	return addr, nil
+}
+
+// loaded returns the wallet if it is loaded, or nil
+func (lw *lazyWallet) loaded() Wallet {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	return lw.w
+}
+
+// metaGetter are the Wallet methods answered by the meta data
+type metaGetter interface {
+	Seed() string
+	LastSeed() string
+	LastSeedIndex() uint64
+	SeedPassphrase() string
+	Timestamp() int64
+	Coin() CoinType
+	Type() string
+	Bip44Coin() *bip44.CoinType
+	Label() string
+	Notes() string
+	TransactionMemo(innerHash cipher.SHA256) []byte
+	Filename() string
+	IsEncrypted() bool
+	CryptoType() crypto.CryptoType
+	Version() string
+	Secrets() string
+	XPub() string
+	IsTemp() bool
+}
+
+// meta returns the loaded wallet, or the header if the wallet is not loaded
+func (lw *lazyWallet) meta() metaGetter {
+	if w := lw.loaded(); w != nil {
+		return w
+	}
+	return lw.header
+}
+
+// Seed implements the Wallet interface
+func (lw *lazyWallet) Seed() string { return lw.meta().Seed() }
+
+// LastSeed implements the Wallet interface
+func (lw *lazyWallet) LastSeed() string { return lw.meta().LastSeed() }
+
+// LastSeedIndex implements the Wallet interface
+func (lw *lazyWallet) LastSeedIndex() uint64 { return lw.meta().LastSeedIndex() }
+
+// SeedPassphrase implements the Wallet interface
+func (lw *lazyWallet) SeedPassphrase() string { return lw.meta().SeedPassphrase() }
+
+// Timestamp implements the Wallet interface
+func (lw *lazyWallet) Timestamp() int64 { return lw.meta().Timestamp() }
+
+// Coin implements the Wallet interface
+func (lw *lazyWallet) Coin() CoinType { return lw.meta().Coin() }
+
+// Type implements the Wallet interface
+func (lw *lazyWallet) Type() string { return lw.meta().Type() }
+
+// Bip44Coin implements the Wallet interface
+func (lw *lazyWallet) Bip44Coin() *bip44.CoinType { return lw.meta().Bip44Coin() }
+
+// Label implements the Wallet interface
+func (lw *lazyWallet) Label() string { return lw.meta().Label() }
+
+// Notes implements the Wallet interface
+func (lw *lazyWallet) Notes() string { return lw.meta().Notes() }
+
+// TransactionMemo implements the Wallet interface
+func (lw *lazyWallet) TransactionMemo(innerHash cipher.SHA256) []byte {
+	return lw.meta().TransactionMemo(innerHash)
+}
+
+// Filename implements the Wallet interface
+func (lw *lazyWallet) Filename() string { return lw.meta().Filename() }
+
+// IsEncrypted implements the Wallet interface
+func (lw *lazyWallet) IsEncrypted() bool { return lw.meta().IsEncrypted() }
+
+// CryptoType implements the Wallet interface
+func (lw *lazyWallet) CryptoType() crypto.CryptoType { return lw.meta().CryptoType() }
+
+// Version implements the Wallet interface
+func (lw *lazyWallet) Version() string { return lw.meta().Version() }
+
+// Secrets implements the Wallet interface
+func (lw *lazyWallet) Secrets() string { return lw.meta().Secrets() }
+
+// XPub implements the Wallet interface
+func (lw *lazyWallet) XPub() string { return lw.meta().XPub() }
+
+// IsTemp implements the Wallet interface
+func (lw *lazyWallet) IsTemp() bool { return lw.meta().IsTemp() }
+
+// AllowEmpty returns whether the wallet may have no entries, see Meta.AllowEmpty.
+// It is never changed after the wallet is created, so it is read from the header
+func (lw *lazyWallet) AllowEmpty() bool { return lw.header.AllowEmpty() }
+
+// AddressType returns the format of the addresses the wallet creates, see Meta.AddressType.
+// It is never changed after the wallet is created, so it is read from the header
+func (lw *lazyWallet) AddressType() AddressType { return lw.header.AddressType() }
+
+// SeedType returns the type of the seed the wallet was created from, see Meta.SeedType.
+// It is never changed after the wallet is created, so it is read from the header
+func (lw *lazyWallet) SeedType() string { return lw.header.SeedType() }
+
+// Fingerprint implements the Wallet interface, the fingerprint is read from the header until the wallet is loaded
+func (lw *lazyWallet) Fingerprint() string {
+	if w := lw.loaded(); w != nil {
+		return w.Fingerprint()
+	}
+	fp, _, _ := lw.header.Header()
+	return fp
+}
+
+// Clone implements the Wallet interface, it returns a copy of the loaded wallet.
+// A wallet that fails to load is copied unloaded.
+func (lw *lazyWallet) Clone() Wallet {
+	if w := lw.mustLoad(); w != nil {
+		return w.Clone()
+	}
+	return &lazyWallet{
+		path:   lw.path,
+		header: lw.header.Clone(),
+	}
+}
+
+// SetTimestamp implements the Wallet interface
+func (lw *lazyWallet) SetTimestamp(t int64) {
+	if w := lw.mustLoad(); w != nil {
+		w.SetTimestamp(t)
+	}
+}
+
+// SetCoin implements the Wallet interface
+func (lw *lazyWallet) SetCoin(ct CoinType) {
+	if w := lw.mustLoad(); w != nil {
+		w.SetCoin(ct)
+	}
+}
+
+// SetBip44Coin implements the Wallet interface
+func (lw *lazyWallet) SetBip44Coin(ct bip44.CoinType) {
+	if w := lw.mustLoad(); w != nil {
+		w.SetBip44Coin(ct)
+	}
+}
+
+// SetLabel implements the Wallet interface
+func (lw *lazyWallet) SetLabel(label string) {
+	if w := lw.mustLoad(); w != nil {
+		w.SetLabel(label)
+	}
+}
+
+// SetNotes implements the Wallet interface
+func (lw *lazyWallet) SetNotes(notes string) {
+	if w := lw.mustLoad(); w != nil {
+		w.SetNotes(notes)
+	}
+}
+
+// SetTransactionMemo implements the Wallet interface
+func (lw *lazyWallet) SetTransactionMemo(innerHash cipher.SHA256, memo []byte) {
+	if w := lw.mustLoad(); w != nil {
+		w.SetTransactionMemo(innerHash, memo)
+	}
+}
+
+// SetHeader implements the Wallet interface
+func (lw *lazyWallet) SetHeader(fingerprint, firstAddress string) {
+	if w := lw.mustLoad(); w != nil {
+		w.SetHeader(fingerprint, firstAddress)
+	}
+}
+
+// SetFilename implements the Wallet interface
+func (lw *lazyWallet) SetFilename(fn string) {
+	if w := lw.mustLoad(); w != nil {
+		w.SetFilename(fn)
+	}
+}
+
+// SetCryptoType implements the Wallet interface
+func (lw *lazyWallet) SetCryptoType(ct crypto.CryptoType) {
+	if w := lw.mustLoad(); w != nil {
+		w.SetCryptoType(ct)
+	}
+}
+
+// SetDecoder implements the Wallet interface
+func (lw *lazyWallet) SetDecoder(d Decoder) {
+	if w := lw.mustLoad(); w != nil {
+		w.SetDecoder(d)
+	}
+}
+
+// SetTemp implements the Wallet interface
+func (lw *lazyWallet) SetTemp(temp bool) {
+	if w := lw.mustLoad(); w != nil {
+		w.SetTemp(temp)
+	}
+}
+
+// Erase implements the Wallet interface. A wallet that is not loaded has no secrets in memory
+// and is not loaded to be erased
+func (lw *lazyWallet) Erase() {
+	if w := lw.loaded(); w != nil {
+		w.Erase()
+	}
+}
+
+// CopyFromRef implements the Wallet interface
+func (lw *lazyWallet) CopyFromRef(src Wallet) {
+	if w := lw.mustLoad(); w != nil {
+		w.CopyFromRef(src)
+	}
+}
+
+// Accounts implements the Wallet interface
+func (lw *lazyWallet) Accounts() []Bip44Account {
+	if w := lw.mustLoad(); w != nil {
+		return w.Accounts()
+	}
+	return nil
+}
+
+// Lock implements the Wallet interface
+func (lw *lazyWallet) Lock(password []byte) error {
+	w, err := lw.load()
+	if err != nil {
+		return err
+	}
+	return w.Lock(password)
+}
+
+// Unlock implements the Wallet interface
+func (lw *lazyWallet) Unlock(password []byte) (Wallet, error) {
+	w, err := lw.load()
+	if err != nil {
+		return nil, err
+	}
+	return w.Unlock(password)
+}
+
+// ScanAddresses implements the Wallet interface
+func (lw *lazyWallet) ScanAddresses(scanN uint64, tf TransactionsFinder) ([]cipher.Addresser, error) {
+	w, err := lw.load()
+	if err != nil {
+		return nil, err
+	}
+	return w.ScanAddresses(scanN, tf)
+}
+
+// GetAddresses implements the Wallet interface
+func (lw *lazyWallet) GetAddresses(options ...Option) ([]cipher.Addresser, error) {
+	w, err := lw.load()
+	if err != nil {
+		return nil, err
+	}
+	return w.GetAddresses(options...)
+}
+
+// GenerateAddresses implements the Wallet interface
+func (lw *lazyWallet) GenerateAddresses(options ...Option) ([]cipher.Addresser, error) {
+	w, err := lw.load()
+	if err != nil {
+		return nil, err
+	}
+	return w.GenerateAddresses(options...)
+}
+
+// GetEntries implements the Wallet interface
+func (lw *lazyWallet) GetEntries(options ...Option) (Entries, error) {
+	w, err := lw.load()
+	if err != nil {
+		return nil, err
+	}
+	return w.GetEntries(options...)
+}
+
+// GetEntryAt implements the Wallet interface
+func (lw *lazyWallet) GetEntryAt(i int, options ...Option) (Entry, error) {
+	w, err := lw.load()
+	if err != nil {
+		return Entry{}, err
+	}
+	return w.GetEntryAt(i, options...)
+}
+
+// GetEntry implements the Wallet interface
+func (lw *lazyWallet) GetEntry(addr cipher.Addresser, options ...Option) (Entry, error) {
+	w, err := lw.load()
+	if err != nil {
+		return Entry{}, err
+	}
+	return w.GetEntry(addr, options...)
+}
+
+// HasEntry implements the Wallet interface
+func (lw *lazyWallet) HasEntry(addr cipher.Addresser, options ...Option) (bool, error) {
+	w, err := lw.load()
+	if err != nil {
+		return false, err
+	}
+	return w.HasEntry(addr, options...)
+}
+
+// SetEntryLabel implements the Wallet interface
+func (lw *lazyWallet) SetEntryLabel(addr cipher.Addresser, label string, options ...Option) error {
+	w, err := lw.load()
+	if err != nil {
+		return err
+	}
+	return w.SetEntryLabel(addr, label, options...)
+}
+
+// EntriesLen implements the Wallet interface
+func (lw *lazyWallet) EntriesLen(options ...Option) (int, error) {
+	w, err := lw.load()
+	if err != nil {
+		return 0, err
+	}
+	return w.EntriesLen(options...)
+}
+
+// Serialize implements the Wallet interface
+func (lw *lazyWallet) Serialize() ([]byte, error) {
+	w, err := lw.load()
+	if err != nil {
+		return nil, err
+	}
+	return w.Serialize()
+}
+
+// Deserialize implements the Wallet interface
+func (lw *lazyWallet) Deserialize(data []byte) error {
+	w, err := lw.load()
+	if err != nil {
+		return err
+	}
+	return w.Deserialize(data)
+}
@@ -0,0 +1,42 @@
+package wallet
+
+import (
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// SignMessage signs the SHA256 hash of msg with the secret key of addr, proving control of the address.
+// The wallet must not be encrypted. Returns ErrUnknownAddress if addr is not in the wallet.
+func SignMessage(w Wallet, addr cipher.Address, msg []byte) (cipher.Sig, error) {
+	switch w.Type() {
+	case WalletTypeXPub, WalletTypeWatchOnly:
+		return cipher.Sig{}, ErrWalletCantSign
+	}
+
+	if w.IsEncrypted() {
+		return cipher.Sig{}, ErrWalletEncrypted
+	}
+
+	if err := checkWalletCoin(w); err != nil {
+		return cipher.Sig{}, err
+	}
+
+	// Search all entries, so that bip44 change addresses can be used too
+	entries, err := w.GetEntries()
+	if err != nil {
+		return cipher.Sig{}, err
+	}
+
+	for _, e := range entries {
+		if e.SkycoinAddress() != addr {
+			continue
+		}
+		return cipher.SignHash(cipher.SumSHA256(msg), e.Secret)
+	}
+
+	return cipher.Sig{}, ErrUnknownAddress
+}
+
+// VerifyMessage checks that sig is a signature of msg created by SignMessage with the secret key of addr
+func VerifyMessage(addr cipher.Address, sig cipher.Sig, msg []byte) error {
+	return cipher.VerifyAddressSignedHash(addr, sig, cipher.SumSHA256(msg))
+}
@@ -1,10 +1,12 @@
 package wallet
 
 import (
+	"encoding/hex"
 	"errors"
 	"strconv"
 	"strings"
 
+	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/cipher/bip44"
 	"github.com/skycoin/skycoin/src/cipher/crypto"
 )
@@ -14,6 +16,7 @@ const (
 	MetaVersion        = "version"        // wallet version
 	MetaFilename       = "filename"       // wallet file name
 	MetaLabel          = "label"          // wallet label
+	MetaNotes          = "notes"          // free-form notes about the wallet, never holds secrets
 	MetaTimestamp      = "tm"             // the timestamp when creating the wallet
 	MetaType           = "type"           // wallet type
 	MetaCoin           = "coin"           // coin type
@@ -21,12 +24,23 @@ const (
 	MetaCryptoType     = "cryptoType"     // encryption/decryption type
 	MetaSeed           = "seed"           // wallet seed
 	MetaLastSeed       = "lastSeed"       // seed for generating next address [deterministic wallets]
+	MetaLastSeedIndex  = "lastSeedIndex"  // position of lastSeed in the seed chain [deterministic wallets]
 	MetaSecrets        = "secrets"        // secrets which records the encrypted seeds and secrets of address entries
 	MetaBip44Coin      = "bip44Coin"      // bip44 coin type
 	MetaAccountsHash   = "accountsHash"   // accounts hash
 	MetaSeedPassphrase = "seedPassphrase" // seed passphrase [bip44 wallets]
 	MetaXPub           = "xpub"           // xpub key [xpub wallets]
 	MetaTemp           = "temp"           // whether the wallet is a temporary wallet
+	MetaScryptN        = "scryptN"        // scrypt N parameter used for encryption
+	MetaScryptR        = "scryptR"        // scrypt r parameter used for encryption
+	MetaScryptP        = "scryptP"        // scrypt p parameter used for encryption
+	MetaFingerprint    = "fingerprint"    // wallet fingerprint, written on save for lazy loading
+	MetaFirstAddress   = "firstAddress"   // address of the first entry, written on save for lazy loading
+	MetaAllowEmpty     = "allowEmpty"     // whether the wallet may have no entries, see Options.NoDefaultAddresses
+	MetaSeedType       = "seedType"       // seed type: raw or mnemonic, see Options.SeedType
+	MetaAddressType    = "addressType"    // address format, see Options.AddressType [deterministic wallets]
+	// MetaTransactionMemo is the prefix of the keys of the transaction memos, followed by the transaction inner hash
+	MetaTransactionMemo = "txnMemo:"
 )
 
 //const (
@@ -96,6 +110,55 @@ func (m Meta) SetLabel(label string) {
 	m[MetaLabel] = label
 }
 
+// Notes gets the wallet notes
+func (m Meta) Notes() string {
+	return m[MetaNotes]
+}
+
+// SetNotes sets the wallet notes, the key is removed if notes is empty
+func (m Meta) SetNotes(notes string) {
+	if notes == "" {
+		delete(m, MetaNotes)
+		return
+	}
+	m[MetaNotes] = notes
+}
+
+// TransactionMemo returns the memo recorded for the transaction of given inner hash, or nil if there is none
+func (m Meta) TransactionMemo(innerHash cipher.SHA256) []byte {
+	memo, err := hex.DecodeString(m[MetaTransactionMemo+innerHash.Hex()])
+	if err != nil || len(memo) == 0 {
+		return nil
+	}
+	return memo
+}
+
+// SetTransactionMemo records the memo of the transaction of given inner hash, hex encoded.
+// The key is removed if memo is empty
+func (m Meta) SetTransactionMemo(innerHash cipher.SHA256, memo []byte) {
+	key := MetaTransactionMemo + innerHash.Hex()
+	if len(memo) == 0 {
+		delete(m, key)
+		return
+	}
+	m[key] = hex.EncodeToString(memo)
+}
+
+// Header returns the fingerprint and first address recorded by SetHeader,
+// ok is false if the wallet was saved without them
+func (m Meta) Header() (fingerprint, firstAddress string, ok bool) {
+	fingerprint, okFp := m[MetaFingerprint]
+	firstAddress, okAddr := m[MetaFirstAddress]
+	return fingerprint, firstAddress, okFp && okAddr
+}
+
+// SetHeader records the fingerprint and the first address of the wallet,
+// which identify the wallet without decoding its entries
+func (m Meta) SetHeader(fingerprint, firstAddress string) {
+	m[MetaFingerprint] = fingerprint
+	m[MetaFirstAddress] = firstAddress
+}
+
 // LastSeed returns the last seed
 func (m Meta) LastSeed() string {
 	return m[MetaLastSeed]
@@ -106,6 +169,19 @@ func (m Meta) SetLastSeed(lseed string) {
 	m[MetaLastSeed] = lseed
 }
 
+// LastSeedIndex returns the position of the last seed in the seed chain,
+// which is the number of keys derived from the seed so far
+func (m Meta) LastSeedIndex() uint64 {
+	// Intentionally ignore the error, the value is validated by Validate()
+	x, _ := strconv.ParseUint(m[MetaLastSeedIndex], 10, 64) //nolint:errcheck
+	return x
+}
+
+// SetLastSeedIndex sets or updates the position of the last seed in the seed chain
+func (m Meta) SetLastSeedIndex(i uint64) {
+	m[MetaLastSeedIndex] = strconv.FormatUint(i, 10)
+}
+
 // Seed returns the seed
 func (m Meta) Seed() string {
 	return m[MetaSeed]
@@ -136,6 +212,20 @@ func (m Meta) SetCoin(ct CoinType) {
 	m[MetaCoin] = string(ct)
 }
 
+// AddressType returns the format of the addresses the wallet creates. If the wallet
+// was created without an address type, it is the format of the coin type's addresses
+func (m Meta) AddressType() AddressType {
+	if t := m[MetaAddressType]; t != "" {
+		return AddressType(t)
+	}
+	return DefaultAddressType(m.Coin())
+}
+
+// SetAddressType sets the format of the addresses the wallet creates
+func (m Meta) SetAddressType(t AddressType) {
+	m[MetaAddressType] = string(t)
+}
+
 // Bip44Coin returns the bip44 coin type, please
 // check the second return value to see if it does
 // exist in the Meta data before using it.
@@ -208,6 +298,42 @@ func (m Meta) SetCryptoType(ct crypto.CryptoType) {
 	m[MetaCryptoType] = string(ct)
 }
 
+// ScryptParams returns the scrypt parameters used for encryption, or nil if they are not set
+func (m Meta) ScryptParams() (*ScryptParams, error) {
+	if m[MetaScryptN] == "" && m[MetaScryptR] == "" && m[MetaScryptP] == "" {
+		return nil, nil
+	}
+
+	var p ScryptParams
+	for _, f := range []struct {
+		key string
+		v   *int
+	}{
+		{MetaScryptN, &p.N},
+		{MetaScryptR, &p.R},
+		{MetaScryptP, &p.P},
+	} {
+		v, err := strconv.Atoi(m[f.key])
+		if err != nil {
+			return nil, ErrInvalidScryptParams
+		}
+		*f.v = v
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// SetScryptParams sets the scrypt parameters used for encryption
+func (m Meta) SetScryptParams(p ScryptParams) {
+	m[MetaScryptN] = strconv.Itoa(p.N)
+	m[MetaScryptR] = strconv.Itoa(p.R)
+	m[MetaScryptP] = strconv.Itoa(p.P)
+}
+
 // Secrets returns the encrypted wallet secrets
 func (m Meta) Secrets() string {
 	return m[MetaSecrets]
@@ -271,6 +397,12 @@ func (m Meta) Validate() error {
 		}
 	}
 
+	if i := m[MetaLastSeedIndex]; i != "" {
+		if _, err := strconv.ParseUint(i, 10, 64); err != nil {
+			return errors.New("invalid lastSeedIndex")
+		}
+	}
+
 	_, ok := m[MetaType]
 	if !ok {
 		return errors.New("type field not set")
@@ -338,6 +470,36 @@ func (m Meta) SetTemp(temp bool) {
 	}
 }
 
+// SetAllowEmpty sets whether the wallet may have no entries
+func (m Meta) SetAllowEmpty(allow bool) {
+	if allow {
+		m[MetaAllowEmpty] = "true"
+	} else {
+		delete(m, MetaAllowEmpty)
+	}
+}
+
+// AllowEmpty returns whether the wallet may have no entries, in which case
+// the service doesn't reject it as an empty wallet
+func (m Meta) AllowEmpty() bool {
+	return m[MetaAllowEmpty] == "true"
+}
+
+// SetSeedType sets the type of the seed the wallet was created from
+func (m Meta) SetSeedType(seedType string) {
+	if seedType != "" {
+		m[MetaSeedType] = seedType
+	} else {
+		delete(m, MetaSeedType)
+	}
+}
+
+// SeedType returns the type of the seed the wallet was created from,
+// empty if it was not recorded when the wallet was created
+func (m Meta) SeedType() string {
+	return m[MetaSeedType]
+}
+
 // IsTemp returns whether the wallet is a temporary wallet
 func (m Meta) IsTemp() bool {
 	if m[MetaTemp] == "true" {
@@ -0,0 +1,104 @@
+package wallet
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// metaMigration upgrades the meta data of a wallet from one format version to the next.
+// Migrations only fill in meta data fields, they must never change the addresses or secrets
+// of the wallet, so that migrating a wallet any number of times gives the same result.
+type metaMigration struct {
+	from string
+	to   string
+	// migrate updates the meta data in place, entriesLen is the number of entries in the wallet file
+	migrate func(m Meta, entriesLen int)
+}
+
+// metaMigrations upgrade a wallet from any older format version to the current Version, in order
+var metaMigrations = []metaMigration{
+	{
+		// 0.2 added wallet encryption, older wallets are not encrypted
+		from: "0.1",
+		to:   "0.2",
+		migrate: func(m Meta, _ int) {
+			if _, ok := m[MetaEncrypted]; !ok {
+				m.setIsEncrypted(false)
+			}
+		},
+	},
+	{
+		from:    "0.2",
+		to:      "0.3",
+		migrate: func(Meta, int) {},
+	},
+	{
+		from:    "0.3",
+		to:      "0.4",
+		migrate: func(Meta, int) {},
+	},
+	{
+		// 0.5 added lastSeedIndex to deterministic wallets. Older wallets generated
+		// exactly one key from the seed chain per entry
+		from: "0.4",
+		to:   "0.5",
+		migrate: func(m Meta, entriesLen int) {
+			if m.Type() != WalletTypeDeterministic {
+				return
+			}
+			if _, ok := m[MetaLastSeedIndex]; !ok {
+				m.SetLastSeedIndex(uint64(entriesLen))
+			}
+		},
+	},
+}
+
+// migrateMeta upgrades the meta data to the current Version,
+// returns false if the version is current or unknown and nothing was changed
+func migrateMeta(m Meta, entriesLen int) bool {
+	var migrated bool
+	for _, mg := range metaMigrations {
+		if m.Version() != mg.from {
+			continue
+		}
+
+		mg.migrate(m, entriesLen)
+		m.SetVersion(mg.to)
+		migrated = true
+	}
+	return migrated
+}
+
+// migrateWalletData upgrades the meta data of serialized wallet data to the current Version.
+// Only the "meta" object is rewritten, the rest of the data is kept as is.
+// The data is returned unchanged if the wallet is already at the current Version.
+func migrateWalletData(data []byte) ([]byte, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	var m Meta
+	if err := json.Unmarshal(raw["meta"], &m); err != nil {
+		return nil, fmt.Errorf("invalid wallet meta: %v", err)
+	}
+
+	var entries []json.RawMessage
+	if e, ok := raw["entries"]; ok {
+		if err := json.Unmarshal(e, &entries); err != nil {
+			return nil, fmt.Errorf("invalid wallet entries: %v", err)
+		}
+	}
+
+	if !migrateMeta(m, len(entries)) {
+		return data, nil
+	}
+
+	mb, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	raw["meta"] = mb
+
+	return json.Marshal(raw)
+}
@@ -0,0 +1,97 @@
+package wallet
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testWalletFile struct {
+	Meta     Meta              `json:"meta"`
+	Entries  []json.RawMessage `json:"entries"`
+	Accounts json.RawMessage   `json:"accounts,omitempty"`
+}
+
+func decodeTestWalletFile(t *testing.T, data []byte) testWalletFile {
+	var f testWalletFile
+	require.NoError(t, json.Unmarshal(data, &f))
+	return f
+}
+
+func TestMigrateWalletData(t *testing.T) {
+	tt := []struct {
+		name          string
+		file          string
+		encrypted     string
+		lastSeedIndex string
+	}{
+		{
+			name:          "deterministic 0.1",
+			file:          "./testdata/test1.wlt",
+			encrypted:     "false",
+			lastSeedIndex: "1",
+		},
+		{
+			name:          "encrypted deterministic 0.2",
+			file:          "./testdata/sha256xor-encrypted.wlt",
+			encrypted:     "true",
+			lastSeedIndex: "1",
+		},
+		{
+			name: "collection 0.2",
+			file: "./testdata/test4-collection.wlt",
+		},
+		{
+			name:      "bip44 0.4",
+			file:      "./testdata/test5-bip44.wlt",
+			encrypted: "false",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := ioutil.ReadFile(tc.file)
+			require.NoError(t, err)
+			before := decodeTestWalletFile(t, data)
+
+			migrated, err := migrateWalletData(data)
+			require.NoError(t, err)
+			after := decodeTestWalletFile(t, migrated)
+
+			require.Equal(t, Version, after.Meta.Version())
+			require.Equal(t, tc.lastSeedIndex, after.Meta[MetaLastSeedIndex])
+			require.Equal(t, tc.encrypted, after.Meta[MetaEncrypted])
+
+			// Addresses and secrets are not changed
+			require.Equal(t, len(before.Entries), len(after.Entries))
+			for i := range before.Entries {
+				require.JSONEq(t, string(before.Entries[i]), string(after.Entries[i]))
+			}
+			if before.Accounts != nil {
+				require.JSONEq(t, string(before.Accounts), string(after.Accounts))
+			}
+			for _, k := range []string{MetaSeed, MetaLastSeed, MetaSecrets, MetaSeedPassphrase, MetaXPub} {
+				require.Equal(t, before.Meta[k], after.Meta[k], k)
+			}
+
+			// Migrating again does not change anything
+			again, err := migrateWalletData(migrated)
+			require.NoError(t, err)
+			require.Equal(t, migrated, again)
+		})
+	}
+}
+
+func TestMigrateMetaUnknownVersion(t *testing.T) {
+	m := Meta{
+		MetaType:    WalletTypeDeterministic,
+		MetaVersion: "9.9",
+	}
+	require.False(t, migrateMeta(m, 1))
+	require.Equal(t, Meta{
+		MetaType:    WalletTypeDeterministic,
+		MetaVersion: "9.9",
+	}, m)
+}
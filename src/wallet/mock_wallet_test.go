@@ -384,6 +384,36 @@ func (_m *MockWallet) Label() string {
 	return r0
 }
 
+// TransactionMemo provides a mock function with given fields: innerHash
+func (_m *MockWallet) TransactionMemo(innerHash cipher.SHA256) []byte {
+	ret := _m.Called(innerHash)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func(cipher.SHA256) []byte); ok {
+		r0 = rf(innerHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	return r0
+}
+
+// Notes provides a mock function with given fields:
+func (_m *MockWallet) Notes() string {
+	ret := _m.Called()
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
 // LastSeed provides a mock function with given fields:
 func (_m *MockWallet) LastSeed() string {
 	ret := _m.Called()
@@ -398,6 +428,20 @@ func (_m *MockWallet) LastSeed() string {
 	return r0
 }
 
+// LastSeedIndex provides a mock function with given fields:
+func (_m *MockWallet) LastSeedIndex() uint64 {
+	ret := _m.Called()
+
+	var r0 uint64
+	if rf, ok := ret.Get(0).(func() uint64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	return r0
+}
+
 // Lock provides a mock function with given fields: password
 func (_m *MockWallet) Lock(password []byte) error {
 	ret := _m.Called(password)
@@ -525,11 +569,47 @@ func (_m *MockWallet) SetFilename(_a0 string) {
 	_m.Called(_a0)
 }
 
+// SetEntryLabel provides a mock function with given fields: addr, label, options
+func (_m *MockWallet) SetEntryLabel(addr cipher.Addresser, label string, options ...Option) error {
+	_va := make([]interface{}, len(options))
+	for _i := range options {
+		_va[_i] = options[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, addr, label)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(cipher.Addresser, string, ...Option) error); ok {
+		r0 = rf(addr, label, options...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetLabel provides a mock function with given fields: _a0
 func (_m *MockWallet) SetLabel(_a0 string) {
 	_m.Called(_a0)
 }
 
+// SetNotes provides a mock function with given fields: _a0
+func (_m *MockWallet) SetNotes(_a0 string) {
+	_m.Called(_a0)
+}
+
+// SetTransactionMemo provides a mock function with given fields: innerHash, memo
+func (_m *MockWallet) SetTransactionMemo(innerHash cipher.SHA256, memo []byte) {
+	_m.Called(innerHash, memo)
+}
+
+// SetHeader provides a mock function with given fields: fingerprint, firstAddress
+func (_m *MockWallet) SetHeader(fingerprint string, firstAddress string) {
+	_m.Called(fingerprint, firstAddress)
+}
+
 // SetTemp provides a mock function with given fields: temp
 func (_m *MockWallet) SetTemp(temp bool) {
 	_m.Called(temp)
@@ -114,9 +114,13 @@ type AdvancedOptions struct {
 	Encrypt                 bool
 	Password                []byte
 	GenerateN               uint64
+	NoDefaultAddresses      bool
 	ScanN                   uint64
+	GapLimit                uint64
+	ScanChunkSize           uint64
 	TF                      TransactionsFinder
 	PrivateKeys             []cipher.SecKey // private keys of collection wallet
+	PublicKeys              []cipher.PubKey // public keys of watch-only wallet
 }
 
 // advancedOptionFunc is a helper function that assert the
@@ -160,6 +164,22 @@ func OptionScanN(n uint64) Option {
 	})
 }
 
+// OptionGapLimit can be used to continue scanning when creating a new wallet,
+// until the given number of consecutive addresses without activity are found
+func OptionGapLimit(n uint64) Option {
+	return advancedOptionFunc(func(opts *AdvancedOptions) {
+		opts.GapLimit = n
+	})
+}
+
+// OptionScanChunkSize can be used to set the number of addresses whose activity
+// is queried at once when scanning with a gap limit
+func OptionScanChunkSize(n uint64) Option {
+	return advancedOptionFunc(func(opts *AdvancedOptions) {
+		opts.ScanChunkSize = n
+	})
+}
+
 // OptionTransactionsFinder can be used to set the transactions finder when creating a new wallet
 func OptionTransactionsFinder(tf TransactionsFinder) Option {
 	return advancedOptionFunc(func(opts *AdvancedOptions) {
@@ -175,9 +195,48 @@ func OptionGenerateN(n uint64) Option {
 	})
 }
 
+// OptionNoDefaultAddresses can be used to create a wallet without the addresses generated by default,
+// so that no address is generated unless OptionGenerateN is set. The wallet is marked as allowed to be empty
+func OptionNoDefaultAddresses(noDefault bool) Option {
+	return func(v interface{}) {
+		switch o := v.(type) {
+		case *AdvancedOptions:
+			o.NoDefaultAddresses = noDefault
+		case interface{ SetAllowEmpty(bool) }:
+			o.SetAllowEmpty(noDefault)
+		}
+	}
+}
+
+// OptionAddressType can be used to set the format of the addresses the wallet creates
+func OptionAddressType(t AddressType) Option {
+	return func(v interface{}) {
+		if o, ok := v.(interface{ SetAddressType(AddressType) }); ok {
+			o.SetAddressType(t)
+		}
+	}
+}
+
+// OptionSeedType can be used to record the type of the seed the wallet is created from
+func OptionSeedType(seedType string) Option {
+	return func(v interface{}) {
+		if o, ok := v.(interface{ SetSeedType(string) }); ok {
+			o.SetSeedType(seedType)
+		}
+	}
+}
+
 // OptionCollectionPrivateKeys can be used to set the private keys when creating a collection wallet
 func OptionCollectionPrivateKeys(keys []cipher.SecKey) Option {
 	return advancedOptionFunc(func(opts *AdvancedOptions) {
 		opts.PrivateKeys = keys
 	})
 }
+
+// OptionWatchOnlyPublicKeys can be used to set the public keys of a watch-only wallet,
+// when creating the wallet or adding addresses to it
+func OptionWatchOnlyPublicKeys(keys []cipher.PubKey) Option {
+	return advancedOptionFunc(func(opts *AdvancedOptions) {
+		opts.PublicKeys = keys
+	})
+}
@@ -0,0 +1,85 @@
+package wallet
+
+import (
+	"github.com/skycoin/skycoin/src/cipher/crypto"
+	"github.com/skycoin/skycoin/src/cipher/encrypt"
+)
+
+// Bounds of the scrypt parameters accepted in ScryptParams
+const (
+	// ScryptMinN is the smallest scrypt N parameter, suitable for low-powered devices
+	ScryptMinN = 1 << 14
+	// ScryptMaxN is the largest scrypt N parameter
+	ScryptMaxN = 1 << 22
+	// ScryptMaxR is the largest scrypt r parameter
+	ScryptMaxR = 32
+	// ScryptMaxP is the largest scrypt p parameter
+	ScryptMaxP = 16
+	// ScryptMaxMemory is the largest amount of memory in bytes that scrypt may use, 128*N*r
+	ScryptMaxMemory = 1 << 30
+)
+
+// ScryptParams are the scrypt key derivation parameters used to encrypt a wallet
+// with the scrypt-chacha20poly1305 crypto types
+type ScryptParams struct {
+	N int
+	R int
+	P int
+}
+
+// Validate checks that the scrypt parameters are within safe bounds
+func (p ScryptParams) Validate() error {
+	if p.N < ScryptMinN || p.N > ScryptMaxN || p.N&(p.N-1) != 0 {
+		return ErrInvalidScryptParams
+	}
+
+	if p.R < 1 || p.R > ScryptMaxR {
+		return ErrInvalidScryptParams
+	}
+
+	if p.P < 1 || p.P > ScryptMaxP {
+		return ErrInvalidScryptParams
+	}
+
+	if 128*uint64(p.N)*uint64(p.R) > ScryptMaxMemory {
+		return ErrInvalidScryptParams
+	}
+
+	return nil
+}
+
+// NewCryptor returns the Cryptor used to encrypt a wallet with the given crypto type and metadata.
+// If the crypto type uses scrypt and the metadata has scrypt parameters, the Cryptor uses them instead of the defaults.
+// Decryption does not depend on the metadata, the parameters are read from the encrypted data.
+func NewCryptor(cryptoType crypto.CryptoType, m Meta) (crypto.Cryptor, error) {
+	switch cryptoType {
+	case crypto.CryptoTypeScryptChacha20poly1305, crypto.CryptoTypeScryptChacha20poly1305Insecure:
+		p, err := m.ScryptParams()
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			return encrypt.ScryptChacha20poly1305{
+				N:      p.N,
+				R:      p.R,
+				P:      p.P,
+				KeyLen: encrypt.ScryptKeyLen,
+			}, nil
+		}
+	}
+
+	return crypto.GetCrypto(cryptoType)
+}
+
+// OptionScryptParams is the option type for setting the scrypt parameters used to encrypt the wallet
+func OptionScryptParams(p ScryptParams) Option {
+	return func(v interface{}) {
+		w, ok := v.(interface {
+			SetScryptParams(ScryptParams)
+		})
+		if !ok {
+			return
+		}
+		w.SetScryptParams(p)
+	}
+}
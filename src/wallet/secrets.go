@@ -44,3 +44,11 @@ func (s Secrets) Erase() {
 		delete(s, k)
 	}
 }
+
+// EraseBytes overwrites b with zeros, used to wipe decrypted or serialized secrets
+// as soon as they are no longer needed instead of leaving them in memory until GC
+func EraseBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -27,3 +27,11 @@ func TestSecrets(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, s, s1)
 }
+
+func TestEraseBytes(t *testing.T) {
+	b := []byte("secret")
+	EraseBytes(b)
+	require.Equal(t, make([]byte, len("secret")), b)
+
+	EraseBytes(nil)
+}
@@ -0,0 +1,114 @@
+package wallet
+
+import (
+	"io"
+	"math"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/bip39"
+)
+
+const (
+	// SeedTypeRaw is a seed of arbitrary text
+	SeedTypeRaw = "raw"
+	// SeedTypeMnemonic is a bip39 mnemonic seed
+	SeedTypeMnemonic = "mnemonic"
+
+	// MnemonicEntropyBits12Words is the entropy size of a 12 words mnemonic
+	MnemonicEntropyBits12Words = 128
+	// MnemonicEntropyBits24Words is the entropy size of a 24 words mnemonic
+	MnemonicEntropyBits24Words = 256
+
+	// MinSeedEntropyBits is the minimum estimated entropy of the raw seeds accepted with Options.RejectWeakSeed
+	MinSeedEntropyBits = 128
+)
+
+// RandReader is the entropy source used to generate mnemonic seeds and wallet filenames.
+// Tests can replace it with a deterministic reader to make them predictable.
+// It defaults to cipher.RandByte, and must not be replaced outside of tests
+var RandReader io.Reader = randByteReader{}
+
+// randByteReader reads random bytes from cipher.RandByte
+type randByteReader struct{}
+
+func (randByteReader) Read(p []byte) (int, error) {
+	return copy(p, cipher.RandByte(len(p))), nil
+}
+
+// randBytes reads n bytes from RandReader
+func randBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(RandReader, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// NewMnemonic generates a bip39 mnemonic seed from entropyBits of random entropy.
+// entropyBits must be MnemonicEntropyBits12Words or MnemonicEntropyBits24Words.
+func NewMnemonic(entropyBits int) (string, error) {
+	switch entropyBits {
+	case MnemonicEntropyBits12Words, MnemonicEntropyBits24Words:
+	default:
+		return "", ErrInvalidEntropyBits
+	}
+
+	entropy, err := randBytes(entropyBits / 8)
+	if err != nil {
+		return "", err
+	}
+
+	return bip39.NewMnemonic(entropy)
+}
+
+// ValidateMnemonic checks that a mnemonic is made of bip39 words and passes
+// the checksum verification. Returns ErrInvalidMnemonic if it doesn't.
+func ValidateMnemonic(mnemonic string) error {
+	if err := bip39.ValidateMnemonic(mnemonic); err != nil {
+		logger.WithError(err).Debug("ValidateMnemonic: bip39.ValidateMnemonic failed")
+		return ErrInvalidMnemonic
+	}
+	return nil
+}
+
+// IsValidSeedType returns true if a seed type is recognized
+func IsValidSeedType(t string) bool {
+	switch t {
+	case "", SeedTypeRaw, SeedTypeMnemonic:
+		return true
+	default:
+		return false
+	}
+}
+
+// SeedEntropy estimates the entropy of a raw seed in bits, from the frequency of its characters.
+// The estimate does not account for dictionary words or other patterns, so it is an upper bound
+// for seeds chosen by people.
+func SeedEntropy(seed string) float64 {
+	runes := []rune(seed)
+	if len(runes) == 0 {
+		return 0
+	}
+
+	counts := make(map[rune]int)
+	for _, r := range runes {
+		counts[r]++
+	}
+
+	n := float64(len(runes))
+	var bitsPerRune float64
+	for _, c := range counts {
+		p := float64(c) / n
+		bitsPerRune -= p * math.Log2(p)
+	}
+
+	return bitsPerRune * n
+}
+
+// CheckSeedStrength returns ErrWeakSeed if the estimated entropy of the raw seed is below MinSeedEntropyBits
+func CheckSeedStrength(seed string) error {
+	if SeedEntropy(seed) < MinSeedEntropyBits {
+		return ErrWeakSeed
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package wallet
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMnemonic(t *testing.T) {
+	cases := []struct {
+		entropyBits int
+		nWords      int
+		err         error
+	}{
+		{
+			entropyBits: MnemonicEntropyBits12Words,
+			nWords:      12,
+		},
+		{
+			entropyBits: MnemonicEntropyBits24Words,
+			nWords:      24,
+		},
+		{
+			entropyBits: 64,
+			err:         ErrInvalidEntropyBits,
+		},
+	}
+
+	for _, tc := range cases {
+		m, err := NewMnemonic(tc.entropyBits)
+		require.Equal(t, tc.err, err)
+		if err != nil {
+			continue
+		}
+
+		require.Len(t, strings.Fields(m), tc.nWords)
+		require.NoError(t, ValidateMnemonic(m))
+	}
+}
+
+func TestRandReader(t *testing.T) {
+	defer func(r io.Reader) {
+		RandReader = r
+	}(RandReader)
+
+	RandReader = bytes.NewReader(make([]byte, 16))
+	m, err := NewMnemonic(MnemonicEntropyBits12Words)
+	require.NoError(t, err)
+	require.Equal(t, "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", m)
+
+	RandReader = bytes.NewReader([]byte{0xab, 0xcd})
+	require.True(t, strings.HasSuffix(NewWalletFilename(), "_abcd."+WalletExt))
+
+	// An exhausted reader fails
+	_, err = NewMnemonic(MnemonicEntropyBits12Words)
+	require.Error(t, err)
+}
+
+func TestValidateMnemonic(t *testing.T) {
+	require.NoError(t, ValidateMnemonic("voyage say extend find sheriff surge priority merit ignore maple cash argue"))
+
+	// Bad checksum
+	require.Equal(t, ErrInvalidMnemonic, ValidateMnemonic("voyage say extend find sheriff surge priority merit ignore maple cash zoo"))
+	// Not a bip39 word
+	require.Equal(t, ErrInvalidMnemonic, ValidateMnemonic("voyage say extend find sheriff surge priority merit ignore maple cash foo"))
+	// Raw seed
+	require.Equal(t, ErrInvalidMnemonic, ValidateMnemonic("seed"))
+}
+
+func TestOptionsValidateSeedType(t *testing.T) {
+	opts := Options{
+		Type:     WalletTypeDeterministic,
+		Seed:     "voyage say extend find sheriff surge priority merit ignore maple cash argue",
+		SeedType: SeedTypeMnemonic,
+	}
+	require.NoError(t, opts.Validate())
+
+	opts.Seed = "seed"
+	require.Equal(t, ErrInvalidMnemonic, opts.Validate())
+
+	opts.SeedType = SeedTypeRaw
+	require.NoError(t, opts.Validate())
+
+	opts.SeedType = "foo"
+	require.Equal(t, ErrInvalidSeedType, opts.Validate())
+}
+
+func TestCheckSeedStrength(t *testing.T) {
+	require.Equal(t, float64(0), SeedEntropy(""))
+	require.Equal(t, float64(0), SeedEntropy("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"))
+	require.Equal(t, float64(8), SeedEntropy("abcd"))
+
+	for _, seed := range []string{
+		"",
+		"test",
+		"seed",
+		"correct horse battery",
+		"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+	} {
+		require.Equal(t, ErrWeakSeed, CheckSeedStrength(seed), seed)
+	}
+
+	for _, seed := range []string{
+		"voyage say extend find sheriff surge priority merit ignore maple cash argue",
+		"0d5ba4b0e1a9a2c5b3f1e7c6d9a8f2b4c1e3d5f7a9b2c4e6f8a1b3c5d7e9f0a2",
+	} {
+		require.NoError(t, CheckSeedStrength(seed), seed)
+	}
+}
+
+func TestOptionsValidateRejectWeakSeed(t *testing.T) {
+	opts := Options{
+		Type: WalletTypeDeterministic,
+		Seed: "test",
+	}
+	require.NoError(t, opts.Validate())
+
+	opts.RejectWeakSeed = true
+	require.Equal(t, ErrWeakSeed, opts.Validate())
+
+	opts.Seed = ""
+	require.Equal(t, ErrWeakSeed, opts.Validate())
+
+	opts.Seed = "0d5ba4b0e1a9a2c5b3f1e7c6d9a8f2b4c1e3d5f7a9b2c4e6f8a1b3c5d7e9f0a2"
+	require.NoError(t, opts.Validate())
+
+	// Wallets without seeds are not checked
+	opts = Options{
+		Type:           WalletTypeCollection,
+		RejectWeakSeed: true,
+	}
+	require.NoError(t, opts.Validate())
+}
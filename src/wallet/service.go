@@ -1,19 +1,26 @@
 package wallet
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
 
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/cipher/bip44"
 	"github.com/skycoin/skycoin/src/cipher/crypto"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/transaction"
 	"github.com/skycoin/skycoin/src/util/file"
 )
 
@@ -29,6 +36,50 @@ type Service struct {
 	config  Config
 	// fingerprints is used to check for duplicate deterministic wallets
 	fingerprints map[string]string
+	// listeners are called after a wallet changes, see OnWalletChange
+	listeners []func(wltID string, event WalletEvent)
+	// pendingChanges are the changes made under the write lock, reported by unlockAndNotify
+	pendingChanges []walletChange
+	// invalidWallets are the problems with the wallet files skipped by NewService, see Config.SkipInvalidWallets
+	invalidWallets []error
+	// lastSecretsAccess is the time in unix nanoseconds at which a wallet was last decrypted, see LastSecretsAccess.
+	// It is accessed atomically, since wallets are also decrypted under the read lock
+	lastSecretsAccess int64
+	// failedUnlocks tracks the failed password attempts of each wallet, see Config.MaxUnlockAttempts.
+	// It is guarded by failedUnlocksMu, since wallets are also decrypted under the read lock
+	failedUnlocks   map[string]*failedUnlocks
+	failedUnlocksMu sync.Mutex
+	// modTimes is the modification time of each wallet file when the service last loaded or saved it, see HasExternalChanges
+	modTimes map[string]time.Time
+	// addrIndex maps the addresses of the wallets to their wallet, see FindWalletByAddress.
+	// It is built by setWallets and updated by queueEvent after every wallet change
+	addrIndex *addressIndex
+	// closed is set by Close, after which the wallet methods return ErrServiceClosed
+	closed bool
+	// balanceCache is the cached balances of the addresses of each wallet, see GetCachedBalance.
+	// It is guarded by balanceCacheMu, since it is read and filled under the read lock
+	balanceCache   map[string]*walletBalanceCache
+	balanceCacheMu sync.Mutex
+	// lastScanQuery is the time of the last balance query of a scan, see Config.ScanRateLimit.
+	// The scans run without the service lock, so it is guarded by scanMu
+	lastScanQuery time.Time
+	scanMu        sync.Mutex
+}
+
+// WalletFileInfo describes the file of a wallet in the wallet directory
+type WalletFileInfo struct {
+	// ModTime is the modification time of the file on disk
+	ModTime time.Time
+	// Size is the size of the file on disk in bytes
+	Size int64
+	// LoadedModTime is the modification time of the file when the service last loaded or saved it
+	LoadedModTime time.Time
+}
+
+// failedUnlocks records the consecutive failed password attempts of a wallet
+type failedUnlocks struct {
+	count       int
+	lockedUntil time.Time
 }
 
 // Config wallet service config
@@ -38,8 +89,91 @@ type Config struct {
 	EnableWalletAPI bool
 	EnableSeedAPI   bool
 	Bip44Coin       *bip44.CoinType
+	// DirPerm is the permission mode of the wallet directory, DefaultDirPerm is used if 0
+	DirPerm os.FileMode
+	// FilePerm is the permission mode of the wallet files, DefaultFilePerm is used if 0
+	FilePerm os.FileMode
+	// MinPasswordLength is the minimum length of the passwords used to encrypt wallets,
+	// not counting surrounding whitespace. Shorter passwords are rejected with ErrWeakPassword.
+	// The check is disabled if 0
+	MinPasswordLength int
+	// TrashDeletedWallets makes DeleteWallet move the wallet files to the WalletTrashDir
+	// subdirectory of the wallet directory, instead of removing them
+	TrashDeletedWallets bool
+	// ReadOnly allows loading and viewing the wallets but makes every method that changes a wallet,
+	// creates or signs a transaction, or accesses the wallet secrets return ErrWalletReadOnly.
+	// It is independent of EnableWalletAPI, which disables the wallet methods entirely.
+	ReadOnly bool
+	// SkipInvalidWallets makes NewService skip the wallet files that can't be loaded, are duplicates
+	// of another wallet or are empty, instead of failing. The skipped files are left on disk
+	// and their problems are reported by Service.InvalidWallets
+	SkipInvalidWallets bool
+	// MaxBackups is the number of timestamped backups kept for each wallet. If greater than 0,
+	// the wallet file is copied to a backup before it is overwritten, and the oldest backups
+	// beyond MaxBackups are removed when saving and by NewService. Backups are disabled if 0.
+	// Encrypting, decrypting or re-encrypting a wallet removes its backups, which would keep
+	// its secrets unencrypted or encrypted with the previous crypto type or password
+	MaxBackups int
+	// MaxWallets is the maximum number of wallets of the service. Creating, importing or duplicating
+	// a wallet beyond it returns ErrMaxWalletsReached. The limit is disabled if 0
+	MaxWallets int
+	// FailOnMaxWallets makes NewService fail if the wallet directory holds more than MaxWallets wallets,
+	// instead of logging a warning and loading them all
+	FailOnMaxWallets bool
+	// MaxUnlockAttempts is the number of consecutive wrong passwords after which a wallet can't be
+	// decrypted for UnlockLockout, every decryption returns ErrWalletLocked meanwhile.
+	// A successful decryption resets the count. The attempts are only tracked in memory. Disabled if 0
+	MaxUnlockAttempts int
+	// UnlockLockout is how long a wallet stays locked after MaxUnlockAttempts wrong passwords,
+	// DefaultUnlockLockout is used if 0
+	UnlockLockout time.Duration
+	// LazyLoad makes NewService read only the meta data of the wallet files, which save records with the
+	// fingerprint and first address of the wallet. The duplicate and empty wallet checks use them, and each
+	// wallet is loaded and cached on first access. Wallets saved without them, e.g. by an older version, are loaded
+	// by NewService. Methods that go through every wallet, like GetWallets and VerifyIntegrity, load them all
+	LazyLoad bool
+	// BalanceCacheTTL is how long GetCachedBalance serves the balance of an address before fetching it again.
+	// The balance cache is disabled if 0
+	BalanceCacheTTL time.Duration
+	// InMemory keeps the wallets only in memory, for tests and ephemeral wallets. NewService starts with no wallets
+	// and doesn't create or read the wallet directory, and changes to the wallets are not saved.
+	// Like temporary wallets, the wallets have no file, so ReloadWallet and GetWalletFileInfo return an error
+	InMemory bool
+	// FilenamePrefix is prepended to the filenames generated for new wallets, e.g. to namespace
+	// the wallets of each tenant, giving filenames like "prefix_2006_01_02_abcd.wlt"
+	FilenamePrefix string
+	// DefaultLabel is the label template of the wallets created by CreateWallet without a label.
+	// "{prefix}" is replaced with FilenamePrefix, "{date}" with the creation date and "{filename}" with
+	// the wallet filename. If empty, a label must be given
+	DefaultLabel string
+	// WalletSource is a read-only source of wallet files, e.g. wallets embedded in the binary.
+	// If set, NewService loads the wallet files in its root instead of WalletDir, which is neither created nor read,
+	// and LazyLoad is ignored. Saving, renaming or deleting the loaded wallets returns ErrReadOnlyWalletSource,
+	// while temporary wallets can still be created. Like InMemory wallets, the wallets have no file in WalletDir
+	WalletSource WalletSource
+	// ScanRateLimit is the minimum time between two balance queries of a scan, to avoid overloading
+	// a shared backend with the queries of CreateWallet, ScanAddresses and ScanAddressesByBalance.
+	// The scans run without holding the service lock, so a slow scan doesn't delay the other wallet operations.
+	// Set Options.Context, or use ScanAddressesContext and ScanAddressesByBalanceContext, to bound a scan. Disabled if 0
+	ScanRateLimit time.Duration
 }
 
+const (
+	// DefaultDirPerm is the default permission mode of the wallet directory
+	DefaultDirPerm os.FileMode = 0700
+	// DefaultFilePerm is the default permission mode of the wallet files
+	DefaultFilePerm os.FileMode = 0600
+	// WalletTrashDir is the subdirectory of the wallet directory that deleted wallets are moved to,
+	// if Config.TrashDeletedWallets is set
+	WalletTrashDir = "trash"
+	// DefaultMaxBackups is the default number of backups kept for each wallet
+	DefaultMaxBackups = 3
+	// DefaultUnlockLockout is the default time a wallet stays locked after Config.MaxUnlockAttempts wrong passwords
+	DefaultUnlockLockout = 5 * time.Minute
+	// RestoredWalletLabel is the label of the wallets created by RestoreWalletFromSeed
+	RestoredWalletLabel = "Restored wallet"
+)
+
 // NewConfig creates a default Config
 func NewConfig() Config {
 	bc := bip44.CoinTypeSkycoin
@@ -49,43 +183,144 @@ func NewConfig() Config {
 		EnableWalletAPI: false,
 		EnableSeedAPI:   false,
 		Bip44Coin:       &bc,
+		DirPerm:         DefaultDirPerm,
+		FilePerm:        DefaultFilePerm,
+		MaxBackups:      DefaultMaxBackups,
+		UnlockLockout:   DefaultUnlockLockout,
+	}
+}
+
+// Validate validates the config
+func (c Config) Validate() error {
+	if c.DirPerm&^os.ModePerm != 0 || c.DirPerm&0002 != 0 {
+		return fmt.Errorf("invalid wallet directory permission %#o, must be a permission mode without world write", c.DirPerm)
+	}
+	if c.DirPerm != 0 && c.DirPerm&0700 != 0700 {
+		return fmt.Errorf("invalid wallet directory permission %#o, owner must have full access", c.DirPerm)
+	}
+
+	if c.FilePerm&^os.ModePerm != 0 || c.FilePerm&0002 != 0 {
+		return fmt.Errorf("invalid wallet file permission %#o, must be a permission mode without world write", c.FilePerm)
+	}
+	if c.FilePerm != 0 && c.FilePerm&0600 != 0600 {
+		return fmt.Errorf("invalid wallet file permission %#o, owner must have read and write access", c.FilePerm)
+	}
+
+	if c.MaxBackups < 0 {
+		return fmt.Errorf("invalid max wallet backups %d, must not be negative", c.MaxBackups)
+	}
+
+	if c.MaxWallets < 0 {
+		return fmt.Errorf("invalid max wallets %d, must not be negative", c.MaxWallets)
+	}
+
+	if c.MaxUnlockAttempts < 0 {
+		return fmt.Errorf("invalid max unlock attempts %d, must not be negative", c.MaxUnlockAttempts)
+	}
+
+	if c.UnlockLockout < 0 {
+		return fmt.Errorf("invalid unlock lockout %v, must not be negative", c.UnlockLockout)
+	}
+
+	if c.ScanRateLimit < 0 {
+		return fmt.Errorf("invalid scan rate limit %v, must not be negative", c.ScanRateLimit)
+	}
+
+	if c.WalletSource != nil && c.InMemory {
+		return errors.New("wallet source and in-memory wallets can't be used together")
+	}
+
+	if strings.ContainsAny(c.FilenamePrefix, `/\`) || strings.HasPrefix(c.FilenamePrefix, ".") {
+		return fmt.Errorf("invalid wallet filename prefix %q, must not contain path separators or start with a dot", c.FilenamePrefix)
 	}
+
+	return nil
 }
 
 // NewService new wallet service
 func NewService(c Config) (*Service, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	if c.DirPerm == 0 {
+		c.DirPerm = DefaultDirPerm
+	}
+	if c.FilePerm == 0 {
+		c.FilePerm = DefaultFilePerm
+	}
+	if c.UnlockLockout == 0 {
+		c.UnlockLockout = DefaultUnlockLockout
+	}
+
 	serv := &Service{
-		config:       c,
-		fingerprints: make(map[string]string),
+		config:        c,
+		fingerprints:  make(map[string]string),
+		failedUnlocks: make(map[string]*failedUnlocks),
+		modTimes:      make(map[string]time.Time),
+		balanceCache:  make(map[string]*walletBalanceCache),
+		addrIndex:     newAddressIndex(),
 	}
 
 	if !serv.config.EnableWalletAPI {
 		return serv, nil
 	}
 
-	if err := os.MkdirAll(c.WalletDir, os.FileMode(0700)); err != nil {
-		return nil, fmt.Errorf("failed to create wallet directory %s: %v", c.WalletDir, err)
+	if serv.config.InMemory {
+		serv.setWallets(Wallets{})
+		return serv, nil
 	}
 
-	// Removes .wlt.bak files before loading wallets
-	if err := removeBackupFiles(serv.config.WalletDir); err != nil {
-		return nil, fmt.Errorf("remove .wlt.bak files in %v failed: %v", serv.config.WalletDir, err)
+	// The wallet directory is only prepared if the wallets are loaded from it
+	if serv.config.WalletSource == nil {
+		if err := os.MkdirAll(c.WalletDir, c.DirPerm); err != nil {
+			return nil, WalletDirError{Op: "create directory", Dir: c.WalletDir, Err: err}
+		}
+
+		// Resolves the temp files of interrupted saves, then removes .wlt.bak files before loading wallets
+		if err := recoverTempFiles(serv.config.WalletDir); err != nil {
+			return nil, WalletDirError{Op: "recover interrupted wallet saves", Dir: serv.config.WalletDir, Err: err}
+		}
+
+		if err := removeBackupFiles(serv.config.WalletDir); err != nil {
+			return nil, WalletDirError{Op: "remove .wlt.bak files", Dir: serv.config.WalletDir, Err: err}
+		}
+
+		if serv.config.MaxBackups > 0 {
+			if err := pruneBackupFiles(serv.config.WalletDir, "", serv.config.MaxBackups); err != nil {
+				return nil, WalletDirError{Op: "prune wallet backups", Dir: serv.config.WalletDir, Err: err}
+			}
+		}
 	}
 
 	// Load all wallets from disk
 	w, err := serv.loadWallets()
 	if err != nil {
-		return nil, fmt.Errorf("failed to load all wallets: %v", err)
+		return nil, err
+	}
+
+	if serv.config.SkipInvalidWallets {
+		serv.invalidWallets = append(serv.invalidWallets, w.removeInvalid()...)
 	}
 
 	// Abort if there are duplicate wallets (identified by fingerprint) on disk
 	if wltID, fp, hasDup := w.containsDuplicate(); hasDup {
-		return nil, fmt.Errorf("duplicate wallet found with fingerprint %s in file %q", fp, wltID)
+		return nil, DuplicateWalletError{WalletID: wltID, Fingerprint: fp}
 	}
 
 	// Abort if there are empty deterministic wallets on disk
 	if wltID, hasEmpty := w.containsEmpty(); hasEmpty {
-		return nil, fmt.Errorf("empty wallet file found: %q", wltID)
+		return nil, EmptyWalletError{WalletID: wltID}
+	}
+
+	if max := serv.config.MaxWallets; max > 0 && len(w) > max {
+		if serv.config.FailOnMaxWallets {
+			return nil, fmt.Errorf("%v: the wallet directory holds %d wallets, the maximum is %d", ErrMaxWalletsReached, len(w), max)
+		}
+		logger.WithFields(logrus.Fields{
+			"wallets":    len(w),
+			"maxWallets": max,
+		}).Warning("The wallet directory holds more wallets than the maximum, no wallets can be added")
 	}
 
 	serv.setWallets(w)
@@ -101,16 +336,173 @@ func NewService(c Config) (*Service, error) {
 	return serv, nil
 }
 
+// save saves the wallet into the wallet directory, with the configured file permission.
+// If Config.MaxBackups is set, the previous wallet file is kept as a timestamped backup.
+func (serv *Service) save(w Wallet) error {
+	return serv.saveFile(w, true)
+}
+
+// saveReencrypted saves a wallet whose encryption state, crypto type or password changed.
+// The previous wallet file is not backed up, and the existing backups of the wallet are removed once it is saved,
+// since they hold the secrets unencrypted or encrypted with the previous crypto type or password.
+func (serv *Service) saveReencrypted(w Wallet) error {
+	return serv.saveFile(w, false)
+}
+
+// saveFile saves the wallet, keeping the previous wallet file as a backup if backup is true,
+// or removing the backups of the wallet otherwise
+func (serv *Service) saveFile(w Wallet, backup bool) error {
+	if err := serv.checkWalletSource(w); err != nil {
+		return err
+	}
+
+	if !serv.hasFile(w) {
+		return nil
+	}
+
+	if backup && serv.config.MaxBackups > 0 {
+		if err := backupWalletFile(serv.config.WalletDir, w.Filename(), serv.config.FilePerm); err != nil {
+			return err
+		}
+	}
+
+	if err := setHeader(w); err != nil {
+		return err
+	}
+
+	if err := saveWithPerm(w, serv.config.WalletDir, serv.config.FilePerm); err != nil {
+		return err
+	}
+
+	// The permission is only applied by Save when the file is created
+	wf := filepath.Join(serv.config.WalletDir, w.Filename())
+	if err := os.Chmod(wf, serv.config.FilePerm); err != nil {
+		return err
+	}
+
+	fi, err := os.Stat(wf)
+	if err != nil {
+		return err
+	}
+	serv.modTimes[w.Filename()] = fi.ModTime()
+
+	switch {
+	case !backup:
+		// The backups are removed even if Config.MaxBackups is 0, they may have been made with an earlier config
+		if err := pruneBackupFiles(serv.config.WalletDir, w.Filename(), 0); err != nil {
+			logger.WithError(err).WithField("filename", w.Filename()).Error("Failed to remove wallet backups")
+		}
+	case serv.config.MaxBackups > 0:
+		if err := pruneBackupFiles(serv.config.WalletDir, w.Filename(), serv.config.MaxBackups); err != nil {
+			logger.WithError(err).WithField("filename", w.Filename()).Warning("Failed to prune wallet backups")
+		}
+	}
+
+	return nil
+}
+
+// hasFile reports whether the wallet is stored in the wallet directory, which temporary wallets,
+// the wallets of an in-memory service and the wallets loaded from Config.WalletSource are not
+func (serv *Service) hasFile(w Wallet) bool {
+	return !w.IsTemp() && !serv.config.InMemory && serv.config.WalletSource == nil
+}
+
+// checkWalletSource returns ErrReadOnlyWalletSource if the wallet was loaded from Config.WalletSource and can't be saved
+func (serv *Service) checkWalletSource(w Wallet) error {
+	if serv.config.WalletSource != nil && !w.IsTemp() {
+		return ErrReadOnlyWalletSource
+	}
+	return nil
+}
+
 // WalletDir returns the configured wallet directory
 func (serv *Service) WalletDir() (string, error) {
 	serv.Lock()
 	defer serv.Unlock()
-	if !serv.config.EnableWalletAPI {
-		return "", ErrWalletAPIDisabled
+	if err := serv.checkEnabled(); err != nil {
+		return "", err
 	}
 	return serv.config.WalletDir, nil
 }
 
+// EffectiveConfig returns a copy of the configuration used by the service.
+// The config holds no secret material, so nothing is redacted.
+func (serv *Service) EffectiveConfig() Config {
+	serv.RLock()
+	defer serv.RUnlock()
+
+	c := serv.config
+	if c.Bip44Coin != nil {
+		bc := *c.Bip44Coin
+		c.Bip44Coin = &bc
+	}
+	return c
+}
+
+// InvalidWallets returns the problems with the wallet files that NewService skipped
+// because Config.SkipInvalidWallets is set. The errors are LoadWalletError, DuplicateWalletError or EmptyWalletError.
+func (serv *Service) InvalidWallets() []error {
+	serv.RLock()
+	defer serv.RUnlock()
+
+	errs := make([]error, len(serv.invalidWallets))
+	copy(errs, serv.invalidWallets)
+	return errs
+}
+
+// VerifyIntegrity checks the loaded wallets and returns the problems found, or nil if there are none.
+// A wallet is reported if it is not stored under its filename, if it has no entries although its type
+// requires them, or if another wallet has the same fingerprint or the same first address.
+// Returns ErrWalletAPIDisabled or ErrServiceClosed as the only error if the wallet API is disabled or the service is closed.
+func (serv *Service) VerifyIntegrity() []error {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return []error{err}
+	}
+
+	return serv.wallets.verify()
+}
+
+// checkEnabled returns ErrServiceClosed if the service is closed, or ErrWalletAPIDisabled if the wallet API is disabled.
+// The caller must hold the service lock.
+func (serv *Service) checkEnabled() error {
+	if serv.closed {
+		return ErrServiceClosed
+	}
+	if !serv.config.EnableWalletAPI {
+		return ErrWalletAPIDisabled
+	}
+	return nil
+}
+
+// Close waits for the wallet operations in progress to complete, then wipes the secrets of the loaded wallets
+// from memory and releases them. Wallets are saved as soon as they change, so there is nothing left to write.
+// Afterwards, the wallet methods return ErrServiceClosed, including Close.
+func (serv *Service) Close() error {
+	serv.Lock()
+	defer serv.Unlock()
+	if serv.closed {
+		return ErrServiceClosed
+	}
+
+	for _, w := range serv.wallets {
+		w.Erase()
+	}
+
+	serv.closed = true
+	serv.wallets = Wallets{}
+	serv.fingerprints = make(map[string]string)
+	serv.modTimes = make(map[string]time.Time)
+	serv.addrIndex = newAddressIndex()
+
+	serv.balanceCacheMu.Lock()
+	serv.balanceCache = make(map[string]*walletBalanceCache)
+	serv.balanceCacheMu.Unlock()
+
+	return nil
+}
+
 // SetEnableWalletAPI sets whether or not enables the wallet related APIs
 func (serv *Service) SetEnableWalletAPI(enable bool) {
 	serv.config.EnableWalletAPI = enable
@@ -118,10 +510,10 @@ func (serv *Service) SetEnableWalletAPI(enable bool) {
 
 func (serv *Service) loadWallets() (Wallets, error) {
 	dir := serv.config.WalletDir
-	entries, err := ioutil.ReadDir(dir)
+	entries, err := serv.readWalletDir()
 	if err != nil {
-		logger.WithError(err).WithField("dir", dir).Error("loadWallets: ioutil.ReadDir failed")
-		return nil, err
+		logger.WithError(err).WithField("dir", dir).Error("loadWallets: readWalletDir failed")
+		return nil, WalletDirError{Op: "read directory", Dir: dir, Err: err}
 	}
 
 	wallets := Wallets{}
@@ -134,10 +526,29 @@ func (serv *Service) loadWallets() (Wallets, error) {
 			}
 
 			fullPath := filepath.Join(serv.config.WalletDir, name)
-			w, err := serv.Load(fullPath)
+			if serv.config.LazyLoad && serv.config.WalletSource == nil {
+				if lw := loadWalletIndex(fullPath); lw != nil {
+					logger.WithField("filename", fullPath).Info("loadWallets: indexed wallet")
+					wallets[name] = lw
+					serv.modTimes[name] = e.ModTime()
+					continue
+				}
+			}
+
+			var w Wallet
+			if serv.config.WalletSource != nil {
+				fullPath = name
+				w, err = LoadFromSource(serv.config.WalletSource, name)
+			} else {
+				w, err = serv.Load(fullPath)
+			}
 			if err != nil {
 				logger.WithError(err).WithField("filename", fullPath).Error("loadWallets: loadWallet failed")
-				return nil, err
+				if serv.config.SkipInvalidWallets {
+					serv.invalidWallets = append(serv.invalidWallets, LoadWalletError{WalletID: name, Err: err})
+					continue
+				}
+				return nil, LoadWalletError{WalletID: name, Err: err}
 			}
 
 			if w == nil {
@@ -148,6 +559,7 @@ func (serv *Service) loadWallets() (Wallets, error) {
 			logger.WithField("filename", fullPath).Info("loadWallets: loaded wallet")
 
 			wallets[name] = w
+			serv.modTimes[name] = e.ModTime()
 		}
 	}
 
@@ -155,13 +567,27 @@ func (serv *Service) loadWallets() (Wallets, error) {
 		if w.Coin() != CoinTypeSkycoin {
 			err := fmt.Errorf("LoadWallets only support skycoin wallets, %s is a %s wallet", name, w.Coin())
 			logger.WithError(err).WithField("name", name).Error()
-			return nil, err
+			if serv.config.SkipInvalidWallets {
+				serv.invalidWallets = append(serv.invalidWallets, LoadWalletError{WalletID: name, Err: err})
+				delete(wallets, name)
+				continue
+			}
+			return nil, LoadWalletError{WalletID: name, Err: err}
 		}
 	}
 
 	return wallets, nil
 }
 
+// readWalletDir returns the files in the wallet directory, or in the root of Config.WalletSource if set
+func (serv *Service) readWalletDir() ([]os.FileInfo, error) {
+	src := serv.config.WalletSource
+	if src == nil {
+		src = DirWalletSource(serv.config.WalletDir)
+	}
+	return src.ReadDir(".")
+}
+
 // Load loads wallet from the given wallet file, it won't not affect the
 // state of the service it self, the loaded wallet will be returned.
 func (serv *Service) Load(filename string) (Wallet, error) {
@@ -179,26 +605,97 @@ func (serv *Service) updateOptions(opts Options) Options {
 		opts.Bip44Coin = &c
 	}
 
-	// generate one default address if options.GenerateN is 0
-	if opts.GenerateN == 0 {
+	// generate one default address if options.GenerateN is 0, unless the wallet is meant to start empty
+	if opts.GenerateN == 0 && !opts.NoDefaultAddresses {
 		opts.GenerateN = 1
 	}
+
+	opts.TF = serv.limitScan(opts.Context, opts.TF)
 	return opts
 }
 
+// limitScan wraps tf to space the queries of the scans by Config.ScanRateLimit and stop once ctx is done.
+// Returns tf unchanged if there is nothing to limit
+func (serv *Service) limitScan(ctx context.Context, tf TransactionsFinder) TransactionsFinder {
+	if tf == nil {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if serv.config.ScanRateLimit == 0 && ctx.Done() == nil {
+		return tf
+	}
+
+	return &rateLimitedTransactionsFinder{
+		tf:       tf,
+		ctx:      ctx,
+		interval: serv.config.ScanRateLimit,
+		mu:       &serv.scanMu,
+		last:     &serv.lastScanQuery,
+	}
+}
+
 // CreateWallet creates a wallet with the given wallet file name and options.
-// A address will be automatically generated by default.
+// A address will be automatically generated by default, unless Options.NoDefaultAddresses is set.
+// The wallet is created without holding the service lock, since the scan of Options.ScanN or
+// Options.GapLimit waits for the balance queries, which may be rate limited by Config.ScanRateLimit.
 func (serv *Service) CreateWallet(wltName string, options Options) (Wallet, error) {
+	wltName, options, err := serv.prepareCreateWallet(wltName, options)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := serv.createWallet(wltName, serv.updateOptions(options))
+	if err != nil {
+		return nil, err
+	}
+
 	serv.Lock()
-	defer serv.Unlock()
-	if !serv.config.EnableWalletAPI {
-		return nil, ErrWalletAPIDisabled
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return nil, err
+	}
+	if serv.config.ReadOnly {
+		return nil, ErrWalletReadOnly
+	}
+
+	w, err = serv.loadWallet(w)
+	if err != nil {
+		return nil, err
+	}
+
+	serv.queueEvent(w.Filename(), WalletEventCreated)
+	return w, nil
+}
+
+// prepareCreateWallet checks that a wallet can be created with the options and sets the default
+// filename and label of the wallet
+func (serv *Service) prepareCreateWallet(wltName string, options Options) (string, Options, error) {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return "", Options{}, err
+	}
+	if serv.config.ReadOnly {
+		return "", Options{}, ErrWalletReadOnly
+	}
+	if options.Encrypt {
+		if err := CheckPasswordStrength(options.Password, serv.config.MinPasswordLength); err != nil {
+			return "", Options{}, err
+		}
+	}
+	if err := serv.checkMaxWallets(1); err != nil {
+		return "", Options{}, err
 	}
 	if wltName == "" {
 		wltName = serv.generateUniqueWalletFilename()
 	}
+	if options.Label == "" {
+		options.Label = serv.defaultLabel(wltName)
+	}
 
-	return serv.loadWallet(wltName, options)
+	return wltName, options, nil
 }
 
 func (serv *Service) createWallet(wltName string, options Options) (Wallet, error) {
@@ -214,12 +711,18 @@ func (serv *Service) createWallet(wltName string, options Options) (Wallet, erro
 	return creator.Create(wltName, options.Label, options.Seed, options)
 }
 
-// loadWallet loads wallet from seed and scan the first N addresses
-func (serv *Service) loadWallet(wltName string, options Options) (Wallet, error) {
-	options = serv.updateOptions(options)
+// checkMaxWallets returns ErrMaxWalletsReached if the service can't hold n more wallets
+func (serv *Service) checkMaxWallets(n int) error {
+	if serv.config.MaxWallets > 0 && len(serv.wallets)+n > serv.config.MaxWallets {
+		return ErrMaxWalletsReached
+	}
+	return nil
+}
 
-	w, err := serv.createWallet(wltName, options)
-	if err != nil {
+// loadWallet adds the created wallet w to the service and saves it.
+// The caller must hold the service write lock.
+func (serv *Service) loadWallet(w Wallet) (Wallet, error) {
+	if err := serv.checkMaxWallets(1); err != nil {
 		return nil, err
 	}
 
@@ -239,7 +742,7 @@ func (serv *Service) loadWallet(wltName string, options Options) (Wallet, error)
 		return nil, err
 	}
 
-	if err := Save(w, serv.config.WalletDir); err != nil {
+	if err := serv.save(w); err != nil {
 		// If save fails, remove the added wallet
 		serv.wallets.remove(w.Filename())
 		return nil, err
@@ -253,98 +756,336 @@ func (serv *Service) loadWallet(wltName string, options Options) (Wallet, error)
 }
 
 func (serv *Service) generateUniqueWalletFilename() string {
-	wltName := NewWalletFilename()
+	wltName := serv.newWalletFilename()
 	for {
 		if w := serv.wallets.get(wltName); w == nil {
 			break
 		}
-		wltName = NewWalletFilename()
+		wltName = serv.newWalletFilename()
 	}
 
 	return wltName
 }
 
-// EncryptWallet encrypts wallet with password
-func (serv *Service) EncryptWallet(wltID string, password []byte) (Wallet, error) {
-	serv.Lock()
-	defer serv.Unlock()
-	if !serv.config.EnableWalletAPI {
-		return nil, ErrWalletAPIDisabled
+// newWalletFilename generates a filename with NewWalletFilename, prefixed with Config.FilenamePrefix if set
+func (serv *Service) newWalletFilename() string {
+	if serv.config.FilenamePrefix == "" {
+		return NewWalletFilename()
 	}
+	return serv.config.FilenamePrefix + "_" + NewWalletFilename()
+}
 
-	w, err := serv.getWallet(wltID)
-	if err != nil {
+// defaultLabel returns the label of a new wallet created without a label, from the Config.DefaultLabel template
+func (serv *Service) defaultLabel(wltName string) string {
+	if serv.config.DefaultLabel == "" {
+		return ""
+	}
+
+	r := strings.NewReplacer(
+		"{prefix}", serv.config.FilenamePrefix,
+		"{date}", time.Now().Format(WalletTimestampFormat),
+		"{filename}", wltName,
+	)
+	return r.Replace(serv.config.DefaultLabel)
+}
+
+// ImportWallet loads the wallet file at path and adds it to the service, saving a copy into the wallet directory.
+// The wallet keeps its filename, unless it is taken, in which case a unique filename is generated.
+// The same duplicate and empty wallet checks as on service startup are applied.
+func (serv *Service) ImportWallet(path string) (Wallet, error) {
+	serv.Lock()
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
 		return nil, err
 	}
+	if serv.config.ReadOnly {
+		return nil, ErrWalletReadOnly
+	}
 
-	if w.IsEncrypted() {
-		return nil, ErrWalletEncrypted
+	if err := serv.checkMaxWallets(1); err != nil {
+		return nil, err
 	}
 
-	if err := w.Lock(password); err != nil {
+	w, err := Load(path)
+	if err != nil {
 		return nil, err
 	}
 
-	// Saves to disk
-	if err := Save(w, serv.config.WalletDir); err != nil {
+	if err := serv.checkImportedWallet(w, path); err != nil {
 		return nil, err
 	}
 
-	// Updates wallets in memory
-	serv.wallets.set(w)
-	return w, nil
+	if !strings.HasSuffix(w.Filename(), "."+WalletExt) || serv.wallets.get(w.Filename()) != nil {
+		w.SetFilename(serv.generateUniqueWalletFilename())
+	} else if !serv.config.InMemory {
+		// A file not loaded by the service must not be overwritten either
+		if _, err := os.Stat(filepath.Join(serv.config.WalletDir, w.Filename())); !os.IsNotExist(err) {
+			w.SetFilename(serv.generateUniqueWalletFilename())
+		}
+	}
+
+	return serv.addImportedWallet(w)
 }
 
-// DecryptWallet decrypts wallet with password
-// TODO: this function will be deprecated in future.
-func (serv *Service) DecryptWallet(wltID string, password []byte) (Wallet, error) {
-	serv.Lock()
-	defer serv.Unlock()
-	if !serv.config.EnableWalletAPI {
-		return nil, ErrWalletAPIDisabled
+// checkImportedWallet applies the checks of the wallets loaded on service startup to a wallet
+// imported from source. The caller must hold the service lock.
+func (serv *Service) checkImportedWallet(w Wallet, source string) error {
+	if w.Coin() != CoinTypeSkycoin {
+		return NewError(fmt.Errorf("only skycoin wallets can be imported, %s is a %s wallet", source, w.Coin()))
 	}
 
-	w, err := serv.getWallet(wltID)
-	if err != nil {
-		return nil, err
+	if _, hasEmpty := (Wallets{w.Filename(): w}).containsEmpty(); hasEmpty {
+		return NewError(fmt.Errorf("empty wallet file: %q", source))
 	}
 
-	// Returns error if wallet is not encrypted
-	if !w.IsEncrypted() {
-		return nil, ErrWalletNotEncrypted
+	if fp := w.Fingerprint(); fp != "" {
+		if _, ok := serv.fingerprints[fp]; ok {
+			if w.Type() == WalletTypeXPub {
+				return ErrXPubKeyUsed
+			}
+			return ErrSeedUsed
+		}
 	}
 
-	// Unlocks the wallet
-	unlockWlt, err := w.Unlock(password)
-	if err != nil {
+	return nil
+}
+
+// addImportedWallet adds an imported wallet to the service and saves it.
+// The caller must hold the service write lock.
+func (serv *Service) addImportedWallet(w Wallet) (Wallet, error) {
+	if err := serv.wallets.add(w); err != nil {
 		return nil, err
 	}
 
-	// Updates the wallet file
-	if err := Save(unlockWlt, serv.config.WalletDir); err != nil {
+	if err := serv.save(w); err != nil {
+		// If save fails, remove the added wallet
+		serv.wallets.remove(w.Filename())
 		return nil, err
 	}
 
-	// Sets the decrypted wallet in memory
-	serv.wallets.set(unlockWlt)
-	return unlockWlt, nil
+	if fp := w.Fingerprint(); fp != "" {
+		serv.fingerprints[fp] = w.Filename()
+	}
+
+	serv.queueEvent(w.Filename(), WalletEventCreated)
+	return w.Clone(), nil
 }
 
-// NewAddresses generate address entries in given wallet,
-// return nil if wallet does not exist.
-// Set password as nil if the wallet is not encrypted, otherwise the password must be provided.
-// func (serv *Service) NewAddresses(wltID string, password []byte, num uint64) ([]cipher.Address, error) {
-// 	serv.Lock()
-// 	defer serv.Unlock()
+// ExportWallet writes the wallet file to destPath. An existing file at destPath is only replaced if overwrite is true.
+// The wallet is written in its serialized form, encrypted wallets are never decrypted.
+func (serv *Service) ExportWallet(wltID, destPath string, overwrite bool) error {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return err
+	}
 
-// 	if !serv.config.EnableWalletAPI {
-// 		return nil, ErrWalletAPIDisabled
-// 	}
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return err
+	}
 
-// 	w, err := serv.getWallet(wltID)
-// 	if err != nil {
-// 		return nil, err
-// 	}
+	if !overwrite {
+		if _, err := os.Stat(destPath); !os.IsNotExist(err) {
+			return ErrExportFileExists
+		}
+	}
+
+	data, err := w.Serialize()
+	if err != nil {
+		return err
+	}
+
+	return file.SaveBinary(destPath, data, serv.config.FilePerm)
+}
+
+// EncryptWallet encrypts wallet with password
+func (serv *Service) EncryptWallet(wltID string, password []byte) (Wallet, error) {
+	return serv.EncryptWalletWithCryptoType(wltID, password, "")
+}
+
+// EncryptWalletWithCryptoType encrypts wallet with password, using the given crypto type.
+// If cryptoType is empty, the wallet's crypto type is used.
+// The crypto type is recorded in the wallet metadata, so that the wallet is decrypted with the same method.
+func (serv *Service) EncryptWalletWithCryptoType(wltID string, password []byte, cryptoType crypto.CryptoType) (Wallet, error) {
+	if cryptoType != "" {
+		if _, err := crypto.GetCrypto(cryptoType); err != nil {
+			return nil, err
+		}
+	}
+
+	serv.Lock()
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return nil, err
+	}
+	if serv.config.ReadOnly {
+		return nil, ErrWalletReadOnly
+	}
+
+	if err := CheckPasswordStrength(password, serv.config.MinPasswordLength); err != nil {
+		return nil, err
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.IsEncrypted() {
+		return nil, ErrWalletEncrypted
+	}
+
+	if cryptoType != "" {
+		w.SetCryptoType(cryptoType)
+	}
+
+	if err := w.Lock(password); err != nil {
+		return nil, err
+	}
+
+	// Saves to disk
+	if err := serv.saveReencrypted(w); err != nil {
+		return nil, err
+	}
+
+	// Updates wallets in memory
+	serv.wallets.set(w)
+	serv.queueEvent(wltID, WalletEventEncrypted)
+	return w, nil
+}
+
+// RekeyAllWallets re-encrypts the wallets in passwords with the crypto type newType, each with its current password.
+// Wallets already encrypted with newType are skipped, encrypted wallets not in passwords are left unchanged.
+// Each wallet is decrypted and encrypted again in memory, then saved with an atomic file replace,
+// so a wallet whose save fails keeps its previous file and crypto type.
+// Returns the IDs of the re-encrypted wallets, sorted, and the error of each wallet that failed.
+// err is set if no wallet can be re-encrypted, e.g. if newType is unknown or the service is read-only.
+func (serv *Service) RekeyAllWallets(passwords map[string][]byte, newType crypto.CryptoType) (migrated []string, failed map[string]error, err error) {
+	if _, err := crypto.GetCrypto(newType); err != nil {
+		return nil, nil, err
+	}
+
+	serv.Lock()
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return nil, nil, err
+	}
+	if serv.config.ReadOnly {
+		return nil, nil, ErrWalletReadOnly
+	}
+
+	ids := make([]string, 0, len(passwords))
+	for id := range passwords {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	failed = make(map[string]error)
+	for _, id := range ids {
+		rekeyed, err := serv.rekeyWallet(id, passwords[id], newType)
+		switch {
+		case err != nil:
+			failed[id] = err
+		case rekeyed:
+			migrated = append(migrated, id)
+		}
+	}
+
+	return migrated, failed, nil
+}
+
+// rekeyWallet re-encrypts the wallet with the crypto type newType, returns false if it already uses newType.
+// The caller must hold the service write lock.
+func (serv *Service) rekeyWallet(wltID string, password []byte, newType crypto.CryptoType) (bool, error) {
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return false, err
+	}
+
+	if !w.IsEncrypted() {
+		return false, ErrWalletNotEncrypted
+	}
+
+	if w.CryptoType() == newType {
+		return false, nil
+	}
+
+	if err := serv.guardUpdate(w, password, func(w Wallet) error {
+		w.SetCryptoType(newType)
+		return nil
+	}); err != nil {
+		return false, err
+	}
+
+	if err := serv.saveReencrypted(w); err != nil {
+		return false, err
+	}
+
+	serv.wallets.set(w)
+	serv.queueEvent(wltID, WalletEventUpdated)
+	return true, nil
+}
+
+// DecryptWallet decrypts wallet with password
+// TODO: this function will be deprecated in future.
+func (serv *Service) DecryptWallet(wltID string, password []byte) (Wallet, error) {
+	serv.Lock()
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return nil, err
+	}
+	if serv.config.ReadOnly {
+		return nil, ErrWalletReadOnly
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Returns error if wallet is not encrypted
+	if !w.IsEncrypted() {
+		return nil, ErrWalletNotEncrypted
+	}
+
+	if err := serv.checkUnlockLockout(wltID); err != nil {
+		return nil, err
+	}
+
+	// Unlocks the wallet
+	unlockWlt, err := w.Unlock(password)
+	serv.recordUnlock(wltID, err)
+	if err != nil {
+		return nil, err
+	}
+
+	// Updates the wallet file
+	if err := serv.saveReencrypted(unlockWlt); err != nil {
+		return nil, err
+	}
+
+	// Sets the decrypted wallet in memory
+	serv.wallets.set(unlockWlt)
+	serv.queueEvent(wltID, WalletEventDecrypted)
+	return unlockWlt, nil
+}
+
+// NewAddresses generate address entries in given wallet,
+// return nil if wallet does not exist.
+// Set password as nil if the wallet is not encrypted, otherwise the password must be provided.
+// func (serv *Service) NewAddresses(wltID string, password []byte, num uint64) ([]cipher.Address, error) {
+// 	serv.Lock()
+// 	defer serv.Unlock()
+
+// 	if !serv.config.EnableWalletAPI {
+// 		return nil, ErrWalletAPIDisabled
+// 	}
+
+// 	w, err := serv.getWallet(wltID)
+// 	if err != nil {
+// 		return nil, err
+// 	}
 
 // 	var addrs []cipher.Address
 // 	f := func(wlt Wallet) error {
@@ -374,7 +1115,7 @@ func (serv *Service) DecryptWallet(wltID string, password []byte) (Wallet, error
 // 	}
 
 // 	// Save the wallet first
-// 	if err := Save(w, serv.config.WalletDir); err != nil {
+// 	if err := serv.save(w); err != nil {
 // 		return nil, err
 // 	}
 
@@ -386,10 +1127,13 @@ func (serv *Service) DecryptWallet(wltID string, password []byte) (Wallet, error
 // NewAddresses generate addresses
 func (serv *Service) NewAddresses(wltID string, password []byte, options ...Option) ([]cipher.Address, error) {
 	serv.Lock()
-	defer serv.Unlock()
+	defer serv.unlockAndNotify()
 
-	if !serv.config.EnableWalletAPI {
-		return nil, ErrWalletAPIDisabled
+	if err := serv.checkEnabled(); err != nil {
+		return nil, err
+	}
+	if serv.config.ReadOnly {
+		return nil, ErrWalletReadOnly
 	}
 
 	w, err := serv.getWallet(wltID)
@@ -397,6 +1141,85 @@ func (serv *Service) NewAddresses(wltID string, password []byte, options ...Opti
 		return nil, err
 	}
 
+	addrs, err := serv.generateAddresses(w, password, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := serv.saveWritable(w); err != nil {
+		return nil, err
+	}
+
+	serv.wallets.set(w)
+	serv.queueEvent(wltID, WalletEventAddressesAdded)
+	return SkycoinAddresses(addrs), nil
+}
+
+// NewAddressesMulti generates addresses in several wallets under a single lock.
+// reqs maps the wallet IDs to the number of addresses to generate, and passwords maps
+// the IDs of the encrypted wallets to their passwords. The result maps the wallet IDs
+// to the new addresses.
+// Nothing is changed if the addresses can't be generated in one of the wallets.
+// If a wallet fails to be saved, its new addresses are discarded and a SaveWalletError
+// is returned for the first such wallet, while the other wallets are still saved and
+// their addresses returned.
+func (serv *Service) NewAddressesMulti(reqs map[string]uint64, passwords map[string][]byte) (map[string][]cipher.Address, error) {
+	serv.Lock()
+	defer serv.unlockAndNotify()
+
+	if err := serv.checkEnabled(); err != nil {
+		return nil, err
+	}
+	if serv.config.ReadOnly {
+		return nil, ErrWalletReadOnly
+	}
+
+	wltIDs := make([]string, 0, len(reqs))
+	for wltID := range reqs {
+		wltIDs = append(wltIDs, wltID)
+	}
+	sort.Strings(wltIDs)
+
+	// Generate the addresses on copies of the wallets, so that the loaded
+	// wallets are only changed once the copies are saved
+	wlts := make([]Wallet, len(wltIDs))
+	addrs := make([][]cipher.Addresser, len(wltIDs))
+	for i, wltID := range wltIDs {
+		w, err := serv.getWallet(wltID)
+		if err != nil {
+			return nil, err
+		}
+
+		addrs[i], err = serv.generateAddresses(w, passwords[wltID], OptionGenerateN(reqs[wltID]))
+		if err != nil {
+			return nil, err
+		}
+		wlts[i] = w
+	}
+
+	var saveErr error
+	newAddrs := make(map[string][]cipher.Address, len(wltIDs))
+	for i, w := range wlts {
+		if err := serv.saveWritable(w); err != nil {
+			if saveErr == nil {
+				saveErr = SaveWalletError{
+					WalletID: wltIDs[i],
+					Err:      err,
+				}
+			}
+			continue
+		}
+
+		serv.wallets.set(w)
+		serv.queueEvent(wltIDs[i], WalletEventAddressesAdded)
+		newAddrs[wltIDs[i]] = SkycoinAddresses(addrs[i])
+	}
+
+	return newAddrs, saveErr
+}
+
+// generateAddresses generates addresses in the wallet, unlocking it with the password if it is encrypted
+func (serv *Service) generateAddresses(w Wallet, password []byte, options ...Option) ([]cipher.Addresser, error) {
 	var addrs []cipher.Addresser
 	f := func(w Wallet) error {
 		var err error
@@ -413,7 +1236,7 @@ func (serv *Service) NewAddresses(wltID string, password []byte, options ...Opti
 				return nil, err
 			}
 		} else {
-			if err := GuardUpdate(w, password, f); err != nil {
+			if err := serv.guardUpdate(w, password, f); err != nil {
 				return nil, err
 			}
 		}
@@ -427,34 +1250,150 @@ func (serv *Service) NewAddresses(wltID string, password []byte, options ...Opti
 		}
 	}
 
-	// check if wallet is writable only when it's not a temporary wallet.
-	// this checking would create a temp file
-	if !w.IsTemp() {
-		// Checks if the wallet file is writable
-		wf := filepath.Join(serv.config.WalletDir, w.Filename())
-		if !file.IsWritable(wf) {
-			return nil, ErrWalletPermission
+	return addrs, nil
+}
+
+// accountCreator is implemented by the wallets that hold several bip44 accounts
+type accountCreator interface {
+	NewAccount(name string) (uint32, error)
+}
+
+// NewAccount adds an account to a bip44 wallet and returns its index.
+// Like the default account of a new wallet, the account starts with one address on each of its chains.
+// The account keys are derived from the seed, so the password is required if the wallet is encrypted.
+// Use OptionAccount to generate or list the addresses of the account.
+func (serv *Service) NewAccount(wltID string, password []byte, name string) (uint32, error) {
+	serv.Lock()
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return 0, err
+	}
+	if serv.config.ReadOnly {
+		return 0, ErrWalletReadOnly
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, ok := w.(accountCreator); !ok {
+		return 0, ErrWalletNotBip44
+	}
+
+	var index uint32
+	f := func(w Wallet) error {
+		var err error
+		index, err = newAccount(w, name, 1, 1)
+		return err
+	}
+
+	if w.IsEncrypted() {
+		err = serv.guardUpdate(w, password, f)
+	} else if len(password) != 0 {
+		err = ErrWalletNotEncrypted
+	} else {
+		err = f(w)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := serv.saveWritable(w); err != nil {
+		return 0, err
+	}
+
+	serv.wallets.set(w)
+	serv.queueEvent(wltID, WalletEventAddressesAdded)
+	return index, nil
+}
+
+// newAccount adds an account to the unlocked bip44 wallet w, with externalN addresses
+// on its external chain and changeN addresses on its change chain
+func newAccount(w Wallet, name string, externalN, changeN uint64) (uint32, error) {
+	index, err := w.(accountCreator).NewAccount(name)
+	if err != nil {
+		return 0, err
+	}
+
+	if externalN > 0 {
+		if _, err := w.GenerateAddresses(OptionAccount(index), OptionGenerateN(externalN)); err != nil {
+			return 0, err
 		}
+	}
 
-		// Save the wallet
-		if err := Save(w, serv.config.WalletDir); err != nil {
-			return nil, err
+	if changeN > 0 {
+		if _, err := w.GenerateAddresses(OptionAccount(index), OptionGenerateN(changeN), OptionChange()); err != nil {
+			return 0, err
 		}
 	}
 
-	serv.wallets.set(w)
-	return SkycoinAddresses(addrs), nil
+	return index, nil
+}
+
+// saveWritable saves the wallet after checking that its file is writable
+func (serv *Service) saveWritable(w Wallet) error {
+	if err := serv.checkWalletSource(w); err != nil {
+		return err
+	}
+
+	// check if wallet is writable only when it's stored on disk.
+	// this checking would create a temp file
+	if !serv.hasFile(w) {
+		return nil
+	}
+
+	// Checks if the wallet file is writable
+	wf := filepath.Join(serv.config.WalletDir, w.Filename())
+	if !file.IsWritable(wf) {
+		return ErrWalletPermission
+	}
+
+	// Save the wallet
+	return serv.save(w)
 }
 
 // ScanAddresses scan ahead addresses to see if contains balance.
 func (serv *Service) ScanAddresses(wltID string, password []byte, num uint64, tf TransactionsFinder) ([]cipher.Address, error) {
-	serv.Lock()
-	defer serv.Unlock()
-	if !serv.config.EnableWalletAPI {
-		return nil, ErrWalletAPIDisabled
+	return serv.ScanAddressesContext(context.Background(), wltID, password, num, tf)
+}
+
+// ScanAddressesContext is ScanAddresses, stopping the scan with the context error once ctx is done
+func (serv *Service) ScanAddressesContext(ctx context.Context, wltID string, password []byte, num uint64, tf TransactionsFinder) ([]cipher.Address, error) {
+	tf = serv.limitScan(ctx, tf)
+	return serv.scanAddresses(wltID, password, func(w Wallet) ([]cipher.Addresser, error) {
+		return w.ScanAddresses(num, tf)
+	})
+}
+
+// ScanAddressesByBalance extends the wallet with the addresses that follow its last address, up to the last
+// one with a balance, until gapLimit consecutive addresses without a balance are found.
+// This recovers funds sent to addresses beyond the generated ones, like NewWallet does with Options.GapLimit.
+// An address is considered to have a balance if its confirmed or predicted coins are not zero.
+// Returns the new addresses, which are saved in the wallet.
+func (serv *Service) ScanAddressesByBalance(wltID string, password []byte, gapLimit uint64, bg BalanceGetter) ([]cipher.Address, error) {
+	return serv.ScanAddressesByBalanceContext(context.Background(), wltID, password, gapLimit, bg)
+}
+
+// ScanAddressesByBalanceContext is ScanAddressesByBalance, stopping the scan with the context error once ctx is done
+func (serv *Service) ScanAddressesByBalanceContext(ctx context.Context, wltID string, password []byte, gapLimit uint64, bg BalanceGetter) ([]cipher.Address, error) {
+	if bg == nil {
+		return nil, ErrNilTransactionsFinder
 	}
 
-	w, err := serv.getWallet(wltID)
+	tf := serv.limitScan(ctx, balanceTransactionsFinder{bg: bg})
+	return serv.scanAddresses(wltID, password, func(w Wallet) ([]cipher.Addresser, error) {
+		return ScanAddressesGapLimit(w, gapLimit, 0, tf)
+	})
+}
+
+// scanAddresses adds the addresses returned by scan to the wallet and saves it.
+// Encrypted wallets are unlocked with password, except bip44 wallets which can scan while locked.
+// The scan waits for the balance queries, which may be rate limited, so it runs on a copy of the wallet
+// without holding the service lock, which is only taken to save the copy. ErrWalletChangedDuringScan
+// is returned if the wallet was changed by another operation in the meantime.
+func (serv *Service) scanAddresses(wltID string, password []byte, scan func(Wallet) ([]cipher.Addresser, error)) ([]cipher.Address, error) {
+	w, loaded, err := serv.getScanWallet(wltID)
 	if err != nil {
 		return nil, err
 	}
@@ -462,7 +1401,7 @@ func (serv *Service) ScanAddresses(wltID string, password []byte, num uint64, tf
 	var addrs []cipher.Addresser
 	f := func(w Wallet) error {
 		var err error
-		addrs, err = w.ScanAddresses(num, tf)
+		addrs, err = scan(w)
 		return err
 	}
 
@@ -477,7 +1416,7 @@ func (serv *Service) ScanAddresses(wltID string, password []byte, num uint64, tf
 		}
 	} else {
 		if w.IsEncrypted() {
-			if err := GuardUpdate(w, password, f); err != nil {
+			if err := serv.guardUpdate(w, password, f); err != nil {
 				return nil, err
 			}
 		} else {
@@ -491,26 +1430,55 @@ func (serv *Service) ScanAddresses(wltID string, password []byte, num uint64, tf
 		}
 	}
 
-	// Checks if the wallet file is writable
-	if !w.IsTemp() {
-		wf := filepath.Join(serv.config.WalletDir, w.Filename())
-		if !file.IsWritable(wf) {
-			return nil, ErrWalletPermission
-		}
+	serv.Lock()
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return nil, err
+	}
+	if serv.config.ReadOnly {
+		return nil, ErrWalletReadOnly
+	}
 
-		// Saves the wallet to disk
-		if err := Save(w, serv.config.WalletDir); err != nil {
-			return nil, err
-		}
+	// Every change replaces the loaded wallet
+	if serv.wallets.get(wltID) != loaded {
+		return nil, ErrWalletChangedDuringScan
+	}
+
+	// Saves the wallet to disk, if its file is writable
+	if err := serv.saveWritable(w); err != nil {
+		return nil, err
 	}
 
 	// Updates wallet in memory
 	serv.wallets.set(w)
+	if len(addrs) > 0 {
+		serv.queueEvent(wltID, WalletEventAddressesAdded)
+	}
 
 	// return new generated addresses
 	return SkycoinAddresses(addrs), nil
 }
 
+// getScanWallet returns a copy of the wallet to scan, and the loaded wallet
+// to check that the wallet is not changed during the scan
+func (serv *Service) getScanWallet(wltID string) (Wallet, Wallet, error) {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return nil, nil, err
+	}
+	if serv.config.ReadOnly {
+		return nil, nil, ErrWalletReadOnly
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return w, serv.wallets.get(wltID), nil
+}
+
 // GetSkycoinAddresses returns all addresses in given wallet
 // func (serv *Service) GetSkycoinAddresses(wltID string) ([]cipher.Address, error) {
 // 	serv.RLock()
@@ -531,8 +1499,8 @@ func (serv *Service) ScanAddresses(wltID string, password []byte, num uint64, tf
 func (serv *Service) GetAddresses(wltID string, options ...Option) ([]cipher.Address, error) {
 	serv.RLock()
 	defer serv.RUnlock()
-	if !serv.config.EnableWalletAPI {
-		return nil, ErrWalletAPIDisabled
+	if err := serv.checkEnabled(); err != nil {
+		return nil, err
 	}
 
 	w, err := serv.getWallet(wltID)
@@ -547,32 +1515,207 @@ func (serv *Service) GetAddresses(wltID string, options ...Option) ([]cipher.Add
 	return SkycoinAddresses(addrs), nil
 }
 
+// GetWalletEntries returns up to limit entries of the wallet starting at offset, and the number of entries
+// of the wallet, without cloning the wallet. For bip44 wallets, the entries are those of GetEntries.
+// The secret keys are not included. An empty page is returned if offset is past the last entry.
+// Returns ErrInvalidPagination if offset is negative or limit is not positive.
+func (serv *Service) GetWalletEntries(wltID string, offset, limit int) ([]Entry, int, error) {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return nil, 0, err
+	}
+
+	if offset < 0 || limit <= 0 {
+		return nil, 0, ErrInvalidPagination
+	}
+
+	w := serv.wallets.get(wltID)
+	if w == nil {
+		return nil, 0, ErrWalletNotExist
+	}
+
+	total, err := w.EntriesLen()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if offset >= total {
+		return []Entry{}, total, nil
+	}
+
+	n := total - offset
+	if n > limit {
+		n = limit
+	}
+
+	entries := make([]Entry, n)
+	for i := range entries {
+		e, err := w.GetEntryAt(offset + i)
+		if err != nil {
+			return nil, 0, err
+		}
+		e.Secret = cipher.SecKey{}
+		entries[i] = e
+	}
+
+	return entries, total, nil
+}
+
 // GetWallet returns wallet by id
 func (serv *Service) GetWallet(wltID string) (Wallet, error) {
 	serv.RLock()
 	defer serv.RUnlock()
-	if !serv.config.EnableWalletAPI {
-		return nil, ErrWalletAPIDisabled
+	if err := serv.checkEnabled(); err != nil {
+		return nil, err
 	}
 
 	return serv.getWallet(wltID)
 }
 
+// GetWalletJSON returns the wallet serialized as it is saved to its file, unlike NewReadableWallet which
+// changes its structure. The secrets of an encrypted wallet stay encrypted, while those of an unencrypted wallet
+// are included as they are on disk.
+// The wallets without a file, i.e. temporary wallets or the wallets of an in-memory service, are serialized the same way
+func (serv *Service) GetWalletJSON(wltID string) ([]byte, error) {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return nil, err
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.Serialize()
+}
+
+// GetWalletCryptoType returns the crypto type used to encrypt the wallet, read from its metadata
+// without unlocking it. Returns an empty crypto type if the wallet is not encrypted.
+func (serv *Service) GetWalletCryptoType(wltID string) (crypto.CryptoType, error) {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return "", err
+	}
+
+	w := serv.wallets.get(wltID)
+	if w == nil {
+		return "", ErrWalletNotExist
+	}
+
+	if !w.IsEncrypted() {
+		return "", nil
+	}
+
+	return w.CryptoType(), nil
+}
+
+// IsWalletEncrypted returns whether the wallet is encrypted, without cloning the wallet
+func (serv *Service) IsWalletEncrypted(wltID string) (bool, error) {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return false, err
+	}
+
+	w := serv.wallets.get(wltID)
+	if w == nil {
+		return false, ErrWalletNotExist
+	}
+
+	return w.IsEncrypted(), nil
+}
+
 // returns the clone of the wallet of given id
 func (serv *Service) getWallet(wltID string) (Wallet, error) {
 	w := serv.wallets.get(wltID)
 	if w == nil {
 		return nil, ErrWalletNotExist
 	}
+	if lw, ok := w.(*lazyWallet); ok {
+		if _, err := lw.load(); err != nil {
+			return nil, err
+		}
+	}
 	return w.Clone(), nil
 }
 
+// GetBalance returns the confirmed and predicted balance of the wallet, and the balance of each of its addresses.
+// The address balances are in the same order as the addresses returned by GetAddresses.
+// Only the wallet's addresses are needed, so encrypted wallets do not require a password.
+func (serv *Service) GetBalance(wltID string, bg BalanceGetter) (BalancePair, []BalancePair, error) {
+	var addrs []cipher.Address
+	if err := serv.View(wltID, func(w Wallet) error {
+		as, err := w.GetAddresses()
+		if err != nil {
+			return err
+		}
+		addrs = SkycoinAddresses(as)
+		return nil
+	}); err != nil {
+		return BalancePair{}, nil, err
+	}
+
+	addrBalances, err := bg.GetBalanceOfAddresses(addrs)
+	if err != nil {
+		return BalancePair{}, nil, err
+	}
+
+	if len(addrBalances) != len(addrs) {
+		return BalancePair{}, nil, fmt.Errorf("got %d balances for %d addresses", len(addrBalances), len(addrs))
+	}
+
+	var total BalancePair
+	for _, b := range addrBalances {
+		total, err = total.Add(b)
+		if err != nil {
+			return BalancePair{}, nil, err
+		}
+	}
+
+	return total, addrBalances, nil
+}
+
+// GetMaxSpendable returns the largest amount of coins the wallet can send in a single transaction.
+// The transaction's inputs are chosen from the wallet's spendable outputs, got from og.
+// headTime is required to calculate coin hours.
+// Returns ErrInsufficientCoinHours if the wallet has coins but the chosen outputs have no coin hours
+// to pay the fee, and 0 if it has no coins.
+func (serv *Service) GetMaxSpendable(wltID string, og UnspentOutputsGetter, headTime uint64) (uint64, error) {
+	var addrs []cipher.Address
+	if err := serv.View(wltID, func(w Wallet) error {
+		as, err := w.GetAddresses()
+		if err != nil {
+			return err
+		}
+		addrs = SkycoinAddresses(as)
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	auxs, err := og.GetSpendableOutputsOfAddresses(addrs)
+	if err != nil {
+		return 0, err
+	}
+
+	uxb, err := transaction.NewUxBalances(auxs.Flatten(), headTime)
+	if err != nil {
+		return 0, err
+	}
+
+	return maxSpendableCoins(uxb, params.UserVerifyTxn.MaxTransactionSize)
+}
+
 // GetWallets returns all wallet clones
 func (serv *Service) GetWallets() (Wallets, error) {
 	serv.RLock()
 	defer serv.RUnlock()
-	if !serv.config.EnableWalletAPI {
-		return nil, ErrWalletAPIDisabled
+	if err := serv.checkEnabled(); err != nil {
+		return nil, err
 	}
 
 	wlts := make(Wallets, len(serv.wallets))
@@ -582,12 +1725,289 @@ func (serv *Service) GetWallets() (Wallets, error) {
 	return wlts, nil
 }
 
+// ForEachWallet calls fn with a clone of each wallet in order of wallet ID, one at a time,
+// without cloning all the wallets up front like GetWallets. It stops and returns the error
+// if fn returns an error. The service is read locked meanwhile, so fn must not call the service.
+func (serv *Service) ForEachWallet(fn func(w Wallet) error) error {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(serv.wallets))
+	for id := range serv.wallets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if err := fn(serv.wallets[id].Clone()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SortField is the field that GetWalletsSorted sorts the wallets by
+type SortField string
+
+const (
+	// SortByLabel sorts the wallets by label
+	SortByLabel SortField = "label"
+	// SortByFilename sorts the wallets by filename, i.e. wallet ID
+	SortByFilename SortField = "filename"
+	// SortByTimestamp sorts the wallets by creation time
+	SortByTimestamp SortField = "timestamp"
+	// SortByEntries sorts the wallets by number of entries, including all the accounts of bip44 wallets
+	SortByEntries SortField = "entries"
+)
+
+// GetWalletsSorted returns clones of the wallets sorted by the given field, in descending order if desc is set.
+// Wallets with equal values are sorted by filename, in the same direction.
+// Returns ErrInvalidSortField if the field is not one of the SortBy values.
+func (serv *Service) GetWalletsSorted(by SortField, desc bool) ([]Wallet, error) {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return nil, err
+	}
+
+	switch by {
+	case SortByLabel, SortByFilename, SortByTimestamp, SortByEntries:
+	default:
+		return nil, ErrInvalidSortField
+	}
+
+	wlts := make([]Wallet, 0, len(serv.wallets))
+	counts := make(map[string]int, len(serv.wallets))
+	for id, w := range serv.wallets {
+		if by == SortByEntries {
+			n, err := entryCount(w)
+			if err != nil {
+				return nil, err
+			}
+			counts[id] = n
+		}
+		wlts = append(wlts, w.Clone())
+	}
+
+	less := func(a, b Wallet) bool {
+		switch by {
+		case SortByLabel:
+			if a.Label() != b.Label() {
+				return a.Label() < b.Label()
+			}
+		case SortByTimestamp:
+			if a.Timestamp() != b.Timestamp() {
+				return a.Timestamp() < b.Timestamp()
+			}
+		case SortByEntries:
+			if counts[a.Filename()] != counts[b.Filename()] {
+				return counts[a.Filename()] < counts[b.Filename()]
+			}
+		}
+		return a.Filename() < b.Filename()
+	}
+
+	sort.Slice(wlts, func(i, j int) bool {
+		if desc {
+			return less(wlts[j], wlts[i])
+		}
+		return less(wlts[i], wlts[j])
+	})
+
+	return wlts, nil
+}
+
+// WalletInfo is a summary of a wallet, returned by GetWalletNames
+type WalletInfo struct {
+	ID         string
+	Label      string
+	Encrypted  bool
+	Type       string
+	EntryCount int
+}
+
+// GetWalletNames returns a summary of each wallet sorted by ID, without cloning the wallets.
+// EntryCount includes the entries of all the accounts and chains of bip44 wallets.
+func (serv *Service) GetWalletNames() ([]WalletInfo, error) {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return nil, err
+	}
+
+	infos := make([]WalletInfo, 0, len(serv.wallets))
+	for id, w := range serv.wallets {
+		n, err := entryCount(w)
+		if err != nil {
+			return nil, err
+		}
+
+		infos = append(infos, WalletInfo{
+			ID:         id,
+			Label:      w.Label(),
+			Encrypted:  w.IsEncrypted(),
+			Type:       w.Type(),
+			EntryCount: n,
+		})
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ID < infos[j].ID
+	})
+
+	return infos, nil
+}
+
+// entryCount returns the number of entries of the wallet, across all the accounts of a bip44 wallet
+func entryCount(w Wallet) (int, error) {
+	if w.Type() != WalletTypeBip44 {
+		return w.EntriesLen()
+	}
+
+	var n int
+	for _, a := range w.Accounts() {
+		l, err := w.EntriesLen(OptionAccount(a.Index))
+		if err != nil {
+			return 0, err
+		}
+		n += l
+	}
+	return n, nil
+}
+
+// ServiceStats are aggregated counts of the wallets of the service, returned by Stats
+type ServiceStats struct {
+	Wallets          int
+	EncryptedWallets int
+	// Entries is the number of entries of all the wallets, including all the accounts and chains of bip44 wallets
+	Entries int
+	// WalletTypes is the number of wallets of each wallet type
+	WalletTypes map[string]int
+	// CryptoTypes is the number of encrypted wallets of each crypto type
+	CryptoTypes map[crypto.CryptoType]int
+	// ModTimes is the modification time of each wallet file when the service last loaded or saved it.
+	// Wallets without a file are not included.
+	ModTimes map[string]time.Time
+	// LastModified is the latest of ModTimes
+	LastModified time.Time
+}
+
+// Stats returns aggregated counts of the wallets, without cloning the wallets.
+// The entries of a wallet that fails to load are not counted.
+func (serv *Service) Stats() ServiceStats {
+	serv.RLock()
+	defer serv.RUnlock()
+
+	stats := ServiceStats{
+		Wallets:     len(serv.wallets),
+		WalletTypes: make(map[string]int),
+		CryptoTypes: make(map[crypto.CryptoType]int),
+		ModTimes:    make(map[string]time.Time, len(serv.modTimes)),
+	}
+
+	for wltID, w := range serv.wallets {
+		stats.WalletTypes[w.Type()]++
+
+		if w.IsEncrypted() {
+			stats.EncryptedWallets++
+			stats.CryptoTypes[w.CryptoType()]++
+		}
+
+		n, err := entryCount(w)
+		if err != nil {
+			logger.WithError(err).WithField("wallet", wltID).Warning("Stats: entryCount failed")
+			continue
+		}
+		stats.Entries += n
+	}
+
+	for wltID, t := range serv.modTimes {
+		stats.ModTimes[wltID] = t
+		if t.After(stats.LastModified) {
+			stats.LastModified = t
+		}
+	}
+
+	return stats
+}
+
+// GetWalletsByLabel returns clones of the wallets with the given label.
+// Returns ErrWalletNotExist if no wallet has the label.
+func (serv *Service) GetWalletsByLabel(label string) (Wallets, error) {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return nil, err
+	}
+
+	wlts := serv.wallets.Filter(func(w Wallet) bool {
+		return w.Label() == label
+	})
+	if len(wlts) == 0 {
+		return nil, ErrWalletNotExist
+	}
+
+	for id, w := range wlts {
+		wlts[id] = w.Clone()
+	}
+	return wlts, nil
+}
+
+// GetWalletByFirstAddress returns a clone of the wallet whose first address is addr.
+// For bip44 wallets, the first external address of the first account that has one is used.
+// Lazily loaded wallets are matched by the first address recorded in their header, so only
+// the returned wallet is loaded. Wallets without entries, or whose first address can't be read, are skipped.
+// Returns ErrWalletNotExist if no wallet has addr as its first address.
+func (serv *Service) GetWalletByFirstAddress(addr cipher.Address) (Wallet, error) {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(serv.wallets))
+	for id := range serv.wallets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	target := addr.String()
+	for _, id := range ids {
+		w := serv.wallets[id]
+
+		var first string
+		var err error
+		if lw, ok := w.(*lazyWallet); ok {
+			first, err = lw.firstAddress()
+		} else {
+			first, err = firstAddress(w)
+		}
+		if err != nil {
+			logger.WithError(err).WithField("wallet", id).Warning("GetWalletByFirstAddress: firstAddress failed")
+			continue
+		}
+
+		if first == target {
+			return w.Clone(), nil
+		}
+	}
+
+	return nil, ErrWalletNotExist
+}
+
 // UpdateWalletLabel updates the wallet label
 func (serv *Service) UpdateWalletLabel(wltID, label string) error {
 	serv.Lock()
-	defer serv.Unlock()
-	if !serv.config.EnableWalletAPI {
-		return ErrWalletAPIDisabled
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return err
+	}
+	if serv.config.ReadOnly {
+		return ErrWalletReadOnly
 	}
 
 	w, err := serv.getWallet(wltID)
@@ -597,167 +2017,1821 @@ func (serv *Service) UpdateWalletLabel(wltID, label string) error {
 
 	w.SetLabel(label)
 
-	if err := Save(w, serv.config.WalletDir); err != nil {
+	if err := serv.save(w); err != nil {
 		return err
 	}
 
 	serv.wallets.set(w)
+	serv.queueEvent(wltID, WalletEventLabelUpdated)
 	return nil
 }
 
-// UnloadWallet removes wallet of given wallet id from the service
-func (serv *Service) UnloadWallet(wltID string) error {
+// SetWalletNotes sets the free-form notes of the wallet, e.g. its purpose or owner.
+// The notes are saved in cleartext and readable without the password, they must not hold secrets.
+// Empty notes remove them from the wallet.
+func (serv *Service) SetWalletNotes(wltID, notes string) error {
 	serv.Lock()
-	defer serv.Unlock()
-	if !serv.config.EnableWalletAPI {
-		return ErrWalletAPIDisabled
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return err
+	}
+	if serv.config.ReadOnly {
+		return ErrWalletReadOnly
 	}
 
-	wlt := serv.wallets.get(wltID)
-	if wlt != nil {
-		if fp := wlt.Fingerprint(); fp != "" {
-			delete(serv.fingerprints, fp)
-		}
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return err
 	}
 
-	serv.wallets.remove(wltID)
+	w.SetNotes(notes)
+
+	if err := serv.save(w); err != nil {
+		return err
+	}
+
+	serv.wallets.set(w)
+	serv.queueEvent(wltID, WalletEventNotesUpdated)
 	return nil
 }
 
-func (serv *Service) setWallets(wlts Wallets) {
-	serv.wallets = wlts
-
-	for wltID, wlt := range wlts {
-		if fp := wlt.Fingerprint(); fp != "" {
-			serv.fingerprints[fp] = wltID
-		}
+// Compact rewrites the wallet file from the loaded wallet, in the form written by every save.
+// Fields that are unknown to this version, e.g. deprecated or hand-edited ones, are dropped, and
+// the meta data of wallets written by older versions is saved at the current version.
+// The file is replaced atomically, after a backup is made if Config.MaxBackups is set.
+func (serv *Service) Compact(wltID string) error {
+	serv.Lock()
+	defer serv.Unlock()
+	if err := serv.checkEnabled(); err != nil {
+		return err
+	}
+	if serv.config.ReadOnly {
+		return ErrWalletReadOnly
 	}
-}
 
-// GetWalletSeed returns seed and seed passphrase of encrypted wallet of given wallet id
-// Returns ErrWalletNotEncrypted if it's not encrypted
-func (serv *Service) GetWalletSeed(wltID string, password []byte) (string, string, error) {
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return err
+	}
+
+	return serv.saveWritable(w)
+}
+
+// SetAddressLabel sets the label of the wallet entry with the given address,
+// an empty label clears it. Returns ErrEntryNotFound if the wallet does not contain the address.
+func (serv *Service) SetAddressLabel(wltID string, addr cipher.Address, label string) error {
+	serv.Lock()
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return err
+	}
+	if serv.config.ReadOnly {
+		return ErrWalletReadOnly
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return err
+	}
+
+	if err := w.SetEntryLabel(addr, label); err != nil {
+		return err
+	}
+
+	if err := serv.save(w); err != nil {
+		return err
+	}
+
+	serv.wallets.set(w)
+	serv.queueEvent(wltID, WalletEventAddressLabelUpdated)
+	return nil
+}
+
+// DuplicateWallet creates a copy of the wallet with a new filename and label.
+// If newWltName is empty, a unique wallet filename is generated.
+// Wallets that have a seed can't be duplicated, since wallets sharing a seed
+// would fail the duplicate wallet check on the next service startup, ErrSeedUsed is returned for them.
+func (serv *Service) DuplicateWallet(wltID, newWltName, newLabel string) (Wallet, error) {
+	serv.Lock()
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return nil, err
+	}
+	if serv.config.ReadOnly {
+		return nil, ErrWalletReadOnly
+	}
+
+	if err := serv.checkMaxWallets(1); err != nil {
+		return nil, err
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return nil, err
+	}
+
+	if fp := w.Fingerprint(); fp != "" {
+		if _, ok := serv.fingerprints[fp]; ok {
+			return nil, ErrSeedUsed
+		}
+	}
+
+	if newWltName == "" {
+		newWltName = serv.generateUniqueWalletFilename()
+	}
+
+	w.SetFilename(newWltName)
+	w.SetLabel(newLabel)
+
+	if err := serv.wallets.add(w); err != nil {
+		return nil, err
+	}
+
+	if err := serv.save(w); err != nil {
+		// If save fails, remove the added wallet
+		serv.wallets.remove(w.Filename())
+		return nil, err
+	}
+
+	serv.queueEvent(w.Filename(), WalletEventCreated)
+	return w.Clone(), nil
+}
+
+// RenameWallet changes the wallet filename, renaming the wallet file on disk.
+// The file is moved with a single rename, so a crash can't leave two copies of the wallet behind.
+func (serv *Service) RenameWallet(wltID, newWltID string) (Wallet, error) {
+	serv.Lock()
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return nil, err
+	}
+	if serv.config.ReadOnly {
+		return nil, ErrWalletReadOnly
+	}
+
+	if filepath.Base(newWltID) != newWltID || !strings.HasSuffix(newWltID, "."+WalletExt) || newWltID == "."+WalletExt {
+		return nil, ErrInvalidWalletFilename
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return nil, err
+	}
+
+	if newWltID == wltID {
+		return w, nil
+	}
+
+	if serv.wallets.get(newWltID) != nil {
+		return nil, ErrWalletNameConflict
+	}
+
+	if err := serv.checkWalletSource(w); err != nil {
+		return nil, err
+	}
+
+	w.SetFilename(newWltID)
+
+	if serv.hasFile(w) {
+		oldPath := filepath.Join(serv.config.WalletDir, wltID)
+		newPath := filepath.Join(serv.config.WalletDir, newWltID)
+
+		if _, err := os.Stat(newPath); !os.IsNotExist(err) {
+			return nil, ErrWalletNameConflict
+		}
+
+		if err := os.Rename(oldPath, newPath); err != nil {
+			return nil, err
+		}
+
+		// Rewrite the file so that its metadata has the new filename
+		if err := serv.save(w); err != nil {
+			if rerr := os.Rename(newPath, oldPath); rerr != nil {
+				logger.WithError(rerr).WithField("filename", newPath).Error("RenameWallet: failed to restore wallet file name")
+			}
+			return nil, err
+		}
+	}
+
+	serv.wallets.remove(wltID)
+	delete(serv.modTimes, wltID)
+	serv.wallets.set(w)
+
+	if fp := w.Fingerprint(); fp != "" {
+		serv.fingerprints[fp] = newWltID
+	}
+
+	serv.queueEvent(wltID, WalletEventUnloaded)
+	serv.queueEvent(newWltID, WalletEventRenamed)
+
+	return w.Clone(), nil
+}
+
+// ReloadWallet reads the wallet of given wallet id again from its file in the wallet directory,
+// to pick up changes made by another process, and replaces the wallet in memory.
+// The file must still hold the same wallet: ErrWalletIdentityChanged is returned and the wallet
+// in memory is kept if the wallet type or first address changed.
+// Reloading only reads the file, so it is allowed when the service is read-only.
+func (serv *Service) ReloadWallet(wltID string) (Wallet, error) {
+	serv.Lock()
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return nil, err
+	}
+
+	old := serv.wallets.get(wltID)
+	if old == nil {
+		return nil, ErrWalletNotExist
+	}
+
+	if !serv.hasFile(old) {
+		return nil, NewError(fmt.Errorf("wallet %q has no file to reload", wltID))
+	}
+
+	// The modification time is read first, so that a change made while loading is detected later
+	wf := filepath.Join(serv.config.WalletDir, wltID)
+	fi, err := os.Stat(wf)
+	if err != nil {
+		return nil, LoadWalletError{WalletID: wltID, Err: err}
+	}
+
+	w, err := serv.Load(wf)
+	if err != nil {
+		return nil, LoadWalletError{WalletID: wltID, Err: err}
+	}
+	if w == nil {
+		return nil, LoadWalletError{WalletID: wltID, Err: errors.New("unknown wallet type")}
+	}
+
+	if w.Coin() != CoinTypeSkycoin {
+		return nil, LoadWalletError{WalletID: wltID, Err: fmt.Errorf("only skycoin wallets can be loaded, %s is a %s wallet", wltID, w.Coin())}
+	}
+
+	if _, hasEmpty := (Wallets{wltID: w}).containsEmpty(); hasEmpty {
+		return nil, EmptyWalletError{WalletID: wltID}
+	}
+
+	same, err := sameWalletIdentity(old, w)
+	if err != nil {
+		return nil, err
+	}
+	if !same {
+		return nil, ErrWalletIdentityChanged
+	}
+
+	if fp := old.Fingerprint(); fp != "" {
+		delete(serv.fingerprints, fp)
+	}
+	if fp := w.Fingerprint(); fp != "" {
+		serv.fingerprints[fp] = wltID
+	}
+
+	serv.wallets.set(w)
+	serv.modTimes[wltID] = fi.ModTime()
+	serv.queueEvent(wltID, WalletEventReloaded)
+
+	return w.Clone(), nil
+}
+
+// GetWalletFileInfo returns the modification time and size of the wallet file on disk,
+// with the modification time of the file when the service last loaded or saved it.
+// Returns an error if the wallet is temporary or in memory, or its file can't be read.
+func (serv *Service) GetWalletFileInfo(wltID string) (WalletFileInfo, error) {
 	serv.RLock()
 	defer serv.RUnlock()
-	if !serv.config.EnableWalletAPI {
-		return "", "", ErrWalletAPIDisabled
+	if err := serv.checkEnabled(); err != nil {
+		return WalletFileInfo{}, err
+	}
+
+	w := serv.wallets.get(wltID)
+	if w == nil {
+		return WalletFileInfo{}, ErrWalletNotExist
+	}
+
+	if !serv.hasFile(w) {
+		return WalletFileInfo{}, NewError(fmt.Errorf("wallet %q has no file", wltID))
+	}
+
+	fi, err := os.Stat(filepath.Join(serv.config.WalletDir, wltID))
+	if err != nil {
+		return WalletFileInfo{}, err
+	}
+
+	return WalletFileInfo{
+		ModTime:       fi.ModTime(),
+		Size:          fi.Size(),
+		LoadedModTime: serv.modTimes[wltID],
+	}, nil
+}
+
+// HasExternalChanges returns true if the wallet file was modified or removed since the service last
+// loaded or saved it, e.g. by another process. ReloadWallet picks up the changes.
+// Temporary wallets and the wallets of an in-memory service have no file and are never changed externally.
+func (serv *Service) HasExternalChanges(wltID string) (bool, error) {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return false, err
+	}
+
+	w := serv.wallets.get(wltID)
+	if w == nil {
+		return false, ErrWalletNotExist
+	}
+
+	if !serv.hasFile(w) {
+		return false, nil
+	}
+
+	fi, err := os.Stat(filepath.Join(serv.config.WalletDir, wltID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	return !fi.ModTime().Equal(serv.modTimes[wltID]), nil
+}
+
+// sameWalletIdentity returns true if the wallets have the same type and first address
+func sameWalletIdentity(a, b Wallet) (bool, error) {
+	if a.Type() != b.Type() {
+		return false, nil
+	}
+
+	al, err := a.EntriesLen()
+	if err != nil {
+		return false, err
+	}
+	bl, err := b.EntriesLen()
+	if err != nil {
+		return false, err
+	}
+
+	if al == 0 || bl == 0 {
+		return al == bl, nil
+	}
+
+	ae, err := a.GetEntryAt(0)
+	if err != nil {
+		return false, err
+	}
+	be, err := b.GetEntryAt(0)
+	if err != nil {
+		return false, err
+	}
+
+	return ae.Address.String() == be.Address.String(), nil
+}
+
+// UnloadWallet removes wallet of given wallet id from the service.
+// Only the wallet in memory is removed, its file stays in the wallet directory
+// and is loaded again when the service restarts. Use DeleteWallet to also remove the file.
+func (serv *Service) UnloadWallet(wltID string) error {
+	serv.Lock()
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return err
+	}
+	if serv.config.ReadOnly {
+		return ErrWalletReadOnly
+	}
+
+	wlt := serv.wallets.get(wltID)
+	if wlt != nil {
+		if fp := wlt.Fingerprint(); fp != "" {
+			delete(serv.fingerprints, fp)
+		}
+	}
+
+	serv.wallets.remove(wltID)
+	delete(serv.modTimes, wltID)
+	if wlt != nil {
+		serv.queueEvent(wltID, WalletEventUnloaded)
+	}
+	return nil
+}
+
+// DeleteWallet removes the wallet of given wallet id from the service and deletes its file,
+// along with its .bak and timestamped backup files, from the wallet directory. If Config.TrashDeletedWallets is set,
+// the files are moved to the WalletTrashDir subdirectory instead, from where they can be restored.
+// The backup files are handled first, so if deleting fails the wallet file is left in place and the wallet stays loaded.
+func (serv *Service) DeleteWallet(wltID string) error {
+	serv.Lock()
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return err
+	}
+	if serv.config.ReadOnly {
+		return ErrWalletReadOnly
+	}
+
+	w := serv.wallets.get(wltID)
+	if w == nil {
+		return ErrWalletNotExist
+	}
+
+	if err := serv.checkWalletSource(w); err != nil {
+		return err
+	}
+
+	if serv.hasFile(w) {
+		if err := serv.deleteWalletFiles(wltID); err != nil {
+			return err
+		}
+	}
+
+	if fp := w.Fingerprint(); fp != "" {
+		delete(serv.fingerprints, fp)
+	}
+
+	serv.wallets.remove(wltID)
+	delete(serv.modTimes, wltID)
+	serv.queueEvent(wltID, WalletEventDeleted)
+	return nil
+}
+
+// deleteWalletFiles deletes the wallet file of given wallet id with its .bak and timestamped backup files.
+// The backup files are handled first, so if deleting fails the wallet file is left in place.
+func (serv *Service) deleteWalletFiles(wltID string) error {
+	backups, err := listBackupFiles(serv.config.WalletDir)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(serv.config.WalletDir, wltID)
+	for _, f := range append(backups[wltID], path+".bak", path) {
+		if err := serv.deleteWalletFile(f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MergeWallets merges the wallet of srcID into the wallet of destID and deletes the source wallet.
+// Both wallets must be deterministic or bip44 wallets generated from the same seed, as checked by their first address,
+// otherwise ErrMergeSeedMismatch is returned. The destination wallet is extended to the longer address chain of the two,
+// and keeps its address labels, the source labels are copied to the addresses without a label.
+// The source wallet may also be a wallet file in the wallet directory that is not loaded by the service,
+// such as a duplicate wallet skipped by NewService because of Config.SkipInvalidWallets.
+// The password is required if the destination wallet is encrypted, and must be nil otherwise.
+func (serv *Service) MergeWallets(destID, srcID string, password []byte) (Wallet, error) {
+	serv.Lock()
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return nil, err
+	}
+	if serv.config.ReadOnly {
+		return nil, ErrWalletReadOnly
+	}
+
+	if destID == srcID {
+		return nil, ErrMergeSameWallet
+	}
+
+	dest, err := serv.getWallet(destID)
+	if err != nil {
+		return nil, err
+	}
+
+	src := serv.wallets.get(srcID)
+	srcLoaded := src != nil
+	if !srcLoaded {
+		if filepath.Base(srcID) != srcID || !strings.HasSuffix(srcID, "."+WalletExt) {
+			return nil, ErrInvalidWalletFilename
+		}
+
+		if serv.config.InMemory {
+			return nil, ErrWalletNotExist
+		}
+
+		path := filepath.Join(serv.config.WalletDir, srcID)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil, ErrWalletNotExist
+		}
+
+		src, err = Load(path)
+		if err != nil {
+			return nil, err
+		}
+		if src == nil {
+			return nil, ErrInvalidWalletType
+		}
+	}
+
+	switch dest.Type() {
+	case WalletTypeDeterministic, WalletTypeBip44:
+	default:
+		return nil, ErrWalletNoSeedChain
+	}
+
+	if src.Type() != dest.Type() || src.Fingerprint() != dest.Fingerprint() {
+		return nil, ErrMergeSeedMismatch
+	}
+
+	merge := func(w Wallet) error {
+		return mergeWalletEntries(w, src)
+	}
+
+	if dest.IsEncrypted() {
+		err = serv.guardUpdate(dest, password, merge)
+	} else if len(password) != 0 {
+		err = ErrWalletNotEncrypted
+	} else {
+		err = merge(dest)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := serv.save(dest); err != nil {
+		return nil, err
+	}
+	serv.wallets.set(dest)
+	serv.queueEvent(destID, WalletEventUpdated)
+
+	if err := serv.deleteWalletFiles(srcID); err != nil {
+		return nil, err
+	}
+
+	if srcLoaded {
+		serv.wallets.remove(srcID)
+		delete(serv.modTimes, srcID)
+		serv.queueEvent(srcID, WalletEventDeleted)
+	}
+	serv.fingerprints[dest.Fingerprint()] = destID
+
+	return dest.Clone(), nil
+}
+
+// mergeWalletEntries generates the addresses that dest is missing compared to src, which must be generated
+// from the same seed, and copies the labels of src to the addresses of dest without a label.
+// For bip44 wallets, both chains of each account of src are merged.
+func mergeWalletEntries(dest, src Wallet) error {
+	if src.Type() != WalletTypeBip44 {
+		return mergeChainEntries(dest, src)
+	}
+
+	accounts := make(map[uint32]struct{})
+	for _, a := range dest.Accounts() {
+		accounts[a.Index] = struct{}{}
+	}
+
+	for _, a := range src.Accounts() {
+		if _, ok := accounts[a.Index]; !ok {
+			return NewError(fmt.Errorf("account %d of the source wallet does not exist in the destination wallet", a.Index))
+		}
+
+		for _, chain := range []Option{OptionExternal(), OptionChange()} {
+			if err := mergeChainEntries(dest, src, OptionAccount(a.Index), chain); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// mergeChainEntries merges the entries of the chain selected by the options
+func mergeChainEntries(dest, src Wallet, options ...Option) error {
+	srcEntries, err := src.GetEntries(options...)
+	if err != nil {
+		return err
+	}
+
+	n, err := dest.EntriesLen(options...)
+	if err != nil {
+		return err
+	}
+
+	if len(srcEntries) > n {
+		opts := append(options, OptionGenerateN(uint64(len(srcEntries)-n)))
+		if _, err := dest.GenerateAddresses(opts...); err != nil {
+			return err
+		}
+	}
+
+	for _, e := range srcEntries {
+		if e.Label == "" {
+			continue
+		}
+
+		de, err := dest.GetEntry(e.Address, options...)
+		if err != nil {
+			return err
+		}
+		if de.Label == "" {
+			if err := dest.SetEntryLabel(e.Address, e.Label, options...); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// deleteWalletFile removes the file, or moves it to the trash directory if Config.TrashDeletedWallets is set.
+// A file that does not exist is ignored.
+func (serv *Service) deleteWalletFile(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	if !serv.config.TrashDeletedWallets {
+		return os.Remove(path)
+	}
+
+	trashDir := filepath.Join(serv.config.WalletDir, WalletTrashDir)
+	if err := os.MkdirAll(trashDir, serv.config.DirPerm); err != nil {
+		return err
+	}
+
+	// Keep the files of a wallet deleted before under the same name
+	dst := filepath.Join(trashDir, filepath.Base(path))
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		dst = fmt.Sprintf("%s.%d", dst, time.Now().UnixNano())
+	}
+
+	return os.Rename(path, dst)
+}
+
+func (serv *Service) setWallets(wlts Wallets) {
+	serv.wallets = wlts
+
+	for wltID, wlt := range wlts {
+		if fp := wlt.Fingerprint(); fp != "" {
+			serv.fingerprints[fp] = wltID
+		}
+		serv.indexWallet(wltID)
+	}
+}
+
+// guardView calls GuardView, recording the access to the wallet secrets
+func (serv *Service) guardView(w Wallet, password []byte, f func(Wallet) error) error {
+	return serv.guard(GuardView, w, password, f)
+}
+
+// guardUpdate calls GuardUpdate, recording the access to the wallet secrets
+func (serv *Service) guardUpdate(w Wallet, password []byte, f func(Wallet) error) error {
+	return serv.guard(GuardUpdate, w, password, f)
+}
+
+// guard calls guardFn, which is GuardView or GuardUpdate, recording the access to the wallet secrets
+// and the failed password attempts
+func (serv *Service) guard(guardFn func(Wallet, []byte, func(Wallet) error) error, w Wallet, password []byte, f func(Wallet) error) error {
+	if w.IsEncrypted() {
+		if err := serv.checkUnlockLockout(w.Filename()); err != nil {
+			return err
+		}
+	}
+
+	atomic.StoreInt64(&serv.lastSecretsAccess, time.Now().UnixNano())
+
+	var unlocked bool
+	err := guardFn(w, password, func(w Wallet) error {
+		unlocked = true
+		return f(w)
+	})
+
+	switch {
+	case unlocked:
+		serv.recordUnlock(w.Filename(), nil)
+	case err == ErrInvalidPassword:
+		serv.recordUnlock(w.Filename(), err)
+	}
+
+	return err
+}
+
+// checkUnlockLockout returns ErrWalletLocked if the wallet can't be decrypted
+// because of too many wrong passwords, see Config.MaxUnlockAttempts
+func (serv *Service) checkUnlockLockout(wltID string) error {
+	if serv.config.MaxUnlockAttempts == 0 {
+		return nil
+	}
+
+	serv.failedUnlocksMu.Lock()
+	defer serv.failedUnlocksMu.Unlock()
+
+	if f, ok := serv.failedUnlocks[wltID]; ok && time.Now().Before(f.lockedUntil) {
+		return ErrWalletLocked
+	}
+	return nil
+}
+
+// recordUnlock records the result of a wallet decryption. The failed attempts are reset by a success,
+// and the wallet is locked for Config.UnlockLockout once Config.MaxUnlockAttempts wrong passwords are counted.
+// Errors other than ErrInvalidPassword are not counted
+func (serv *Service) recordUnlock(wltID string, err error) {
+	if serv.config.MaxUnlockAttempts == 0 {
+		return
+	}
+
+	serv.failedUnlocksMu.Lock()
+	defer serv.failedUnlocksMu.Unlock()
+
+	switch err {
+	case nil:
+		delete(serv.failedUnlocks, wltID)
+	case ErrInvalidPassword:
+		f, ok := serv.failedUnlocks[wltID]
+		if !ok {
+			f = &failedUnlocks{}
+			serv.failedUnlocks[wltID] = f
+		}
+
+		f.count++
+		if f.count >= serv.config.MaxUnlockAttempts {
+			f.count = 0
+			f.lockedUntil = time.Now().Add(serv.config.UnlockLockout)
+			logger.WithFields(logrus.Fields{
+				"wltID":    wltID,
+				"attempts": serv.config.MaxUnlockAttempts,
+				"lockout":  serv.config.UnlockLockout,
+			}).Warning("Too many wrong wallet passwords, the wallet is locked")
+		}
+	}
+}
+
+// LastSecretsAccess returns the time at which an encrypted wallet was last decrypted by the service,
+// or the zero time if none was. Together with LockAll, it can be used to wipe the wallet secrets
+// from memory after a period of inactivity.
+func (serv *Service) LastSecretsAccess() time.Time {
+	t := atomic.LoadInt64(&serv.lastSecretsAccess)
+	if t == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, t)
+}
+
+// LockAll makes sure that no decrypted secrets of the encrypted wallets remain in memory.
+// Encrypted wallets are only decrypted into temporary copies by GuardView and GuardUpdate,
+// which are wiped before the service lock is released, so LockAll waits for the wallet operations
+// in progress to complete. As a defense in depth, it then wipes any plaintext secrets found
+// in the encrypted wallets loaded by the service, which would otherwise be a bug.
+// Wallets that are not encrypted can't be locked without a password and are left unchanged.
+func (serv *Service) LockAll() error {
+	serv.Lock()
+	defer serv.Unlock()
+	if err := serv.checkEnabled(); err != nil {
+		return err
+	}
+
+	for wltID, w := range serv.wallets {
+		// A lazily loaded wallet has no secrets in memory until it is loaded
+		if lw, ok := w.(*lazyWallet); ok {
+			if w = lw.loaded(); w == nil {
+				continue
+			}
+		}
+
+		if !w.IsEncrypted() || !hasPlaintextSecrets(w) {
+			continue
+		}
+
+		logger.Critical().WithField("wallet", wltID).Error("LockAll: encrypted wallet has plaintext secrets in memory, wiping them")
+		w.Erase()
+	}
+
+	return nil
+}
+
+// hasPlaintextSecrets returns true if the wallet holds a seed or a secret key in plaintext
+func hasPlaintextSecrets(w Wallet) bool {
+	if w.Seed() != "" || w.LastSeed() != "" || w.SeedPassphrase() != "" {
+		return true
+	}
+
+	entries, err := w.GetEntries()
+	if err != nil {
+		return false
+	}
+	for _, e := range entries {
+		if e.Secret != (cipher.SecKey{}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetWalletSeed returns seed and seed passphrase of encrypted wallet of given wallet id
+// Returns ErrWalletNotEncrypted if it's not encrypted
+func (serv *Service) GetWalletSeed(wltID string, password []byte) (string, string, error) {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return "", "", err
+	}
+
+	if !serv.config.EnableSeedAPI {
+		return "", "", ErrSeedAPIDisabled
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return "", "", err
+	}
+
+	if w.Type() == WalletTypeWatchOnly {
+		return "", "", ErrWalletNoSeed
+	}
+
+	if !w.IsEncrypted() {
+		return "", "", ErrWalletNotEncrypted
+	}
+
+	var seed, seedPassphrase string
+	if err := serv.guardView(w, password, func(wlt Wallet) error {
+		seed = wlt.Seed()
+		seedPassphrase = wlt.SeedPassphrase()
+		return nil
+	}); err != nil {
+		return "", "", err
+	}
+
+	return seed, seedPassphrase, nil
+}
+
+// GetWalletSeedInfo returns how far the seed chain of a deterministic wallet has been consumed.
+// lastIndex is the number of keys derived from the seed chain, which is where address generation
+// resumes, and entryCount is the number of entries in the wallet. The two differ if entries
+// were pruned or imported out of order.
+func (serv *Service) GetWalletSeedInfo(wltID string) (lastIndex uint64, entryCount int, err error) {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return 0, 0, err
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if w.Type() != WalletTypeDeterministic {
+		return 0, 0, ErrWalletNoSeedChain
+	}
+
+	entryCount, err = w.EntriesLen()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return w.LastSeedIndex(), entryCount, nil
+}
+
+// UpdateSecrets opens a wallet for modification of secret data and saves it safely
+func (serv *Service) UpdateSecrets(wltID string, password []byte, f func(Wallet) error) error {
+	serv.Lock()
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return err
+	}
+	if serv.config.ReadOnly {
+		return ErrWalletReadOnly
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return err
+	}
+
+	if w.Type() == WalletTypeWatchOnly {
+		return ErrWatchOnlyWallet
+	}
+
+	if w.IsEncrypted() {
+		if err := serv.guardUpdate(w, password, f); err != nil {
+			return err
+		}
+	} else if len(password) != 0 {
+		return ErrWalletNotEncrypted
+	} else {
+		if err := f(w); err != nil {
+			return err
+		}
+	}
+
+	// Save the wallet to disk
+	if err := serv.save(w); err != nil {
+		return err
+	}
+
+	serv.wallets.set(w)
+	serv.queueEvent(wltID, WalletEventUpdated)
+
+	return nil
+}
+
+// Update opens a wallet for modification of non-secret data and saves it safely
+func (serv *Service) Update(wltID string, f func(Wallet) error) error {
+	serv.Lock()
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return err
+	}
+	if serv.config.ReadOnly {
+		return ErrWalletReadOnly
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return err
+	}
+
+	if err := f(w); err != nil {
+		return err
+	}
+
+	// Save the wallet to disk
+	if err := serv.save(w); err != nil {
+		return err
+	}
+
+	serv.wallets.set(w)
+	serv.queueEvent(wltID, WalletEventUpdated)
+
+	return nil
+}
+
+// ViewSecrets opens a wallet for reading secret data
+func (serv *Service) ViewSecrets(wltID string, password []byte, f func(Wallet) error) error {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return err
+	}
+	if serv.config.ReadOnly {
+		return ErrWalletReadOnly
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return err
+	}
+
+	if w.Type() == WalletTypeWatchOnly {
+		return ErrWatchOnlyWallet
+	}
+
+	if w.IsEncrypted() {
+		return serv.guardView(w, password, f)
+	} else if len(password) != 0 {
+		return ErrWalletNotEncrypted
+	} else {
+		return f(w)
+	}
+}
+
+// VerifyPassword checks that the password decrypts the wallet, without changing the wallet in memory or on disk.
+// Returns ErrInvalidPassword if the password is wrong, and ErrWalletNotEncrypted if the wallet is not encrypted.
+func (serv *Service) VerifyPassword(wltID string, password []byte) error {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return err
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return err
+	}
+
+	return serv.guardView(w, password, func(Wallet) error {
+		return nil
+	})
+}
+
+// AddInputsToTransaction appends inputs owned by the wallet to an existing unsigned transaction
+// and signs them. Set the password as nil if the wallet is not encrypted, otherwise the password must be provided.
+// Refer to the AddInputsToTransaction function for details.
+func (serv *Service) AddInputsToTransaction(wltID string, password []byte, txn *coin.Transaction, additionalCoins uint64, auxs coin.AddressUxOuts, headTime uint64) (*coin.Transaction, []transaction.UxBalance, error) {
+	var newTxn *coin.Transaction
+	var inputs []transaction.UxBalance
+	if err := serv.ViewSecrets(wltID, password, func(w Wallet) error {
+		var err error
+		newTxn, inputs, err = AddInputsToTransaction(w, txn, additionalCoins, auxs, headTime)
+		return err
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	serv.InvalidateBalanceCache(wltID)
+
+	return newTxn, inputs, nil
+}
+
+// CreateTransactionsBatched creates and signs the transactions needed to make a spend
+// that would otherwise need more than maxInputsPerTxn inputs.
+// Set the password as nil if the wallet is not encrypted, otherwise the password must be provided.
+// Refer to the CreateTransactionsBatched function for details.
+func (serv *Service) CreateTransactionsBatched(wltID string, password []byte, p transaction.Params, auxs coin.AddressUxOuts, headTime uint64, maxInputsPerTxn int) ([]*coin.Transaction, [][]transaction.UxBalance, error) {
+	var txns []*coin.Transaction
+	var inputs [][]transaction.UxBalance
+	if err := serv.ViewSecrets(wltID, password, func(w Wallet) error {
+		var err error
+		txns, inputs, err = CreateTransactionsBatched(w, p, auxs, headTime, maxInputsPerTxn)
+		return err
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	serv.InvalidateBalanceCache(wltID)
+
+	return txns, inputs, nil
+}
+
+// CreateConsolidationTransaction creates a signed transaction merging up to maxInputs of the wallet's
+// smallest outputs into a single output owned by the wallet.
+// Set the password as nil if the wallet is not encrypted, otherwise the password must be provided.
+// Refer to the CreateConsolidationTransaction function for details.
+func (serv *Service) CreateConsolidationTransaction(wltID string, password []byte, auxs coin.AddressUxOuts, headTime uint64, maxInputs int) (*coin.Transaction, []transaction.UxBalance, error) {
+	var txn *coin.Transaction
+	var inputs []transaction.UxBalance
+	if err := serv.ViewSecrets(wltID, password, func(w Wallet) error {
+		var err error
+		txn, inputs, err = CreateConsolidationTransaction(w, auxs, headTime, maxInputs)
+		return err
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	serv.InvalidateBalanceCache(wltID)
+
+	return txn, inputs, nil
+}
+
+// CreateSweepTransaction creates a signed transaction sending the entire balance of the wallet's outputs
+// in auxs to dest. Set the password as nil if the wallet is not encrypted, otherwise the password must be provided.
+// Refer to the CreateSweepTransaction function for details.
+func (serv *Service) CreateSweepTransaction(wltID string, password []byte, dest cipher.Address, auxs coin.AddressUxOuts, headTime uint64) (*coin.Transaction, []transaction.UxBalance, error) {
+	var txn *coin.Transaction
+	var inputs []transaction.UxBalance
+	if err := serv.ViewSecrets(wltID, password, func(w Wallet) error {
+		var err error
+		txn, inputs, err = CreateSweepTransaction(w, dest, auxs, headTime)
+		return err
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	serv.InvalidateBalanceCache(wltID)
+
+	return txn, inputs, nil
+}
+
+// SignMessage signs msg with the secret key of addr, to prove control of the address to a third party.
+// Set the password as nil if the wallet is not encrypted, otherwise the password must be provided.
+// Returns ErrUnknownAddress if addr is not in the wallet. Refer to the SignMessage function for details.
+func (serv *Service) SignMessage(wltID string, password []byte, addr cipher.Address, msg []byte) (cipher.Sig, error) {
+	var sig cipher.Sig
+	if err := serv.ViewSecrets(wltID, password, func(w Wallet) error {
+		var err error
+		sig, err = SignMessage(w, addr, msg)
+		return err
+	}); err != nil {
+		return cipher.Sig{}, err
+	}
+
+	return sig, nil
+}
+
+// TransactionOptions are the options of the transactions created by the wallet service, which are checked
+// or recorded against the spending wallet. They are shared with the transaction creation of the visor,
+// which creates the wallet transactions of the node through Service.CreateVerifiedTransaction
+type TransactionOptions struct {
+	// ChangeAddress if set, the change is sent to this address, which must belong to the wallet.
+	// Unlike transaction.Params.ChangeAddress, which accepts any address, it is checked against the wallet entries
+	ChangeAddress *cipher.Address
+	// GenerateChange if true, the change is sent to a newly generated address of the wallet.
+	// The password is required to generate the address if the wallet is encrypted, unless it is a bip44 wallet.
+	// The address is only saved if the transaction is created
+	GenerateChange bool
+	// ChangeWalletID if set, the change is sent to a newly generated address of this wallet instead of
+	// the spending wallet. It must have the coin type of the spending wallet, otherwise ErrChangeWalletCoinMismatch
+	// is returned. For bip44 wallets the address is generated on the change chain.
+	// The address is only saved if the transaction is created
+	ChangeWalletID string
+	// ChangeWalletPassword is the password of the change wallet, required to generate the address
+	// if it is encrypted, unless it is a bip44 wallet
+	ChangeWalletPassword []byte
+	// SpendTime if set, is the head time at which the transaction is meant to be injected, which must not be
+	// before the current head time. The coin hours of the inputs are calculated at SpendTime instead of the
+	// current head time, and only the outputs mature at SpendTime are chosen: outputs that are at least
+	// MinInputAge seconds old and have coin hours at SpendTime. The transaction may not be valid before
+	// the head reaches SpendTime. ErrNoMatureUxOuts is returned if no output is mature at SpendTime
+	SpendTime uint64
+	// MinInputAge is the number of seconds an output must have existed at SpendTime to be chosen.
+	// It is ignored if SpendTime is not set
+	MinInputAge uint64
+	// Memo if set, is recorded in the wallet for the created transaction, e.g. a payment reference.
+	// Transactions have no field for arbitrary data, so the memo is not sent with the transaction.
+	// It is saved in cleartext in the wallet file, see Service.GetTransactionMemo
+	Memo []byte
+	// DustThreshold if set, is the minimum number of droplets sent by each output of the transaction.
+	// Smaller outputs are rejected with ErrDustOutput
+	DustThreshold uint64
+	// RequireOutputHours if true, rejects the transaction with ErrOutputWithoutHours if one of its outputs,
+	// including the change output, receives no coin hours. An output without coin hours can't pay the fee
+	// of the transaction spending it, so it is stuck unless spent along with another output
+	RequireOutputHours bool
+}
+
+// CreateTransactionParams are the parameters of one transaction created by the wallet service
+type CreateTransactionParams struct {
+	// WalletID is the wallet to spend from
+	WalletID string
+	// Password is required if the wallet is encrypted, and must be nil otherwise
+	Password []byte
+	// Params are the transaction creation parameters
+	Params transaction.Params
+	// UxOuts if set, confines the outputs chosen for spending to these outputs, which must all be available.
+	// ErrUnknownUxOut is returned if one of them is not an output of the wallet in the provided outputs
+	UxOuts []cipher.SHA256
+
+	TransactionOptions
+}
+
+// MaxMemoLength is the maximum length of a transaction memo in bytes
+const MaxMemoLength = 256
+
+// Validate validates the parameters specific to the wallet service.
+// Params is validated when the transaction is created
+func (p CreateTransactionParams) Validate() error {
+	return p.TransactionOptions.Validate(p.Params)
+}
+
+// Validate validates the options of a transaction created with the transaction parameters p
+func (o TransactionOptions) Validate(p transaction.Params) error {
+	if len(o.Memo) > MaxMemoLength {
+		return ErrMemoTooLong
+	}
+
+	if o.ChangeWalletID == "" && len(o.ChangeWalletPassword) != 0 {
+		return ErrChangeWalletPasswordWithoutID
+	}
+
+	var changeOptions int
+	for _, set := range []bool{p.ChangeAddress != nil, o.ChangeAddress != nil, o.GenerateChange, o.ChangeWalletID != ""} {
+		if set {
+			changeOptions++
+		}
+	}
+	if changeOptions > 1 {
+		return ErrChangeAddressConflict
+	}
+
+	for _, to := range p.To {
+		if to.Coins < o.DustThreshold {
+			return ErrDustOutput
+		}
+	}
+
+	return nil
+}
+
+// checkOutputHours returns ErrOutputWithoutHours if o.RequireOutputHours is set and an output of txn has no coin hours
+func checkOutputHours(o TransactionOptions, txn *coin.Transaction) error {
+	if !o.RequireOutputHours {
+		return nil
+	}
+
+	for _, out := range txn.Out {
+		if out.Hours == 0 {
+			return ErrOutputWithoutHours
+		}
+	}
+
+	return nil
+}
+
+// matureUxOuts returns the outputs of auxs that are at least minAge seconds old at spendTime
+// and have coin hours at spendTime, so that they can pay their share of the fee.
+// Returns ErrNoMatureUxOuts if there are none.
+func matureUxOuts(auxs coin.AddressUxOuts, spendTime, minAge uint64) (coin.AddressUxOuts, error) {
+	mature := make(coin.AddressUxOuts)
+	for addr, uxa := range auxs {
+		for _, ux := range uxa {
+			if ux.Head.Time > spendTime || spendTime-ux.Head.Time < minAge {
+				continue
+			}
+
+			hours, err := ux.CoinHours(spendTime)
+			if err != nil {
+				return nil, err
+			}
+			if hours == 0 {
+				continue
+			}
+
+			mature[addr] = append(mature[addr], ux)
+		}
+	}
+
+	if len(mature) == 0 {
+		return nil, ErrNoMatureUxOuts
+	}
+
+	return mature, nil
+}
+
+// applySpendTime restricts auxs to the outputs that can be spent at o.SpendTime, and returns the
+// head time to create the transaction at. auxs and headTime are returned unchanged if SpendTime is not set.
+func applySpendTime(o TransactionOptions, auxs coin.AddressUxOuts, headTime uint64) (coin.AddressUxOuts, uint64, error) {
+	if o.SpendTime == 0 {
+		return auxs, headTime, nil
+	}
+
+	if o.SpendTime < headTime {
+		return nil, 0, ErrSpendTimeBeforeHead
+	}
+
+	auxs, err := matureUxOuts(auxs, o.SpendTime, o.MinInputAge)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return auxs, o.SpendTime, nil
+}
+
+// filterUxOuts returns the outputs of auxs whose hashes are in uxOuts.
+// Returns ErrUnknownUxOut if one of uxOuts is not in auxs.
+func filterUxOuts(auxs coin.AddressUxOuts, uxOuts []cipher.SHA256) (coin.AddressUxOuts, error) {
+	want := make(map[cipher.SHA256]struct{}, len(uxOuts))
+	for _, h := range uxOuts {
+		want[h] = struct{}{}
+	}
+
+	filtered := make(coin.AddressUxOuts)
+	found := 0
+	for addr, uxa := range auxs {
+		for _, ux := range uxa {
+			if _, ok := want[ux.Hash()]; ok {
+				filtered[addr] = append(filtered[addr], ux)
+				found++
+			}
+		}
+	}
+
+	if found != len(want) {
+		return nil, ErrUnknownUxOut
+	}
+
+	return filtered, nil
+}
+
+// setChangeAddress sets params.Params.ChangeAddress from params.ChangeAddress, params.GenerateChange
+// or params.ChangeWalletID. w must be a copy of the loaded wallet. A change address is generated without
+// saving it, in w or in a copy of the change wallet, which is returned so that the caller saves it once the
+// transaction is created. The returned wallet is nil if no address is generated.
+func (serv *Service) setChangeAddress(w Wallet, params *CreateTransactionParams) (Wallet, error) {
+	var cw Wallet
+	var password []byte
+	switch {
+	case params.ChangeAddress != nil:
+		has, err := w.HasEntry(*params.ChangeAddress)
+		if err != nil {
+			return nil, err
+		}
+		if !has {
+			return nil, ErrChangeAddressNotInWallet
+		}
+
+		addr := *params.ChangeAddress
+		params.Params.ChangeAddress = &addr
+		return nil, nil
+
+	case params.GenerateChange:
+		cw = w
+		password = params.Password
+
+	case params.ChangeWalletID == w.Filename():
+		cw = w
+		password = params.ChangeWalletPassword
+
+	case params.ChangeWalletID != "":
+		var err error
+		cw, err = serv.getWallet(params.ChangeWalletID)
+		if err != nil {
+			return nil, err
+		}
+
+		if cw.Coin() != w.Coin() {
+			return nil, ErrChangeWalletCoinMismatch
+		}
+		password = params.ChangeWalletPassword
+
+	default:
+		return nil, nil
+	}
+
+	// For bip44 wallets the address is generated on the change chain,
+	// the option is ignored by other wallet types
+	addrs, err := serv.generateAddresses(cw, password, OptionGenerateN(1), OptionChange())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(addrs) != 1 {
+		err := fmt.Errorf("expected 1 new change address, got %d", len(addrs))
+		logger.Critical().WithError(err).Error("setChangeAddress failed")
+		return nil, err
+	}
+
+	addr := addrs[0].(cipher.Address)
+	params.Params.ChangeAddress = &addr
+	return cw, nil
+}
+
+// BatchError is returned by Service.BatchCreateTransactions when some of the transactions in the batch could not be created.
+// Errors maps the index of each failed entry in the params list to its error.
+type BatchError struct {
+	Errors map[int]error
+}
+
+func (e BatchError) Error() string {
+	idxs := make([]int, 0, len(e.Errors))
+	for i := range e.Errors {
+		idxs = append(idxs, i)
+	}
+	sort.Ints(idxs)
+
+	msgs := make([]string, len(idxs))
+	for j, i := range idxs {
+		msgs[j] = fmt.Sprintf("transaction %d: %v", i, e.Errors[i])
+	}
+
+	return fmt.Sprintf("%d of the batched transactions failed: %s", len(idxs), strings.Join(msgs, "; "))
+}
+
+// BatchCreateTransactions creates and signs a transaction for each entry of paramsList, all under a single lock.
+// Outputs to spend are chosen from auxs, restricted to the addresses of each entry's wallet.
+// An output spent by one transaction of the batch is never chosen by a later one, so the
+// transactions can be injected together. Change outputs of the batch are not available to later entries.
+// The entries are processed in order and each one succeeds or fails independently.
+// The returned slices always have the same length as paramsList; for a failed entry, the transaction
+// and its inputs are nil and the outputs it would have spent remain available to later entries.
+// If any entry fails, the successful transactions are still returned along with a BatchError.
+func (serv *Service) BatchCreateTransactions(paramsList []CreateTransactionParams, auxs coin.AddressUxOuts, headTime uint64) ([]*coin.Transaction, [][]transaction.UxBalance, error) {
+	serv.Lock()
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return nil, nil, err
+	}
+	if serv.config.ReadOnly {
+		return nil, nil, ErrWalletReadOnly
+	}
+
+	txns := make([]*coin.Transaction, len(paramsList))
+	inputs := make([][]transaction.UxBalance, len(paramsList))
+	spent := make(map[cipher.SHA256]struct{})
+	errs := make(map[int]error)
+
+	for i, bp := range paramsList {
+		txn, uxb, err := serv.batchCreateTransaction(bp, auxs, spent, headTime)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		for _, ux := range uxb {
+			spent[ux.Hash] = struct{}{}
+		}
+
+		txns[i] = txn
+		inputs[i] = uxb
+	}
+
+	if len(errs) != 0 {
+		return txns, inputs, BatchError{Errors: errs}
+	}
+
+	return txns, inputs, nil
+}
+
+// batchCreateTransaction creates one signed transaction of a batch, spending the outputs of auxs
+// that belong to the wallet, excluding the outputs in spent. The caller must hold the service lock.
+func (serv *Service) batchCreateTransaction(bp CreateTransactionParams, auxs coin.AddressUxOuts, spent map[cipher.SHA256]struct{}, headTime uint64) (*coin.Transaction, []transaction.UxBalance, error) {
+	w, err := serv.getWallet(bp.WalletID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wltAuxs := make(coin.AddressUxOuts)
+	for addr, uxa := range auxs {
+		has, err := w.HasEntry(addr)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !has {
+			continue
+		}
+
+		for _, ux := range uxa {
+			if _, ok := spent[ux.Hash()]; !ok {
+				wltAuxs[addr] = append(wltAuxs[addr], ux)
+			}
+		}
+	}
+
+	return serv.createTransaction(bp, wltAuxs, headTime, transaction.TxnSigned, nil)
+}
+
+// createTransaction creates a transaction spending from the wallet params.WalletID, signed if signed is TxnSigned,
+// applying params.TransactionOptions. verify, if not nil, is called with the created transaction, and its error
+// is returned before the generated change address or the memo are saved. The caller must hold the service write lock.
+func (serv *Service) createTransaction(params CreateTransactionParams, auxs coin.AddressUxOuts, headTime uint64,
+	signed transaction.TxnSignedFlag, verify func(*coin.Transaction) error) (*coin.Transaction, []transaction.UxBalance, error) {
+	if err := params.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	w, err := serv.getWallet(params.WalletID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cw, err := serv.setChangeAddress(w, &params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(params.UxOuts) != 0 {
+		auxs, err = filterUxOuts(auxs, params.UxOuts)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	auxs, headTime, err = applySpendTime(params.TransactionOptions, auxs, headTime)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var txn *coin.Transaction
+	var uxb []transaction.UxBalance
+	switch signed {
+	case transaction.TxnSigned:
+		f := func(w Wallet) error {
+			var err error
+			txn, uxb, err = CreateTransactionSigned(w, params.Params, auxs, headTime)
+			return err
+		}
+
+		switch {
+		case w.IsEncrypted():
+			err = serv.guardView(w, params.Password, f)
+		case len(params.Password) != 0:
+			err = ErrWalletNotEncrypted
+		default:
+			err = f(w)
+		}
+	case transaction.TxnUnsigned:
+		txn, uxb, err = CreateTransaction(w, params.Params, auxs, headTime)
+	default:
+		logger.Panic("Invalid TxnSignedFlag")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := checkOutputHours(params.TransactionOptions, txn); err != nil {
+		return nil, nil, err
+	}
+
+	if verify != nil {
+		if err := verify(txn); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := serv.saveCreatedTransaction(w, cw, txn, params.Memo); err != nil {
+		return nil, nil, err
+	}
+
+	serv.InvalidateBalanceCache(params.WalletID)
+
+	return txn, uxb, nil
+}
+
+// CreateUnsignedTransaction creates an unsigned transaction spending from the wallet params.WalletID,
+// to be signed on another machine with SignTransaction. No secrets are needed, so this also works for
+// watch-only and xpub wallets; params.Password is only used if params.GenerateChange is set.
+// The returned inputs are the outputs spent by the transaction, in the order of the transaction inputs.
+// Refer to CreateTransaction for information about transaction creation.
+func (serv *Service) CreateUnsignedTransaction(params CreateTransactionParams, auxs coin.AddressUxOuts, headTime uint64) (*coin.Transaction, []transaction.UxBalance, error) {
+	return serv.CreateVerifiedTransaction(params, auxs, headTime, transaction.TxnUnsigned, nil)
+}
+
+// CreateVerifiedTransaction creates a transaction spending the outputs of auxs from the wallet params.WalletID,
+// signed if signed is TxnSigned, applying params.TransactionOptions. verify, if not nil, is called with the created
+// transaction before anything is recorded in the wallet, e.g. to check the transaction against the blockchain,
+// and its error is returned. auxs must only hold outputs of the wallet.
+// The returned inputs are the outputs spent by the transaction, in the order of the transaction inputs.
+func (serv *Service) CreateVerifiedTransaction(params CreateTransactionParams, auxs coin.AddressUxOuts, headTime uint64,
+	signed transaction.TxnSignedFlag, verify func(*coin.Transaction) error) (*coin.Transaction, []transaction.UxBalance, error) {
+	serv.Lock()
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return nil, nil, err
+	}
+	if serv.config.ReadOnly {
+		return nil, nil, ErrWalletReadOnly
+	}
+
+	return serv.createTransaction(params, auxs, headTime, signed, verify)
+}
+
+// saveCreatedTransaction saves the wallets changed for txn once it is created: the change wallet cw,
+// if a change address was generated, which may be the spending wallet w, and w if memo is set.
+// The memo is keyed by the inner hash of the transaction, which doesn't change when an unsigned
+// transaction is signed. The caller must hold the service write lock.
+func (serv *Service) saveCreatedTransaction(w, cw Wallet, txn *coin.Transaction, memo []byte) error {
+	if cw != nil && cw != w {
+		if err := serv.saveWritable(cw); err != nil {
+			return err
+		}
+
+		serv.wallets.set(cw)
+		serv.queueEvent(cw.Filename(), WalletEventAddressesAdded)
+	}
+
+	if cw != w && len(memo) == 0 {
+		return nil
+	}
+
+	if len(memo) != 0 {
+		w.SetTransactionMemo(txn.InnerHash, memo)
+	}
+
+	if err := serv.saveWritable(w); err != nil {
+		return err
+	}
+
+	serv.wallets.set(w)
+	if cw == w {
+		serv.queueEvent(w.Filename(), WalletEventAddressesAdded)
+	}
+	if len(memo) != 0 {
+		serv.queueEvent(w.Filename(), WalletEventUpdated)
+	}
+	return nil
+}
+
+// SetTransactionMemo records a memo in the wallet for the transaction of given inner hash, replacing the
+// previous one, e.g. for a transaction created without TransactionOptions.Memo. The memo is removed if empty.
+// Returns ErrMemoTooLong if it is longer than MaxMemoLength.
+func (serv *Service) SetTransactionMemo(wltID string, innerHash cipher.SHA256, memo []byte) error {
+	serv.Lock()
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return err
+	}
+	if serv.config.ReadOnly {
+		return ErrWalletReadOnly
+	}
+
+	if len(memo) > MaxMemoLength {
+		return ErrMemoTooLong
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return err
+	}
+
+	w.SetTransactionMemo(innerHash, memo)
+	if err := serv.save(w); err != nil {
+		return err
+	}
+
+	serv.wallets.set(w)
+	serv.queueEvent(wltID, WalletEventUpdated)
+	return nil
+}
+
+// GetTransactionMemo returns the memo recorded in the wallet for the transaction of given inner hash,
+// or nil if there is none. The inner hash of a transaction is coin.Transaction.InnerHash
+func (serv *Service) GetTransactionMemo(wltID string, innerHash cipher.SHA256) ([]byte, error) {
+	var memo []byte
+	if err := serv.View(wltID, func(w Wallet) error {
+		memo = w.TransactionMemo(innerHash)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return memo, nil
+}
+
+// DescribeTransaction summarizes a transaction spending from the wallet for display, with the memo recorded
+// in the wallet for it. Refer to the DescribeTransaction function for details.
+func (serv *Service) DescribeTransaction(wltID string, txn *coin.Transaction, inputs []transaction.UxBalance) (TransactionSummary, error) {
+	memo, err := serv.GetTransactionMemo(wltID, txn.InnerHash)
+	if err != nil {
+		return TransactionSummary{}, err
+	}
+
+	s, err := DescribeTransaction(txn, inputs)
+	if err != nil {
+		return TransactionSummary{}, err
+	}
+
+	s.Memo = memo
+	return s, nil
+}
+
+// CreateTransferTransaction creates and signs a transaction that sends amount coins from the wallet srcID
+// to an address of the wallet destID, spending from auxs. The destination is the last address of destID;
+// if it has none, one is generated, which requires destID to be unencrypted. The generated address is only
+// saved if the transaction is created. Only the source wallet is
+// decrypted, so password is the password of srcID. The coin hours are shared evenly with the destination.
+// The returned inputs are the outputs spent by the transaction, in the order of the transaction inputs.
+func (serv *Service) CreateTransferTransaction(srcID string, password []byte, destID string, amount uint64, auxs coin.AddressUxOuts, headTime uint64) (*coin.Transaction, []transaction.UxBalance, error) {
+	serv.Lock()
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
+		return nil, nil, err
+	}
+	if serv.config.ReadOnly {
+		return nil, nil, ErrWalletReadOnly
 	}
 
-	if !serv.config.EnableSeedAPI {
-		return "", "", ErrSeedAPIDisabled
+	if srcID == destID {
+		return nil, nil, ErrTransferToSameWallet
 	}
 
-	w, err := serv.getWallet(wltID)
+	if _, err := serv.getWallet(srcID); err != nil {
+		return nil, nil, err
+	}
+
+	dest, dw, err := serv.peekTransferAddress(destID)
 	if err != nil {
-		return "", "", err
+		return nil, nil, err
 	}
 
-	if !w.IsEncrypted() {
-		return "", "", ErrWalletNotEncrypted
+	shareFactor := decimal.New(5, -1)
+	txn, inputs, err := serv.batchCreateTransaction(CreateTransactionParams{
+		WalletID: srcID,
+		Password: password,
+		Params: transaction.Params{
+			HoursSelection: transaction.HoursSelection{
+				Type:        transaction.HoursSelectionTypeAuto,
+				Mode:        transaction.HoursSelectionModeShare,
+				ShareFactor: &shareFactor,
+			},
+			To: []coin.TransactionOutput{
+				{
+					Address: dest,
+					Coins:   amount,
+				},
+			},
+		},
+	}, auxs, nil, headTime)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	var seed, seedPassphrase string
-	if err := GuardView(w, password, func(wlt Wallet) error {
-		seed = wlt.Seed()
-		seedPassphrase = wlt.SeedPassphrase()
-		return nil
-	}); err != nil {
-		return "", "", err
+	if dw != nil {
+		if err := serv.saveWritable(dw); err != nil {
+			return nil, nil, err
+		}
+
+		serv.wallets.set(dw)
+		serv.queueEvent(destID, WalletEventAddressesAdded)
 	}
 
-	return seed, seedPassphrase, nil
+	return txn, inputs, nil
 }
 
-// UpdateSecrets opens a wallet for modification of secret data and saves it safely
-func (serv *Service) UpdateSecrets(wltID string, password []byte, f func(Wallet) error) error {
-	serv.Lock()
-	defer serv.Unlock()
-	if !serv.config.EnableWalletAPI {
-		return ErrWalletAPIDisabled
+// peekTransferAddress returns the last address of the wallet. If it has none, one is generated
+// without saving it, in a copy of the wallet, which is returned so that the caller saves it once the
+// transaction is created. The returned wallet is nil if no address is generated.
+// The caller must hold the service write lock.
+func (serv *Service) peekTransferAddress(wltID string) (cipher.Address, Wallet, error) {
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return cipher.Address{}, nil, err
 	}
 
-	w, err := serv.getWallet(wltID)
+	addrs, err := w.GetAddresses()
 	if err != nil {
-		return err
+		return cipher.Address{}, nil, err
 	}
 
-	if w.IsEncrypted() {
-		if err := GuardUpdate(w, password, f); err != nil {
-			return err
-		}
-	} else if len(password) != 0 {
-		return ErrWalletNotEncrypted
-	} else {
-		if err := f(w); err != nil {
-			return err
-		}
+	if len(addrs) != 0 {
+		return SkycoinAddresses(addrs)[len(addrs)-1], nil, nil
 	}
 
-	// Save the wallet to disk
-	if err := Save(w, serv.config.WalletDir); err != nil {
-		return err
+	if w.IsEncrypted() {
+		return cipher.Address{}, nil, ErrWalletEncrypted
 	}
 
-	serv.wallets.set(w)
+	addrs, err = w.GenerateAddresses(OptionGenerateN(1))
+	if err != nil {
+		return cipher.Address{}, nil, err
+	}
 
-	return nil
+	return SkycoinAddresses(addrs)[len(addrs)-1], w, nil
 }
 
-// Update opens a wallet for modification of non-secret data and saves it safely
-func (serv *Service) Update(wltID string, f func(Wallet) error) error {
-	serv.Lock()
-	defer serv.Unlock()
-	if !serv.config.EnableWalletAPI {
-		return ErrWalletAPIDisabled
+// GetTransactionInputs resolves the inputs of txn, e.g. a transaction created earlier by CreateTransaction,
+// to the outputs of auxs they spend. The returned inputs are in the order of the transaction inputs,
+// with their coin hours calculated at headTime, as returned by the transaction creation methods.
+// Returns ErrTransactionInputNotFound if an input is not in auxs.
+func (serv *Service) GetTransactionInputs(txn *coin.Transaction, auxs coin.AddressUxOuts, headTime uint64) ([]transaction.UxBalance, error) {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return nil, err
 	}
 
-	w, err := serv.getWallet(wltID)
-	if err != nil {
-		return err
+	uxOuts := make(map[cipher.SHA256]coin.UxOut)
+	for _, uxa := range auxs {
+		for _, ux := range uxa {
+			uxOuts[ux.Hash()] = ux
+		}
 	}
 
-	if err := f(w); err != nil {
-		return err
-	}
+	inputs := make([]transaction.UxBalance, len(txn.In))
+	for i, h := range txn.In {
+		ux, ok := uxOuts[h]
+		if !ok {
+			logger.WithField("uxid", h.Hex()).Debug("GetTransactionInputs: input not found")
+			return nil, ErrTransactionInputNotFound
+		}
 
-	// Save the wallet to disk
-	if err := Save(w, serv.config.WalletDir); err != nil {
-		return err
+		var err error
+		inputs[i], err = transaction.NewUxBalance(headTime, ux)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	serv.wallets.set(w)
-
-	return nil
+	return inputs, nil
 }
 
-// ViewSecrets opens a wallet for reading secret data
-func (serv *Service) ViewSecrets(wltID string, password []byte, f func(Wallet) error) error {
+// SignTransaction signs the inputs of txn at signIndexes with the keys of the wallet, or all unsigned inputs if signIndexes is empty.
+// Inputs that are not requested are left untouched, so a transaction spending from several wallets can be signed by each of them in turn.
+// inputs are the outputs spent by the transaction, in the order of the transaction inputs, as returned by CreateUnsignedTransaction;
+// each of them is checked against the transaction input hash before signing.
+// Returns ErrUnknownAddress if the address of an input to sign is not in the wallet.
+// Set the password as nil if the wallet is not encrypted, otherwise the password must be provided.
+func (serv *Service) SignTransaction(wltID string, password []byte, txn *coin.Transaction, signIndexes []int, inputs []transaction.UxBalance) (*coin.Transaction, error) {
 	serv.RLock()
 	defer serv.RUnlock()
-	if !serv.config.EnableWalletAPI {
-		return ErrWalletAPIDisabled
+	if err := serv.checkEnabled(); err != nil {
+		return nil, err
+	}
+	if serv.config.ReadOnly {
+		return nil, ErrWalletReadOnly
 	}
 
 	w, err := serv.getWallet(wltID)
 	if err != nil {
+		return nil, err
+	}
+
+	uxOuts, err := uxOutsFromBalances(txn, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkSignAddresses(w, txn, signIndexes, uxOuts); err != nil {
+		return nil, err
+	}
+
+	var signedTxn *coin.Transaction
+	f := func(w Wallet) error {
+		var err error
+		signedTxn, err = SignTransaction(w, txn, signIndexes, uxOuts)
 		return err
 	}
 
-	if w.IsEncrypted() {
-		return GuardView(w, password, f)
-	} else if len(password) != 0 {
-		return ErrWalletNotEncrypted
-	} else {
-		return f(w)
+	switch {
+	case w.IsEncrypted():
+		err = serv.guardView(w, password, f)
+	case len(password) != 0:
+		err = ErrWalletNotEncrypted
+	default:
+		err = f(w)
+	}
+	if err != nil {
+		return nil, err
 	}
+
+	return signedTxn, nil
 }
 
 // View opens a wallet for reading non-secret data
 func (serv *Service) View(wltID string, f func(Wallet) error) error {
 	serv.RLock()
 	defer serv.RUnlock()
-	if !serv.config.EnableWalletAPI {
-		return ErrWalletAPIDisabled
+	if err := serv.checkEnabled(); err != nil {
+		return err
 	}
 
 	w, err := serv.getWallet(wltID)
@@ -770,46 +3844,32 @@ func (serv *Service) View(wltID string, f func(Wallet) error) error {
 
 // RecoverWallet recovers an encrypted wallet from seed.
 // The recovered wallet will be encrypted with the new password, if provided.
+// Returns ErrSeedAPIDisabled if Config.EnableSeedAPI is false.
 func (serv *Service) RecoverWallet(wltName, seed, seedPassphrase string,
 	password []byte) (Wallet, error) {
 	serv.Lock()
-	defer serv.Unlock()
-	if !serv.config.EnableWalletAPI {
-		return nil, ErrWalletAPIDisabled
-	}
-
-	w, err := serv.getWallet(wltName)
-	if err != nil {
+	defer serv.unlockAndNotify()
+	if err := serv.checkEnabled(); err != nil {
 		return nil, err
 	}
-
-	if !w.IsEncrypted() {
-		return nil, ErrWalletNotEncrypted
+	if !serv.config.EnableSeedAPI {
+		return nil, ErrSeedAPIDisabled
+	}
+	if serv.config.ReadOnly {
+		return nil, ErrWalletReadOnly
 	}
 
-	switch w.Type() {
-	case WalletTypeBip44, WalletTypeDeterministic:
-	default:
-		return nil, ErrWalletTypeNotRecoverable
+	if err := CheckPasswordStrength(password, serv.config.MinPasswordLength); err != nil {
+		return nil, err
 	}
 
-	// Create a wallet from this seed and compare the fingerprint
-	w2, err := serv.createWallet(wltName, Options{
-		Type:           w.Type(),
-		Coin:           w.Coin(),
-		Bip44Coin:      w.Bip44Coin(),
-		Label:          w.Label(),
-		Seed:           seed,
-		SeedPassphrase: seedPassphrase,
-		GenerateN:      1,
-	})
+	w, err := serv.getWallet(wltName)
 	if err != nil {
-		err = NewError(fmt.Errorf("RecoverWallet failed to create temporary wallet for fingerprint comparison: %v", err))
-		logger.Critical().WithError(err).Error()
 		return nil, err
 	}
-	if w.Fingerprint() != w2.Fingerprint() {
-		return nil, ErrWalletRecoverSeedWrong
+
+	if err := serv.verifyRecoverySeed(w, seed, seedPassphrase); err != nil {
+		return nil, err
 	}
 
 	var options []Option
@@ -830,6 +3890,8 @@ func (serv *Service) RecoverWallet(wltName, seed, seedPassphrase string,
 		Label:          w.Label(),
 		Seed:           seed,
 		SeedPassphrase: seedPassphrase,
+		SeedType:       walletSeedType(w),
+		AddressType:    walletAddressType(w),
 		Encrypt:        len(password) != 0,
 		Password:       password,
 		CryptoType:     w.CryptoType(),
@@ -854,17 +3916,239 @@ func (serv *Service) RecoverWallet(wltName, seed, seedPassphrase string,
 				return nil, err
 			}
 		}
+
+		// regenerate the other accounts, which are derived from the seed
+		if err := serv.recoverAccounts(w, w3, password); err != nil {
+			return nil, err
+		}
 	}
 
-	// Preserve the timestamp of the old wallet
+	// Preserve the timestamp and notes of the old wallet
 	w3.SetTimestamp(w.Timestamp())
+	w3.SetNotes(w.Notes())
 
-	// Save to disk
-	if err := Save(w3, serv.config.WalletDir); err != nil {
+	// Save to disk, the recovered wallet may have a new password
+	if err := serv.saveReencrypted(w3); err != nil {
 		return nil, err
 	}
 
 	serv.wallets.set(w3)
+	serv.queueEvent(wltName, WalletEventRecovered)
 
 	return w3.Clone(), nil
 }
+
+// recoverAccounts adds the accounts of the bip44 wallet w after its default account to the recovered wallet w3,
+// with the same names and numbers of addresses. password unlocks w3 if it is encrypted.
+func (serv *Service) recoverAccounts(w, w3 Wallet, password []byte) error {
+	accounts := w.Accounts()
+	if len(accounts) < 2 {
+		return nil
+	}
+
+	f := func(w3 Wallet) error {
+		for _, a := range accounts[1:] {
+			el, err := w.EntriesLen(OptionAccount(a.Index), OptionExternal())
+			if err != nil {
+				return err
+			}
+			cl, err := w.EntriesLen(OptionAccount(a.Index), OptionChange())
+			if err != nil {
+				return err
+			}
+
+			index, err := newAccount(w3, a.Name, uint64(el), uint64(cl))
+			if err != nil {
+				return err
+			}
+			if index != a.Index {
+				return fmt.Errorf("recovered account %q has index %d, expected %d", a.Name, index, a.Index)
+			}
+		}
+		return nil
+	}
+
+	if w3.IsEncrypted() {
+		return serv.guardUpdate(w3, password, f)
+	}
+	return f(w3)
+}
+
+// RestoreWalletFromSeed creates a deterministic wallet from the seed of a wallet whose file was lost.
+// Unlike RecoverWallet, no wallet needs to exist. The first scanN addresses are scanned with bg and the wallet
+// is extended up to the last address with a balance. The wallet is encrypted with password, if provided.
+// The wallet label is RestoredWalletLabel, it can be changed with UpdateWalletLabel.
+// Like CreateWallet, an error is returned if a wallet of the service already has the seed.
+// Returns ErrSeedAPIDisabled if Config.EnableSeedAPI is false.
+func (serv *Service) RestoreWalletFromSeed(wltName, seed string, password []byte, scanN uint64, bg BalanceGetter) (Wallet, error) {
+	if !serv.config.EnableSeedAPI {
+		return nil, ErrSeedAPIDisabled
+	}
+
+	options := Options{
+		Type:  WalletTypeDeterministic,
+		Seed:  seed,
+		Label: RestoredWalletLabel,
+		ScanN: scanN,
+	}
+
+	if scanN > 0 {
+		if bg == nil {
+			return nil, ErrNilTransactionsFinder
+		}
+		options.TF = balanceTransactionsFinder{bg: bg}
+	}
+
+	if len(password) > 0 {
+		options.Encrypt = true
+		options.Password = password
+	}
+
+	return serv.CreateWallet(wltName, options)
+}
+
+// RecoverWalletDryRun checks that the seed recovers the encrypted wallet, without
+// replacing the wallet in memory or on disk. Returns ErrWalletRecoverSeedWrong if the seed does not match.
+// Returns ErrSeedAPIDisabled if Config.EnableSeedAPI is false.
+func (serv *Service) RecoverWalletDryRun(wltName, seed, seedPassphrase string) error {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return err
+	}
+	if !serv.config.EnableSeedAPI {
+		return ErrSeedAPIDisabled
+	}
+
+	w, err := serv.getWallet(wltName)
+	if err != nil {
+		return err
+	}
+
+	return serv.verifyRecoverySeed(w, seed, seedPassphrase)
+}
+
+// walletSeedType returns the seed type recorded in the wallet, see Meta.SeedType
+func walletSeedType(w Wallet) string {
+	if st, ok := w.(interface{ SeedType() string }); ok {
+		return st.SeedType()
+	}
+	return ""
+}
+
+// walletAddressType returns the address type of a deterministic wallet, see Meta.AddressType.
+// Other wallet types don't have an address type
+func walletAddressType(w Wallet) AddressType {
+	if w.Type() != WalletTypeDeterministic {
+		return ""
+	}
+	if at, ok := w.(interface{ AddressType() AddressType }); ok {
+		return at.AddressType()
+	}
+	return ""
+}
+
+// verifyRecoverySeed creates a temporary wallet from the seed and compares its
+// fingerprint with the encrypted wallet w
+func (serv *Service) verifyRecoverySeed(w Wallet, seed, seedPassphrase string) error {
+	if !w.IsEncrypted() {
+		return ErrWalletNotEncrypted
+	}
+
+	switch w.Type() {
+	case WalletTypeBip44, WalletTypeDeterministic:
+	default:
+		return ErrWalletTypeNotRecoverable
+	}
+
+	// Bip44 wallets are always created from a mnemonic, as are the deterministic wallets
+	// created with SeedTypeMnemonic, reject a seed with a bad checksum before comparing fingerprints
+	if w.Type() == WalletTypeBip44 || walletSeedType(w) == SeedTypeMnemonic {
+		if err := ValidateMnemonic(seed); err != nil {
+			return err
+		}
+	}
+
+	// Create a wallet from this seed and compare the fingerprint
+	w2, err := serv.createWallet(w.Filename(), Options{
+		Type:           w.Type(),
+		Coin:           w.Coin(),
+		AddressType:    walletAddressType(w),
+		Bip44Coin:      w.Bip44Coin(),
+		Label:          w.Label(),
+		Seed:           seed,
+		SeedPassphrase: seedPassphrase,
+		GenerateN:      1,
+	})
+	if err != nil {
+		err = NewError(fmt.Errorf("RecoverWallet failed to create temporary wallet for fingerprint comparison: %v", err))
+		logger.Critical().WithError(err).Error()
+		return err
+	}
+	if w.Fingerprint() != w2.Fingerprint() {
+		return ErrWalletRecoverSeedWrong
+	}
+
+	return nil
+}
+
+// SignWalletFile signs the serialized bytes of the wallet of given id with secKey.
+// The serialized bytes are the same as the content of the wallet file, so the signature
+// can be used to check that a copy of the wallet file was not tampered with.
+func (serv *Service) SignWalletFile(wltID string, secKey cipher.SecKey) (cipher.Sig, error) {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return cipher.Sig{}, err
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return cipher.Sig{}, err
+	}
+
+	h, err := walletHash(w)
+	if err != nil {
+		return cipher.Sig{}, err
+	}
+
+	return cipher.SignHash(h, secKey)
+}
+
+// VerifyWalletSignature checks that sig is a signature of the serialized bytes of
+// the wallet of given id, produced by the secret key of pubKey.
+// Returns ErrInvalidWalletSignature if the signature does not match.
+func (serv *Service) VerifyWalletSignature(wltID string, sig cipher.Sig, pubKey cipher.PubKey) error {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return err
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return err
+	}
+
+	h, err := walletHash(w)
+	if err != nil {
+		return err
+	}
+
+	if err := cipher.VerifyPubKeySignedHash(pubKey, sig, h); err != nil {
+		logger.WithError(err).WithField("wltID", wltID).Debug("VerifyWalletSignature: cipher.VerifyPubKeySignedHash failed")
+		return ErrInvalidWalletSignature
+	}
+
+	return nil
+}
+
+// walletHash returns the SHA256 hash of the serialized wallet
+func walletHash(w Wallet) (cipher.SHA256, error) {
+	data, err := w.Serialize()
+	if err != nil {
+		return cipher.SHA256{}, err
+	}
+
+	return cipher.SumSHA256(data), nil
+}
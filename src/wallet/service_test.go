@@ -1,26 +1,41 @@
 package wallet_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/shopspring/decimal"
 	"github.com/skycoin/skycoin/src/cipher/bip39"
+	"github.com/skycoin/skycoin/src/cipher/bip44"
 	"github.com/skycoin/skycoin/src/testutil"
 	"github.com/skycoin/skycoin/src/wallet/bip44wallet"
 	"github.com/skycoin/skycoin/src/wallet/collection"
 	_ "github.com/skycoin/skycoin/src/wallet/deterministic"
+	_ "github.com/skycoin/skycoin/src/wallet/watchonly"
 	_ "github.com/skycoin/skycoin/src/wallet/xpubwallet"
 	"github.com/stretchr/testify/require"
 
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/cipher/crypto"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/transaction"
+	"github.com/skycoin/skycoin/src/util/fee"
 	"github.com/skycoin/skycoin/src/wallet"
 )
 
@@ -85,6 +100,11 @@ func TestNewServiceDupWallets(t *testing.T) {
 	require.NotNil(t, err)
 	require.Error(t, err)
 	require.True(t, strings.HasPrefix(err.Error(), "duplicate wallet found with fingerprint deterministic-2M755W9o7933roLASK9PZTmqRsjQUsVen9y in file"), err.Error())
+
+	var dupErr wallet.DuplicateWalletError
+	require.True(t, errors.As(err, &dupErr))
+	require.Equal(t, "deterministic-2M755W9o7933roLASK9PZTmqRsjQUsVen9y", dupErr.Fingerprint)
+	require.NotEmpty(t, dupErr.WalletID)
 }
 
 func TestNewServiceEmptyWallet(t *testing.T) {
@@ -109,6 +129,181 @@ func TestNewServiceEmptyWallet(t *testing.T) {
 				EnableWalletAPI: true,
 			})
 			testutil.RequireError(t, err, fmt.Sprintf("empty wallet file found: %q", tc.fn))
+			require.Equal(t, wallet.EmptyWalletError{WalletID: tc.fn}, err)
+		})
+	}
+}
+
+func TestNewServiceSkipInvalidWallets(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	for _, fn := range []string{
+		"duplicate_wallets/test3.wlt",
+		"duplicate_wallets/test3.1.wlt",
+		"empty_wallet/empty.wlt",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join("./testdata", fn))
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, filepath.Base(fn)), data, 0600))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "corrupt.wlt"), []byte("{corrupt"), 0600))
+
+	// Fails on the first invalid wallet by default
+	_, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		EnableWalletAPI: true,
+	})
+	var loadErr wallet.LoadWalletError
+	require.True(t, errors.As(err, &loadErr))
+	require.Equal(t, "corrupt.wlt", loadErr.WalletID)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:          dir,
+		EnableWalletAPI:    true,
+		SkipInvalidWallets: true,
+	})
+	require.NoError(t, err)
+
+	wlts, err := s.GetWallets()
+	require.NoError(t, err)
+	require.Len(t, wlts, 1)
+	require.Contains(t, wlts, "test3.1.wlt")
+
+	invalid := s.InvalidWallets()
+	require.Len(t, invalid, 3)
+	require.True(t, errors.As(invalid[0], &loadErr))
+	require.Equal(t, "corrupt.wlt", loadErr.WalletID)
+	require.Equal(t, wallet.EmptyWalletError{WalletID: "empty.wlt"}, invalid[1])
+	require.Equal(t, wallet.DuplicateWalletError{
+		WalletID:    "test3.wlt",
+		Fingerprint: "deterministic-2M755W9o7933roLASK9PZTmqRsjQUsVen9y",
+	}, invalid[2])
+
+	// The skipped files are left on disk to be fixed
+	for _, fn := range []string{"corrupt.wlt", "test3.wlt", "empty.wlt"} {
+		_, err := os.Stat(filepath.Join(dir, fn))
+		require.NoError(t, err)
+	}
+}
+
+func TestNewServiceErrors(t *testing.T) {
+	// The wallet directory can't be created under a regular file
+	f, err := ioutil.TempFile("", "wallets")
+	require.NoError(t, err)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	dir := filepath.Join(f.Name(), "wallets")
+	_, err = wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		EnableWalletAPI: true,
+	})
+	var dirErr wallet.WalletDirError
+	require.True(t, errors.As(err, &dirErr), err)
+	require.Equal(t, "create directory", dirErr.Op)
+	require.Equal(t, dir, dirErr.Dir)
+	var pathErr *os.PathError
+	require.True(t, errors.As(err, &pathErr))
+
+	// A wallet file that can't be loaded
+	dir = prepareWltDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "bad.wlt"), []byte("not a wallet"), 0600))
+	_, err = wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		EnableWalletAPI: true,
+	})
+	var loadErr wallet.LoadWalletError
+	require.True(t, errors.As(err, &loadErr), err)
+	require.Equal(t, "bad.wlt", loadErr.WalletID)
+	require.Error(t, errors.Unwrap(err))
+}
+
+func TestNewServicePermissions(t *testing.T) {
+	cases := []struct {
+		name     string
+		dirPerm  os.FileMode
+		filePerm os.FileMode
+		err      bool
+	}{
+		{
+			name:     "default",
+			dirPerm:  0,
+			filePerm: 0,
+		},
+		{
+			name:     "group readable",
+			dirPerm:  0750,
+			filePerm: 0640,
+		},
+		{
+			name:    "world writable dir",
+			dirPerm: 0777,
+			err:     true,
+		},
+		{
+			name:    "dir without owner access",
+			dirPerm: 0500,
+			err:     true,
+		},
+		{
+			name:     "world writable file",
+			filePerm: 0666,
+			err:      true,
+		},
+		{
+			name:     "read only file",
+			filePerm: 0400,
+			err:      true,
+		},
+		{
+			name:     "not a permission",
+			filePerm: os.ModeDir | 0600,
+			err:      true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := filepath.Join(prepareWltDir(), "wallets")
+			s, err := wallet.NewService(wallet.Config{
+				WalletDir:       dir,
+				EnableWalletAPI: true,
+				DirPerm:         tc.dirPerm,
+				FilePerm:        tc.filePerm,
+			})
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			dirPerm := tc.dirPerm
+			if dirPerm == 0 {
+				dirPerm = wallet.DefaultDirPerm
+			}
+			filePerm := tc.filePerm
+			if filePerm == 0 {
+				filePerm = wallet.DefaultFilePerm
+			}
+
+			fi, err := os.Stat(dir)
+			require.NoError(t, err)
+			require.Equal(t, dirPerm, fi.Mode().Perm())
+
+			w, err := s.CreateWallet("t.wlt", wallet.Options{
+				Seed:  "seed",
+				Label: "label",
+				Type:  wallet.WalletTypeDeterministic,
+			})
+			require.NoError(t, err)
+
+			fi, err = os.Stat(filepath.Join(dir, w.Filename()))
+			require.NoError(t, err)
+			require.Equal(t, filePerm, fi.Mode().Perm())
+
+			require.Equal(t, dirPerm, s.EffectiveConfig().DirPerm)
+			require.Equal(t, filePerm, s.EffectiveConfig().FilePerm)
 		})
 	}
 }
@@ -305,6 +500,82 @@ func (mb mockTxnsFinder) AddressesActivity(addrs []cipher.Addresser) ([]bool, er
 	return active, nil
 }
 
+// countingTxnsFinder counts the calls to AddressesActivity
+type countingTxnsFinder struct {
+	mockTxnsFinder
+	calls int
+}
+
+func (c *countingTxnsFinder) AddressesActivity(addrs []cipher.Addresser) ([]bool, error) {
+	c.calls++
+	return c.mockTxnsFinder.AddressesActivity(addrs)
+}
+
+func TestServiceCreateWalletScanChunkSize(t *testing.T) {
+	seed := "seed"
+	_, seckeys := cipher.MustGenerateDeterministicKeyPairsSeed([]byte(seed), 10)
+	addrs := make([]cipher.Address, 10)
+	for i, s := range seckeys {
+		addrs[i] = cipher.MustAddressFromSecKey(s)
+	}
+
+	tt := []struct {
+		name      string
+		chunkSize uint64
+		calls     int
+	}{
+		{
+			name:  "default chunk size",
+			calls: 3,
+		},
+		{
+			name:      "chunk size smaller than gap limit",
+			chunkSize: 2,
+			calls:     3,
+		},
+		{
+			name:      "chunk size=20",
+			chunkSize: 20,
+			calls:     1,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := wallet.NewService(wallet.Config{
+				WalletDir:       prepareWltDir(),
+				CryptoType:      crypto.CryptoTypeSha256Xor,
+				EnableWalletAPI: true,
+			})
+			require.NoError(t, err)
+
+			tf := &countingTxnsFinder{
+				mockTxnsFinder: mockTxnsFinder{
+					addrs[1]: true,
+					addrs[3]: true,
+					addrs[5]: true,
+					addrs[7]: true,
+				},
+			}
+
+			w, err := s.CreateWallet("t.wlt", wallet.Options{
+				Type:          wallet.WalletTypeDeterministic,
+				Seed:          seed,
+				Label:         "wallet",
+				GapLimit:      5,
+				ScanChunkSize: tc.chunkSize,
+				TF:            tf,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tc.calls, tf.calls)
+
+			wAddrs, err := s.GetAddresses(w.Filename())
+			require.NoError(t, err)
+			require.Equal(t, addrs[:8], wAddrs)
+		})
+	}
+}
+
 func TestServiceLoadWallet(t *testing.T) {
 	// Prepare addresses
 	seed := "seed"
@@ -422,6 +693,157 @@ func TestServiceLoadWallet(t *testing.T) {
 			expectAddrNum: 2,
 			expectAddrs:   addrs[:2],
 		},
+		{
+			name: "raw wallet gap limit=5 address=9",
+			opts: wallet.Options{
+				Type:     wallet.WalletTypeDeterministic,
+				Seed:     seed,
+				Label:    "wallet",
+				GapLimit: 5,
+				TF: mockTxnsFinder{
+					addrs[4]: true,
+					addrs[8]: true,
+				},
+			},
+			err:           nil,
+			expectAddrNum: 9,
+			expectAddrs:   addrs[:9],
+		},
+		{
+			name: "encrypted wallet scan=5 gap limit=5 address=9",
+			opts: wallet.Options{
+				Type:     wallet.WalletTypeDeterministic,
+				Seed:     seed,
+				Label:    "wallet",
+				Encrypt:  true,
+				Password: []byte("pwd"),
+				ScanN:    5,
+				GapLimit: 5,
+				TF: mockTxnsFinder{
+					addrs[4]: true,
+					addrs[8]: true,
+				},
+			},
+			err:           nil,
+			expectAddrNum: 9,
+			expectAddrs:   addrs[:9],
+		},
+		{
+			name: "raw wallet gap limit=4 stops at gap",
+			opts: wallet.Options{
+				Type:     wallet.WalletTypeDeterministic,
+				Seed:     seed,
+				Label:    "wallet",
+				GapLimit: 4,
+				TF: mockTxnsFinder{
+					addrs[4]: true,
+					addrs[9]: true,
+				},
+			},
+			err:           nil,
+			expectAddrNum: 5,
+			expectAddrs:   addrs[:5],
+		},
+		{
+			name: "raw wallet gap limit=3 chunk=9 stops at inner gap",
+			opts: wallet.Options{
+				Type:          wallet.WalletTypeDeterministic,
+				Seed:          seed,
+				Label:         "wallet",
+				GapLimit:      3,
+				ScanChunkSize: 9,
+				TF: mockTxnsFinder{
+					addrs[2]: true,
+					addrs[6]: true,
+				},
+			},
+			err:           nil,
+			expectAddrNum: 3,
+			expectAddrs:   addrs[:3],
+		},
+		{
+			name: "raw wallet gap limit=3 chunk=4 gap across chunks",
+			opts: wallet.Options{
+				Type:          wallet.WalletTypeDeterministic,
+				Seed:          seed,
+				Label:         "wallet",
+				GapLimit:      3,
+				ScanChunkSize: 4,
+				TF: mockTxnsFinder{
+					addrs[3]: true,
+					addrs[6]: true,
+				},
+			},
+			err:           nil,
+			expectAddrNum: 7,
+			expectAddrs:   addrs[:7],
+		},
+		{
+			name: "raw wallet gap limit=4 chunk=2 address=5",
+			opts: wallet.Options{
+				Type:          wallet.WalletTypeDeterministic,
+				Seed:          seed,
+				Label:         "wallet",
+				GapLimit:      4,
+				ScanChunkSize: 2,
+				TF: mockTxnsFinder{
+					addrs[4]: true,
+					addrs[9]: true,
+				},
+			},
+			err:           nil,
+			expectAddrNum: 5,
+			expectAddrs:   addrs[:5],
+		},
+		{
+			name: "scan chunk size too large",
+			opts: wallet.Options{
+				Type:          wallet.WalletTypeDeterministic,
+				Seed:          seed,
+				Label:         "wallet",
+				GapLimit:      5,
+				ScanChunkSize: wallet.MaxScanChunkSize + 1,
+				TF:            mockTxnsFinder{},
+			},
+			err: wallet.ErrScanChunkSizeTooLarge,
+		},
+		{
+			name: "gap limit too large",
+			opts: wallet.Options{
+				Type:     wallet.WalletTypeDeterministic,
+				Seed:     seed,
+				Label:    "wallet",
+				GapLimit: wallet.MaxGapLimit + 1,
+				TF:       mockTxnsFinder{},
+			},
+			err: wallet.ErrGapLimitTooLarge,
+		},
+		{
+			name: "gap limit without transactions finder",
+			opts: wallet.Options{
+				Type:     wallet.WalletTypeDeterministic,
+				Seed:     seed,
+				Label:    "wallet",
+				GapLimit: 5,
+			},
+			err: wallet.ErrNilTransactionsFinder,
+		},
+		{
+			name: "bip44 raw wallet gap limit=5 address=9",
+			opts: wallet.Options{
+				Type:     wallet.WalletTypeBip44,
+				Seed:     bip44Seed,
+				Label:    "wallet",
+				GapLimit: 5,
+				TF: mockTxnsFinder{
+					bip44Addrs[4]: true,
+					bip44Addrs[8]: true,
+				},
+			},
+			err:           nil,
+			expectAddrNum: 10,
+			expectAddrs:   bip44Addrs[:9],
+		},
 		{
 			name: "bip44 raw wallet address=1",
 			opts: wallet.Options{
@@ -4439,3 +4861,6146 @@ func combineAddrs(a []cipher.Address, b []cipher.Address) []cipher.Address {
 	copy(addrs[len(a):], b[:])
 	return addrs
 }
+
+func TestServiceSignVerifyWalletFile(t *testing.T) {
+	dir := prepareWltDir()
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.NoError(t, err)
+
+	pk, sk := cipher.GenerateKeyPair()
+	sig, err := s.SignWalletFile(w.Filename(), sk)
+	require.NoError(t, err)
+
+	require.NoError(t, s.VerifyWalletSignature(w.Filename(), sig, pk))
+
+	// A different key does not verify
+	pk2, _ := cipher.GenerateKeyPair()
+	require.Equal(t, wallet.ErrInvalidWalletSignature, s.VerifyWalletSignature(w.Filename(), sig, pk2))
+
+	// A modified wallet does not verify
+	require.NoError(t, s.UpdateWalletLabel(w.Filename(), "label2"))
+	require.Equal(t, wallet.ErrInvalidWalletSignature, s.VerifyWalletSignature(w.Filename(), sig, pk))
+
+	// A wallet reloaded from disk verifies with a signature of the in-memory wallet
+	sig, err = s.SignWalletFile(w.Filename(), sk)
+	require.NoError(t, err)
+	s2, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, s2.VerifyWalletSignature(w.Filename(), sig, pk))
+
+	_, err = s.SignWalletFile("foo.wlt", sk)
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+	require.Equal(t, wallet.ErrWalletNotExist, s.VerifyWalletSignature("foo.wlt", sig, pk))
+
+	s.SetEnableWalletAPI(false)
+	_, err = s.SignWalletFile(w.Filename(), sk)
+	require.Equal(t, wallet.ErrWalletAPIDisabled, err)
+	require.Equal(t, wallet.ErrWalletAPIDisabled, s.VerifyWalletSignature(w.Filename(), sig, pk))
+}
+
+func TestServiceDuplicateWallet(t *testing.T) {
+	dir := prepareWltDir()
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.NoError(t, err)
+
+	// Wallets that have a seed can't be duplicated
+	_, err = s.DuplicateWallet(w.Filename(), "t2.wlt", "cold")
+	require.Equal(t, wallet.ErrSeedUsed, err)
+	_, err = os.Stat(filepath.Join(dir, "t2.wlt"))
+	require.True(t, os.IsNotExist(err))
+
+	cw, err := s.CreateWallet("c.wlt", wallet.Options{
+		Type:  wallet.WalletTypeCollection,
+		Label: "hot",
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.UpdateSecrets(cw.Filename(), nil, func(w wallet.Wallet) error {
+		pk, sk := cipher.GenerateKeyPair()
+		return w.(*collection.Wallet).AddEntry(wallet.Entry{
+			Address: cipher.AddressFromPubKey(pk),
+			Public:  pk,
+			Secret:  sk,
+		})
+	}))
+
+	dw, err := s.DuplicateWallet(cw.Filename(), "c2.wlt", "cold")
+	require.NoError(t, err)
+	require.Equal(t, "c2.wlt", dw.Filename())
+	require.Equal(t, "cold", dw.Label())
+	_, err = os.Stat(filepath.Join(dir, "c2.wlt"))
+	require.NoError(t, err)
+
+	addrs, err := s.GetAddresses(cw.Filename())
+	require.NoError(t, err)
+	dupAddrs, err := s.GetAddresses(dw.Filename())
+	require.NoError(t, err)
+	require.Equal(t, addrs, dupAddrs)
+
+	// The original wallet is unchanged
+	ow, err := s.GetWallet(cw.Filename())
+	require.NoError(t, err)
+	require.Equal(t, "hot", ow.Label())
+
+	// The new filename must not be taken
+	_, err = s.DuplicateWallet(cw.Filename(), "c2.wlt", "cold")
+	require.Equal(t, wallet.ErrWalletNameConflict, err)
+
+	// A filename is generated if not provided
+	dw, err = s.DuplicateWallet(cw.Filename(), "", "cold")
+	require.NoError(t, err)
+	require.NotEmpty(t, dw.Filename())
+	_, err = os.Stat(filepath.Join(dir, dw.Filename()))
+	require.NoError(t, err)
+
+	_, err = s.DuplicateWallet("foo.wlt", "c3.wlt", "cold")
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+
+	s.SetEnableWalletAPI(false)
+	_, err = s.DuplicateWallet(cw.Filename(), "c3.wlt", "cold")
+	require.Equal(t, wallet.ErrWalletAPIDisabled, err)
+}
+
+func TestServiceRenameWallet(t *testing.T) {
+	dir := prepareWltDir()
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("t2.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed2",
+		Label: "label2",
+	})
+	require.NoError(t, err)
+
+	for _, name := range []string{"t3", "t3.txt", ".wlt", "../t3.wlt", ""} {
+		_, err = s.RenameWallet(w.Filename(), name)
+		require.Equal(t, wallet.ErrInvalidWalletFilename, err, name)
+	}
+
+	_, err = s.RenameWallet(w.Filename(), "t2.wlt")
+	require.Equal(t, wallet.ErrWalletNameConflict, err)
+
+	_, err = s.RenameWallet("foo.wlt", "t3.wlt")
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+
+	rw, err := s.RenameWallet(w.Filename(), "t3.wlt")
+	require.NoError(t, err)
+	require.Equal(t, "t3.wlt", rw.Filename())
+	require.Equal(t, w.Fingerprint(), rw.Fingerprint())
+
+	_, err = s.GetWallet("t.wlt")
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+	_, err = s.GetWallet("t3.wlt")
+	require.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(dir, "t.wlt"))
+	require.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dir, "t3.wlt"))
+	require.NoError(t, err)
+
+	// The wallets on disk load without duplicates
+	s2, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+	wlts, err := s2.GetWallets()
+	require.NoError(t, err)
+	require.Len(t, wlts, 2)
+	require.Equal(t, "t3.wlt", wlts["t3.wlt"].Filename())
+
+	// The fingerprint refers to the renamed wallet, so the seed can't be reused
+	_, err = s.CreateWallet("t4.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.Error(t, err)
+
+	// Unloading the renamed wallet releases its fingerprint
+	require.NoError(t, s.UnloadWallet("t3.wlt"))
+	require.NoError(t, os.Remove(filepath.Join(dir, "t3.wlt")))
+	_, err = s.CreateWallet("t4.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.NoError(t, err)
+
+	s.SetEnableWalletAPI(false)
+	_, err = s.RenameWallet("t2.wlt", "t5.wlt")
+	require.Equal(t, wallet.ErrWalletAPIDisabled, err)
+}
+
+func TestServiceEffectiveConfig(t *testing.T) {
+	c := wallet.NewConfig()
+	c.WalletDir = prepareWltDir()
+	c.EnableWalletAPI = true
+	c.CryptoType = crypto.CryptoTypeSha256Xor
+
+	s, err := wallet.NewService(c)
+	require.NoError(t, err)
+
+	ec := s.EffectiveConfig()
+	require.Equal(t, c, ec)
+
+	// Modifying the returned config does not affect the service
+	*ec.Bip44Coin = bip44.CoinTypeBitcoin
+	ec.WalletDir = "foo"
+	require.Equal(t, c, s.EffectiveConfig())
+
+	s.SetEnableWalletAPI(false)
+	require.False(t, s.EffectiveConfig().EnableWalletAPI)
+}
+
+func TestServiceWatchOnlyWallet(t *testing.T) {
+	dir := prepareWltDir()
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+		EnableSeedAPI:   true,
+	})
+	require.NoError(t, err)
+
+	pk, _ := cipher.GenerateKeyPair()
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:                wallet.WalletTypeWatchOnly,
+		Label:               "watch",
+		WatchOnlyPublicKeys: []cipher.PubKey{pk},
+	})
+	require.NoError(t, err)
+	require.False(t, w.IsEncrypted())
+
+	addrs, err := s.GetAddresses(w.Filename())
+	require.NoError(t, err)
+	require.Equal(t, []cipher.Address{cipher.AddressFromPubKey(pk)}, addrs)
+
+	// The wallet is loaded like any other wallet
+	s2, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+	w2, err := s2.GetWallet(w.Filename())
+	require.NoError(t, err)
+	require.Equal(t, wallet.WalletTypeWatchOnly, w2.Type())
+
+	_, _, err = s.GetWalletSeed(w.Filename(), nil)
+	require.Equal(t, wallet.ErrWalletNoSeed, err)
+
+	err = s.UpdateSecrets(w.Filename(), nil, func(wallet.Wallet) error { return nil })
+	require.Equal(t, wallet.ErrWatchOnlyWallet, err)
+
+	err = s.ViewSecrets(w.Filename(), nil, func(wallet.Wallet) error { return nil })
+	require.Equal(t, wallet.ErrWatchOnlyWallet, err)
+
+	_, err = s.NewAddresses(w.Filename(), nil, wallet.OptionGenerateN(1))
+	require.Equal(t, wallet.ErrWatchOnlyWallet, err)
+
+	_, err = s.EncryptWallet(w.Filename(), []byte("pwd"))
+	require.Equal(t, wallet.ErrWatchOnlyWallet, err)
+}
+
+func TestServiceImportWallet(t *testing.T) {
+	srcDir := prepareWltDir()
+	src, err := wallet.NewService(wallet.Config{
+		WalletDir:       srcDir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := src.CreateWallet("t.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.NoError(t, err)
+
+	cw, err := src.CreateWallet("c.wlt", wallet.Options{
+		Type:  wallet.WalletTypeCollection,
+		Label: "collection",
+	})
+	require.NoError(t, err)
+
+	dir := prepareWltDir()
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	iw, err := s.ImportWallet(filepath.Join(srcDir, w.Filename()))
+	require.NoError(t, err)
+	require.Equal(t, w.Filename(), iw.Filename())
+	require.Equal(t, w.Fingerprint(), iw.Fingerprint())
+	_, err = os.Stat(filepath.Join(dir, w.Filename()))
+	require.NoError(t, err)
+
+	addrs, err := src.GetAddresses(w.Filename())
+	require.NoError(t, err)
+	importedAddrs, err := s.GetAddresses(iw.Filename())
+	require.NoError(t, err)
+	require.Equal(t, addrs, importedAddrs)
+
+	// A wallet with the same seed can't be imported twice
+	_, err = s.ImportWallet(filepath.Join(srcDir, w.Filename()))
+	require.Equal(t, wallet.ErrSeedUsed, err)
+
+	// A filename collision is resolved by generating a new filename
+	_, err = s.CreateWallet(cw.Filename(), wallet.Options{
+		Type:  wallet.WalletTypeCollection,
+		Label: "collection",
+	})
+	require.NoError(t, err)
+	icw, err := s.ImportWallet(filepath.Join(srcDir, cw.Filename()))
+	require.NoError(t, err)
+	require.NotEqual(t, cw.Filename(), icw.Filename())
+	_, err = os.Stat(filepath.Join(dir, icw.Filename()))
+	require.NoError(t, err)
+
+	// The imported wallets load on startup
+	s2, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+	wlts, err := s2.GetWallets()
+	require.NoError(t, err)
+	require.Len(t, wlts, 3)
+
+	_, err = s.ImportWallet(filepath.Join(srcDir, "foo.wlt"))
+	require.Error(t, err)
+
+	s.SetEnableWalletAPI(false)
+	_, err = s.ImportWallet(filepath.Join(srcDir, w.Filename()))
+	require.Equal(t, wallet.ErrWalletAPIDisabled, err)
+}
+
+func TestServiceExportWallet(t *testing.T) {
+	dir := prepareWltDir()
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     "seed",
+		Label:    "label",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+
+	exportDir := prepareWltDir()
+	dest := filepath.Join(exportDir, "backup.wlt")
+	require.NoError(t, s.ExportWallet(w.Filename(), dest, false))
+
+	// The exported wallet stays encrypted
+	ew, err := wallet.Load(dest)
+	require.NoError(t, err)
+	require.True(t, ew.IsEncrypted())
+	require.Empty(t, ew.Seed())
+	require.Equal(t, w.Fingerprint(), ew.Fingerprint())
+
+	// An existing file is not overwritten without the overwrite flag
+	require.NoError(t, s.UpdateWalletLabel(w.Filename(), "label2"))
+	require.Equal(t, wallet.ErrExportFileExists, s.ExportWallet(w.Filename(), dest, false))
+	ew, err = wallet.Load(dest)
+	require.NoError(t, err)
+	require.Equal(t, "label", ew.Label())
+
+	require.NoError(t, s.ExportWallet(w.Filename(), dest, true))
+	ew, err = wallet.Load(dest)
+	require.NoError(t, err)
+	require.Equal(t, "label2", ew.Label())
+
+	require.Equal(t, wallet.ErrWalletNotExist, s.ExportWallet("foo.wlt", dest, true))
+
+	s.SetEnableWalletAPI(false)
+	require.Equal(t, wallet.ErrWalletAPIDisabled, s.ExportWallet(w.Filename(), dest, true))
+}
+
+func TestServiceExportImportAll(t *testing.T) {
+	srcDir := prepareWltDir()
+	src, err := wallet.NewService(wallet.Config{
+		WalletDir:       srcDir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := src.CreateWallet("t.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     "seed",
+		Label:    "label",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+
+	cw, err := src.CreateWallet("c.wlt", wallet.Options{
+		Type:  wallet.WalletTypeCollection,
+		Label: "collection",
+	})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, src.ExportAll(&buf))
+	backup := buf.Bytes()
+
+	// The backup keeps the wallets encrypted
+	require.NotContains(t, string(backup), `"seed": "seed"`)
+
+	dir := prepareWltDir()
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	var events []wallet.WalletEvent
+	s.OnWalletChange(func(_ string, event wallet.WalletEvent) {
+		events = append(events, event)
+	})
+
+	require.NoError(t, s.ImportAll(bytes.NewReader(backup), false))
+	require.Equal(t, []wallet.WalletEvent{wallet.WalletEventCreated, wallet.WalletEventCreated}, events)
+
+	for _, id := range []string{w.Filename(), cw.Filename()} {
+		_, err := os.Stat(filepath.Join(dir, id))
+		require.NoError(t, err)
+	}
+
+	iw, err := s.GetWallet(w.Filename())
+	require.NoError(t, err)
+	require.True(t, iw.IsEncrypted())
+	require.Equal(t, w.Fingerprint(), iw.Fingerprint())
+	require.NoError(t, s.ViewSecrets(w.Filename(), []byte("pwd"), func(w wallet.Wallet) error {
+		require.Equal(t, "seed", w.Seed())
+		return nil
+	}))
+
+	icw, err := s.GetWallet(cw.Filename())
+	require.NoError(t, err)
+	require.Equal(t, "collection", icw.Label())
+
+	// Existing wallets are not replaced without the overwrite flag
+	err = s.ImportAll(bytes.NewReader(backup), false)
+	require.Equal(t, wallet.ErrBackupWalletExists, err)
+
+	require.NoError(t, s.UpdateWalletLabel(cw.Filename(), "changed"))
+	events = nil
+	require.NoError(t, s.ImportAll(bytes.NewReader(backup), true))
+	require.Equal(t, []wallet.WalletEvent{wallet.WalletEventUpdated, wallet.WalletEventUpdated}, events)
+	icw, err = s.GetWallet(cw.Filename())
+	require.NoError(t, err)
+	require.Equal(t, "collection", icw.Label())
+
+	// The imported wallets load on startup
+	s2, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+	wlts, err := s2.GetWallets()
+	require.NoError(t, err)
+	require.Len(t, wlts, 2)
+
+	t.Run("duplicate seed", func(t *testing.T) {
+		dir := prepareWltDir()
+		s, err := wallet.NewService(wallet.Config{
+			WalletDir:       dir,
+			CryptoType:      crypto.CryptoTypeSha256Xor,
+			EnableWalletAPI: true,
+		})
+		require.NoError(t, err)
+
+		_, err = s.CreateWallet("other.wlt", wallet.Options{
+			Type:  wallet.WalletTypeDeterministic,
+			Seed:  "seed",
+			Label: "label",
+		})
+		require.NoError(t, err)
+
+		err = s.ImportAll(bytes.NewReader(backup), false)
+		_, ok := err.(wallet.DuplicateWalletError)
+		require.True(t, ok, "%v", err)
+
+		// Nothing is imported
+		wlts, err := s.GetWallets()
+		require.NoError(t, err)
+		require.Len(t, wlts, 1)
+		_, err = os.Stat(filepath.Join(dir, cw.Filename()))
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("invalid filename", func(t *testing.T) {
+		data := strings.Replace(string(backup), `"filename": "c.wlt"`, `"filename": "../c.wlt"`, 1)
+		s, err := wallet.NewService(wallet.Config{
+			WalletDir:       prepareWltDir(),
+			EnableWalletAPI: true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, wallet.ErrInvalidWalletFilename, s.ImportAll(strings.NewReader(data), false))
+	})
+
+	t.Run("invalid document", func(t *testing.T) {
+		s, err := wallet.NewService(wallet.Config{
+			WalletDir:       prepareWltDir(),
+			EnableWalletAPI: true,
+		})
+		require.NoError(t, err)
+		require.Error(t, s.ImportAll(strings.NewReader("{"), false))
+		require.Error(t, s.ImportAll(strings.NewReader(`{"version":"0","wallets":[]}`), false))
+	})
+
+	t.Run("read only", func(t *testing.T) {
+		s, err := wallet.NewService(wallet.Config{
+			WalletDir:       prepareWltDir(),
+			EnableWalletAPI: true,
+			ReadOnly:        true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, wallet.ErrWalletReadOnly, s.ImportAll(bytes.NewReader(backup), false))
+	})
+
+	src.SetEnableWalletAPI(false)
+	require.Equal(t, wallet.ErrWalletAPIDisabled, src.ExportAll(&buf))
+}
+
+func TestServiceBatchCreateTransactions(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+	dir := prepareWltDir()
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w1, err := s.CreateWallet("t1.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed1",
+		Label: "label1",
+	})
+	require.NoError(t, err)
+	e1, err := w1.GetEntryAt(0)
+	require.NoError(t, err)
+
+	w2, err := s.CreateWallet("t2.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     "seed2",
+		Label:    "label2",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+	var e2 wallet.Entry
+	require.NoError(t, s.ViewSecrets(w2.Filename(), []byte("pwd"), func(w wallet.Wallet) error {
+		var err error
+		e2, err = w.GetEntryAt(0)
+		return err
+	}))
+
+	makeUxOuts := func(e wallet.Entry, n int, coins uint64) []coin.UxOut {
+		var uxouts []coin.UxOut
+		for i := 0; i < n; i++ {
+			uxout := makeUxOut(t, e.Secret, coins, 100)
+			uxout.Head.Time = headTime
+			uxout.Head.BkSeq = uint64(i + 1)
+			uxouts = append(uxouts, uxout)
+		}
+		return uxouts
+	}
+
+	auxs := coin.AddressUxOuts{
+		e1.SkycoinAddress(): makeUxOuts(e1, 3, 2e6),
+		e2.SkycoinAddress(): makeUxOuts(e2, 1, 5e6),
+	}
+
+	makeParams := func(coins uint64) transaction.Params {
+		changeAddr := makeAddress()
+		return transaction.Params{
+			HoursSelection: transaction.HoursSelection{
+				Type: transaction.HoursSelectionTypeManual,
+			},
+			ChangeAddress: &changeAddr,
+			To: []coin.TransactionOutput{
+				{
+					Address: makeAddress(),
+					Coins:   coins,
+					Hours:   1,
+				},
+			},
+		}
+	}
+
+	paramsList := []wallet.CreateTransactionParams{
+		{WalletID: w1.Filename(), Params: makeParams(3e6)},
+		{WalletID: w1.Filename(), Params: makeParams(2e6)},
+		{WalletID: w1.Filename(), Params: makeParams(1e6)},
+		{WalletID: w2.Filename(), Password: []byte("wrong"), Params: makeParams(1e6)},
+		{WalletID: w2.Filename(), Password: []byte("pwd"), Params: makeParams(1e6)},
+		{WalletID: "foo.wlt", Params: makeParams(1e6)},
+	}
+
+	txns, inputs, err := s.BatchCreateTransactions(paramsList, auxs, headTime)
+	require.Error(t, err)
+	batchErr, ok := err.(wallet.BatchError)
+	require.True(t, ok)
+	require.Len(t, batchErr.Errors, 3)
+	require.Equal(t, transaction.ErrNoUnspents, batchErr.Errors[2])
+	require.Equal(t, wallet.ErrInvalidPassword, batchErr.Errors[3])
+	require.Equal(t, wallet.ErrWalletNotExist, batchErr.Errors[5])
+
+	require.Len(t, txns, len(paramsList))
+	require.Len(t, inputs, len(paramsList))
+
+	// No output is spent twice across the batch
+	seen := make(map[cipher.SHA256]struct{})
+	for i, txn := range txns {
+		if _, failed := batchErr.Errors[i]; failed {
+			require.Nil(t, txn)
+			require.Nil(t, inputs[i])
+			continue
+		}
+
+		require.NotNil(t, txn)
+		require.Len(t, txn.In, len(inputs[i]))
+		for _, in := range txn.In {
+			_, ok := seen[in]
+			require.False(t, ok)
+			seen[in] = struct{}{}
+		}
+	}
+	require.Len(t, inputs[0], 2)
+	require.Len(t, inputs[1], 1)
+	require.Len(t, inputs[4], 1)
+
+	// A fully successful batch returns no error
+	txns, _, err = s.BatchCreateTransactions(paramsList[:2], auxs, headTime)
+	require.NoError(t, err)
+	require.Len(t, txns, 2)
+
+	s.SetEnableWalletAPI(false)
+	_, _, err = s.BatchCreateTransactions(paramsList, auxs, headTime)
+	require.Equal(t, wallet.ErrWalletAPIDisabled, err)
+}
+
+func TestNewServiceRecoverTempFiles(t *testing.T) {
+	dir := prepareWltDir()
+	c := wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	}
+	s, err := wallet.NewService(c)
+	require.NoError(t, err)
+
+	for _, name := range []string{"a.wlt", "b.wlt"} {
+		_, err := s.CreateWallet(name, wallet.Options{
+			Type:  wallet.WalletTypeDeterministic,
+			Seed:  name,
+			Label: "old",
+		})
+		require.NoError(t, err)
+	}
+
+	readNewLabel := func(name string) []byte {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		return []byte(strings.Replace(string(data), `"old"`, `"new"`, 1))
+	}
+
+	// a.wlt is intact, the interrupted save is discarded
+	aTmp := filepath.Join(dir, "a.wlt.tmp.00000000")
+	require.NoError(t, ioutil.WriteFile(aTmp, readNewLabel("a.wlt"), 0600))
+
+	// b.wlt was truncated, the interrupted save is completed
+	bData := readNewLabel("b.wlt")
+	bTmp := filepath.Join(dir, "b.wlt.tmp.00000000")
+	require.NoError(t, ioutil.WriteFile(bTmp, bData, 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "b.wlt"), bData[:len(bData)/2], 0600))
+
+	// c.wlt was never written and its temp file is incomplete, so it is discarded
+	cTmp := filepath.Join(dir, "c.wlt.tmp.00000000")
+	require.NoError(t, ioutil.WriteFile(cTmp, bData[:len(bData)/2], 0600))
+
+	s, err = wallet.NewService(c)
+	require.NoError(t, err)
+
+	testutil.RequireFileNotExists(t, aTmp)
+	testutil.RequireFileNotExists(t, bTmp)
+	testutil.RequireFileNotExists(t, cTmp)
+	testutil.RequireFileNotExists(t, filepath.Join(dir, "c.wlt"))
+
+	w, err := s.GetWallet("a.wlt")
+	require.NoError(t, err)
+	require.Equal(t, "old", w.Label())
+
+	w, err = s.GetWallet("b.wlt")
+	require.NoError(t, err)
+	require.Equal(t, "new", w.Label())
+
+	// Saving leaves no temp file behind
+	require.NoError(t, s.UpdateWalletLabel("a.wlt", "new"))
+	fs, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	for _, f := range fs {
+		require.False(t, strings.Contains(f.Name(), ".tmp."), f.Name())
+	}
+}
+
+func TestServiceVerifyPassword(t *testing.T) {
+	dir := prepareWltDir()
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     "seed",
+		Label:    "label",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+
+	uw, err := s.CreateWallet("u.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed2",
+		Label: "label",
+	})
+	require.NoError(t, err)
+
+	wf := filepath.Join(dir, w.Filename())
+	before, err := ioutil.ReadFile(wf)
+	require.NoError(t, err)
+
+	require.NoError(t, s.VerifyPassword(w.Filename(), []byte("pwd")))
+	require.Equal(t, wallet.ErrInvalidPassword, s.VerifyPassword(w.Filename(), []byte("wrong")))
+	require.Equal(t, wallet.ErrMissingPassword, s.VerifyPassword(w.Filename(), nil))
+	require.Equal(t, wallet.ErrWalletNotEncrypted, s.VerifyPassword(uw.Filename(), []byte("pwd")))
+	require.Equal(t, wallet.ErrWalletNotExist, s.VerifyPassword("foo.wlt", []byte("pwd")))
+
+	// The wallet is not modified, in memory or on disk
+	after, err := ioutil.ReadFile(wf)
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+	w2, err := s.GetWallet(w.Filename())
+	require.NoError(t, err)
+	require.True(t, w2.IsEncrypted())
+	require.Empty(t, w2.Seed())
+
+	s.SetEnableWalletAPI(false)
+	require.Equal(t, wallet.ErrWalletAPIDisabled, s.VerifyPassword(w.Filename(), []byte("pwd")))
+}
+
+func TestServiceEncryptWalletWithCryptoType(t *testing.T) {
+	dir := prepareWltDir()
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.NoError(t, err)
+
+	_, err = s.EncryptWalletWithCryptoType(w.Filename(), []byte("pwd"), crypto.CryptoType("foo"))
+	require.Error(t, err)
+
+	ew, err := s.EncryptWalletWithCryptoType(w.Filename(), []byte("pwd"), crypto.CryptoTypeArgon2idChacha20poly1305)
+	require.NoError(t, err)
+	require.True(t, ew.IsEncrypted())
+	require.Equal(t, crypto.CryptoTypeArgon2idChacha20poly1305, ew.CryptoType())
+
+	// The crypto type is recorded in the wallet file
+	lw, err := wallet.Load(filepath.Join(dir, w.Filename()))
+	require.NoError(t, err)
+	require.Equal(t, crypto.CryptoTypeArgon2idChacha20poly1305, lw.CryptoType())
+
+	require.NoError(t, s.VerifyPassword(w.Filename(), []byte("pwd")))
+	require.Equal(t, wallet.ErrInvalidPassword, s.VerifyPassword(w.Filename(), []byte("wrong")))
+
+	dw, err := s.DecryptWallet(w.Filename(), []byte("pwd"))
+	require.NoError(t, err)
+	require.Equal(t, "seed", dw.Seed())
+}
+
+func TestServiceCreateWalletScryptParams(t *testing.T) {
+	dir := prepareWltDir()
+	c := wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeScryptChacha20poly1305,
+		EnableWalletAPI: true,
+	}
+	s, err := wallet.NewService(c)
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("t.wlt", wallet.Options{
+		Type:         wallet.WalletTypeDeterministic,
+		Seed:         "seed",
+		Label:        "label",
+		Encrypt:      true,
+		Password:     []byte("pwd"),
+		ScryptParams: &wallet.ScryptParams{N: 1 << 10, R: 8, P: 1},
+	})
+	require.Equal(t, wallet.ErrInvalidScryptParams, err)
+
+	params := wallet.ScryptParams{N: 1 << 14, R: 8, P: 1}
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:         wallet.WalletTypeDeterministic,
+		Seed:         "seed",
+		Label:        "label",
+		Encrypt:      true,
+		Password:     []byte("pwd"),
+		ScryptParams: &params,
+	})
+	require.NoError(t, err)
+	require.True(t, w.IsEncrypted())
+
+	// The parameters are stored in the wallet file
+	lw, err := wallet.Load(filepath.Join(dir, w.Filename()))
+	require.NoError(t, err)
+	p, err := lw.(interface {
+		ScryptParams() (*wallet.ScryptParams, error)
+	}).ScryptParams()
+	require.NoError(t, err)
+	require.Equal(t, &params, p)
+
+	// The wallet is unlocked by another service, the parameters are read from the encrypted data
+	s2, err := wallet.NewService(c)
+	require.NoError(t, err)
+	require.NoError(t, s2.VerifyPassword(w.Filename(), []byte("pwd")))
+
+	// The parameters are reused when the wallet is encrypted again
+	_, err = s2.DecryptWallet(w.Filename(), []byte("pwd"))
+	require.NoError(t, err)
+	_, err = s2.EncryptWallet(w.Filename(), []byte("pwd2"))
+	require.NoError(t, err)
+	lw, err = wallet.Load(filepath.Join(dir, w.Filename()))
+	require.NoError(t, err)
+	p, err = lw.(interface {
+		ScryptParams() (*wallet.ScryptParams, error)
+	}).ScryptParams()
+	require.NoError(t, err)
+	require.Equal(t, &params, p)
+	require.NoError(t, s2.VerifyPassword(w.Filename(), []byte("pwd2")))
+}
+
+type fakeBalanceGetter map[cipher.Address]wallet.BalancePair
+
+func (bg fakeBalanceGetter) GetBalanceOfAddresses(addrs []cipher.Address) ([]wallet.BalancePair, error) {
+	bps := make([]wallet.BalancePair, len(addrs))
+	for i, a := range addrs {
+		bps[i] = bg[a]
+	}
+	return bps, nil
+}
+
+type errBalanceGetter struct{}
+
+func (errBalanceGetter) GetBalanceOfAddresses(addrs []cipher.Address) ([]wallet.BalancePair, error) {
+	return nil, errors.New("balance error")
+}
+
+func TestServiceGetBalance(t *testing.T) {
+	dir := prepareWltDir()
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "seed",
+		Label:     "label",
+		GenerateN: 3,
+		Encrypt:   true,
+		Password:  []byte("pwd"),
+	})
+	require.NoError(t, err)
+
+	addrs, err := s.GetAddresses(w.Filename())
+	require.NoError(t, err)
+	require.Len(t, addrs, 3)
+
+	bg := fakeBalanceGetter{
+		addrs[0]: {
+			Confirmed: wallet.NewBalance(1e6, 10),
+			Predicted: wallet.NewBalance(2e6, 20),
+		},
+		addrs[2]: {
+			Confirmed: wallet.NewBalance(3e6, 30),
+			Predicted: wallet.NewBalance(1e6, 5),
+		},
+	}
+
+	// The wallet is encrypted, no password is needed
+	total, addrBalances, err := s.GetBalance(w.Filename(), bg)
+	require.NoError(t, err)
+	require.Equal(t, wallet.BalancePair{
+		Confirmed: wallet.NewBalance(4e6, 40),
+		Predicted: wallet.NewBalance(3e6, 25),
+	}, total)
+	require.Equal(t, []wallet.BalancePair{bg[addrs[0]], {}, bg[addrs[2]]}, addrBalances)
+
+	_, _, err = s.GetBalance(w.Filename(), errBalanceGetter{})
+	require.EqualError(t, err, "balance error")
+
+	_, _, err = s.GetBalance("foo.wlt", bg)
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+
+	s.SetEnableWalletAPI(false)
+	_, _, err = s.GetBalance(w.Filename(), bg)
+	require.Equal(t, wallet.ErrWalletAPIDisabled, err)
+}
+
+func TestServiceSetAddressLabel(t *testing.T) {
+	tt := []struct {
+		name       string
+		walletType string
+		seed       string
+	}{
+		{
+			name:       "deterministic",
+			walletType: wallet.WalletTypeDeterministic,
+			seed:       "seed",
+		},
+		{
+			name:       "bip44",
+			walletType: wallet.WalletTypeBip44,
+			seed:       bip39.MustNewDefaultMnemonic(),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := prepareWltDir()
+			s, err := wallet.NewService(wallet.Config{
+				WalletDir:       dir,
+				CryptoType:      crypto.CryptoTypeSha256Xor,
+				EnableWalletAPI: true,
+			})
+			require.NoError(t, err)
+
+			w, err := s.CreateWallet("t.wlt", wallet.Options{
+				Type:      tc.walletType,
+				Seed:      tc.seed,
+				Label:     "label",
+				GenerateN: 2,
+			})
+			require.NoError(t, err)
+
+			addrs, err := s.GetAddresses(w.Filename())
+			require.NoError(t, err)
+			require.True(t, len(addrs) >= 2)
+
+			require.NoError(t, s.SetAddressLabel(w.Filename(), addrs[1], "savings"))
+
+			checkLabels := func(w wallet.Wallet) {
+				entries, err := w.GetEntries()
+				require.NoError(t, err)
+				require.Len(t, entries, len(addrs))
+				for i, e := range entries {
+					if i == 1 {
+						require.Equal(t, "savings", e.Label)
+					} else {
+						require.Empty(t, e.Label)
+					}
+				}
+			}
+
+			w, err = s.GetWallet(w.Filename())
+			require.NoError(t, err)
+			checkLabels(w)
+
+			// The label is persisted
+			lw, err := wallet.Load(filepath.Join(dir, w.Filename()))
+			require.NoError(t, err)
+			checkLabels(lw)
+
+			// Clears the label
+			require.NoError(t, s.SetAddressLabel(w.Filename(), addrs[1], ""))
+			w, err = s.GetWallet(w.Filename())
+			require.NoError(t, err)
+			e, err := w.GetEntry(addrs[1])
+			require.NoError(t, err)
+			require.Empty(t, e.Label)
+
+			err = s.SetAddressLabel(w.Filename(), testutil.MakeAddress(), "foo")
+			require.Equal(t, wallet.ErrEntryNotFound, err)
+
+			err = s.SetAddressLabel("foo.wlt", addrs[0], "foo")
+			require.Equal(t, wallet.ErrWalletNotExist, err)
+
+			s.SetEnableWalletAPI(false)
+			err = s.SetAddressLabel(w.Filename(), addrs[0], "foo")
+			require.Equal(t, wallet.ErrWalletAPIDisabled, err)
+		})
+	}
+}
+
+func TestLoadWalletWithoutLabels(t *testing.T) {
+	for _, f := range []string{"test1.wlt", "test4-collection.wlt", "test5-bip44.wlt", "xpub-test.wlt"} {
+		t.Run(f, func(t *testing.T) {
+			w, err := wallet.Load(filepath.Join("./testdata", f))
+			require.NoError(t, err)
+
+			entries, err := w.GetEntries()
+			require.NoError(t, err)
+			require.NotEmpty(t, entries)
+			for _, e := range entries {
+				require.Empty(t, e.Label)
+			}
+		})
+	}
+}
+
+func TestServiceRecoverWalletCoinType(t *testing.T) {
+	dir := prepareWltDir()
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+		EnableSeedAPI:   true,
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("bad.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Coin:  wallet.CoinType("foo"),
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.Equal(t, wallet.ErrInvalidCoinType, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Coin:      wallet.CoinTypeBitcoin,
+		Seed:      "seed",
+		Label:     "label",
+		GenerateN: 2,
+		Encrypt:   true,
+		Password:  []byte("pwd"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, wallet.CoinTypeBitcoin, w.Coin())
+
+	addrs, err := w.GetAddresses()
+	require.NoError(t, err)
+	for _, a := range addrs {
+		_, ok := a.(cipher.BitcoinAddress)
+		require.True(t, ok)
+	}
+
+	// The coin type is kept in the wallet file
+	lw, err := wallet.Load(filepath.Join(dir, w.Filename()))
+	require.NoError(t, err)
+	require.Equal(t, wallet.CoinTypeBitcoin, lw.Coin())
+
+	// Recovering regenerates the addresses with the coin type of the wallet
+	require.NoError(t, s.RecoverWalletDryRun(w.Filename(), "seed", ""))
+	rw, err := s.RecoverWallet(w.Filename(), "seed", "", []byte("pwd"))
+	require.NoError(t, err)
+	require.Equal(t, wallet.CoinTypeBitcoin, rw.Coin())
+	raddrs, err := rw.GetAddresses()
+	require.NoError(t, err)
+	require.Equal(t, addrs, raddrs)
+}
+
+func TestServiceRecoverWalletAddressType(t *testing.T) {
+	dir := prepareWltDir()
+	c := wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+		EnableSeedAPI:   true,
+	}
+	s, err := wallet.NewService(c)
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("bad.wlt", wallet.Options{
+		Type:        wallet.WalletTypeDeterministic,
+		AddressType: wallet.AddressTypeBitcoin,
+		Seed:        "seed",
+		Label:       "label",
+	})
+	require.Equal(t, wallet.ErrAddressTypeCoinMismatch, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:        wallet.WalletTypeDeterministic,
+		Coin:        wallet.CoinTypeBitcoin,
+		AddressType: wallet.AddressTypeBitcoin,
+		Seed:        "seed",
+		Label:       "label",
+		GenerateN:   2,
+		Encrypt:     true,
+		Password:    []byte("pwd"),
+	})
+	require.NoError(t, err)
+
+	addrs, err := w.GetAddresses()
+	require.NoError(t, err)
+	for _, a := range addrs {
+		_, ok := a.(cipher.BitcoinAddress)
+		require.True(t, ok)
+	}
+
+	addressType := func(w wallet.Wallet) wallet.AddressType {
+		return w.(interface{ AddressType() wallet.AddressType }).AddressType()
+	}
+
+	// The address type is kept in the wallet file
+	lw, err := wallet.Load(filepath.Join(dir, w.Filename()))
+	require.NoError(t, err)
+	require.Equal(t, wallet.AddressTypeBitcoin, addressType(lw))
+
+	w, err = s.GetWallet(w.Filename())
+	require.NoError(t, err)
+	require.Equal(t, wallet.AddressTypeBitcoin, addressType(w))
+
+	// Recovering regenerates the addresses with the address type of the wallet
+	require.NoError(t, s.RecoverWalletDryRun(w.Filename(), "seed", ""))
+	rw, err := s.RecoverWallet(w.Filename(), "seed", "", []byte("pwd"))
+	require.NoError(t, err)
+	require.Equal(t, wallet.AddressTypeBitcoin, addressType(rw))
+	raddrs, err := rw.GetAddresses()
+	require.NoError(t, err)
+	require.Equal(t, addrs, raddrs)
+
+	lw, err = wallet.Load(filepath.Join(dir, w.Filename()))
+	require.NoError(t, err)
+	require.Equal(t, wallet.AddressTypeBitcoin, addressType(lw))
+
+	// The address type is read from the wallets loaded from the wallet directory
+	_, err = s.CreateWallet("s.wlt", wallet.Options{
+		Type:        wallet.WalletTypeDeterministic,
+		AddressType: wallet.AddressTypeSkycoin,
+		Seed:        "seed2",
+		Label:       "label",
+	})
+	require.NoError(t, err)
+	// The service only loads skycoin wallets from the wallet directory
+	require.NoError(t, s.DeleteWallet(w.Filename()))
+
+	s, err = wallet.NewService(c)
+	require.NoError(t, err)
+	sw, err := s.GetWallet("s.wlt")
+	require.NoError(t, err)
+	require.Equal(t, wallet.AddressTypeSkycoin, addressType(sw))
+}
+
+func TestServiceRecoverWalletSeedType(t *testing.T) {
+	dir := prepareWltDir()
+	c := wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+		EnableSeedAPI:   true,
+	}
+	s, err := wallet.NewService(c)
+	require.NoError(t, err)
+
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	// The words are valid, but the checksum is not
+	badMnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon"
+	require.Equal(t, wallet.ErrInvalidMnemonic, wallet.ValidateMnemonic(badMnemonic))
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     mnemonic,
+		SeedType: wallet.SeedTypeMnemonic,
+		Label:    "label",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+
+	// A raw seed is not checked as a mnemonic
+	rw, err := s.CreateWallet("raw.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     badMnemonic,
+		SeedType: wallet.SeedTypeRaw,
+		Label:    "label",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+
+	// The seed type is kept in the wallet file
+	lw, err := wallet.Load(filepath.Join(dir, w.Filename()))
+	require.NoError(t, err)
+	require.Equal(t, wallet.SeedTypeMnemonic, lw.(interface{ SeedType() string }).SeedType())
+
+	require.Equal(t, wallet.ErrInvalidMnemonic, s.RecoverWalletDryRun(w.Filename(), badMnemonic, ""))
+	_, err = s.RecoverWallet(w.Filename(), badMnemonic, "", []byte("pwd"))
+	require.Equal(t, wallet.ErrInvalidMnemonic, err)
+	require.NoError(t, s.RecoverWalletDryRun(w.Filename(), mnemonic, ""))
+
+	require.Equal(t, wallet.ErrWalletRecoverSeedWrong, s.RecoverWalletDryRun(rw.Filename(), mnemonic, ""))
+	require.NoError(t, s.RecoverWalletDryRun(rw.Filename(), badMnemonic, ""))
+
+	// The seed type is read from the wallets loaded from the wallet directory
+	s, err = wallet.NewService(c)
+	require.NoError(t, err)
+	require.Equal(t, wallet.ErrInvalidMnemonic, s.RecoverWalletDryRun(w.Filename(), badMnemonic, ""))
+
+	// The recovered wallet keeps the seed type
+	w2, err := s.RecoverWallet(w.Filename(), mnemonic, "", []byte("pwd"))
+	require.NoError(t, err)
+	require.Equal(t, wallet.SeedTypeMnemonic, w2.(interface{ SeedType() string }).SeedType())
+}
+
+func TestServiceRecoverWalletDryRun(t *testing.T) {
+	dir := prepareWltDir()
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+		EnableSeedAPI:   true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "seed",
+		Label:     "label",
+		GenerateN: 3,
+		Encrypt:   true,
+		Password:  []byte("pwd"),
+	})
+	require.NoError(t, err)
+
+	uw, err := s.CreateWallet("u.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed2",
+		Label: "label",
+	})
+	require.NoError(t, err)
+
+	wf := filepath.Join(dir, w.Filename())
+	before, err := ioutil.ReadFile(wf)
+	require.NoError(t, err)
+
+	require.NoError(t, s.RecoverWalletDryRun(w.Filename(), "seed", ""))
+	require.Equal(t, wallet.ErrWalletRecoverSeedWrong, s.RecoverWalletDryRun(w.Filename(), "seed2", ""))
+	require.Equal(t, wallet.ErrWalletNotEncrypted, s.RecoverWalletDryRun(uw.Filename(), "seed2", ""))
+	require.Equal(t, wallet.ErrWalletNotExist, s.RecoverWalletDryRun("foo.wlt", "seed", ""))
+
+	// The wallet is not modified, in memory or on disk
+	after, err := ioutil.ReadFile(wf)
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+	w2, err := s.GetWallet(w.Filename())
+	require.NoError(t, err)
+	require.True(t, w2.IsEncrypted())
+	require.Equal(t, w.Timestamp(), w2.Timestamp())
+	l, err := w2.EntriesLen()
+	require.NoError(t, err)
+	require.Equal(t, 3, l)
+
+	s.SetEnableWalletAPI(false)
+	require.Equal(t, wallet.ErrWalletAPIDisabled, s.RecoverWalletDryRun(w.Filename(), "seed", ""))
+}
+
+func TestServiceCreateUnsignedTransactionSignOffline(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+
+	// The offline service holds the keys
+	offline, err := wallet.NewService(wallet.Config{
+		WalletDir:       prepareWltDir(),
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	ow, err := offline.CreateWallet("t.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     "seed",
+		Label:    "label",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+	var e wallet.Entry
+	require.NoError(t, offline.ViewSecrets(ow.Filename(), []byte("pwd"), func(w wallet.Wallet) error {
+		var err error
+		e, err = w.GetEntryAt(0)
+		return err
+	}))
+
+	// The online service only knows the public key
+	online, err := wallet.NewService(wallet.Config{
+		WalletDir:       prepareWltDir(),
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	ww, err := online.CreateWallet("t.wlt", wallet.Options{
+		Type:                wallet.WalletTypeWatchOnly,
+		Label:               "watch",
+		WatchOnlyPublicKeys: []cipher.PubKey{e.Public},
+	})
+	require.NoError(t, err)
+
+	var uxouts []coin.UxOut
+	for i := 0; i < 2; i++ {
+		uxout := makeUxOut(t, e.Secret, 2e6, 100)
+		uxout.Head.Time = headTime
+		uxout.Head.BkSeq = uint64(i + 1)
+		uxouts = append(uxouts, uxout)
+	}
+	auxs := coin.AddressUxOuts{e.SkycoinAddress(): uxouts}
+
+	changeAddr := makeAddress()
+	params := wallet.CreateTransactionParams{
+		WalletID: ww.Filename(),
+		Params: transaction.Params{
+			HoursSelection: transaction.HoursSelection{
+				Type: transaction.HoursSelectionTypeManual,
+			},
+			ChangeAddress: &changeAddr,
+			To: []coin.TransactionOutput{
+				{
+					Address: makeAddress(),
+					Coins:   3e6,
+					Hours:   1,
+				},
+			},
+		},
+	}
+
+	txn, inputs, err := online.CreateUnsignedTransaction(params, auxs, headTime)
+	require.NoError(t, err)
+	require.True(t, txn.IsFullyUnsigned())
+	require.NoError(t, txn.VerifyUnsigned())
+	require.Len(t, inputs, 2)
+
+	// Move the unsigned transaction to the offline machine
+	b := wallet.UnsignedTransaction{
+		Txn:    *txn,
+		Inputs: inputs,
+	}.Serialize()
+	ut, err := wallet.DeserializeUnsignedTransaction(b)
+	require.NoError(t, err)
+	require.Equal(t, txn.Hash(), ut.Txn.Hash())
+	require.Equal(t, inputs, ut.Inputs)
+
+	_, err = offline.SignTransaction(ow.Filename(), []byte("wrong"), &ut.Txn, nil, ut.Inputs)
+	require.Equal(t, wallet.ErrInvalidPassword, err)
+
+	_, err = offline.SignTransaction(ow.Filename(), []byte("pwd"), &ut.Txn, nil, ut.Inputs[:1])
+	require.Equal(t, wallet.ErrTransactionInputsMismatch, err)
+
+	badInputs := append([]transaction.UxBalance{}, ut.Inputs...)
+	badInputs[0].Coins++
+	_, err = offline.SignTransaction(ow.Filename(), []byte("pwd"), &ut.Txn, nil, badInputs)
+	require.Equal(t, wallet.ErrTransactionInputsMismatch, err)
+
+	// The watch-only wallet can't sign
+	_, err = online.SignTransaction(ww.Filename(), nil, &ut.Txn, nil, ut.Inputs)
+	require.Equal(t, wallet.ErrWalletCantSign, err)
+
+	signedTxn, err := offline.SignTransaction(ow.Filename(), []byte("pwd"), &ut.Txn, nil, ut.Inputs)
+	require.NoError(t, err)
+	require.True(t, signedTxn.IsFullySigned())
+	require.Equal(t, txn.InnerHash, signedTxn.InnerHash)
+	uxIn := make(coin.UxArray, len(signedTxn.In))
+	for i, h := range signedTxn.In {
+		for _, ux := range uxouts {
+			if ux.Hash() == h {
+				uxIn[i] = ux
+			}
+		}
+	}
+	require.NoError(t, signedTxn.VerifyInputSignatures(uxIn))
+
+	// The unsigned transaction is not modified
+	require.True(t, ut.Txn.IsFullyUnsigned())
+
+	online.SetEnableWalletAPI(false)
+	_, _, err = online.CreateUnsignedTransaction(params, auxs, headTime)
+	require.Equal(t, wallet.ErrWalletAPIDisabled, err)
+
+	offline.SetEnableWalletAPI(false)
+	_, err = offline.SignTransaction(ow.Filename(), []byte("pwd"), &ut.Txn, nil, ut.Inputs)
+	require.Equal(t, wallet.ErrWalletAPIDisabled, err)
+}
+
+func TestServiceSignTransactionCoSign(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       prepareWltDir(),
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	wa, err := s.CreateWallet("a.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seeda",
+		Label: "a",
+	})
+	require.NoError(t, err)
+	ea, err := wa.GetEntryAt(0)
+	require.NoError(t, err)
+
+	wb, err := s.CreateWallet("b.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     "seedb",
+		Label:    "b",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+	var eb wallet.Entry
+	require.NoError(t, s.ViewSecrets(wb.Filename(), []byte("pwd"), func(w wallet.Wallet) error {
+		var err error
+		eb, err = w.GetEntryAt(0)
+		return err
+	}))
+
+	// A watch-only wallet of both addresses builds a transaction spending from the two wallets
+	ww, err := s.CreateWallet("w.wlt", wallet.Options{
+		Type:                wallet.WalletTypeWatchOnly,
+		Label:               "watch",
+		WatchOnlyPublicKeys: []cipher.PubKey{ea.Public, eb.Public},
+	})
+	require.NoError(t, err)
+
+	uxa := makeUxOut(t, ea.Secret, 2e6, 100)
+	uxa.Head.Time = headTime
+	uxa.Head.BkSeq = 1
+	uxb := makeUxOut(t, eb.Secret, 2e6, 100)
+	uxb.Head.Time = headTime
+	uxb.Head.BkSeq = 2
+	auxs := coin.AddressUxOuts{
+		ea.SkycoinAddress(): []coin.UxOut{uxa},
+		eb.SkycoinAddress(): []coin.UxOut{uxb},
+	}
+
+	changeAddr := makeAddress()
+	txn, inputs, err := s.CreateUnsignedTransaction(wallet.CreateTransactionParams{
+		WalletID: ww.Filename(),
+		Params: transaction.Params{
+			HoursSelection: transaction.HoursSelection{
+				Type: transaction.HoursSelectionTypeManual,
+			},
+			ChangeAddress: &changeAddr,
+			To: []coin.TransactionOutput{
+				{
+					Address: makeAddress(),
+					Coins:   3e6,
+					Hours:   1,
+				},
+			},
+		},
+	}, auxs, headTime)
+	require.NoError(t, err)
+	require.Len(t, inputs, 2)
+
+	ia, ib := 0, 1
+	if inputs[0].Address != ea.SkycoinAddress() {
+		ia, ib = 1, 0
+	}
+
+	// Wallet a does not have the address of the other input
+	_, err = s.SignTransaction(wa.Filename(), nil, txn, []int{ib}, inputs)
+	require.Equal(t, wallet.ErrUnknownAddress, err)
+	_, err = s.SignTransaction(wa.Filename(), nil, txn, nil, inputs)
+	require.Equal(t, wallet.ErrUnknownAddress, err)
+
+	// Each wallet signs its own input
+	txn2, err := s.SignTransaction(wa.Filename(), nil, txn, []int{ia}, inputs)
+	require.NoError(t, err)
+	require.False(t, txn2.Sigs[ia].Null())
+	require.True(t, txn2.Sigs[ib].Null())
+	require.False(t, txn2.IsFullySigned())
+
+	_, err = s.SignTransaction(wb.Filename(), []byte("wrong"), txn2, []int{ib}, inputs)
+	require.Equal(t, wallet.ErrInvalidPassword, err)
+
+	txn3, err := s.SignTransaction(wb.Filename(), []byte("pwd"), txn2, nil, inputs)
+	require.NoError(t, err)
+	require.True(t, txn3.IsFullySigned())
+	require.Equal(t, txn2.Sigs[ia], txn3.Sigs[ia])
+
+	uxIn := coin.UxArray{uxa, uxb}
+	if ia == 1 {
+		uxIn = coin.UxArray{uxb, uxa}
+	}
+	require.NoError(t, txn3.VerifyInputSignatures(uxIn))
+}
+
+func TestServiceGetWalletsByLabelAndFirstAddress(t *testing.T) {
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       prepareWltDir(),
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w1, err := s.CreateWallet("t1.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "seed1",
+		Label:     "savings",
+		GenerateN: 2,
+	})
+	require.NoError(t, err)
+
+	w2, err := s.CreateWallet("t2.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed2",
+		Label: "savings",
+	})
+	require.NoError(t, err)
+
+	w3, err := s.CreateWallet("t3.wlt", wallet.Options{
+		Type:     wallet.WalletTypeBip44,
+		Seed:     bip39.MustNewDefaultMnemonic(),
+		Label:    "spending",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+
+	wlts, err := s.GetWalletsByLabel("savings")
+	require.NoError(t, err)
+	require.Len(t, wlts, 2)
+	require.Contains(t, wlts, w1.Filename())
+	require.Contains(t, wlts, w2.Filename())
+
+	wlts, err = s.GetWalletsByLabel("spending")
+	require.NoError(t, err)
+	require.Len(t, wlts, 1)
+	require.True(t, wlts[w3.Filename()].IsEncrypted())
+
+	_, err = s.GetWalletsByLabel("foo")
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+
+	// The returned wallets are clones
+	wlts[w3.Filename()].SetLabel("changed")
+	w, err := s.GetWallet(w3.Filename())
+	require.NoError(t, err)
+	require.Equal(t, "spending", w.Label())
+
+	for _, w := range []wallet.Wallet{w1, w2, w3} {
+		addrs, err := s.GetAddresses(w.Filename())
+		require.NoError(t, err)
+
+		fw, err := s.GetWalletByFirstAddress(addrs[0])
+		require.NoError(t, err)
+		require.Equal(t, w.Filename(), fw.Filename())
+	}
+
+	// Only the first address is matched
+	addrs, err := s.GetAddresses(w1.Filename())
+	require.NoError(t, err)
+	_, err = s.GetWalletByFirstAddress(addrs[1])
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+
+	_, err = s.GetWalletByFirstAddress(testutil.MakeAddress())
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+
+	s.SetEnableWalletAPI(false)
+	_, err = s.GetWalletsByLabel("savings")
+	require.Equal(t, wallet.ErrWalletAPIDisabled, err)
+	_, err = s.GetWalletByFirstAddress(addrs[0])
+	require.Equal(t, wallet.ErrWalletAPIDisabled, err)
+}
+
+func TestServiceDeleteWallet(t *testing.T) {
+	for _, trash := range []bool{false, true} {
+		t.Run(fmt.Sprintf("trash=%v", trash), func(t *testing.T) {
+			dir := prepareWltDir()
+			c := wallet.Config{
+				WalletDir:           dir,
+				CryptoType:          crypto.CryptoTypeSha256Xor,
+				EnableWalletAPI:     true,
+				TrashDeletedWallets: trash,
+			}
+			s, err := wallet.NewService(c)
+			require.NoError(t, err)
+
+			w, err := s.CreateWallet("t.wlt", wallet.Options{
+				Type:  wallet.WalletTypeDeterministic,
+				Seed:  "seed",
+				Label: "label",
+			})
+			require.NoError(t, err)
+
+			path := filepath.Join(dir, w.Filename())
+			data, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+			require.NoError(t, ioutil.WriteFile(path+".bak", data, 0600))
+
+			require.NoError(t, s.DeleteWallet(w.Filename()))
+
+			_, err = s.GetWallet(w.Filename())
+			require.Equal(t, wallet.ErrWalletNotExist, err)
+			require.Equal(t, wallet.ErrWalletNotExist, s.DeleteWallet(w.Filename()))
+
+			_, err = os.Stat(path)
+			require.True(t, os.IsNotExist(err))
+			_, err = os.Stat(path + ".bak")
+			require.True(t, os.IsNotExist(err))
+
+			trashDir := filepath.Join(dir, wallet.WalletTrashDir)
+			if trash {
+				trashed, err := ioutil.ReadFile(filepath.Join(trashDir, w.Filename()))
+				require.NoError(t, err)
+				require.Equal(t, data, trashed)
+				_, err = os.Stat(filepath.Join(trashDir, w.Filename()+".bak"))
+				require.NoError(t, err)
+			} else {
+				_, err = os.Stat(trashDir)
+				require.True(t, os.IsNotExist(err))
+			}
+
+			// The wallet does not come back after a restart, and its seed can be used again
+			s, err = wallet.NewService(c)
+			require.NoError(t, err)
+			_, err = s.GetWallet(w.Filename())
+			require.Equal(t, wallet.ErrWalletNotExist, err)
+
+			_, err = s.CreateWallet("t.wlt", wallet.Options{
+				Type:  wallet.WalletTypeDeterministic,
+				Seed:  "seed",
+				Label: "label",
+			})
+			require.NoError(t, err)
+
+			// Deleting the wallet again keeps the first trashed copy
+			require.NoError(t, s.DeleteWallet("t.wlt"))
+			if trash {
+				fs, err := ioutil.ReadDir(trashDir)
+				require.NoError(t, err)
+				require.Len(t, fs, 3)
+			}
+
+			s.SetEnableWalletAPI(false)
+			require.Equal(t, wallet.ErrWalletAPIDisabled, s.DeleteWallet("t.wlt"))
+		})
+	}
+}
+
+func TestServiceOnWalletChange(t *testing.T) {
+	dir := prepareWltDir()
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	type change struct {
+		wltID string
+		event wallet.WalletEvent
+	}
+	var changes []change
+	s.OnWalletChange(func(wltID string, event wallet.WalletEvent) {
+		changes = append(changes, change{wltID, event})
+
+		// The listener is called outside of the lock, so it can call back into the service
+		_, err := s.GetWallets()
+		require.NoError(t, err)
+	})
+
+	var calls int
+	s.OnWalletChange(func(string, wallet.WalletEvent) {
+		calls++
+	})
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.NoError(t, err)
+
+	_, err = s.NewAddresses(w.Filename(), nil, wallet.OptionGenerateN(2))
+	require.NoError(t, err)
+	require.NoError(t, s.UpdateWalletLabel(w.Filename(), "new label"))
+	addrs, err := s.GetAddresses(w.Filename())
+	require.NoError(t, err)
+	require.NoError(t, s.SetAddressLabel(w.Filename(), addrs[0], "savings"))
+	_, err = s.EncryptWalletWithCryptoType(w.Filename(), []byte("pwd"), crypto.CryptoTypeSha256Xor)
+	require.NoError(t, err)
+	_, err = s.DecryptWallet(w.Filename(), []byte("pwd"))
+	require.NoError(t, err)
+	_, err = s.RenameWallet(w.Filename(), "t2.wlt")
+	require.NoError(t, err)
+	require.NoError(t, s.UnloadWallet("t2.wlt"))
+
+	// Failed changes are not reported
+	require.Error(t, s.UpdateWalletLabel("t2.wlt", "foo"))
+	_, err = s.CreateWallet("t3.wlt", wallet.Options{
+		Type: wallet.WalletTypeDeterministic,
+		Seed: "seed",
+	})
+	require.Error(t, err)
+
+	require.Equal(t, []change{
+		{"t.wlt", wallet.WalletEventCreated},
+		{"t.wlt", wallet.WalletEventAddressesAdded},
+		{"t.wlt", wallet.WalletEventLabelUpdated},
+		{"t.wlt", wallet.WalletEventAddressLabelUpdated},
+		{"t.wlt", wallet.WalletEventEncrypted},
+		{"t.wlt", wallet.WalletEventDecrypted},
+		{"t.wlt", wallet.WalletEventUnloaded},
+		{"t2.wlt", wallet.WalletEventRenamed},
+		{"t2.wlt", wallet.WalletEventUnloaded},
+	}, changes)
+	require.Equal(t, len(changes), calls)
+}
+
+func TestServiceReadOnly(t *testing.T) {
+	dir := prepareWltDir()
+	c := wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	}
+	s, err := wallet.NewService(c)
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.NoError(t, err)
+	addrs, err := w.GetAddresses()
+	require.NoError(t, err)
+
+	c.ReadOnly = true
+	s, err = wallet.NewService(c)
+	require.NoError(t, err)
+
+	// Reads are allowed
+	w2, err := s.GetWallet("t.wlt")
+	require.NoError(t, err)
+	require.Equal(t, w.Fingerprint(), w2.Fingerprint())
+
+	wlts, err := s.GetWallets()
+	require.NoError(t, err)
+	require.Len(t, wlts, 1)
+
+	gotAddrs, err := s.GetAddresses("t.wlt")
+	require.NoError(t, err)
+	require.Equal(t, wallet.SkycoinAddresses(addrs), gotAddrs)
+
+	require.NoError(t, s.View("t.wlt", func(w wallet.Wallet) error {
+		require.Equal(t, "label", w.Label())
+		return nil
+	}))
+
+	// Changes and spending are not
+	_, err = s.CreateWallet("t2.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed2",
+		Label: "label2",
+	})
+	require.Equal(t, wallet.ErrWalletReadOnly, err)
+
+	_, err = s.NewAddresses("t.wlt", nil, wallet.OptionGenerateN(1))
+	require.Equal(t, wallet.ErrWalletReadOnly, err)
+
+	_, err = s.EncryptWalletWithCryptoType("t.wlt", []byte("pwd"), crypto.CryptoTypeSha256Xor)
+	require.Equal(t, wallet.ErrWalletReadOnly, err)
+
+	_, err = s.DecryptWallet("t.wlt", []byte("pwd"))
+	require.Equal(t, wallet.ErrWalletReadOnly, err)
+
+	_, _, err = s.CreateUnsignedTransaction(wallet.CreateTransactionParams{WalletID: "t.wlt"}, nil, 0)
+	require.Equal(t, wallet.ErrWalletReadOnly, err)
+
+	_, _, err = s.AddInputsToTransaction("t.wlt", nil, &coin.Transaction{}, 1, nil, 0)
+	require.Equal(t, wallet.ErrWalletReadOnly, err)
+
+	require.Equal(t, wallet.ErrWalletReadOnly, s.UpdateWalletLabel("t.wlt", "new"))
+	require.Equal(t, wallet.ErrWalletReadOnly, s.UnloadWallet("t.wlt"))
+	require.Equal(t, wallet.ErrWalletReadOnly, s.DeleteWallet("t.wlt"))
+
+	// The wallet is untouched
+	w2, err = s.GetWallet("t.wlt")
+	require.NoError(t, err)
+	require.Equal(t, "label", w2.Label())
+	require.False(t, w2.IsEncrypted())
+	_, err = os.Stat(filepath.Join(dir, "t.wlt"))
+	require.NoError(t, err)
+}
+
+func TestServiceGetWalletSeedInfo(t *testing.T) {
+	// Wallets that don't track the seed index yet have one key per entry
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       "./testdata",
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	lastIndex, entryCount, err := s.GetWalletSeedInfo("test1.wlt")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), lastIndex)
+	require.Equal(t, 1, entryCount)
+
+	_, _, err = s.GetWalletSeedInfo("test5-bip44.wlt")
+	require.Equal(t, wallet.ErrWalletNoSeedChain, err)
+
+	_, _, err = s.GetWalletSeedInfo("none.wlt")
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+
+	c := wallet.Config{
+		WalletDir:       prepareWltDir(),
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	}
+	s, err = wallet.NewService(c)
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("t.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "seed",
+		Label:     "label",
+		GenerateN: 2,
+	})
+	require.NoError(t, err)
+
+	lastIndex, entryCount, err = s.GetWalletSeedInfo("t.wlt")
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), lastIndex)
+	require.Equal(t, 2, entryCount)
+
+	_, err = s.NewAddresses("t.wlt", nil, wallet.OptionGenerateN(3))
+	require.NoError(t, err)
+
+	// Scanning only keeps the seed chain position of the addresses that are kept
+	_, keys := cipher.MustGenerateDeterministicKeyPairsSeed([]byte("seed"), 8)
+	tf := mockTxnsFinder{cipher.MustAddressFromSecKey(keys[6]): true}
+	addrs, err := s.ScanAddresses("t.wlt", nil, 5, tf)
+	require.NoError(t, err)
+	require.Len(t, addrs, 2)
+
+	lastIndex, entryCount, err = s.GetWalletSeedInfo("t.wlt")
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), lastIndex)
+	require.Equal(t, 7, entryCount)
+
+	// The position is saved with the wallet
+	s, err = wallet.NewService(c)
+	require.NoError(t, err)
+	lastIndex, entryCount, err = s.GetWalletSeedInfo("t.wlt")
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), lastIndex)
+	require.Equal(t, 7, entryCount)
+
+	newAddrs, err := s.NewAddresses("t.wlt", nil, wallet.OptionGenerateN(1))
+	require.NoError(t, err)
+	require.Equal(t, []cipher.Address{cipher.MustAddressFromSecKey(keys[7])}, newAddrs)
+}
+
+func TestServiceNewAddressesMulti(t *testing.T) {
+	dir := prepareWltDir()
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("t1.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed1",
+		Label: "label1",
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("t2.wlt", wallet.Options{
+		Type:       wallet.WalletTypeDeterministic,
+		Seed:       "seed2",
+		Label:      "label2",
+		Encrypt:    true,
+		Password:   []byte("pwd"),
+		CryptoType: crypto.CryptoTypeSha256Xor,
+	})
+	require.NoError(t, err)
+
+	var changes []string
+	s.OnWalletChange(func(wltID string, event wallet.WalletEvent) {
+		changes = append(changes, wltID)
+	})
+
+	// Nothing changes if one of the wallets fails
+	for _, tc := range []struct {
+		name      string
+		reqs      map[string]uint64
+		passwords map[string][]byte
+		err       error
+	}{
+		{
+			name:      "wallet not exist",
+			reqs:      map[string]uint64{"t1.wlt": 2, "t3.wlt": 1},
+			passwords: map[string][]byte{},
+			err:       wallet.ErrWalletNotExist,
+		},
+		{
+			name:      "missing password",
+			reqs:      map[string]uint64{"t1.wlt": 2, "t2.wlt": 1},
+			passwords: map[string][]byte{},
+			err:       wallet.ErrMissingPassword,
+		},
+		{
+			name:      "password for unencrypted wallet",
+			reqs:      map[string]uint64{"t1.wlt": 2, "t2.wlt": 1},
+			passwords: map[string][]byte{"t1.wlt": []byte("pwd"), "t2.wlt": []byte("pwd")},
+			err:       wallet.ErrWalletNotEncrypted,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			addrs, err := s.NewAddressesMulti(tc.reqs, tc.passwords)
+			require.Equal(t, tc.err, err)
+			require.Nil(t, addrs)
+
+			for _, wltID := range []string{"t1.wlt", "t2.wlt"} {
+				as, err := s.GetAddresses(wltID)
+				require.NoError(t, err)
+				require.Len(t, as, 1)
+			}
+			require.Empty(t, changes)
+		})
+	}
+
+	addrs, err := s.NewAddressesMulti(map[string]uint64{
+		"t1.wlt": 2,
+		"t2.wlt": 3,
+	}, map[string][]byte{
+		"t2.wlt": []byte("pwd"),
+	})
+	require.NoError(t, err)
+	require.Len(t, addrs, 2)
+	require.Len(t, addrs["t1.wlt"], 2)
+	require.Len(t, addrs["t2.wlt"], 3)
+	require.Equal(t, []string{"t1.wlt", "t2.wlt"}, changes)
+
+	for wltID, n := range map[string]int{"t1.wlt": 3, "t2.wlt": 4} {
+		as, err := s.GetAddresses(wltID)
+		require.NoError(t, err)
+		require.Len(t, as, n)
+		require.Equal(t, addrs[wltID], as[1:])
+	}
+
+	// The new addresses of a wallet that fails to save are discarded, the other wallets are saved
+	require.NoError(t, os.Remove(filepath.Join(dir, "t1.wlt")))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "t1.wlt"), 0700))
+
+	addrs, err = s.NewAddressesMulti(map[string]uint64{
+		"t1.wlt": 1,
+		"t2.wlt": 1,
+	}, map[string][]byte{
+		"t2.wlt": []byte("pwd"),
+	})
+	saveErr, ok := err.(wallet.SaveWalletError)
+	require.True(t, ok, "%v", err)
+	require.Equal(t, "t1.wlt", saveErr.WalletID)
+	require.Len(t, addrs, 1)
+	require.Len(t, addrs["t2.wlt"], 1)
+
+	as, err := s.GetAddresses("t1.wlt")
+	require.NoError(t, err)
+	require.Len(t, as, 3)
+	as, err = s.GetAddresses("t2.wlt")
+	require.NoError(t, err)
+	require.Len(t, as, 5)
+}
+
+func TestServiceMinPasswordLength(t *testing.T) {
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:         prepareWltDir(),
+		CryptoType:        crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI:   true,
+		EnableSeedAPI:     true,
+		MinPasswordLength: 6,
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("t1.wlt", wallet.Options{
+		Type:       wallet.WalletTypeDeterministic,
+		Seed:       "seed1",
+		Label:      "label1",
+		Encrypt:    true,
+		Password:   []byte("pwd"),
+		CryptoType: crypto.CryptoTypeSha256Xor,
+	})
+	require.Equal(t, wallet.ErrWeakPassword, err)
+
+	_, err = s.CreateWallet("t1.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed1",
+		Label: "label1",
+	})
+	require.NoError(t, err)
+
+	// Surrounding whitespace does not count
+	for _, pwd := range []string{"12345", "  12345  ", "      "} {
+		_, err = s.EncryptWalletWithCryptoType("t1.wlt", []byte(pwd), crypto.CryptoTypeSha256Xor)
+		require.Equal(t, wallet.ErrWeakPassword, err)
+	}
+
+	_, err = s.EncryptWalletWithCryptoType("t1.wlt", nil, crypto.CryptoTypeSha256Xor)
+	require.Equal(t, wallet.ErrMissingPassword, err)
+
+	w, err := s.EncryptWalletWithCryptoType("t1.wlt", []byte("123456"), crypto.CryptoTypeSha256Xor)
+	require.NoError(t, err)
+	require.True(t, w.IsEncrypted())
+
+	_, err = s.RecoverWallet("t1.wlt", "seed1", "", []byte("pwd"))
+	require.Equal(t, wallet.ErrWeakPassword, err)
+}
+
+func TestServiceCreateTransactionsBatched(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+	dir := prepareWltDir()
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     "seed",
+		Label:    "label",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+
+	var entries []wallet.Entry
+	require.NoError(t, s.ViewSecrets(w.Filename(), []byte("pwd"), func(w wallet.Wallet) error {
+		var err error
+		entries, err = w.GetEntries()
+		return err
+	}))
+	require.Len(t, entries, 1)
+
+	var uxouts coin.UxArray
+	for i := 0; i < 9; i++ {
+		ux := makeUxOut(t, entries[0].Secret, 1e6, 100)
+		ux.Head.Time = headTime
+		ux.Head.BkSeq = uint64(i + 1)
+		uxouts = append(uxouts, ux)
+	}
+	auxs := coin.NewAddressUxOuts(uxouts)
+
+	// The hours of the 5 outputs chosen before consolidating are not enough
+	// once the consolidation fee is burned, so more outputs must be chosen
+	hours := fee.RemainingHours(5*100, params.UserVerifyTxn.BurnFactor)
+
+	p := transaction.Params{
+		HoursSelection: transaction.HoursSelection{
+			Type: transaction.HoursSelectionTypeManual,
+		},
+		To: []coin.TransactionOutput{
+			{
+				Address: testutil.MakeAddress(),
+				Coins:   5e6,
+				Hours:   hours,
+			},
+		},
+	}
+
+	t.Run("fees covered", func(t *testing.T) {
+		txns, inputs, err := s.CreateTransactionsBatched(w.Filename(), []byte("pwd"), p, auxs, headTime, 4)
+		require.NoError(t, err)
+		require.Len(t, txns, 2)
+		require.Len(t, inputs, 2)
+		require.Len(t, txns[0].In, 4)
+		// The consolidated output alone with one more output would not cover the requested hours
+		require.Len(t, txns[1].In, 3)
+
+		unspents := make(map[cipher.SHA256]coin.UxOut)
+		for _, ux := range uxouts {
+			unspents[ux.Hash()] = ux
+		}
+
+		for i, txn := range txns {
+			require.NoError(t, txn.Verify())
+			require.True(t, txn.IsFullySigned())
+
+			var inHours uint64
+			for _, h := range txn.In {
+				ux, ok := unspents[h]
+				require.True(t, ok)
+				hours, err := ux.CoinHours(headTime)
+				require.NoError(t, err)
+				inHours += hours
+			}
+
+			for _, ux := range coin.CreateUnspents(coin.BlockHeader{Time: headTime, BkSeq: uint64(10 + i)}, *txn) {
+				unspents[ux.Hash()] = ux
+			}
+
+			outHours, err := txn.OutputHours()
+			require.NoError(t, err)
+			require.True(t, inHours >= outHours)
+			require.NoError(t, fee.VerifyTransactionFee(txn, inHours-outHours, params.UserVerifyTxn.BurnFactor))
+		}
+
+		require.Equal(t, p.To[0], txns[1].Out[0])
+	})
+
+	t.Run("invalid password", func(t *testing.T) {
+		_, _, err := s.CreateTransactionsBatched(w.Filename(), []byte("wrong"), p, auxs, headTime, 4)
+		require.Equal(t, wallet.ErrInvalidPassword, err)
+	})
+
+	t.Run("invalid max inputs", func(t *testing.T) {
+		_, _, err := s.CreateTransactionsBatched(w.Filename(), []byte("pwd"), p, auxs, headTime, 1)
+		require.Equal(t, wallet.ErrInvalidMaxInputsPerTxn, err)
+	})
+
+	t.Run("wallet not exist", func(t *testing.T) {
+		_, _, err := s.CreateTransactionsBatched("foo.wlt", nil, p, auxs, headTime, 4)
+		require.Equal(t, wallet.ErrWalletNotExist, err)
+	})
+}
+
+func TestServiceCreateConsolidationTransaction(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+	dir := prepareWltDir()
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.NoError(t, err)
+	_, err = s.NewAddresses(w.Filename(), nil, wallet.OptionGenerateN(1))
+	require.NoError(t, err)
+
+	var entries []wallet.Entry
+	require.NoError(t, s.ViewSecrets(w.Filename(), nil, func(w wallet.Wallet) error {
+		var err error
+		entries, err = w.GetEntries()
+		return err
+	}))
+	require.Len(t, entries, 2)
+
+	makeUx := func(e wallet.Entry, coins, hours uint64) coin.UxOut {
+		ux := makeUxOut(t, e.Secret, coins, hours)
+		ux.Head.Time = headTime
+		return ux
+	}
+
+	small1 := makeUx(entries[0], 1e6, 10)
+	small2 := makeUx(entries[1], 1e6, 20)
+	small3 := makeUx(entries[1], 2e6, 30)
+	large := makeUx(entries[0], 100e6, 40)
+	auxs := coin.NewAddressUxOuts(coin.UxArray{large, small3, small2, small1})
+
+	t.Run("smallest outputs", func(t *testing.T) {
+		txn, inputs, err := s.CreateConsolidationTransaction(w.Filename(), nil, auxs, headTime, 3)
+		require.NoError(t, err)
+		require.NoError(t, txn.Verify())
+
+		require.Len(t, inputs, 3)
+		require.Equal(t, []cipher.SHA256{small1.Hash(), small2.Hash(), small3.Hash()}, txn.In)
+
+		require.Len(t, txn.Out, 1)
+		require.Equal(t, uint64(4e6), txn.Out[0].Coins)
+		require.Equal(t, fee.RemainingHours(60, params.UserVerifyTxn.BurnFactor), txn.Out[0].Hours)
+
+		addr := entries[0].SkycoinAddress()
+		if bytes.Compare(entries[1].SkycoinAddress().Bytes(), addr.Bytes()) < 0 {
+			addr = entries[1].SkycoinAddress()
+		}
+		require.Equal(t, addr, txn.Out[0].Address)
+	})
+
+	t.Run("invalid max inputs", func(t *testing.T) {
+		_, _, err := s.CreateConsolidationTransaction(w.Filename(), nil, auxs, headTime, 1)
+		require.Equal(t, wallet.ErrInvalidMaxInputsPerTxn, err)
+	})
+
+	t.Run("nothing to consolidate", func(t *testing.T) {
+		_, _, err := s.CreateConsolidationTransaction(w.Filename(), nil, coin.NewAddressUxOuts(coin.UxArray{small1}), headTime, 3)
+		require.Equal(t, wallet.ErrNothingToConsolidate, err)
+	})
+
+	t.Run("net loss", func(t *testing.T) {
+		dust := coin.UxArray{makeUx(entries[0], 1e6, 0), makeUx(entries[1], 1e6, 1)}
+		_, _, err := s.CreateConsolidationTransaction(w.Filename(), nil, coin.NewAddressUxOuts(dust), headTime, 3)
+		require.Equal(t, wallet.ErrConsolidationNetLoss, err)
+	})
+
+	t.Run("no coin hours", func(t *testing.T) {
+		dust := coin.UxArray{makeUx(entries[0], 1e6, 0), makeUx(entries[1], 1e6, 0)}
+		_, _, err := s.CreateConsolidationTransaction(w.Filename(), nil, coin.NewAddressUxOuts(dust), headTime, 3)
+		require.Equal(t, fee.ErrTxnNoFee, err)
+	})
+
+	t.Run("address not in wallet", func(t *testing.T) {
+		_, sk := cipher.GenerateKeyPair()
+		other := coin.UxArray{makeUxOut(t, sk, 1e6, 10), small1}
+		_, _, err := s.CreateConsolidationTransaction(w.Filename(), nil, coin.NewAddressUxOuts(other), headTime, 3)
+		require.Error(t, err)
+	})
+
+	t.Run("wallet not exist", func(t *testing.T) {
+		_, _, err := s.CreateConsolidationTransaction("foo.wlt", nil, auxs, headTime, 3)
+		require.Equal(t, wallet.ErrWalletNotExist, err)
+	})
+}
+
+func TestServiceCreateSweepTransaction(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+	dir := prepareWltDir()
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     "seed",
+		Label:    "label",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+	_, err = s.NewAddresses(w.Filename(), []byte("pwd"), wallet.OptionGenerateN(1))
+	require.NoError(t, err)
+
+	var entries []wallet.Entry
+	require.NoError(t, s.ViewSecrets(w.Filename(), []byte("pwd"), func(w wallet.Wallet) error {
+		var err error
+		entries, err = w.GetEntries()
+		return err
+	}))
+	require.Len(t, entries, 2)
+
+	makeUx := func(e wallet.Entry, coins, hours uint64) coin.UxOut {
+		ux := makeUxOut(t, e.Secret, coins, hours)
+		ux.Head.Time = headTime
+		return ux
+	}
+
+	uxs := coin.UxArray{
+		makeUx(entries[0], 1e6, 10),
+		makeUx(entries[0], 2e6, 20),
+		makeUx(entries[1], 3e6, 30),
+	}
+	auxs := coin.NewAddressUxOuts(uxs)
+	dest := makeAddress()
+
+	t.Run("sweep", func(t *testing.T) {
+		txn, inputs, err := s.CreateSweepTransaction(w.Filename(), []byte("pwd"), dest, auxs, headTime)
+		require.NoError(t, err)
+		require.NoError(t, txn.Verify())
+		require.True(t, txn.IsFullySigned())
+
+		require.Len(t, inputs, len(uxs))
+		require.Len(t, txn.In, len(uxs))
+		for _, ux := range uxs {
+			require.Contains(t, txn.In, ux.Hash())
+		}
+
+		require.Len(t, txn.Out, 1)
+		require.Equal(t, dest, txn.Out[0].Address)
+		require.Equal(t, uint64(6e6), txn.Out[0].Coins)
+		require.Equal(t, fee.RemainingHours(60, params.UserVerifyTxn.BurnFactor), txn.Out[0].Hours)
+	})
+
+	t.Run("zero balance", func(t *testing.T) {
+		_, _, err := s.CreateSweepTransaction(w.Filename(), []byte("pwd"), dest, coin.AddressUxOuts{}, headTime)
+		require.Equal(t, wallet.ErrNoSpendableOutputs, err)
+	})
+
+	t.Run("null destination", func(t *testing.T) {
+		_, _, err := s.CreateSweepTransaction(w.Filename(), []byte("pwd"), cipher.Address{}, auxs, headTime)
+		require.Equal(t, transaction.ErrNullAddressReceiver, err)
+	})
+
+	t.Run("invalid password", func(t *testing.T) {
+		_, _, err := s.CreateSweepTransaction(w.Filename(), []byte("wrong"), dest, auxs, headTime)
+		require.Equal(t, wallet.ErrInvalidPassword, err)
+	})
+
+	t.Run("wallet not exist", func(t *testing.T) {
+		_, _, err := s.CreateSweepTransaction("foo.wlt", nil, dest, auxs, headTime)
+		require.Equal(t, wallet.ErrWalletNotExist, err)
+	})
+}
+
+func TestServiceViewSecretsErasesDecryptedWallet(t *testing.T) {
+	dir := prepareWltDir()
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     "seed",
+		Label:    "label",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+
+	var unlocked wallet.Wallet
+	require.NoError(t, s.ViewSecrets(w.Filename(), []byte("pwd"), func(w wallet.Wallet) error {
+		e, err := w.GetEntryAt(0)
+		require.NoError(t, err)
+		require.NotEqual(t, cipher.SecKey{}, e.Secret)
+		require.Equal(t, "seed", w.Seed())
+		unlocked = w
+		return nil
+	}))
+
+	// The secrets of the decrypted wallet are wiped once the function returns
+	e, err := unlocked.GetEntryAt(0)
+	require.NoError(t, err)
+	require.Equal(t, cipher.SecKey{}, e.Secret)
+	require.Empty(t, unlocked.Seed())
+	require.Empty(t, unlocked.LastSeed())
+
+	// The wallet in the service can still be unlocked
+	require.NoError(t, s.ViewSecrets(w.Filename(), []byte("pwd"), func(w wallet.Wallet) error {
+		require.Equal(t, "seed", w.Seed())
+		return nil
+	}))
+}
+
+func TestServiceReloadWallet(t *testing.T) {
+	dir := prepareWltDir()
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.NoError(t, err)
+
+	var events []wallet.WalletEvent
+	s.OnWalletChange(func(_ string, event wallet.WalletEvent) {
+		events = append(events, event)
+	})
+
+	// Another service changes the wallet file
+	other, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+	_, err = other.NewAddresses(w.Filename(), nil, wallet.OptionGenerateN(2))
+	require.NoError(t, err)
+	require.NoError(t, other.UpdateWalletLabel(w.Filename(), "changed"))
+
+	rw, err := s.ReloadWallet(w.Filename())
+	require.NoError(t, err)
+	require.Equal(t, "changed", rw.Label())
+	l, err := rw.EntriesLen()
+	require.NoError(t, err)
+	require.Equal(t, 3, l)
+	require.Equal(t, []wallet.WalletEvent{wallet.WalletEventReloaded}, events)
+
+	gw, err := s.GetWallet(w.Filename())
+	require.NoError(t, err)
+	require.Equal(t, "changed", gw.Label())
+
+	// The wallet can still be found by its first address
+	addrs, err := gw.GetAddresses()
+	require.NoError(t, err)
+	fw, err := s.GetWalletByFirstAddress(addrs[0].(cipher.Address))
+	require.NoError(t, err)
+	require.Equal(t, w.Filename(), fw.Filename())
+
+	// A file that holds a different wallet is refused
+	otherDir := prepareWltDir()
+	s2, err := wallet.NewService(wallet.Config{
+		WalletDir:       otherDir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+	_, err = s2.CreateWallet(w.Filename(), wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed2",
+		Label: "label2",
+	})
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(filepath.Join(otherDir, w.Filename()))
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, w.Filename()), data, 0600))
+
+	_, err = s.ReloadWallet(w.Filename())
+	require.Equal(t, wallet.ErrWalletIdentityChanged, err)
+	gw, err = s.GetWallet(w.Filename())
+	require.NoError(t, err)
+	require.Equal(t, "changed", gw.Label())
+
+	// A file that can't be loaded is reported
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, w.Filename()), []byte("{"), 0600))
+	_, err = s.ReloadWallet(w.Filename())
+	_, ok := err.(wallet.LoadWalletError)
+	require.True(t, ok, "%v", err)
+
+	_, err = s.ReloadWallet("foo.wlt")
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+
+	tw, err := s.CreateWallet("temp.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed3",
+		Label: "label3",
+		Temp:  true,
+	})
+	require.NoError(t, err)
+	_, err = s.ReloadWallet(tw.Filename())
+	require.Error(t, err)
+
+	s.SetEnableWalletAPI(false)
+	_, err = s.ReloadWallet(w.Filename())
+	require.Equal(t, wallet.ErrWalletAPIDisabled, err)
+}
+
+func TestServiceSignMessage(t *testing.T) {
+	dir := prepareWltDir()
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     "seed",
+		Label:    "label",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+
+	addrs, err := w.GetAddresses()
+	require.NoError(t, err)
+	require.Len(t, addrs, 1)
+	addr := addrs[0].(cipher.Address)
+	msg := []byte("I control this address")
+
+	// Wrong password and unknown address are reported differently
+	_, err = s.SignMessage(w.Filename(), []byte("wrong"), addr, msg)
+	require.Equal(t, wallet.ErrInvalidPassword, err)
+	_, err = s.SignMessage(w.Filename(), nil, addr, msg)
+	require.Equal(t, wallet.ErrMissingPassword, err)
+	_, err = s.SignMessage(w.Filename(), []byte("pwd"), testutil.MakeAddress(), msg)
+	require.Equal(t, wallet.ErrUnknownAddress, err)
+
+	sig, err := s.SignMessage(w.Filename(), []byte("pwd"), addr, msg)
+	require.NoError(t, err)
+	require.NoError(t, wallet.VerifyMessage(addr, sig, msg))
+	require.Error(t, wallet.VerifyMessage(addr, sig, []byte("another message")))
+	require.Error(t, wallet.VerifyMessage(testutil.MakeAddress(), sig, msg))
+
+	// The wallet stays encrypted
+	w, err = s.GetWallet(w.Filename())
+	require.NoError(t, err)
+	require.True(t, w.IsEncrypted())
+
+	// Unencrypted wallets sign without a password
+	w2, err := s.CreateWallet("t2.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed2",
+		Label: "label2",
+	})
+	require.NoError(t, err)
+	addrs, err = w2.GetAddresses()
+	require.NoError(t, err)
+	addr2 := addrs[0].(cipher.Address)
+
+	sig, err = s.SignMessage(w2.Filename(), nil, addr2, msg)
+	require.NoError(t, err)
+	require.NoError(t, wallet.VerifyMessage(addr2, sig, msg))
+
+	_, err = s.SignMessage(w2.Filename(), []byte("pwd"), addr2, msg)
+	require.Equal(t, wallet.ErrWalletNotEncrypted, err)
+}
+
+func TestServiceGetWalletNames(t *testing.T) {
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       prepareWltDir(),
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	infos, err := s.GetWalletNames()
+	require.NoError(t, err)
+	require.Empty(t, infos)
+
+	_, err = s.CreateWallet("b.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "seed1",
+		Label:     "savings",
+		GenerateN: 3,
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("a.wlt", wallet.Options{
+		Type:     wallet.WalletTypeBip44,
+		Seed:     bip39.MustNewDefaultMnemonic(),
+		Label:    "spending",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+	// Adds a second change address, the entries of both chains are counted
+	_, err = s.NewAddresses("a.wlt", []byte("pwd"), wallet.OptionGenerateN(1), wallet.OptionChange())
+	require.NoError(t, err)
+
+	infos, err = s.GetWalletNames()
+	require.NoError(t, err)
+	require.Equal(t, []wallet.WalletInfo{
+		{
+			ID:         "a.wlt",
+			Label:      "spending",
+			Encrypted:  true,
+			Type:       wallet.WalletTypeBip44,
+			EntryCount: 3,
+		},
+		{
+			ID:         "b.wlt",
+			Label:      "savings",
+			Type:       wallet.WalletTypeDeterministic,
+			EntryCount: 3,
+		},
+	}, infos)
+
+	s.SetEnableWalletAPI(false)
+	_, err = s.GetWalletNames()
+	require.Equal(t, wallet.ErrWalletAPIDisabled, err)
+}
+
+func TestServiceWalletCoinMismatch(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       prepareWltDir(),
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Coin:      wallet.CoinTypeBitcoin,
+		Seed:      "seed",
+		Label:     "label",
+		GenerateN: 2,
+	})
+	require.NoError(t, err)
+
+	var entries []wallet.Entry
+	require.NoError(t, s.View(w.Filename(), func(w wallet.Wallet) error {
+		var err error
+		entries, err = w.GetEntries()
+		return err
+	}))
+
+	auxs := make(coin.AddressUxOuts)
+	for _, e := range entries {
+		addr := cipher.AddressFromPubKey(e.Public)
+		auxs[addr] = append(auxs[addr], makeUxOut(t, e.Secret, 10e6, 100))
+	}
+
+	changeAddr := testutil.MakeAddress()
+	err = s.View(w.Filename(), func(w wallet.Wallet) error {
+		_, _, err := wallet.CreateTransaction(w, transaction.Params{
+			HoursSelection: transaction.HoursSelection{
+				Type: transaction.HoursSelectionTypeManual,
+			},
+			ChangeAddress: &changeAddr,
+			To: []coin.TransactionOutput{
+				{
+					Address: testutil.MakeAddress(),
+					Coins:   1e6,
+					Hours:   1,
+				},
+			},
+		}, auxs, headTime)
+		return err
+	})
+	require.Equal(t, wallet.ErrWalletCoinMismatch, err)
+
+	_, _, err = s.CreateSweepTransaction(w.Filename(), nil, testutil.MakeAddress(), auxs, headTime)
+	require.Equal(t, wallet.ErrWalletCoinMismatch, err)
+
+	_, _, err = s.CreateConsolidationTransaction(w.Filename(), nil, auxs, headTime, 10)
+	require.Equal(t, wallet.ErrWalletCoinMismatch, err)
+
+	_, err = s.SignMessage(w.Filename(), nil, cipher.AddressFromPubKey(entries[0].Public), []byte("msg"))
+	require.Equal(t, wallet.ErrWalletCoinMismatch, err)
+}
+
+func TestServiceWalletBackups(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+		MaxBackups:      2,
+	})
+	require.NoError(t, err)
+
+	listBackups := func() []string {
+		fs, err := filepath.Glob(filepath.Join(dir, "t.wlt.*."+wallet.BackupExt))
+		require.NoError(t, err)
+		return fs
+	}
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.NoError(t, err)
+
+	// Nothing is backed up when the wallet file is created
+	require.Empty(t, listBackups())
+
+	for i := 0; i < 3; i++ {
+		_, err = s.NewAddresses(w.Filename(), nil, wallet.OptionGenerateN(1))
+		require.NoError(t, err)
+	}
+
+	// The oldest backup was pruned, the newest one holds the wallet before the last save
+	bfs := listBackups()
+	require.Len(t, bfs, 2)
+	bw, err := wallet.Load(bfs[1])
+	require.NoError(t, err)
+	l, err := bw.EntriesLen()
+	require.NoError(t, err)
+	require.Equal(t, 3, l)
+
+	// NewService prunes the backups, leaving the other .bak files alone
+	legacy := filepath.Join(dir, "t.wlt.bak")
+	other := filepath.Join(dir, "notes.bak")
+	for _, f := range []string{legacy, other} {
+		require.NoError(t, ioutil.WriteFile(f, []byte("{}"), 0600))
+	}
+
+	s, err = wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+		MaxBackups:      1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, bfs[1:], listBackups())
+	for _, f := range []string{legacy, other} {
+		_, err := os.Stat(f)
+		require.NoError(t, err)
+	}
+
+	// Deleting the wallet deletes its backups
+	require.NoError(t, s.DeleteWallet(w.Filename()))
+	require.Empty(t, listBackups())
+
+	_, err = wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		EnableWalletAPI: true,
+		MaxBackups:      -1,
+	})
+	testutil.RequireError(t, err, "invalid max wallet backups -1, must not be negative")
+}
+
+func TestServiceWalletBackupsReencrypt(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+		EnableSeedAPI:   true,
+		MaxBackups:      3,
+	})
+	require.NoError(t, err)
+
+	listBackups := func() []string {
+		fs, err := filepath.Glob(filepath.Join(dir, "t.wlt.*."+wallet.BackupExt))
+		require.NoError(t, err)
+		return fs
+	}
+
+	// requireNoSeed checks that no file of the wallet directory holds the seed
+	seed := bip39.MustNewDefaultMnemonic()
+	requireNoSeed := func() {
+		fs, err := ioutil.ReadDir(dir)
+		require.NoError(t, err)
+		for _, f := range fs {
+			data, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+			require.NoError(t, err)
+			require.False(t, bytes.Contains(data, []byte(seed)), f.Name())
+		}
+	}
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  seed,
+		Label: "label",
+	})
+	require.NoError(t, err)
+
+	_, err = s.NewAddresses(w.Filename(), nil, wallet.OptionGenerateN(1))
+	require.NoError(t, err)
+	require.Len(t, listBackups(), 1)
+
+	// Encrypting removes the backups of the unencrypted wallet
+	_, err = s.EncryptWallet(w.Filename(), []byte("pwd"))
+	require.NoError(t, err)
+	require.Empty(t, listBackups())
+	requireNoSeed()
+
+	// Changing the crypto type removes the backups encrypted with the previous one
+	_, err = s.NewAddresses(w.Filename(), []byte("pwd"), wallet.OptionGenerateN(1))
+	require.NoError(t, err)
+	require.Len(t, listBackups(), 1)
+	migrated, failed, err := s.RekeyAllWallets(map[string][]byte{w.Filename(): []byte("pwd")}, crypto.CryptoTypeSha256Xor)
+	require.NoError(t, err)
+	require.Empty(t, failed)
+	require.Equal(t, []string{w.Filename()}, migrated)
+	require.Empty(t, listBackups())
+
+	// Recovering the wallet with a new password removes the backups encrypted with the previous one
+	_, err = s.NewAddresses(w.Filename(), []byte("pwd"), wallet.OptionGenerateN(1))
+	require.NoError(t, err)
+	require.Len(t, listBackups(), 1)
+	_, err = s.RecoverWallet(w.Filename(), seed, "", []byte("pwd2"))
+	require.NoError(t, err)
+	require.Empty(t, listBackups())
+	requireNoSeed()
+}
+
+func TestServiceMergeWallets(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	dest, err := s.CreateWallet("dest.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "seed",
+		Label:     "dest",
+		GenerateN: 2,
+		Encrypt:   true,
+		Password:  []byte("pwd"),
+	})
+	require.NoError(t, err)
+	destAddrs, err := dest.GetAddresses()
+	require.NoError(t, err)
+	require.NoError(t, s.SetAddressLabel(dest.Filename(), destAddrs[0].(cipher.Address), "dest label"))
+
+	// A wallet of the same seed, with more addresses, can't be loaded with dest.
+	// It is merged from its file in the wallet directory
+	src, err := wallet.NewWallet("src.wlt", "src", "seed", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		GenerateN: 4,
+	})
+	require.NoError(t, err)
+	srcAddrs, err := src.GetAddresses()
+	require.NoError(t, err)
+	require.Equal(t, destAddrs, srcAddrs[:2])
+	require.NoError(t, src.SetEntryLabel(srcAddrs[0], "src label"))
+	require.NoError(t, src.SetEntryLabel(srcAddrs[3], "src label 3"))
+	require.NoError(t, wallet.Save(src, dir))
+
+	other, err := s.CreateWallet("other.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "other seed",
+		Label: "other",
+	})
+	require.NoError(t, err)
+
+	_, err = s.MergeWallets(dest.Filename(), dest.Filename(), []byte("pwd"))
+	require.Equal(t, wallet.ErrMergeSameWallet, err)
+	_, err = s.MergeWallets(dest.Filename(), other.Filename(), []byte("pwd"))
+	require.Equal(t, wallet.ErrMergeSeedMismatch, err)
+	_, err = s.MergeWallets(dest.Filename(), "missing.wlt", []byte("pwd"))
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+	_, err = s.MergeWallets(dest.Filename(), "src.wlt", []byte("wrong"))
+	require.Equal(t, wallet.ErrInvalidPassword, err)
+
+	var events []wallet.WalletEvent
+	s.OnWalletChange(func(wltID string, event wallet.WalletEvent) {
+		events = append(events, event)
+	})
+
+	w, err := s.MergeWallets(dest.Filename(), "src.wlt", []byte("pwd"))
+	require.NoError(t, err)
+	require.True(t, w.IsEncrypted())
+	require.Equal(t, []wallet.WalletEvent{wallet.WalletEventUpdated}, events)
+
+	entries, err := w.GetEntries()
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	for i, e := range entries {
+		require.Equal(t, srcAddrs[i], e.Address)
+	}
+	require.Equal(t, "dest label", entries[0].Label)
+	require.Equal(t, "", entries[1].Label)
+	require.Equal(t, "src label 3", entries[3].Label)
+
+	// The source wallet file is deleted and the merged wallet is saved
+	_, err = os.Stat(filepath.Join(dir, "src.wlt"))
+	require.True(t, os.IsNotExist(err))
+	lw, err := wallet.Load(filepath.Join(dir, dest.Filename()))
+	require.NoError(t, err)
+	l, err := lw.EntriesLen()
+	require.NoError(t, err)
+	require.Equal(t, 4, l)
+
+	// The merged wallet can still be decrypted
+	require.NoError(t, s.VerifyPassword(dest.Filename(), []byte("pwd")))
+
+	// bip44 wallets are merged on both chains
+	seed := bip39.MustNewDefaultMnemonic()
+	_, err = s.CreateWallet("b.wlt", wallet.Options{
+		Type:  wallet.WalletTypeBip44,
+		Seed:  seed,
+		Label: "b",
+	})
+	require.NoError(t, err)
+
+	bsrc, err := wallet.NewWallet("bsrc.wlt", "bsrc", seed, wallet.Options{
+		Type: wallet.WalletTypeBip44,
+	})
+	require.NoError(t, err)
+	_, err = bsrc.GenerateAddresses(wallet.OptionChange(), wallet.OptionGenerateN(2))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Save(bsrc, dir))
+
+	_, err = s.MergeWallets("b.wlt", "bsrc.wlt", []byte("pwd"))
+	require.Equal(t, wallet.ErrWalletNotEncrypted, err)
+
+	w, err = s.MergeWallets("b.wlt", "bsrc.wlt", nil)
+	require.NoError(t, err)
+	srcChange, err := bsrc.GetAddresses(wallet.OptionChange())
+	require.NoError(t, err)
+	change, err := w.GetAddresses(wallet.OptionChange())
+	require.NoError(t, err)
+	require.Equal(t, srcChange, change)
+	external, err := w.GetAddresses(wallet.OptionExternal())
+	require.NoError(t, err)
+	require.Len(t, external, 1)
+}
+
+func TestServiceLockAll(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     "seed",
+		Label:    "label",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("t2.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed2",
+		Label: "label2",
+	})
+	require.NoError(t, err)
+
+	// Creating the encrypted wallet does not decrypt it
+	require.True(t, s.LastSecretsAccess().IsZero())
+
+	before := time.Now()
+	require.NoError(t, s.ViewSecrets(w.Filename(), []byte("pwd"), func(w wallet.Wallet) error {
+		require.Equal(t, "seed", w.Seed())
+		return nil
+	}))
+	require.False(t, s.LastSecretsAccess().Before(before))
+
+	require.NoError(t, s.LockAll())
+
+	w, err = s.GetWallet(w.Filename())
+	require.NoError(t, err)
+	require.True(t, w.IsEncrypted())
+	require.Empty(t, w.Seed())
+	entries, err := w.GetEntries()
+	require.NoError(t, err)
+	for _, e := range entries {
+		require.True(t, e.Secret == cipher.SecKey{})
+	}
+
+	// Unencrypted wallets are left unchanged
+	w2, err := s.GetWallet("t2.wlt")
+	require.NoError(t, err)
+	require.Equal(t, "seed2", w2.Seed())
+
+	// The wallet can still be decrypted
+	require.NoError(t, s.VerifyPassword(w.Filename(), []byte("pwd")))
+
+	// Lazily loaded wallets are not loaded to be locked
+	ls, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+		LazyLoad:        true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, ls.LockAll())
+	require.NoError(t, os.Remove(filepath.Join(dir, w.Filename())))
+	_, err = ls.GetWallet(w.Filename())
+	require.IsType(t, wallet.LoadWalletError{}, err)
+
+	s.SetEnableWalletAPI(false)
+	require.Equal(t, wallet.ErrWalletAPIDisabled, s.LockAll())
+}
+
+func TestServiceMaxWallets(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+		MaxWallets:      2,
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err = s.CreateWallet(fmt.Sprintf("t%d.wlt", i), wallet.Options{
+			Type:  wallet.WalletTypeDeterministic,
+			Seed:  fmt.Sprintf("seed%d", i),
+			Label: "label",
+		})
+		require.NoError(t, err)
+	}
+
+	_, err = s.CreateWallet("t2.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed2",
+		Label: "label",
+	})
+	require.Equal(t, wallet.ErrMaxWalletsReached, err)
+
+	w, err := wallet.NewWallet("t2.wlt", "label", "seed2", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		GenerateN: 1,
+	})
+	require.NoError(t, err)
+	importDir := prepareWltDir()
+	defer os.RemoveAll(importDir)
+	require.NoError(t, wallet.Save(w, importDir))
+	_, err = s.ImportWallet(filepath.Join(importDir, "t2.wlt"))
+	require.Equal(t, wallet.ErrMaxWalletsReached, err)
+
+	// Deleting a wallet makes room for another one
+	require.NoError(t, s.DeleteWallet("t1.wlt"))
+	_, err = s.ImportWallet(filepath.Join(importDir, "t2.wlt"))
+	require.NoError(t, err)
+
+	// NewService warns about, or refuses, a wallet directory that holds more wallets than the maximum
+	_, err = wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		EnableWalletAPI: true,
+		MaxWallets:      1,
+	})
+	require.NoError(t, err)
+
+	_, err = wallet.NewService(wallet.Config{
+		WalletDir:        dir,
+		EnableWalletAPI:  true,
+		MaxWallets:       1,
+		FailOnMaxWallets: true,
+	})
+	testutil.RequireError(t, err, "maximum number of wallets reached: the wallet directory holds 2 wallets, the maximum is 1")
+}
+
+func TestServiceEncryptedWalletPublicData(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	c := wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	}
+	s, err := wallet.NewService(c)
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "seed",
+		Label:     "label",
+		Encrypt:   true,
+		Password:  []byte("pwd"),
+		GenerateN: 2,
+	})
+	require.NoError(t, err)
+	addrsr, err := w.GetAddresses()
+	require.NoError(t, err)
+	require.Len(t, addrsr, 2)
+	addrs := wallet.SkycoinAddresses(addrsr)
+	require.NoError(t, s.SetAddressLabel(w.Filename(), addrs[0], "savings"))
+
+	// Reload the wallet from disk, the public data is readable without a password
+	s, err = wallet.NewService(c)
+	require.NoError(t, err)
+	w, err = s.GetWallet("t.wlt")
+	require.NoError(t, err)
+	require.True(t, w.IsEncrypted())
+	require.Equal(t, "label", w.Label())
+	require.Empty(t, w.Seed())
+
+	entries, err := w.GetEntries()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "savings", entries[0].Label)
+	for i, e := range entries {
+		require.Equal(t, addrs[i], e.SkycoinAddress())
+		require.False(t, e.Public == cipher.PubKey{})
+		require.True(t, e.Secret == cipher.SecKey{})
+	}
+
+	loaded, err := s.GetAddresses("t.wlt")
+	require.NoError(t, err)
+	require.Equal(t, addrs, loaded)
+
+	bg := fakeBalanceGetter{
+		addrs[0]: wallet.BalancePair{Confirmed: wallet.Balance{Coins: 1e6}},
+	}
+	total, _, err := s.GetBalance("t.wlt", bg)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1e6), total.Confirmed.Coins)
+
+	// Spending requires the password
+	_, _, err = s.CreateSweepTransaction("t.wlt", nil, addrs[1], coin.AddressUxOuts{}, 0)
+	require.Equal(t, wallet.ErrMissingPassword, err)
+}
+
+func TestServiceCreateTransactionChangeAddress(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	c := wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	}
+	s, err := wallet.NewService(c)
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "seed",
+		Label:     "label",
+		Encrypt:   true,
+		Password:  []byte("pwd"),
+		GenerateN: 2,
+	})
+	require.NoError(t, err)
+
+	var entries []wallet.Entry
+	require.NoError(t, s.ViewSecrets(w.Filename(), []byte("pwd"), func(w wallet.Wallet) error {
+		var err error
+		entries, err = w.GetEntries()
+		return err
+	}))
+
+	uxout := makeUxOut(t, entries[0].Secret, 2e6, 100)
+	uxout.Head.Time = headTime
+	auxs := coin.AddressUxOuts{entries[0].SkycoinAddress(): []coin.UxOut{uxout}}
+
+	newParams := func() wallet.CreateTransactionParams {
+		return wallet.CreateTransactionParams{
+			WalletID: w.Filename(),
+			Password: []byte("pwd"),
+			Params: transaction.Params{
+				HoursSelection: transaction.HoursSelection{
+					Type: transaction.HoursSelectionTypeManual,
+				},
+				To: []coin.TransactionOutput{
+					{
+						Address: makeAddress(),
+						Coins:   1e6,
+						Hours:   1,
+					},
+				},
+			},
+		}
+	}
+
+	// The change address must belong to the wallet
+	p := newParams()
+	other := makeAddress()
+	p.ChangeAddress = &other
+	_, _, err = s.BatchCreateTransactions([]wallet.CreateTransactionParams{p}, auxs, headTime)
+	require.Equal(t, wallet.BatchError{Errors: map[int]error{0: wallet.ErrChangeAddressNotInWallet}}, err)
+
+	p = newParams()
+	changeAddr := entries[1].SkycoinAddress()
+	p.ChangeAddress = &changeAddr
+	txns, _, err := s.BatchCreateTransactions([]wallet.CreateTransactionParams{p}, auxs, headTime)
+	require.NoError(t, err)
+	require.Len(t, txns[0].Out, 2)
+	require.Equal(t, changeAddr, txns[0].Out[1].Address)
+
+	// The options cannot be combined
+	p = newParams()
+	p.ChangeAddress = &changeAddr
+	p.GenerateChange = true
+	_, _, err = s.CreateUnsignedTransaction(p, auxs, headTime)
+	require.Equal(t, wallet.ErrChangeAddressConflict, err)
+
+	p = newParams()
+	p.GenerateChange = true
+	p.Params.ChangeAddress = &other
+	_, _, err = s.CreateUnsignedTransaction(p, auxs, headTime)
+	require.Equal(t, wallet.ErrChangeAddressConflict, err)
+
+	// Generating a change address of an encrypted wallet requires the password
+	p = newParams()
+	p.GenerateChange = true
+	p.Password = nil
+	_, _, err = s.CreateUnsignedTransaction(p, auxs, headTime)
+	require.Equal(t, wallet.ErrMissingPassword, err)
+
+	// No address is generated if the transaction is not created
+	p = newParams()
+	p.GenerateChange = true
+	p.Params.To[0].Coins = 3e6
+	_, _, err = s.CreateUnsignedTransaction(p, auxs, headTime)
+	require.Equal(t, transaction.ErrInsufficientBalance, err)
+
+	p = newParams()
+	p.GenerateChange = true
+	verifyErr := errors.New("verify failed")
+	_, _, err = s.CreateVerifiedTransaction(p, auxs, headTime, transaction.TxnSigned, func(*coin.Transaction) error {
+		return verifyErr
+	})
+	require.Equal(t, verifyErr, err)
+
+	addrs, err := s.GetAddresses(w.Filename())
+	require.NoError(t, err)
+	require.Len(t, addrs, 2)
+
+	p = newParams()
+	p.GenerateChange = true
+	txn, _, err := s.CreateUnsignedTransaction(p, auxs, headTime)
+	require.NoError(t, err)
+
+	addrs, err = s.GetAddresses(w.Filename())
+	require.NoError(t, err)
+	require.Len(t, addrs, 3)
+	require.Equal(t, addrs[2], txn.Out[1].Address)
+
+	// The generated address is persisted
+	s, err = wallet.NewService(c)
+	require.NoError(t, err)
+	addrs, err = s.GetAddresses(w.Filename())
+	require.NoError(t, err)
+	require.Len(t, addrs, 3)
+}
+
+func TestServiceCreateTransactionChangeWallet(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	c := wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	}
+	s, err := wallet.NewService(c)
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "seed",
+		Label:     "label",
+		Encrypt:   true,
+		Password:  []byte("pwd"),
+		GenerateN: 2,
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("change.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     "change seed",
+		Label:    "change",
+		Encrypt:  true,
+		Password: []byte("cpwd"),
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("btc.wlt", wallet.Options{
+		Type:  wallet.WalletTypeCollection,
+		Coin:  wallet.CoinTypeBitcoin,
+		Label: "btc",
+	})
+	require.NoError(t, err)
+
+	var entries []wallet.Entry
+	require.NoError(t, s.ViewSecrets(w.Filename(), []byte("pwd"), func(w wallet.Wallet) error {
+		var err error
+		entries, err = w.GetEntries()
+		return err
+	}))
+
+	uxout := makeUxOut(t, entries[0].Secret, 2e6, 100)
+	uxout.Head.Time = headTime
+	auxs := coin.AddressUxOuts{entries[0].SkycoinAddress(): []coin.UxOut{uxout}}
+
+	newParams := func(changeWalletID string) wallet.CreateTransactionParams {
+		return wallet.CreateTransactionParams{
+			WalletID: w.Filename(),
+			Password: []byte("pwd"),
+			Params: transaction.Params{
+				HoursSelection: transaction.HoursSelection{
+					Type: transaction.HoursSelectionTypeManual,
+				},
+				To: []coin.TransactionOutput{
+					{
+						Address: makeAddress(),
+						Coins:   1e6,
+						Hours:   1,
+					},
+				},
+			},
+			TransactionOptions: wallet.TransactionOptions{
+				ChangeWalletID:       changeWalletID,
+				ChangeWalletPassword: []byte("cpwd"),
+			},
+		}
+	}
+
+	p := newParams("missing.wlt")
+	_, _, err = s.CreateUnsignedTransaction(p, auxs, headTime)
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+
+	// The change wallet must have the coin type of the spending wallet
+	p = newParams("btc.wlt")
+	p.ChangeWalletPassword = nil
+	_, _, err = s.CreateUnsignedTransaction(p, auxs, headTime)
+	require.Equal(t, wallet.ErrChangeWalletCoinMismatch, err)
+
+	p = newParams("")
+	_, _, err = s.CreateUnsignedTransaction(p, auxs, headTime)
+	require.Equal(t, wallet.ErrChangeWalletPasswordWithoutID, err)
+
+	p = newParams("change.wlt")
+	p.GenerateChange = true
+	_, _, err = s.CreateUnsignedTransaction(p, auxs, headTime)
+	require.Equal(t, wallet.ErrChangeAddressConflict, err)
+
+	// No change wallet address is saved if the transaction is not created
+	p = newParams("change.wlt")
+	p.ChangeWalletPassword = []byte("bad")
+	_, _, err = s.CreateUnsignedTransaction(p, auxs, headTime)
+	require.Equal(t, wallet.ErrInvalidPassword, err)
+
+	p = newParams("change.wlt")
+	p.Password = []byte("bad")
+	_, _, err = s.BatchCreateTransactions([]wallet.CreateTransactionParams{p}, auxs, headTime)
+	require.Equal(t, wallet.BatchError{Errors: map[int]error{0: wallet.ErrInvalidPassword}}, err)
+
+	p = newParams("change.wlt")
+	p.Params.To[0].Coins = 3e6
+	_, _, err = s.BatchCreateTransactions([]wallet.CreateTransactionParams{p}, auxs, headTime)
+	require.Equal(t, wallet.BatchError{Errors: map[int]error{0: transaction.ErrInsufficientBalance}}, err)
+
+	changeAddrs, err := s.GetAddresses("change.wlt")
+	require.NoError(t, err)
+	require.Len(t, changeAddrs, 1)
+
+	txns, _, err := s.BatchCreateTransactions([]wallet.CreateTransactionParams{newParams("change.wlt")}, auxs, headTime)
+	require.NoError(t, err)
+
+	changeAddrs, err = s.GetAddresses("change.wlt")
+	require.NoError(t, err)
+	require.Len(t, changeAddrs, 2)
+	require.Len(t, txns[0].Out, 2)
+	require.Equal(t, changeAddrs[1], txns[0].Out[1].Address)
+
+	addrs, err := s.GetAddresses(w.Filename())
+	require.NoError(t, err)
+	require.Len(t, addrs, 2)
+
+	// The change wallet may be the spending wallet
+	p = newParams(w.Filename())
+	p.ChangeWalletPassword = []byte("pwd")
+	txns, _, err = s.BatchCreateTransactions([]wallet.CreateTransactionParams{p}, auxs, headTime)
+	require.NoError(t, err)
+
+	addrs, err = s.GetAddresses(w.Filename())
+	require.NoError(t, err)
+	require.Len(t, addrs, 3)
+	require.Equal(t, addrs[2], txns[0].Out[1].Address)
+
+	// The generated addresses are persisted, bitcoin wallets can't be loaded
+	require.NoError(t, s.DeleteWallet("btc.wlt"))
+	s, err = wallet.NewService(c)
+	require.NoError(t, err)
+	changeAddrs, err = s.GetAddresses("change.wlt")
+	require.NoError(t, err)
+	require.Len(t, changeAddrs, 2)
+	addrs, err = s.GetAddresses(w.Filename())
+	require.NoError(t, err)
+	require.Len(t, addrs, 3)
+}
+func TestServiceVerifyIntegrity(t *testing.T) {
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       prepareWltDir(),
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+	require.Empty(t, s.VerifyIntegrity())
+
+	w, err := s.CreateWallet("t1.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("t2.wlt", wallet.Options{
+		Type:  wallet.WalletTypeCollection,
+		Label: "empty collection",
+	})
+	require.NoError(t, err)
+	require.Empty(t, s.VerifyIntegrity())
+
+	// A watch-only wallet of the first key of the deterministic wallet
+	e, err := w.GetEntryAt(0)
+	require.NoError(t, err)
+	_, err = s.CreateWallet("t3.wlt", wallet.Options{
+		Type:                wallet.WalletTypeWatchOnly,
+		Label:               "watch",
+		WatchOnlyPublicKeys: []cipher.PubKey{e.Public},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, []error{
+		wallet.SharedAddressError{
+			WalletID:      "t3.wlt",
+			OtherWalletID: "t1.wlt",
+			Address:       e.Address.String(),
+		},
+	}, s.VerifyIntegrity())
+
+	s.SetEnableWalletAPI(false)
+	require.Equal(t, []error{wallet.ErrWalletAPIDisabled}, s.VerifyIntegrity())
+}
+
+func TestServiceGetTransactionInputs(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       prepareWltDir(),
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.NoError(t, err)
+	e, err := w.GetEntryAt(0)
+	require.NoError(t, err)
+
+	var uxouts []coin.UxOut
+	for i := 0; i < 3; i++ {
+		uxout := makeUxOut(t, e.Secret, 2e6, 100)
+		uxout.Head.Time = headTime - 3600
+		uxout.Head.BkSeq = uint64(i + 1)
+		uxouts = append(uxouts, uxout)
+	}
+	auxs := coin.AddressUxOuts{e.SkycoinAddress(): uxouts}
+
+	params := wallet.CreateTransactionParams{
+		WalletID: w.Filename(),
+		Params: transaction.Params{
+			HoursSelection: transaction.HoursSelection{
+				Type: transaction.HoursSelectionTypeManual,
+			},
+			To: []coin.TransactionOutput{
+				{
+					Address: makeAddress(),
+					Coins:   3e6,
+					Hours:   1,
+				},
+			},
+		},
+	}
+	txns, inputs, err := s.BatchCreateTransactions([]wallet.CreateTransactionParams{params}, auxs, headTime)
+	require.NoError(t, err)
+
+	resolved, err := s.GetTransactionInputs(txns[0], auxs, headTime)
+	require.NoError(t, err)
+	require.Equal(t, inputs[0], resolved)
+
+	// Coin hours are calculated at the given head time
+	resolved, err = s.GetTransactionInputs(txns[0], auxs, headTime+3600)
+	require.NoError(t, err)
+	for i, in := range resolved {
+		require.True(t, in.Hours > inputs[0][i].Hours)
+	}
+
+	// An input missing from auxs can't be resolved
+	missing := make(coin.AddressUxOuts)
+	for _, ux := range uxouts {
+		if ux.Hash() != txns[0].In[0] {
+			missing[ux.Body.Address] = append(missing[ux.Body.Address], ux)
+		}
+	}
+	_, err = s.GetTransactionInputs(txns[0], missing, headTime)
+	require.Equal(t, wallet.ErrTransactionInputNotFound, err)
+
+	s.SetEnableWalletAPI(false)
+	_, err = s.GetTransactionInputs(txns[0], auxs, headTime)
+	require.Equal(t, wallet.ErrWalletAPIDisabled, err)
+}
+
+func TestServiceScanAddressesByBalance(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	c := wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	}
+	s, err := wallet.NewService(c)
+	require.NoError(t, err)
+
+	var addrs []cipher.Address
+	_, seckeys := cipher.MustGenerateDeterministicKeyPairsSeed([]byte("seed"), 12)
+	for _, k := range seckeys {
+		addrs = append(addrs, cipher.MustAddressFromSecKey(k))
+	}
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     "seed",
+		Label:    "label",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+
+	// Funds on the 4th and 7th addresses, separated by 2 unused addresses
+	bg := fakeBalanceGetter{
+		addrs[3]: wallet.BalancePair{Confirmed: wallet.Balance{Coins: 1e6}},
+		addrs[6]: wallet.BalancePair{Predicted: wallet.Balance{Coins: 1e6}},
+	}
+
+	_, err = s.ScanAddressesByBalance(w.Filename(), nil, 3, bg)
+	require.Equal(t, wallet.ErrMissingPassword, err)
+
+	_, err = s.ScanAddressesByBalance(w.Filename(), []byte("pwd"), 3, nil)
+	require.Equal(t, wallet.ErrNilTransactionsFinder, err)
+
+	// A gap limit of 2 stops at the 2 unused addresses that follow the first address
+	newAddrs, err := s.ScanAddressesByBalance(w.Filename(), []byte("pwd"), 2, bg)
+	require.NoError(t, err)
+	require.Empty(t, newAddrs)
+
+	newAddrs, err = s.ScanAddressesByBalance(w.Filename(), []byte("pwd"), 3, bg)
+	require.NoError(t, err)
+	require.Equal(t, addrs[1:7], newAddrs)
+
+	// Nothing is found past the last funded address
+	newAddrs, err = s.ScanAddressesByBalance(w.Filename(), []byte("pwd"), 3, bg)
+	require.NoError(t, err)
+	require.Empty(t, newAddrs)
+
+	// The addresses are saved
+	s, err = wallet.NewService(c)
+	require.NoError(t, err)
+	wltAddrs, err := s.GetAddresses(w.Filename())
+	require.NoError(t, err)
+	require.Equal(t, addrs[:7], wltAddrs)
+}
+
+func TestServiceNewAccount(t *testing.T) {
+	seed := "voyage say extend find sheriff surge priority merit ignore maple cash argue"
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       prepareWltDir(),
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+		EnableSeedAPI:   true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:     wallet.WalletTypeBip44,
+		Seed:     seed,
+		Label:    "label",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+
+	_, err = s.NewAccount(w.Filename(), nil, "savings")
+	require.Equal(t, wallet.ErrMissingPassword, err)
+	_, err = s.NewAccount(w.Filename(), []byte("wrong"), "savings")
+	require.Equal(t, wallet.ErrInvalidPassword, err)
+
+	index, err := s.NewAccount(w.Filename(), []byte("pwd"), "savings")
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), index)
+
+	_, err = s.NewAddresses(w.Filename(), nil, wallet.OptionAccount(index), wallet.OptionGenerateN(2))
+	require.NoError(t, err)
+
+	w, err = s.GetWallet(w.Filename())
+	require.NoError(t, err)
+	require.Equal(t, []wallet.Bip44Account{
+		{Name: "default", Index: 0},
+		{Name: "savings", Index: 1},
+	}, w.Accounts())
+	addrs, err := w.GetAddresses(wallet.OptionAccount(1), wallet.OptionExternal())
+	require.NoError(t, err)
+	require.Len(t, addrs, 3)
+	changeAddrs, err := w.GetAddresses(wallet.OptionAccount(1), wallet.OptionChange())
+	require.NoError(t, err)
+	require.Len(t, changeAddrs, 1)
+	defaultAddrs, err := w.GetAddresses(wallet.OptionAccount(0))
+	require.NoError(t, err)
+	require.NotEqual(t, defaultAddrs[0], addrs[0])
+
+	// Recovering the wallet restores all of its accounts
+	w2, err := s.RecoverWallet(w.Filename(), seed, "", []byte("pwd2"))
+	require.NoError(t, err)
+	require.Equal(t, w.Accounts(), w2.Accounts())
+	for _, a := range w.Accounts() {
+		for _, opt := range []wallet.Option{wallet.OptionExternal(), wallet.OptionChange()} {
+			addrs, err := w.GetAddresses(wallet.OptionAccount(a.Index), opt)
+			require.NoError(t, err)
+			addrs2, err := w2.GetAddresses(wallet.OptionAccount(a.Index), opt)
+			require.NoError(t, err)
+			require.Equal(t, addrs, addrs2)
+		}
+	}
+
+	// Accounts are only supported by bip44 wallets
+	w3, err := s.CreateWallet("t3.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.NoError(t, err)
+	_, err = s.NewAccount(w3.Filename(), nil, "savings")
+	require.Equal(t, wallet.ErrWalletNotBip44, err)
+}
+
+func TestServiceGetWalletCryptoType(t *testing.T) {
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       prepareWltDir(),
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t1.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     "seed1",
+		Label:    "label",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+
+	ct, err := s.GetWalletCryptoType(w.Filename())
+	require.NoError(t, err)
+	require.Equal(t, crypto.CryptoTypeSha256Xor, ct)
+
+	w, err = s.CreateWallet("t2.wlt", wallet.Options{
+		Type:       wallet.WalletTypeDeterministic,
+		Seed:       "seed2",
+		Label:      "label",
+		Encrypt:    true,
+		Password:   []byte("pwd"),
+		CryptoType: crypto.CryptoTypeScryptChacha20poly1305,
+	})
+	require.NoError(t, err)
+
+	ct, err = s.GetWalletCryptoType(w.Filename())
+	require.NoError(t, err)
+	require.Equal(t, crypto.CryptoTypeScryptChacha20poly1305, ct)
+
+	w, err = s.CreateWallet("t3.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed3",
+		Label: "label",
+	})
+	require.NoError(t, err)
+
+	ct, err = s.GetWalletCryptoType(w.Filename())
+	require.NoError(t, err)
+	require.Empty(t, ct)
+
+	_, err = s.GetWalletCryptoType("unknown.wlt")
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+
+	s.SetEnableWalletAPI(false)
+	_, err = s.GetWalletCryptoType(w.Filename())
+	require.Equal(t, wallet.ErrWalletAPIDisabled, err)
+}
+
+func TestServiceCompact(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	// A wallet of an older version, with a field unknown to this version
+	data, err := ioutil.ReadFile("./testdata/v2_no_encrypt.wlt")
+	require.NoError(t, err)
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &raw))
+	raw["deprecated"] = "value"
+	data, err = json.Marshal(raw)
+	require.NoError(t, err)
+	wf := filepath.Join(dir, "v2_no_encrypt.wlt")
+	require.NoError(t, ioutil.WriteFile(wf, data, 0600))
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	addrs, err := s.GetAddresses("v2_no_encrypt.wlt")
+	require.NoError(t, err)
+
+	require.NoError(t, s.Compact("v2_no_encrypt.wlt"))
+
+	data, err = ioutil.ReadFile(wf)
+	require.NoError(t, err)
+	raw = nil
+	require.NoError(t, json.Unmarshal(data, &raw))
+	require.NotContains(t, raw, "deprecated")
+	require.Equal(t, wallet.Version, raw["meta"].(map[string]interface{})["version"])
+
+	w, err := wallet.Load(wf)
+	require.NoError(t, err)
+	compactAddrs, err := w.GetAddresses()
+	require.NoError(t, err)
+	require.Equal(t, addrs, wallet.SkycoinAddresses(compactAddrs))
+
+	require.Equal(t, wallet.ErrWalletNotExist, s.Compact("unknown.wlt"))
+
+	s.SetEnableWalletAPI(false)
+	require.Equal(t, wallet.ErrWalletAPIDisabled, s.Compact("v2_no_encrypt.wlt"))
+}
+
+func TestServiceUnlockLockout(t *testing.T) {
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:         prepareWltDir(),
+		CryptoType:        crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI:   true,
+		MaxUnlockAttempts: 2,
+		UnlockLockout:     200 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     "seed",
+		Label:    "label",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+
+	// A successful unlock resets the failed attempts
+	require.Equal(t, wallet.ErrInvalidPassword, s.VerifyPassword(w.Filename(), []byte("wrong")))
+	require.NoError(t, s.VerifyPassword(w.Filename(), []byte("pwd")))
+	require.Equal(t, wallet.ErrInvalidPassword, s.VerifyPassword(w.Filename(), []byte("wrong")))
+	require.NoError(t, s.VerifyPassword(w.Filename(), []byte("pwd")))
+
+	// A missing password is not counted
+	require.Equal(t, wallet.ErrMissingPassword, s.VerifyPassword(w.Filename(), nil))
+
+	// The wallet is locked after 2 wrong passwords, even for the right password
+	require.Equal(t, wallet.ErrInvalidPassword, s.VerifyPassword(w.Filename(), []byte("wrong")))
+	err = s.ViewSecrets(w.Filename(), []byte("wrong"), func(wallet.Wallet) error {
+		return nil
+	})
+	require.Equal(t, wallet.ErrInvalidPassword, err)
+	require.Equal(t, wallet.ErrWalletLocked, s.VerifyPassword(w.Filename(), []byte("pwd")))
+	_, err = s.DecryptWallet(w.Filename(), []byte("pwd"))
+	require.Equal(t, wallet.ErrWalletLocked, err)
+	_, err = s.NewAddresses(w.Filename(), []byte("pwd"), wallet.OptionGenerateN(1))
+	require.Equal(t, wallet.ErrWalletLocked, err)
+
+	// Other wallets are not affected
+	w2, err := s.CreateWallet("t2.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     "seed2",
+		Label:    "label",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.VerifyPassword(w2.Filename(), []byte("pwd")))
+
+	// The wallet can be decrypted again after the lockout
+	time.Sleep(250 * time.Millisecond)
+	_, err = s.DecryptWallet(w.Filename(), []byte("pwd"))
+	require.NoError(t, err)
+}
+
+func TestServiceCreateTransactionUxOuts(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "seed",
+		Label:     "label",
+		GenerateN: 2,
+	})
+	require.NoError(t, err)
+
+	entries, err := w.GetEntries()
+	require.NoError(t, err)
+
+	ux0 := makeUxOut(t, entries[0].Secret, 2e6, 100)
+	ux0.Head.Time = headTime
+	ux1 := makeUxOut(t, entries[1].Secret, 5e6, 100)
+	ux1.Head.Time = headTime
+	auxs := coin.AddressUxOuts{
+		entries[0].SkycoinAddress(): []coin.UxOut{ux0},
+		entries[1].SkycoinAddress(): []coin.UxOut{ux1},
+	}
+
+	newParams := func(coins uint64, uxOuts ...cipher.SHA256) wallet.CreateTransactionParams {
+		return wallet.CreateTransactionParams{
+			WalletID: w.Filename(),
+			Params: transaction.Params{
+				HoursSelection: transaction.HoursSelection{
+					Type: transaction.HoursSelectionTypeManual,
+				},
+				To: []coin.TransactionOutput{
+					{
+						Address: makeAddress(),
+						Coins:   coins,
+						Hours:   1,
+					},
+				},
+			},
+			UxOuts: uxOuts,
+		}
+	}
+
+	// Only the whitelisted output is spent
+	txn, uxb, err := s.CreateUnsignedTransaction(newParams(1e6, ux0.Hash()), auxs, headTime)
+	require.NoError(t, err)
+	require.Equal(t, []cipher.SHA256{ux0.Hash()}, txn.In)
+	require.Len(t, uxb, 1)
+
+	txns, _, err := s.BatchCreateTransactions([]wallet.CreateTransactionParams{newParams(1e6, ux0.Hash())}, auxs, headTime)
+	require.NoError(t, err)
+	require.Equal(t, []cipher.SHA256{ux0.Hash()}, txns[0].In)
+
+	// The whitelist must cover the amount
+	_, _, err = s.CreateUnsignedTransaction(newParams(3e6, ux0.Hash()), auxs, headTime)
+	require.Equal(t, transaction.ErrInsufficientBalance, err)
+
+	// Unknown outputs are rejected
+	unknown := makeUxOut(t, entries[0].Secret, 1e6, 100)
+	_, _, err = s.CreateUnsignedTransaction(newParams(1e6, unknown.Hash()), auxs, headTime)
+	require.Equal(t, wallet.ErrUnknownUxOut, err)
+
+	_, _, err = s.BatchCreateTransactions([]wallet.CreateTransactionParams{newParams(1e6, ux0.Hash(), unknown.Hash())}, auxs, headTime)
+	require.Equal(t, wallet.BatchError{Errors: map[int]error{0: wallet.ErrUnknownUxOut}}, err)
+}
+
+func TestServiceSetWalletNotes(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	data, err := ioutil.ReadFile("./testdata/v2_no_encrypt.wlt")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "v2_no_encrypt.wlt"), data, 0600))
+
+	c := wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	}
+	s, err := wallet.NewService(c)
+	require.NoError(t, err)
+
+	// Wallets written without notes load with empty notes
+	w, err := s.GetWallet("v2_no_encrypt.wlt")
+	require.NoError(t, err)
+	require.Empty(t, w.Notes())
+
+	_, err = s.CreateWallet("t.wlt", wallet.Options{
+		Seed:     "seed-notes",
+		Label:    "label",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+		Type:     wallet.WalletTypeDeterministic,
+	})
+	require.NoError(t, err)
+
+	err = s.SetWalletNotes("unknown.wlt", "notes")
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+
+	// The notes of an encrypted wallet are set and read without the password
+	require.NoError(t, s.SetWalletNotes("t.wlt", "cold storage, owned by ops"))
+
+	w, err = s.GetWallet("t.wlt")
+	require.NoError(t, err)
+	require.Equal(t, "cold storage, owned by ops", w.Notes())
+	require.True(t, w.IsEncrypted())
+
+	// The notes are saved
+	s, err = wallet.NewService(c)
+	require.NoError(t, err)
+	w, err = s.GetWallet("t.wlt")
+	require.NoError(t, err)
+	require.Equal(t, "cold storage, owned by ops", w.Notes())
+
+	// Empty notes remove them
+	require.NoError(t, s.SetWalletNotes("t.wlt", ""))
+	w, err = s.GetWallet("t.wlt")
+	require.NoError(t, err)
+	require.Empty(t, w.Notes())
+
+	// The notes can't be set on a read only service
+	c.ReadOnly = true
+	s, err = wallet.NewService(c)
+	require.NoError(t, err)
+	err = s.SetWalletNotes("t.wlt", "notes")
+	require.Equal(t, wallet.ErrWalletReadOnly, err)
+}
+
+func TestServiceLazyLoad(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	// A wallet saved by an older version, without the header
+	data, err := ioutil.ReadFile("./testdata/v2_no_encrypt.wlt")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "v2_no_encrypt.wlt"), data, 0600))
+
+	c := wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	}
+	s, err := wallet.NewService(c)
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Seed:      "seed-lazy",
+		Label:     "label",
+		Type:      wallet.WalletTypeDeterministic,
+		GenerateN: 2,
+	})
+	require.NoError(t, err)
+	addrs, err := w.GetAddresses()
+	require.NoError(t, err)
+
+	// Save records the header in the wallet meta data
+	data, err = ioutil.ReadFile(filepath.Join(dir, "t.wlt"))
+	require.NoError(t, err)
+	var raw map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &raw))
+	var m wallet.Meta
+	require.NoError(t, json.Unmarshal(raw["meta"], &m))
+	fp, firstAddr, ok := m.Header()
+	require.True(t, ok)
+	require.Equal(t, w.Fingerprint(), fp)
+	require.Equal(t, addrs[0].String(), firstAddr)
+
+	// The wallets are loaded on first access
+	c.LazyLoad = true
+	s, err = wallet.NewService(c)
+	require.NoError(t, err)
+
+	names, err := s.GetWalletNames()
+	require.NoError(t, err)
+	require.Len(t, names, 2)
+
+	lw, err := s.GetWallet("t.wlt")
+	require.NoError(t, err)
+	require.Equal(t, "label", lw.Label())
+	lwAddrs, err := lw.GetAddresses()
+	require.NoError(t, err)
+	require.Equal(t, addrs, lwAddrs)
+
+	_, err = s.NewAddresses("t.wlt", nil, wallet.OptionGenerateN(1))
+	require.NoError(t, err)
+
+	// The entries of the indexed wallets are not read by NewService
+	raw["entries"] = json.RawMessage("5")
+	data, err = json.Marshal(raw)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "t.wlt"), data, 0600))
+
+	s, err = wallet.NewService(c)
+	require.NoError(t, err)
+
+	// Looking a wallet up by its first address uses the headers, so the other wallets are not loaded
+	v2Addrs, err := s.GetAddresses("v2_no_encrypt.wlt")
+	require.NoError(t, err)
+	fw, err := s.GetWalletByFirstAddress(v2Addrs[0])
+	require.NoError(t, err)
+	require.Equal(t, "v2_no_encrypt.wlt", fw.Filename())
+
+	_, err = s.GetWallet("t.wlt")
+	require.IsType(t, wallet.LoadWalletError{}, err)
+
+	c.LazyLoad = false
+	_, err = wallet.NewService(c)
+	require.IsType(t, wallet.LoadWalletError{}, err)
+	c.LazyLoad = true
+
+	// Duplicate wallets are found from the headers
+	m.SetFilename("t2.wlt")
+	meta, err := json.Marshal(m)
+	require.NoError(t, err)
+	raw["meta"] = meta
+	data, err = json.Marshal(raw)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "t2.wlt"), data, 0600))
+
+	_, err = wallet.NewService(c)
+	require.IsType(t, wallet.DuplicateWalletError{}, err)
+	require.Equal(t, fp, err.(wallet.DuplicateWalletError).Fingerprint)
+
+	// Empty wallets are found from the headers
+	m.SetHeader("deterministic-", "")
+	meta, err = json.Marshal(m)
+	require.NoError(t, err)
+	raw["meta"] = meta
+	data, err = json.Marshal(raw)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "t2.wlt"), data, 0600))
+
+	_, err = wallet.NewService(c)
+	require.Equal(t, wallet.EmptyWalletError{WalletID: "t2.wlt"}, err)
+}
+
+func TestServiceCreateTransferTransaction(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	src, err := s.CreateWallet("src.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     "seed-src",
+		Label:    "src",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+
+	dest, err := s.CreateWallet("dest.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "seed-dest",
+		Label:     "dest",
+		Encrypt:   true,
+		Password:  []byte("other"),
+		GenerateN: 3,
+	})
+	require.NoError(t, err)
+	destAddrs, err := dest.GetAddresses()
+	require.NoError(t, err)
+
+	var entries []wallet.Entry
+	require.NoError(t, s.ViewSecrets(src.Filename(), []byte("pwd"), func(w wallet.Wallet) error {
+		var err error
+		entries, err = w.GetEntries()
+		return err
+	}))
+
+	uxout := makeUxOut(t, entries[0].Secret, 5e6, 100)
+	uxout.Head.Time = headTime
+	auxs := coin.AddressUxOuts{entries[0].SkycoinAddress(): []coin.UxOut{uxout}}
+
+	_, _, err = s.CreateTransferTransaction("src.wlt", []byte("pwd"), "src.wlt", 1e6, auxs, headTime)
+	require.Equal(t, wallet.ErrTransferToSameWallet, err)
+
+	_, _, err = s.CreateTransferTransaction("src.wlt", []byte("pwd"), "unknown.wlt", 1e6, auxs, headTime)
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+
+	_, _, err = s.CreateTransferTransaction("src.wlt", []byte("wrong"), "dest.wlt", 1e6, auxs, headTime)
+	require.Equal(t, wallet.ErrInvalidPassword, err)
+
+	// Only the password of the source wallet is needed
+	txn, uxb, err := s.CreateTransferTransaction("src.wlt", []byte("pwd"), "dest.wlt", 1e6, auxs, headTime)
+	require.NoError(t, err)
+	require.Len(t, uxb, 1)
+	require.NoError(t, txn.Verify())
+	require.Len(t, txn.Out, 2)
+	require.Equal(t, destAddrs[2].(cipher.Address), txn.Out[0].Address)
+	require.Equal(t, uint64(1e6), txn.Out[0].Coins)
+	require.Equal(t, entries[0].SkycoinAddress(), txn.Out[1].Address)
+
+	// The destination wallet is unchanged
+	addrs, err := s.GetAddresses("dest.wlt")
+	require.NoError(t, err)
+	require.Len(t, addrs, 3)
+
+	_, _, err = s.CreateTransferTransaction("src.wlt", []byte("pwd"), "dest.wlt", 10e6, auxs, headTime)
+	require.Equal(t, transaction.ErrInsufficientBalance, err)
+
+	// An address is generated for a destination wallet without addresses
+	_, err = s.CreateWallet("empty.wlt", wallet.Options{
+		Type:               wallet.WalletTypeDeterministic,
+		Seed:               "seed-empty",
+		Label:              "empty",
+		NoDefaultAddresses: true,
+	})
+	require.NoError(t, err)
+
+	// The address is not saved if the transaction is not created
+	_, _, err = s.CreateTransferTransaction("src.wlt", []byte("pwd"), "empty.wlt", 10e6, auxs, headTime)
+	require.Equal(t, transaction.ErrInsufficientBalance, err)
+	addrs, err = s.GetAddresses("empty.wlt")
+	require.NoError(t, err)
+	require.Empty(t, addrs)
+
+	txn, _, err = s.CreateTransferTransaction("src.wlt", []byte("pwd"), "empty.wlt", 1e6, auxs, headTime)
+	require.NoError(t, err)
+	addrs, err = s.GetAddresses("empty.wlt")
+	require.NoError(t, err)
+	require.Len(t, addrs, 1)
+	require.Equal(t, addrs[0], txn.Out[0].Address)
+
+	lw, err := wallet.Load(filepath.Join(dir, "empty.wlt"))
+	require.NoError(t, err)
+	l, err := lw.EntriesLen()
+	require.NoError(t, err)
+	require.Equal(t, 1, l)
+}
+
+func TestServiceWalletFileInfo(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	c := wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	}
+	s, err := wallet.NewService(c)
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("t.wlt", wallet.Options{
+		Seed:  "seed",
+		Label: "label",
+		Type:  wallet.WalletTypeDeterministic,
+	})
+	require.NoError(t, err)
+
+	_, err = s.GetWalletFileInfo("unknown.wlt")
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+	_, err = s.HasExternalChanges("unknown.wlt")
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+
+	wf := filepath.Join(dir, "t.wlt")
+	fi, err := os.Stat(wf)
+	require.NoError(t, err)
+
+	info, err := s.GetWalletFileInfo("t.wlt")
+	require.NoError(t, err)
+	require.Equal(t, fi.Size(), info.Size)
+	require.True(t, fi.ModTime().Equal(info.ModTime))
+	require.True(t, info.ModTime.Equal(info.LoadedModTime))
+
+	changed, err := s.HasExternalChanges("t.wlt")
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	// Changes saved by the service are not external
+	require.NoError(t, s.UpdateWalletLabel("t.wlt", "new label"))
+	changed, err = s.HasExternalChanges("t.wlt")
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	// Wallets loaded on startup
+	s, err = wallet.NewService(c)
+	require.NoError(t, err)
+	changed, err = s.HasExternalChanges("t.wlt")
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	// Another process changes the file
+	mt := fi.ModTime().Add(time.Second)
+	require.NoError(t, os.Chtimes(wf, mt, mt))
+
+	changed, err = s.HasExternalChanges("t.wlt")
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	info, err = s.GetWalletFileInfo("t.wlt")
+	require.NoError(t, err)
+	require.True(t, mt.Equal(info.ModTime))
+	require.False(t, info.ModTime.Equal(info.LoadedModTime))
+
+	_, err = s.ReloadWallet("t.wlt")
+	require.NoError(t, err)
+	changed, err = s.HasExternalChanges("t.wlt")
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	// The file is removed
+	require.NoError(t, os.Remove(wf))
+	changed, err = s.HasExternalChanges("t.wlt")
+	require.NoError(t, err)
+	require.True(t, changed)
+	_, err = s.GetWalletFileInfo("t.wlt")
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestServiceCreateTransactionSpendTime(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+	spendTime := headTime + 36000
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.NoError(t, err)
+
+	entries, err := w.GetEntries()
+	require.NoError(t, err)
+	addr := entries[0].SkycoinAddress()
+
+	// 2 coins earn 20 coin hours by the spend time
+	ux := makeUxOut(t, entries[0].Secret, 2e6, 10)
+	ux.Head.Time = headTime
+	ux.Head.BkSeq = 1
+	// An output created after the spend time
+	late := makeUxOut(t, entries[0].Secret, 3e6, 10)
+	late.Head.Time = spendTime + 1
+	late.Head.BkSeq = 2
+	auxs := coin.AddressUxOuts{addr: []coin.UxOut{ux, late}}
+
+	// An output created at the spend time has not accrued any coin hours by then
+	fresh := makeUxOut(t, entries[0].Secret, 3e6, 0)
+	fresh.Head.Time = spendTime
+	fresh.Head.BkSeq = 3
+
+	// An output with coin hours that is younger than the minimum input age at the spend time
+	young := makeUxOut(t, entries[0].Secret, 3e6, 100)
+	young.Head.Time = spendTime - 60
+	young.Head.BkSeq = 4
+
+	newParams := func(spendTime uint64) wallet.CreateTransactionParams {
+		return wallet.CreateTransactionParams{
+			WalletID: w.Filename(),
+			Params: transaction.Params{
+				HoursSelection: transaction.HoursSelection{
+					Type: transaction.HoursSelectionTypeManual,
+				},
+				To: []coin.TransactionOutput{
+					{
+						Address: makeAddress(),
+						Coins:   1e6,
+						Hours:   20,
+					},
+				},
+			},
+			TransactionOptions: wallet.TransactionOptions{
+				SpendTime: spendTime,
+			},
+		}
+	}
+
+	_, _, err = s.CreateUnsignedTransaction(newParams(0), coin.AddressUxOuts{addr: []coin.UxOut{ux}}, headTime)
+	require.Equal(t, transaction.ErrInsufficientHours, err)
+
+	// The coin hours are calculated at the spend time, and the late output is not spent
+	txn, uxb, err := s.CreateUnsignedTransaction(newParams(spendTime), auxs, headTime)
+	require.NoError(t, err)
+	require.Equal(t, []cipher.SHA256{ux.Hash()}, txn.In)
+	require.Equal(t, uint64(30), uxb[0].Hours)
+
+	txns, _, err := s.BatchCreateTransactions([]wallet.CreateTransactionParams{newParams(spendTime)}, auxs, headTime)
+	require.NoError(t, err)
+	require.Equal(t, []cipher.SHA256{ux.Hash()}, txns[0].In)
+
+	_, _, err = s.CreateUnsignedTransaction(newParams(headTime-1), auxs, headTime)
+	require.Equal(t, wallet.ErrSpendTimeBeforeHead, err)
+
+	_, _, err = s.CreateUnsignedTransaction(newParams(spendTime), coin.AddressUxOuts{addr: []coin.UxOut{late}}, headTime)
+	require.Equal(t, wallet.ErrNoMatureUxOuts, err)
+
+	// The output without coin hours at the spend time is not mature
+	_, _, err = s.CreateUnsignedTransaction(newParams(spendTime), coin.AddressUxOuts{addr: []coin.UxOut{fresh}}, headTime)
+	require.Equal(t, wallet.ErrNoMatureUxOuts, err)
+
+	// The output younger than MinInputAge is not spent
+	p := newParams(spendTime)
+	p.TransactionOptions.MinInputAge = 3600
+	txn, _, err = s.CreateUnsignedTransaction(p, coin.AddressUxOuts{addr: []coin.UxOut{young, ux}}, headTime)
+	require.NoError(t, err)
+	require.Equal(t, []cipher.SHA256{ux.Hash()}, txn.In)
+
+	_, _, err = s.CreateUnsignedTransaction(p, coin.AddressUxOuts{addr: []coin.UxOut{young}}, headTime)
+	require.Equal(t, wallet.ErrNoMatureUxOuts, err)
+
+	// Without MinInputAge, the young output is mature
+	txn, _, err = s.CreateUnsignedTransaction(newParams(spendTime), coin.AddressUxOuts{addr: []coin.UxOut{young}}, headTime)
+	require.NoError(t, err)
+	require.Equal(t, []cipher.SHA256{young.Hash()}, txn.In)
+}
+
+func TestServiceRekeyAllWallets(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	c := wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	}
+	s, err := wallet.NewService(c)
+	require.NoError(t, err)
+
+	for _, o := range []struct {
+		id         string
+		encrypt    bool
+		cryptoType crypto.CryptoType
+	}{
+		{"a.wlt", true, crypto.CryptoTypeSha256Xor},
+		{"b.wlt", true, crypto.CryptoTypeSha256Xor},
+		{"c.wlt", true, crypto.CryptoTypeScryptChacha20poly1305},
+		{"d.wlt", false, ""},
+	} {
+		opts := wallet.Options{
+			Type:       wallet.WalletTypeDeterministic,
+			Seed:       "seed-" + o.id,
+			Label:      o.id,
+			Encrypt:    o.encrypt,
+			CryptoType: o.cryptoType,
+		}
+		if o.encrypt {
+			opts.Password = []byte("pwd")
+		}
+		_, err := s.CreateWallet(o.id, opts)
+		require.NoError(t, err)
+	}
+
+	_, _, err = s.RekeyAllWallets(map[string][]byte{"a.wlt": []byte("pwd")}, "unknown")
+	require.Error(t, err)
+
+	migrated, failed, err := s.RekeyAllWallets(map[string][]byte{
+		"a.wlt": []byte("pwd"),
+		"b.wlt": []byte("wrong"),
+		"c.wlt": []byte("pwd"),
+		"d.wlt": []byte("pwd"),
+		"x.wlt": []byte("pwd"),
+	}, crypto.CryptoTypeScryptChacha20poly1305)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.wlt"}, migrated)
+	require.Equal(t, map[string]error{
+		"b.wlt": wallet.ErrInvalidPassword,
+		"d.wlt": wallet.ErrWalletNotEncrypted,
+		"x.wlt": wallet.ErrWalletNotExist,
+	}, failed)
+
+	// The wallets are saved with their crypto type
+	s, err = wallet.NewService(c)
+	require.NoError(t, err)
+	for id, ct := range map[string]crypto.CryptoType{
+		"a.wlt": crypto.CryptoTypeScryptChacha20poly1305,
+		"b.wlt": crypto.CryptoTypeSha256Xor,
+		"c.wlt": crypto.CryptoTypeScryptChacha20poly1305,
+	} {
+		got, err := s.GetWalletCryptoType(id)
+		require.NoError(t, err)
+		require.Equal(t, ct, got, id)
+	}
+
+	// The re-encrypted wallet is decrypted with the same password
+	require.NoError(t, s.ViewSecrets("a.wlt", []byte("pwd"), func(w wallet.Wallet) error {
+		require.Equal(t, "seed-a.wlt", w.Seed())
+		return nil
+	}))
+}
+
+// countingBalanceGetter records the addresses whose balances are requested
+type countingBalanceGetter struct {
+	balances  fakeBalanceGetter
+	requested []cipher.Address
+}
+
+func (bg *countingBalanceGetter) GetBalanceOfAddresses(addrs []cipher.Address) ([]wallet.BalancePair, error) {
+	bg.requested = append(bg.requested, addrs...)
+	return bg.balances.GetBalanceOfAddresses(addrs)
+}
+
+func TestServiceGetCachedBalance(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+		BalanceCacheTTL: time.Hour,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "seed",
+		Label:     "label",
+		GenerateN: 2,
+	})
+	require.NoError(t, err)
+
+	entries, err := w.GetEntries()
+	require.NoError(t, err)
+	addrs := []cipher.Address{entries[0].SkycoinAddress(), entries[1].SkycoinAddress()}
+
+	bg := &countingBalanceGetter{
+		balances: fakeBalanceGetter{
+			addrs[0]: {
+				Confirmed: wallet.NewBalance(1e6, 10),
+				Predicted: wallet.NewBalance(2e6, 20),
+			},
+			addrs[1]: {
+				Confirmed: wallet.NewBalance(3e6, 30),
+				Predicted: wallet.NewBalance(1e6, 5),
+			},
+		},
+	}
+	expect := wallet.BalancePair{
+		Confirmed: wallet.NewBalance(4e6, 40),
+		Predicted: wallet.NewBalance(3e6, 25),
+	}
+
+	total, err := s.GetCachedBalance(w.Filename(), bg)
+	require.NoError(t, err)
+	require.Equal(t, expect, total)
+	require.Equal(t, addrs, bg.requested)
+
+	// The balances are served from the cache within the TTL
+	bg.requested = nil
+	total, err = s.GetCachedBalance(w.Filename(), bg)
+	require.NoError(t, err)
+	require.Equal(t, expect, total)
+	require.Empty(t, bg.requested)
+
+	// Adding addresses clears the cache
+	newAddrs, err := s.NewAddresses(w.Filename(), nil, wallet.OptionGenerateN(1))
+	require.NoError(t, err)
+	bg.requested = nil
+	total, err = s.GetCachedBalance(w.Filename(), bg)
+	require.NoError(t, err)
+	require.Equal(t, expect, total)
+	require.Equal(t, append(addrs, newAddrs[0]), bg.requested)
+
+	// Creating a transaction from the wallet clears the cache
+	ux := makeUxOut(t, entries[0].Secret, 2e6, 10)
+	_, _, err = s.CreateUnsignedTransaction(wallet.CreateTransactionParams{
+		WalletID: w.Filename(),
+		Params: transaction.Params{
+			HoursSelection: transaction.HoursSelection{
+				Type: transaction.HoursSelectionTypeManual,
+			},
+			To: []coin.TransactionOutput{
+				{
+					Address: makeAddress(),
+					Coins:   1e6,
+				},
+			},
+		},
+	}, coin.AddressUxOuts{addrs[0]: []coin.UxOut{ux}}, headTime)
+	require.NoError(t, err)
+	bg.requested = nil
+	_, err = s.GetCachedBalance(w.Filename(), bg)
+	require.NoError(t, err)
+	require.Len(t, bg.requested, 3)
+
+	// The balances are fetched again once expired, and always if the cache is disabled
+	for _, ttl := range []time.Duration{time.Nanosecond, 0} {
+		s2, err := wallet.NewService(wallet.Config{
+			WalletDir:       dir,
+			CryptoType:      crypto.CryptoTypeSha256Xor,
+			EnableWalletAPI: true,
+			BalanceCacheTTL: ttl,
+		})
+		require.NoError(t, err)
+		for i := 0; i < 2; i++ {
+			bg.requested = nil
+			total, err = s2.GetCachedBalance(w.Filename(), bg)
+			require.NoError(t, err)
+			require.Equal(t, expect, total)
+			require.Len(t, bg.requested, 3)
+		}
+	}
+
+	_, err = s.GetCachedBalance("foo.wlt", bg)
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+}
+
+func TestServiceInMemory(t *testing.T) {
+	dir := filepath.Join(prepareWltDir(), "in-memory")
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+		MaxBackups:      2,
+		InMemory:        true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.NoError(t, err)
+
+	addrs, err := s.NewAddresses(w.Filename(), nil, wallet.OptionGenerateN(2))
+	require.NoError(t, err)
+	require.Len(t, addrs, 2)
+
+	_, err = s.EncryptWalletWithCryptoType(w.Filename(), []byte("pwd"), crypto.CryptoTypeSha256Xor)
+	require.NoError(t, err)
+
+	w, err = s.RenameWallet(w.Filename(), "renamed.wlt")
+	require.NoError(t, err)
+
+	w, err = s.GetWallet("renamed.wlt")
+	require.NoError(t, err)
+	require.True(t, w.IsEncrypted())
+	n, err := w.EntriesLen()
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	_, err = s.CreateWallet("t2.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.Equal(t, wallet.NewError(fmt.Errorf("fingerprint conflict for %q wallet", wallet.WalletTypeDeterministic)), err)
+
+	// The wallets have no file
+	_, err = s.ReloadWallet("renamed.wlt")
+	require.Error(t, err)
+	_, err = s.GetWalletFileInfo("renamed.wlt")
+	require.Error(t, err)
+	changed, err := s.HasExternalChanges("renamed.wlt")
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	require.NoError(t, s.DeleteWallet("renamed.wlt"))
+	_, err = s.GetWallet("renamed.wlt")
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+
+	// Nothing was written to disk
+	_, err = os.Stat(dir)
+	require.True(t, os.IsNotExist(err))
+}
+
+type fakeUnspentOutputsGetter struct {
+	auxs coin.AddressUxOuts
+}
+
+func (g fakeUnspentOutputsGetter) GetSpendableOutputsOfAddresses(addrs []cipher.Address) (coin.AddressUxOuts, error) {
+	auxs := make(coin.AddressUxOuts)
+	for _, a := range addrs {
+		if uxs, ok := g.auxs[a]; ok {
+			auxs[a] = uxs
+		}
+	}
+	return auxs, nil
+}
+
+func TestServiceGetMaxSpendable(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "seed",
+		Label:     "label",
+		GenerateN: 3,
+	})
+	require.NoError(t, err)
+
+	addrs, err := s.GetAddresses(w.Filename())
+	require.NoError(t, err)
+
+	var headTime uint64 = 1e9
+	var nOutputs byte
+	makeUxOut := func(addr cipher.Address, coins, hours uint64) coin.UxOut {
+		nOutputs++
+		return coin.UxOut{
+			Head: coin.UxHead{
+				Time:  headTime,
+				BkSeq: 1,
+			},
+			Body: coin.UxBody{
+				SrcTransaction: cipher.SumSHA256([]byte{nOutputs}),
+				Address:        addr,
+				Coins:          coins,
+				Hours:          hours,
+			},
+		}
+	}
+
+	n, err := s.GetMaxSpendable(w.Filename(), fakeUnspentOutputsGetter{}, headTime)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), n)
+
+	// Outputs without coin hours are spent along with an output that has coin hours
+	og := fakeUnspentOutputsGetter{
+		auxs: coin.AddressUxOuts{
+			addrs[0]: coin.UxArray{makeUxOut(addrs[0], 2e6, 10)},
+			addrs[2]: coin.UxArray{makeUxOut(addrs[2], 3e6, 0)},
+		},
+	}
+	n, err = s.GetMaxSpendable(w.Filename(), og, headTime)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5e6), n)
+
+	og = fakeUnspentOutputsGetter{
+		auxs: coin.AddressUxOuts{
+			addrs[1]: coin.UxArray{makeUxOut(addrs[1], 2e6, 0)},
+		},
+	}
+	_, err = s.GetMaxSpendable(w.Filename(), og, headTime)
+	require.Equal(t, wallet.ErrInsufficientCoinHours, err)
+
+	// The wallet has more outputs than fit in a single transaction
+	maxInputs := 0
+	for transaction.EstimateTransactionSize(maxInputs+1, 1) <= int(params.UserVerifyTxn.MaxTransactionSize) {
+		maxInputs++
+	}
+
+	var uxa coin.UxArray
+	var expected uint64
+	for i := 0; i < maxInputs+10; i++ {
+		coins := uint64(i+1) * 1e6
+		uxa = append(uxa, makeUxOut(addrs[0], coins, 1))
+		if i >= 10 {
+			expected += coins
+		}
+	}
+	og = fakeUnspentOutputsGetter{
+		auxs: coin.AddressUxOuts{
+			addrs[0]: uxa,
+		},
+	}
+	n, err = s.GetMaxSpendable(w.Filename(), og, headTime)
+	require.NoError(t, err)
+	require.Equal(t, expected, n)
+
+	// If the outputs with the most coins have no coin hours, the smallest of them is
+	// replaced by the largest output with coin hours
+	uxa = nil
+	expected = 0
+	for i := 0; i < maxInputs+10; i++ {
+		coins := uint64(i+1) * 1e6
+		hours := uint64(0)
+		if i == 5 {
+			hours = 1
+			expected += coins
+		}
+		uxa = append(uxa, makeUxOut(addrs[0], coins, hours))
+		if i >= 11 {
+			expected += coins
+		}
+	}
+	og = fakeUnspentOutputsGetter{
+		auxs: coin.AddressUxOuts{
+			addrs[0]: uxa,
+		},
+	}
+	n, err = s.GetMaxSpendable(w.Filename(), og, headTime)
+	require.NoError(t, err)
+	require.Equal(t, expected, n)
+
+	_, err = s.GetMaxSpendable("foo.wlt", og, headTime)
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+}
+
+func TestServiceClose(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "seed",
+		Label:     "label",
+		GenerateN: 2,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, s.Close())
+
+	// The wallet file is kept
+	_, err = os.Stat(filepath.Join(dir, w.Filename()))
+	require.NoError(t, err)
+
+	_, err = s.GetWallet(w.Filename())
+	require.Equal(t, wallet.ErrServiceClosed, err)
+	_, err = s.GetWallets()
+	require.Equal(t, wallet.ErrServiceClosed, err)
+	_, err = s.CreateWallet("t2.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed2",
+		Label: "label",
+	})
+	require.Equal(t, wallet.ErrServiceClosed, err)
+	require.Equal(t, wallet.ErrServiceClosed, s.Close())
+
+	// The wallet file can be loaded again
+	s, err = wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+	w2, err := s.GetWallet(w.Filename())
+	require.NoError(t, err)
+	require.Equal(t, "seed", w2.Seed())
+}
+
+func TestServiceTransactionMemo(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.NoError(t, err)
+
+	entries, err := w.GetEntries()
+	require.NoError(t, err)
+	addr := entries[0].SkycoinAddress()
+	auxs := coin.AddressUxOuts{
+		addr: []coin.UxOut{
+			makeUxOut(t, entries[0].Secret, 2e6, 10),
+			makeUxOut(t, entries[0].Secret, 2e6, 10),
+		},
+	}
+
+	newParams := func(memo []byte) wallet.CreateTransactionParams {
+		return wallet.CreateTransactionParams{
+			WalletID: w.Filename(),
+			Params: transaction.Params{
+				HoursSelection: transaction.HoursSelection{
+					Type: transaction.HoursSelectionTypeManual,
+				},
+				To: []coin.TransactionOutput{
+					{
+						Address: makeAddress(),
+						Coins:   1e6,
+					},
+				},
+			},
+			TransactionOptions: wallet.TransactionOptions{
+				Memo: memo,
+			},
+		}
+	}
+
+	_, _, err = s.CreateUnsignedTransaction(newParams(make([]byte, wallet.MaxMemoLength+1)), auxs, headTime)
+	require.Equal(t, wallet.ErrMemoTooLong, err)
+
+	txn, uxb, err := s.CreateUnsignedTransaction(newParams([]byte("invoice-42")), auxs, headTime)
+	require.NoError(t, err)
+
+	memo, err := s.GetTransactionMemo(w.Filename(), txn.InnerHash)
+	require.NoError(t, err)
+	require.Equal(t, []byte("invoice-42"), memo)
+
+	summary, err := s.DescribeTransaction(w.Filename(), txn, uxb)
+	require.NoError(t, err)
+	require.Equal(t, []byte("invoice-42"), summary.Memo)
+
+	txns, _, err := s.BatchCreateTransactions([]wallet.CreateTransactionParams{newParams([]byte{0, 1, 2})}, auxs, headTime)
+	require.NoError(t, err)
+
+	// The memos are saved in the wallet file
+	s, err = wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	memo, err = s.GetTransactionMemo(w.Filename(), txn.InnerHash)
+	require.NoError(t, err)
+	require.Equal(t, []byte("invoice-42"), memo)
+	memo, err = s.GetTransactionMemo(w.Filename(), txns[0].InnerHash)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0, 1, 2}, memo)
+
+	require.NoError(t, s.SetTransactionMemo(w.Filename(), txn.InnerHash, nil))
+	memo, err = s.GetTransactionMemo(w.Filename(), txn.InnerHash)
+	require.NoError(t, err)
+	require.Nil(t, memo)
+
+	err = s.SetTransactionMemo(w.Filename(), txn.InnerHash, make([]byte, wallet.MaxMemoLength+1))
+	require.Equal(t, wallet.ErrMemoTooLong, err)
+	err = s.SetTransactionMemo("foo.wlt", txn.InnerHash, []byte("memo"))
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+}
+
+func TestServiceIsWalletEncrypted(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w1, err := s.CreateWallet("t1.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     "seed1",
+		Label:    "label",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+
+	w2, err := s.CreateWallet("t2.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed2",
+		Label: "label",
+	})
+	require.NoError(t, err)
+
+	encrypted, err := s.IsWalletEncrypted(w1.Filename())
+	require.NoError(t, err)
+	require.True(t, encrypted)
+
+	encrypted, err = s.IsWalletEncrypted(w2.Filename())
+	require.NoError(t, err)
+	require.False(t, encrypted)
+
+	_, err = s.IsWalletEncrypted("unknown.wlt")
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+
+	s.SetEnableWalletAPI(false)
+	_, err = s.IsWalletEncrypted(w1.Filename())
+	require.Equal(t, wallet.ErrWalletAPIDisabled, err)
+}
+
+func TestServiceEncryptedBlob(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w1, err := s.CreateWallet("t1.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "seed1",
+		Label:     "label1",
+		GenerateN: 3,
+	})
+	require.NoError(t, err)
+
+	w2, err := s.CreateWallet("t2.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "seed2",
+		Label:     "label2",
+		GenerateN: 2,
+		Encrypt:   true,
+		Password:  []byte("pwd2"),
+	})
+	require.NoError(t, err)
+
+	_, err = s.ExportEncryptedBlob(w1.Filename(), nil)
+	require.Equal(t, wallet.ErrMissingPassword, err)
+	_, err = s.ExportEncryptedBlob(w2.Filename(), []byte("wrong"))
+	require.Equal(t, wallet.ErrInvalidPassword, err)
+
+	blob1, err := s.ExportEncryptedBlob(w1.Filename(), []byte("pwd1"))
+	require.NoError(t, err)
+	require.NotContains(t, string(blob1), "seed1")
+	blob2, err := s.ExportEncryptedBlob(w2.Filename(), []byte("pwd2"))
+	require.NoError(t, err)
+
+	// The seeds are already used by the wallets of the service
+	_, err = s.ImportEncryptedBlob(blob1, []byte("pwd1"), "")
+	require.Equal(t, wallet.ErrSeedUsed, err)
+
+	dir2 := prepareWltDir()
+	defer os.RemoveAll(dir2)
+	s2, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir2,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+		EnableSeedAPI:   true,
+	})
+	require.NoError(t, err)
+
+	_, err = s2.ImportEncryptedBlob(blob1, []byte("pwd2"), "")
+	require.Equal(t, wallet.ErrInvalidPassword, err)
+	_, err = s2.ImportEncryptedBlob(blob1[1:], []byte("pwd1"), "")
+	require.Equal(t, wallet.ErrInvalidEncryptedBlob, err)
+	_, err = s2.ImportEncryptedBlob(blob1, []byte("pwd1"), "foo")
+	require.Equal(t, wallet.ErrInvalidWalletFilename, err)
+
+	// The imported wallets are encrypted with the blob password
+	for _, tc := range []struct {
+		blob []byte
+		pwd  []byte
+		name string
+		src  wallet.Wallet
+		seed string
+	}{
+		{blob1, []byte("pwd1"), "m1.wlt", w1, "seed1"},
+		{blob2, []byte("pwd2"), "", w2, "seed2"},
+	} {
+		w, err := s2.ImportEncryptedBlob(tc.blob, tc.pwd, tc.name)
+		require.NoError(t, err)
+		if tc.name != "" {
+			require.Equal(t, tc.name, w.Filename())
+		}
+		require.True(t, w.IsEncrypted())
+		require.Equal(t, tc.src.Label(), w.Label())
+
+		addrs, err := s2.GetAddresses(w.Filename())
+		require.NoError(t, err)
+		srcAddrs, err := s.GetAddresses(tc.src.Filename())
+		require.NoError(t, err)
+		require.Equal(t, srcAddrs, addrs)
+
+		seed, _, err := s2.GetWalletSeed(w.Filename(), tc.pwd)
+		require.NoError(t, err)
+		require.Equal(t, tc.seed, seed)
+	}
+
+	_, err = s2.ImportEncryptedBlob(blob2, []byte("pwd2"), "m1.wlt")
+	require.Equal(t, wallet.ErrWalletNameConflict, err)
+
+	// Malformed blobs are rejected, without decrypting or decompressing them without bounds
+	makeBlob := func(cryptoType crypto.CryptoType, payload []byte) []byte {
+		blob := append([]byte("SKYWLT"), wallet.EncryptedBlobVersion, byte(len(cryptoType)))
+		blob = append(blob, cryptoType...)
+		return append(blob, payload...)
+	}
+
+	makeArgon2idPayload := func(length uint16, meta string) []byte {
+		data := make([]byte, 2)
+		binary.LittleEndian.PutUint16(data, length)
+		data = append(data, meta...)
+		data = append(data, make([]byte, 32)...)
+		return []byte(base64.StdEncoding.EncodeToString(data))
+	}
+
+	hugeMeta := fmt.Sprintf(`{"time":1,"memory":%d,"threads":1,"keyLen":32,"nonce":"AAAAAAAAAAAAAAAA"}`, uint32(1<<31))
+
+	var bomb bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&bomb, gzip.BestCompression)
+	require.NoError(t, err)
+	zeros := make([]byte, 1<<20)
+	for i := 0; i < 65; i++ {
+		_, err := zw.Write(zeros)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	cryptor, err := crypto.GetCrypto(crypto.CryptoTypeSha256Xor)
+	require.NoError(t, err)
+	bombPayload, err := cryptor.Encrypt(bomb.Bytes(), []byte("pwd1"))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name string
+		blob []byte
+	}{
+		{"unregistered crypto type", makeBlob("foo", blob1[len("SKYWLT")+2+len(crypto.CryptoTypeSha256Xor):])},
+		{"metadata length overflow", makeBlob(crypto.CryptoTypeArgon2idChacha20poly1305, makeArgon2idPayload(65535, "{}"))},
+		{"unbounded argon2id memory", makeBlob(crypto.CryptoTypeArgon2idChacha20poly1305, makeArgon2idPayload(uint16(len(hugeMeta)), hugeMeta))},
+		{"gzip bomb", makeBlob(crypto.CryptoTypeSha256Xor, bombPayload)},
+	} {
+		_, err = s2.ImportEncryptedBlob(tc.blob, []byte("pwd1"), "")
+		require.Equal(t, wallet.ErrInvalidEncryptedBlob, err, tc.name)
+	}
+
+	// A wrong password is still reported as such with the chacha20poly1305 crypto types
+	s3, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeScryptChacha20poly1305Insecure,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+	blob3, err := s3.ExportEncryptedBlob(w1.Filename(), []byte("pwd1"))
+	require.NoError(t, err)
+	_, err = s2.ImportEncryptedBlob(blob3, []byte("pwd2"), "")
+	require.Equal(t, wallet.ErrInvalidPassword, err)
+}
+
+func TestServiceFindWalletByAddress(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w1, err := s.CreateWallet("t1.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "seed1",
+		Label:     "label1",
+		GenerateN: 2,
+	})
+	require.NoError(t, err)
+
+	w2, err := s.CreateWallet("t2.wlt", wallet.Options{
+		Type:       wallet.WalletTypeDeterministic,
+		Seed:       "seed2",
+		Label:      "label2",
+		Encrypt:    true,
+		Password:   []byte("pwd"),
+		CryptoType: crypto.CryptoTypeSha256Xor,
+	})
+	require.NoError(t, err)
+
+	as1, err := w1.GetAddresses()
+	require.NoError(t, err)
+	addrs1 := wallet.SkycoinAddresses(as1)
+	require.Len(t, addrs1, 2)
+
+	as2, err := w2.GetAddresses()
+	require.NoError(t, err)
+	addrs2 := wallet.SkycoinAddresses(as2)
+	require.Len(t, addrs2, 1)
+
+	// The addresses of an encrypted wallet are found without its password
+	for _, a := range addrs1 {
+		wltID, err := s.FindWalletByAddress(a)
+		require.NoError(t, err)
+		require.Equal(t, "t1.wlt", wltID)
+	}
+
+	wltID, err := s.FindWalletByAddress(addrs2[0])
+	require.NoError(t, err)
+	require.Equal(t, "t2.wlt", wltID)
+
+	// A new address is found
+	newAddrs, err := s.NewAddresses("t2.wlt", []byte("pwd"), wallet.OptionGenerateN(1))
+	require.NoError(t, err)
+	require.Len(t, newAddrs, 1)
+
+	wltID, err = s.FindWalletByAddress(newAddrs[0])
+	require.NoError(t, err)
+	require.Equal(t, "t2.wlt", wltID)
+
+	// The addresses of a renamed wallet are found under its new name
+	_, err = s.RenameWallet("t1.wlt", "t3.wlt")
+	require.NoError(t, err)
+
+	wltID, err = s.FindWalletByAddress(addrs1[1])
+	require.NoError(t, err)
+	require.Equal(t, "t3.wlt", wltID)
+
+	// The addresses of a deleted wallet are not found
+	err = s.DeleteWallet("t3.wlt")
+	require.NoError(t, err)
+
+	_, err = s.FindWalletByAddress(addrs1[0])
+	require.Equal(t, wallet.ErrUnknownAddress, err)
+
+	_, err = s.FindWalletByAddress(testutil.MakeAddress())
+	require.Equal(t, wallet.ErrUnknownAddress, err)
+
+	s.SetEnableWalletAPI(false)
+	_, err = s.FindWalletByAddress(addrs2[0])
+	require.Equal(t, wallet.ErrWalletAPIDisabled, err)
+}
+
+func TestServiceStats(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	stats := s.Stats()
+	require.Equal(t, 0, stats.Wallets)
+	require.Equal(t, 0, stats.Entries)
+	require.Empty(t, stats.ModTimes)
+	require.True(t, stats.LastModified.IsZero())
+
+	_, err = s.CreateWallet("t1.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "seed1",
+		Label:     "label1",
+		GenerateN: 3,
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("t2.wlt", wallet.Options{
+		Type:       wallet.WalletTypeDeterministic,
+		Seed:       "seed2",
+		Label:      "label2",
+		Encrypt:    true,
+		Password:   []byte("pwd"),
+		CryptoType: crypto.CryptoTypeSha256Xor,
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("t3.wlt", wallet.Options{
+		Type:      wallet.WalletTypeBip44,
+		Seed:      bip39.MustNewDefaultMnemonic(),
+		Label:     "label3",
+		GenerateN: 2,
+	})
+	require.NoError(t, err)
+
+	stats = s.Stats()
+	require.Equal(t, 3, stats.Wallets)
+	require.Equal(t, 1, stats.EncryptedWallets)
+	// The bip44 wallet has a change entry
+	require.Equal(t, 7, stats.Entries)
+	require.Equal(t, map[string]int{
+		wallet.WalletTypeDeterministic: 2,
+		wallet.WalletTypeBip44:         1,
+	}, stats.WalletTypes)
+	require.Equal(t, map[crypto.CryptoType]int{
+		crypto.CryptoTypeSha256Xor: 1,
+	}, stats.CryptoTypes)
+	require.Len(t, stats.ModTimes, 3)
+
+	for _, wltID := range []string{"t1.wlt", "t2.wlt", "t3.wlt"} {
+		info, err := s.GetWalletFileInfo(wltID)
+		require.NoError(t, err)
+		require.Equal(t, info.LoadedModTime, stats.ModTimes[wltID])
+		require.False(t, stats.ModTimes[wltID].After(stats.LastModified))
+	}
+
+	err = s.DeleteWallet("t1.wlt")
+	require.NoError(t, err)
+
+	stats = s.Stats()
+	require.Equal(t, 2, stats.Wallets)
+	require.Equal(t, 4, stats.Entries)
+	require.Len(t, stats.ModTimes, 2)
+}
+
+func TestServiceRestoreWalletFromSeed(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+		EnableSeedAPI:   true,
+	})
+	require.NoError(t, err)
+
+	lost, err := wallet.NewWallet("lost.wlt", "label", "seed1", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		GenerateN: 5,
+	})
+	require.NoError(t, err)
+	addrs, err := lost.GetAddresses()
+	require.NoError(t, err)
+
+	bg := fakeBalanceGetter{
+		addrs[3].(cipher.Address): wallet.BalancePair{
+			Confirmed: wallet.Balance{Coins: 1e6, Hours: 10},
+			Predicted: wallet.Balance{Coins: 1e6, Hours: 10},
+		},
+	}
+
+	_, err = s.RestoreWalletFromSeed("t1.wlt", "seed1", nil, 10, nil)
+	require.Equal(t, wallet.ErrNilTransactionsFinder, err)
+
+	w, err := s.RestoreWalletFromSeed("t1.wlt", "seed1", []byte("pwd"), 10, bg)
+	require.NoError(t, err)
+	require.True(t, w.IsEncrypted())
+
+	restoredAddrs, err := w.GetAddresses()
+	require.NoError(t, err)
+	require.Equal(t, addrs[:4], restoredAddrs)
+
+	_, err = os.Stat(filepath.Join(dir, "t1.wlt"))
+	require.NoError(t, err)
+
+	// The seed is already used by t1.wlt
+	_, err = s.RestoreWalletFromSeed("t2.wlt", "seed1", nil, 0, nil)
+	require.Error(t, err)
+
+	w, err = s.RestoreWalletFromSeed("t2.wlt", "seed2", nil, 0, nil)
+	require.NoError(t, err)
+	require.False(t, w.IsEncrypted())
+	l, err := w.EntriesLen()
+	require.NoError(t, err)
+	require.Equal(t, 1, l)
+}
+
+func TestServiceFilenamePrefixAndDefaultLabel(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		EnableWalletAPI: true,
+		FilenamePrefix:  "tenant1",
+		DefaultLabel:    "{prefix}-{date}",
+	})
+	require.NoError(t, err)
+
+	date := time.Now().Format(wallet.WalletTimestampFormat)
+
+	w1, err := s.CreateWallet("", wallet.Options{
+		Type: wallet.WalletTypeDeterministic,
+		Seed: "seed1",
+	})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(w1.Filename(), "tenant1_"))
+	require.True(t, strings.HasSuffix(w1.Filename(), "."+wallet.WalletExt))
+	require.Equal(t, "tenant1-"+date, w1.Label())
+
+	// The given filename and label are kept
+	w2, err := s.CreateWallet("t2.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed2",
+		Label: "label2",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "t2.wlt", w2.Filename())
+	require.Equal(t, "label2", w2.Label())
+
+	// Without a default label, the label is required
+	s2, err := wallet.NewService(wallet.Config{
+		EnableWalletAPI: true,
+		InMemory:        true,
+	})
+	require.NoError(t, err)
+	_, err = s2.CreateWallet("", wallet.Options{
+		Type: wallet.WalletTypeDeterministic,
+		Seed: "seed1",
+	})
+	testutil.RequireError(t, err, "missing label")
+
+	for _, prefix := range []string{"a/b", `a\b`, ".hidden"} {
+		_, err = wallet.NewService(wallet.Config{
+			WalletDir:       dir,
+			EnableWalletAPI: true,
+			FilenamePrefix:  prefix,
+		})
+		testutil.RequireError(t, err, fmt.Sprintf("invalid wallet filename prefix %q, must not contain path separators or start with a dot", prefix))
+	}
+}
+
+func TestServiceForEachWallet(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	for _, id := range []string{"t2.wlt", "t1.wlt", "t3.wlt"} {
+		_, err := s.CreateWallet(id, wallet.Options{
+			Type:  wallet.WalletTypeDeterministic,
+			Seed:  "seed-" + id,
+			Label: "label",
+		})
+		require.NoError(t, err)
+	}
+
+	var ids []string
+	err = s.ForEachWallet(func(w wallet.Wallet) error {
+		ids = append(ids, w.Filename())
+		// The wallets are clones
+		w.SetLabel("changed")
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"t1.wlt", "t2.wlt", "t3.wlt"}, ids)
+
+	w, err := s.GetWallet("t1.wlt")
+	require.NoError(t, err)
+	require.Equal(t, "label", w.Label())
+
+	// Iteration stops at the first error
+	errStop := errors.New("stop")
+	ids = nil
+	err = s.ForEachWallet(func(w wallet.Wallet) error {
+		ids = append(ids, w.Filename())
+		if len(ids) == 2 {
+			return errStop
+		}
+		return nil
+	})
+	require.Equal(t, errStop, err)
+	require.Equal(t, []string{"t1.wlt", "t2.wlt"}, ids)
+
+	s.SetEnableWalletAPI(false)
+	err = s.ForEachWallet(func(w wallet.Wallet) error { return nil })
+	require.Equal(t, wallet.ErrWalletAPIDisabled, err)
+}
+
+func TestServiceRecoverWalletSeedAPIDisabled(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     "seed",
+		Label:    "label",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+
+	_, err = s.RecoverWallet(w.Filename(), "seed", "", []byte("pwd"))
+	require.Equal(t, wallet.ErrSeedAPIDisabled, err)
+
+	err = s.RecoverWalletDryRun(w.Filename(), "seed", "")
+	require.Equal(t, wallet.ErrSeedAPIDisabled, err)
+
+	_, err = s.RestoreWalletFromSeed("t2.wlt", "seed2", nil, 0, nil)
+	require.Equal(t, wallet.ErrSeedAPIDisabled, err)
+}
+
+func TestServiceCreateTransactionDustAndOutputHours(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.NoError(t, err)
+
+	entries, err := w.GetEntries()
+	require.NoError(t, err)
+
+	ux := makeUxOut(t, entries[0].Secret, 2e6, 100)
+	ux.Head.Time = headTime
+	auxs := coin.AddressUxOuts{entries[0].SkycoinAddress(): []coin.UxOut{ux}}
+
+	newParams := func(coins uint64, shareFactor string) wallet.CreateTransactionParams {
+		sf := decimal.RequireFromString(shareFactor)
+		return wallet.CreateTransactionParams{
+			WalletID: w.Filename(),
+			Params: transaction.Params{
+				HoursSelection: transaction.HoursSelection{
+					Type:        transaction.HoursSelectionTypeAuto,
+					Mode:        transaction.HoursSelectionModeShare,
+					ShareFactor: &sf,
+				},
+				To: []coin.TransactionOutput{
+					{
+						Address: makeAddress(),
+						Coins:   coins,
+					},
+				},
+			},
+		}
+	}
+
+	// Outputs below the dust threshold are rejected
+	p := newParams(1e3, "0.5")
+	p.DustThreshold = 1e6
+	_, _, err = s.CreateUnsignedTransaction(p, auxs, headTime)
+	require.Equal(t, wallet.ErrDustOutput, err)
+
+	p = newParams(1e6, "0.5")
+	p.DustThreshold = 1e6
+	_, _, err = s.CreateUnsignedTransaction(p, auxs, headTime)
+	require.NoError(t, err)
+
+	// With share factor 0, the receiver gets no coin hours
+	p = newParams(1e6, "0")
+	txn, _, err := s.CreateUnsignedTransaction(p, auxs, headTime)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), txn.Out[0].Hours)
+
+	p.RequireOutputHours = true
+	_, _, err = s.CreateUnsignedTransaction(p, auxs, headTime)
+	require.Equal(t, wallet.ErrOutputWithoutHours, err)
+
+	_, _, err = s.BatchCreateTransactions([]wallet.CreateTransactionParams{p}, auxs, headTime)
+	require.Equal(t, wallet.BatchError{Errors: map[int]error{0: wallet.ErrOutputWithoutHours}}, err)
+
+	p = newParams(1e6, "0.5")
+	p.RequireOutputHours = true
+	txn, _, err = s.CreateUnsignedTransaction(p, auxs, headTime)
+	require.NoError(t, err)
+	for _, o := range txn.Out {
+		require.NotEqual(t, uint64(0), o.Hours)
+	}
+}
+
+func TestServiceGetWalletEntries(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "seed",
+		Label:     "label",
+		GenerateN: 5,
+	})
+	require.NoError(t, err)
+
+	allEntries, err := w.GetEntries()
+	require.NoError(t, err)
+	require.Len(t, allEntries, 5)
+
+	entries, total, err := s.GetWalletEntries("t.wlt", 1, 3)
+	require.NoError(t, err)
+	require.Equal(t, 5, total)
+	require.Len(t, entries, 3)
+	for i, e := range entries {
+		require.Equal(t, allEntries[i+1].Address, e.Address)
+		require.Equal(t, allEntries[i+1].Public, e.Public)
+		require.True(t, e.Secret == cipher.SecKey{})
+	}
+
+	// The last page is short
+	entries, total, err = s.GetWalletEntries("t.wlt", 3, 3)
+	require.NoError(t, err)
+	require.Equal(t, 5, total)
+	require.Len(t, entries, 2)
+	require.Equal(t, allEntries[4].Address, entries[1].Address)
+
+	// A page past the end is empty
+	entries, total, err = s.GetWalletEntries("t.wlt", 5, 3)
+	require.NoError(t, err)
+	require.Equal(t, 5, total)
+	require.Empty(t, entries)
+
+	_, _, err = s.GetWalletEntries("t.wlt", -1, 3)
+	require.Equal(t, wallet.ErrInvalidPagination, err)
+
+	_, _, err = s.GetWalletEntries("t.wlt", 0, 0)
+	require.Equal(t, wallet.ErrInvalidPagination, err)
+
+	_, _, err = s.GetWalletEntries("unknown.wlt", 0, 3)
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+}
+
+func TestServiceValidateWallet(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("t.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "seed",
+		Label:     "label",
+		GenerateN: 3,
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("bip44.wlt", wallet.Options{
+		Type:       wallet.WalletTypeBip44,
+		Seed:       bip39.MustNewDefaultMnemonic(),
+		Label:      "label",
+		GenerateN:  2,
+		Encrypt:    true,
+		Password:   []byte("pwd"),
+		CryptoType: crypto.CryptoTypeSha256Xor,
+	})
+	require.NoError(t, err)
+
+	_, err = s.NewAccount("bip44.wlt", []byte("pwd"), "second")
+	require.NoError(t, err)
+
+	_, err = s.NewAddresses("bip44.wlt", []byte("pwd"), wallet.OptionGenerateN(2), wallet.OptionAccount(1), wallet.OptionChange())
+	require.NoError(t, err)
+
+	_, sk := cipher.GenerateKeyPair()
+	_, err = s.CreateWallet("collection.wlt", wallet.Options{
+		Type:                  wallet.WalletTypeCollection,
+		Label:                 "label",
+		CollectionPrivateKeys: []cipher.SecKey{sk},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, s.ValidateWallet("t.wlt", nil))
+	require.NoError(t, s.ValidateWallet("bip44.wlt", nil))
+	require.NoError(t, s.ValidateWallet("bip44.wlt", []byte("pwd")))
+	require.NoError(t, s.ValidateWallet("collection.wlt", nil))
+
+	require.Equal(t, wallet.ErrInvalidPassword, s.ValidateWallet("bip44.wlt", []byte("wrong")))
+	require.Equal(t, wallet.ErrWalletNotEncrypted, s.ValidateWallet("t.wlt", []byte("pwd")))
+	require.Equal(t, wallet.ErrWalletNotExist, s.ValidateWallet("unknown.wlt", nil))
+
+	// Tamper with the wallet file and reload it. The entries whose keys and address don't match
+	// are already rejected by the loader
+	tamper := func(t *testing.T, f func(meta, entry map[string]interface{})) {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "t.wlt"))
+		require.NoError(t, err)
+
+		var v map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &v))
+		f(v["meta"].(map[string]interface{}), v["entries"].([]interface{})[1].(map[string]interface{}))
+
+		data, err = json.Marshal(v)
+		require.NoError(t, err)
+
+		tdir := prepareWltDir()
+		defer os.RemoveAll(tdir)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(tdir, "t.wlt"), data, 0600))
+
+		ts, err := wallet.NewService(wallet.Config{
+			WalletDir:       tdir,
+			EnableWalletAPI: true,
+		})
+		require.NoError(t, err)
+
+		err = ts.ValidateWallet("t.wlt", nil)
+		require.Error(t, err)
+		require.IsType(t, wallet.Error{}, err)
+	}
+
+	t.Run("entry not derived from the seed", func(t *testing.T) {
+		tamper(t, func(meta, entry map[string]interface{}) {
+			p, sk := cipher.GenerateKeyPair()
+			entry["public_key"] = p.Hex()
+			entry["address"] = cipher.AddressFromPubKey(p).String()
+			entry["secret_key"] = sk.Hex()
+		})
+	})
+
+	t.Run("first address does not match", func(t *testing.T) {
+		tamper(t, func(meta, entry map[string]interface{}) {
+			meta["firstAddress"] = testutil.MakeAddress().String()
+		})
+	})
+}
+
+// mapWalletSource is a WalletSource of in-memory files, in the root directory
+type mapWalletSource map[string][]byte
+
+func (m mapWalletSource) Open(name string) (io.ReadCloser, error) {
+	data, ok := m[name]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m mapWalletSource) ReadDir(name string) ([]os.FileInfo, error) {
+	if name != "." {
+		return nil, os.ErrNotExist
+	}
+
+	infos := make([]os.FileInfo, 0, len(m))
+	for n, data := range m {
+		infos = append(infos, mapFileInfo{name: n, size: int64(len(data))})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name() < infos[j].Name()
+	})
+	return infos, nil
+}
+
+// mapFileInfo is the os.FileInfo of a regular file of a mapWalletSource
+type mapFileInfo struct {
+	name string
+	size int64
+}
+
+func (fi mapFileInfo) Name() string       { return fi.name }
+func (fi mapFileInfo) Size() int64        { return fi.size }
+func (fi mapFileInfo) Mode() os.FileMode  { return 0444 }
+func (fi mapFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi mapFileInfo) IsDir() bool        { return false }
+func (fi mapFileInfo) Sys() interface{}   { return nil }
+
+func TestServiceWalletSource(t *testing.T) {
+	data, err := ioutil.ReadFile("./testdata/test1.wlt")
+	require.NoError(t, err)
+
+	src := mapWalletSource{
+		"test1.wlt":  data,
+		"readme.txt": []byte("not a wallet"),
+	}
+
+	dir := filepath.Join(prepareWltDir(), "wallets")
+	defer os.RemoveAll(filepath.Dir(dir))
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		EnableWalletAPI: true,
+		WalletSource:    src,
+	})
+	require.NoError(t, err)
+
+	// The wallet directory is not created
+	_, err = os.Stat(dir)
+	require.True(t, os.IsNotExist(err))
+
+	wlts, err := s.GetWallets()
+	require.NoError(t, err)
+	require.Len(t, wlts, 1)
+
+	w, err := s.GetWallet("test1.wlt")
+	require.NoError(t, err)
+	require.Equal(t, "test1.wlt", w.Filename())
+	require.Equal(t, "test3", w.Label())
+
+	require.Equal(t, wallet.ErrReadOnlyWalletSource, s.UpdateWalletLabel("test1.wlt", "label"))
+
+	_, err = s.NewAddresses("test1.wlt", nil, wallet.OptionGenerateN(1))
+	require.Equal(t, wallet.ErrReadOnlyWalletSource, err)
+
+	_, err = s.RenameWallet("test1.wlt", "test2.wlt")
+	require.Equal(t, wallet.ErrReadOnlyWalletSource, err)
+
+	require.Equal(t, wallet.ErrReadOnlyWalletSource, s.DeleteWallet("test1.wlt"))
+
+	_, err = s.CreateWallet("t.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.Equal(t, wallet.ErrReadOnlyWalletSource, err)
+
+	// Temporary wallets are never saved
+	_, err = s.CreateWallet("temp.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+		Temp:  true,
+	})
+	require.NoError(t, err)
+
+	// The wallets are unchanged
+	w, err = s.GetWallet("test1.wlt")
+	require.NoError(t, err)
+	require.Equal(t, "test3", w.Label())
+	l, err := w.EntriesLen()
+	require.NoError(t, err)
+	require.Equal(t, 1, l)
+
+	_, err = os.Stat(dir)
+	require.True(t, os.IsNotExist(err))
+
+	_, err = wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		EnableWalletAPI: true,
+		WalletSource:    src,
+		InMemory:        true,
+	})
+	require.Error(t, err)
+}
+
+func TestServiceGetWalletsSorted(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	for _, w := range []struct {
+		id    string
+		label string
+		n     uint64
+	}{
+		{"b.wlt", "alpha", 3},
+		{"a.wlt", "charlie", 1},
+		{"c.wlt", "bravo", 2},
+	} {
+		_, err := s.CreateWallet(w.id, wallet.Options{
+			Type:      wallet.WalletTypeDeterministic,
+			Seed:      w.id,
+			Label:     w.label,
+			GenerateN: w.n,
+		})
+		require.NoError(t, err)
+	}
+
+	ids := func(wlts []wallet.Wallet) []string {
+		var ids []string
+		for _, w := range wlts {
+			ids = append(ids, w.Filename())
+		}
+		return ids
+	}
+
+	cases := []struct {
+		by   wallet.SortField
+		desc bool
+		ids  []string
+	}{
+		{wallet.SortByLabel, false, []string{"b.wlt", "c.wlt", "a.wlt"}},
+		{wallet.SortByLabel, true, []string{"a.wlt", "c.wlt", "b.wlt"}},
+		{wallet.SortByFilename, false, []string{"a.wlt", "b.wlt", "c.wlt"}},
+		{wallet.SortByFilename, true, []string{"c.wlt", "b.wlt", "a.wlt"}},
+		{wallet.SortByEntries, false, []string{"a.wlt", "c.wlt", "b.wlt"}},
+		{wallet.SortByEntries, true, []string{"b.wlt", "c.wlt", "a.wlt"}},
+	}
+
+	for _, tc := range cases {
+		wlts, err := s.GetWalletsSorted(tc.by, tc.desc)
+		require.NoError(t, err)
+		require.Equal(t, tc.ids, ids(wlts), "by=%s desc=%v", tc.by, tc.desc)
+	}
+
+	wlts, err := s.GetWalletsSorted(wallet.SortByTimestamp, false)
+	require.NoError(t, err)
+	require.Len(t, wlts, 3)
+	for i := 1; i < len(wlts); i++ {
+		require.True(t, wlts[i-1].Timestamp() <= wlts[i].Timestamp())
+	}
+
+	_, err = s.GetWalletsSorted("size", false)
+	require.Equal(t, wallet.ErrInvalidSortField, err)
+}
+
+func TestServiceCreateWalletNoDefaultAddresses(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	for _, id := range []string{"a.wlt", "b.wlt"} {
+		w, err := s.CreateWallet(id, wallet.Options{
+			Type:               wallet.WalletTypeBip44,
+			Seed:               bip39.MustNewDefaultMnemonic(),
+			Label:              "label",
+			Encrypt:            true,
+			Password:           []byte("pwd"),
+			CryptoType:         crypto.CryptoTypeSha256Xor,
+			NoDefaultAddresses: true,
+		})
+		require.NoError(t, err)
+
+		l, err := w.EntriesLen()
+		require.NoError(t, err)
+		require.Equal(t, 0, l)
+
+		l, err = w.EntriesLen(wallet.OptionChange())
+		require.NoError(t, err)
+		require.Equal(t, 0, l)
+	}
+
+	w, err := s.CreateWallet("c.wlt", wallet.Options{
+		Type:               wallet.WalletTypeDeterministic,
+		Seed:               "seed",
+		Label:              "label",
+		NoDefaultAddresses: true,
+	})
+	require.NoError(t, err)
+	l, err := w.EntriesLen()
+	require.NoError(t, err)
+	require.Equal(t, 0, l)
+
+	// GenerateN is still honored
+	w, err = s.CreateWallet("d.wlt", wallet.Options{
+		Type:               wallet.WalletTypeDeterministic,
+		Seed:               "seed2",
+		Label:              "label",
+		GenerateN:          2,
+		NoDefaultAddresses: true,
+	})
+	require.NoError(t, err)
+	l, err = w.EntriesLen()
+	require.NoError(t, err)
+	require.Equal(t, 2, l)
+
+	require.Empty(t, s.VerifyIntegrity())
+
+	// The empty wallets are not rejected when the wallets are loaded again
+	for _, lazy := range []bool{false, true} {
+		s2, err := wallet.NewService(wallet.Config{
+			WalletDir:       dir,
+			EnableWalletAPI: true,
+			LazyLoad:        lazy,
+		})
+		require.NoError(t, err)
+
+		wlts, err := s2.GetWallets()
+		require.NoError(t, err)
+		require.Len(t, wlts, 4)
+	}
+
+	// The addresses are generated explicitly later
+	addrs, err := s.NewAddresses("a.wlt", []byte("pwd"), wallet.OptionGenerateN(1), wallet.OptionAccount(0))
+	require.NoError(t, err)
+	require.Len(t, addrs, 1)
+
+	// Without the option, one address is generated by default
+	w, err = s.CreateWallet("e.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed3",
+		Label: "label",
+	})
+	require.NoError(t, err)
+	l, err = w.EntriesLen()
+	require.NoError(t, err)
+	require.Equal(t, 1, l)
+}
+
+func TestServiceGetWalletJSON(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("t.wlt", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "seed",
+		Label:     "label",
+		GenerateN: 2,
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("encrypted.wlt", wallet.Options{
+		Type:       wallet.WalletTypeBip44,
+		Seed:       bip39.MustNewDefaultMnemonic(),
+		Label:      "label",
+		Encrypt:    true,
+		Password:   []byte("pwd"),
+		CryptoType: crypto.CryptoTypeSha256Xor,
+	})
+	require.NoError(t, err)
+
+	for _, id := range []string{"t.wlt", "encrypted.wlt"} {
+		data, err := s.GetWalletJSON(id)
+		require.NoError(t, err)
+
+		fileData, err := ioutil.ReadFile(filepath.Join(dir, id))
+		require.NoError(t, err)
+		require.Equal(t, string(fileData), string(data))
+	}
+
+	// The secrets of the encrypted wallet stay encrypted
+	data, err := s.GetWalletJSON("encrypted.wlt")
+	require.NoError(t, err)
+	var v struct {
+		Meta map[string]string `json:"meta"`
+	}
+	require.NoError(t, json.Unmarshal(data, &v))
+	require.Equal(t, "true", v.Meta["encrypted"])
+	require.Empty(t, v.Meta["seed"])
+	require.NotEmpty(t, v.Meta["secrets"])
+
+	_, err = s.GetWalletJSON("unknown.wlt")
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+}
+
+// timedTxnsFinder records the time of the calls to AddressesActivity
+type timedTxnsFinder struct {
+	mockTxnsFinder
+	calls []time.Time
+}
+
+func (f *timedTxnsFinder) AddressesActivity(addrs []cipher.Addresser) ([]bool, error) {
+	f.calls = append(f.calls, time.Now())
+	return f.mockTxnsFinder.AddressesActivity(addrs)
+}
+
+func TestServiceScanRateLimit(t *testing.T) {
+	_, err := wallet.NewService(wallet.Config{
+		WalletDir:     "./",
+		ScanRateLimit: -time.Second,
+	})
+	require.Error(t, err)
+
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	interval := 20 * time.Millisecond
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+		ScanRateLimit:   interval,
+	})
+	require.NoError(t, err)
+
+	_, seckeys := cipher.MustGenerateDeterministicKeyPairsSeed([]byte("seed"), 10)
+	addrs := make([]cipher.Address, len(seckeys))
+	for i, k := range seckeys {
+		addrs[i] = cipher.MustAddressFromSecKey(k)
+	}
+
+	// Scanning one address at a time queries the finder once per address
+	tf := &timedTxnsFinder{mockTxnsFinder: mockTxnsFinder{addrs[2]: true}}
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:          wallet.WalletTypeDeterministic,
+		Seed:          "seed",
+		Label:         "label",
+		GapLimit:      3,
+		ScanChunkSize: 1,
+		TF:            tf,
+	})
+	require.NoError(t, err)
+	wltAddrs, err := s.GetAddresses(w.Filename())
+	require.NoError(t, err)
+	require.Equal(t, addrs[:3], wltAddrs)
+	require.True(t, len(tf.calls) > 1)
+	for i := 1; i < len(tf.calls); i++ {
+		require.True(t, tf.calls[i].Sub(tf.calls[i-1]) >= interval)
+	}
+
+	// ScanAddresses is limited too
+	tf.calls = nil
+	_, err = s.ScanAddresses(w.Filename(), nil, 2, tf)
+	require.NoError(t, err)
+	_, err = s.ScanAddresses(w.Filename(), nil, 2, tf)
+	require.NoError(t, err)
+	require.Len(t, tf.calls, 2)
+	require.True(t, tf.calls[1].Sub(tf.calls[0]) >= interval)
+
+	// A done context stops the scan
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tf.calls = nil
+	_, err = s.CreateWallet("t2.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     "seed2",
+		Label:    "label",
+		GapLimit: 3,
+		TF:       tf,
+		Context:  ctx,
+	})
+	require.Equal(t, context.Canceled, err)
+	require.Empty(t, tf.calls)
+	_, err = s.GetWallet("t2.wlt")
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+
+	// A scan doesn't wait for a query that would start after the deadline, the queries follow the previous scan
+	_, err = s.ScanAddresses(w.Filename(), nil, 1, tf)
+	require.NoError(t, err)
+	ctx, cancel = context.WithDeadline(context.Background(), tf.calls[len(tf.calls)-1].Add(interval/2))
+	defer cancel()
+	tf.calls = nil
+	_, err = s.CreateWallet("t3.wlt", wallet.Options{
+		Type:     wallet.WalletTypeDeterministic,
+		Seed:     "seed3",
+		Label:    "label",
+		GapLimit: 3,
+		TF:       tf,
+		Context:  ctx,
+	})
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.Empty(t, tf.calls)
+}
+
+// blockingTxnsFinder signals started when AddressesActivity is called and waits for release
+type blockingTxnsFinder struct {
+	mockTxnsFinder
+	started chan struct{}
+	release chan struct{}
+}
+
+func (f *blockingTxnsFinder) AddressesActivity(addrs []cipher.Addresser) ([]bool, error) {
+	f.started <- struct{}{}
+	<-f.release
+	return f.mockTxnsFinder.AddressesActivity(addrs)
+}
+
+func TestServiceScanAddressesConcurrent(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      crypto.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+		ScanRateLimit:   time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	_, seckeys := cipher.MustGenerateDeterministicKeyPairsSeed([]byte("seed"), 4)
+	addrs := make([]cipher.Address, len(seckeys))
+	for i, k := range seckeys {
+		addrs[i] = cipher.MustAddressFromSecKey(k)
+	}
+
+	w, err := s.CreateWallet("t.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.NoError(t, err)
+
+	type scanResult struct {
+		addrs []cipher.Address
+		err   error
+	}
+	scan := func(tf wallet.TransactionsFinder) chan scanResult {
+		done := make(chan scanResult, 1)
+		go func() {
+			addrs, err := s.ScanAddresses(w.Filename(), nil, 2, tf)
+			done <- scanResult{addrs: addrs, err: err}
+		}()
+		return done
+	}
+
+	// The other wallet operations don't wait for a scan
+	tf := &blockingTxnsFinder{
+		mockTxnsFinder: mockTxnsFinder{addrs[2]: true},
+		started:        make(chan struct{}, 1),
+		release:        make(chan struct{}),
+	}
+	done := scan(tf)
+	<-tf.started
+
+	_, err = s.CreateWallet("t2.wlt", wallet.Options{
+		Type:  wallet.WalletTypeDeterministic,
+		Seed:  "seed2",
+		Label: "label",
+	})
+	require.NoError(t, err)
+	wltAddrs, err := s.GetAddresses(w.Filename())
+	require.NoError(t, err)
+	require.Equal(t, addrs[:1], wltAddrs)
+
+	close(tf.release)
+	r := <-done
+	require.NoError(t, r.err)
+	require.Equal(t, addrs[1:3], r.addrs)
+
+	// The scan fails if the wallet is changed in the meantime
+	tf = &blockingTxnsFinder{
+		mockTxnsFinder: mockTxnsFinder{addrs[3]: true},
+		started:        make(chan struct{}, 1),
+		release:        make(chan struct{}),
+	}
+	done = scan(tf)
+	<-tf.started
+
+	err = s.UpdateWalletLabel(w.Filename(), "new label")
+	require.NoError(t, err)
+
+	close(tf.release)
+	r = <-done
+	require.Equal(t, wallet.ErrWalletChangedDuringScan, r.err)
+
+	w, err = s.GetWallet(w.Filename())
+	require.NoError(t, err)
+	require.Equal(t, "new label", w.Label())
+	wltAddrs, err = s.GetAddresses(w.Filename())
+	require.NoError(t, err)
+	require.Equal(t, addrs[:3], wltAddrs)
+
+	// A done context stops the scans
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.ScanAddressesContext(ctx, w.Filename(), nil, 2, mockTxnsFinder{addrs[3]: true})
+	require.Equal(t, context.Canceled, err)
+	_, err = s.ScanAddressesByBalanceContext(ctx, w.Filename(), nil, 2, fakeBalanceGetter{
+		addrs[3]: wallet.BalancePair{Confirmed: wallet.Balance{Coins: 1e6}},
+	})
+	require.Equal(t, context.Canceled, err)
+
+	wltAddrs, err = s.GetAddresses(w.Filename())
+	require.NoError(t, err)
+	require.Equal(t, addrs[:3], wltAddrs)
+}
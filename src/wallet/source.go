@@ -0,0 +1,57 @@
+package wallet
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// WalletSource is a read-only source of wallet files, e.g. wallets embedded in the binary.
+// Names are slash-separated paths relative to the root of the source, which is ".".
+type WalletSource interface {
+	// Open opens the named file for reading
+	Open(name string) (io.ReadCloser, error)
+	// ReadDir returns the files in the named directory
+	ReadDir(name string) ([]os.FileInfo, error)
+}
+
+// DirWalletSource is a WalletSource reading the wallet files of a directory
+type DirWalletSource string
+
+// Open implements the WalletSource interface
+func (d DirWalletSource) Open(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(string(d), filepath.FromSlash(name)))
+}
+
+// ReadDir implements the WalletSource interface
+func (d DirWalletSource) ReadDir(name string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(filepath.Join(string(d), filepath.FromSlash(name)))
+}
+
+// LoadFromSource loads wallet from the file of given name in src
+func LoadFromSource(src WalletSource, name string) (Wallet, error) {
+	f, err := src.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := loadWalletData(data)
+	if err != nil {
+		logger.WithError(err).WithField("filename", name).Error("LoadFromSource: loadWalletData failed")
+		return nil, err
+	}
+	if w == nil {
+		return nil, nil
+	}
+
+	w.SetFilename(path.Base(name))
+	return w, nil
+}
@@ -1,12 +1,18 @@
 package wallet
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
 	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/transaction"
+	"github.com/skycoin/skycoin/src/util/fee"
+	"github.com/skycoin/skycoin/src/util/mathutil"
 )
 
 var (
@@ -17,8 +23,62 @@ var (
 	// ErrWalletCantSign is returned is attempting to sign a transaction with a wallet
 	// that does not have the capability to sign transactions (e.g. an xpub or watch wallet)
 	ErrWalletCantSign = NewError(errors.New("wallet does not have the signing capability"))
+	// ErrTransactionFullySigned is returned when adding inputs to a transaction that is already fully signed
+	ErrTransactionFullySigned = NewError(errors.New("cannot add inputs to a fully signed transaction"))
+	// ErrInvalidMaxInputsPerTxn is returned if the maximum number of inputs per transaction is too small to batch a spend
+	ErrInvalidMaxInputsPerTxn = NewError(errors.New("max inputs per transaction must be at least 2"))
+	// ErrNothingToConsolidate is returned if the wallet does not have at least 2 outputs to consolidate
+	ErrNothingToConsolidate = NewError(errors.New("at least 2 outputs are needed to consolidate"))
+	// ErrConsolidationNetLoss is returned if consolidating the outputs would burn all of their coin hours
+	ErrConsolidationNetLoss = NewError(errors.New("consolidation fee would burn all coin hours of the outputs"))
+	// ErrNoSpendableOutputs is returned if the wallet has no outputs to sweep
+	ErrNoSpendableOutputs = NewError(errors.New("wallet has no spendable outputs"))
+	// ErrTransactionInputsMismatch is returned if the outputs provided for signing do not match the transaction inputs
+	ErrTransactionInputsMismatch = NewError(errors.New("outputs do not match the transaction inputs"))
+	// ErrWalletCoinMismatch is returned if a wallet of another coin type is used to create or sign a skycoin transaction
+	ErrWalletCoinMismatch = NewError(errors.New("wallet coin type does not match the transaction coin type"))
+	// ErrChangeAddressConflict is returned if more than one of TransactionOptions.ChangeAddress,
+	// TransactionOptions.GenerateChange, TransactionOptions.ChangeWalletID and transaction.Params.ChangeAddress are set
+	ErrChangeAddressConflict = NewError(errors.New("ChangeAddress, GenerateChange, ChangeWalletID and Params.ChangeAddress cannot be combined"))
+	// ErrChangeWalletPasswordWithoutID is returned if TransactionOptions.ChangeWalletPassword is set without ChangeWalletID
+	ErrChangeWalletPasswordWithoutID = NewError(errors.New("ChangeWalletPassword requires ChangeWalletID"))
+	// ErrChangeWalletCoinMismatch is returned if the coin type of the change wallet does not match the spending wallet
+	ErrChangeWalletCoinMismatch = NewError(errors.New("change wallet coin type does not match the spending wallet coin type"))
+	// ErrChangeAddressNotInWallet is returned if TransactionOptions.ChangeAddress is not an address of the spending wallet
+	ErrChangeAddressNotInWallet = NewError(errors.New("change address does not belong to the wallet"))
+	// ErrTransactionInputNotFound is returned if a transaction input is not one of the provided outputs
+	ErrTransactionInputNotFound = NewError(errors.New("transaction input not found in the provided outputs"))
+	// ErrTransferToSameWallet is returned if the source and destination wallets of a transfer are the same
+	ErrTransferToSameWallet = NewError(errors.New("cannot transfer to the source wallet"))
+	// ErrSpendTimeBeforeHead is returned if TransactionOptions.SpendTime is before the current head time
+	ErrSpendTimeBeforeHead = NewError(errors.New("spend time is before the head time"))
+	// ErrNoMatureUxOuts is returned if none of the outputs to spend is mature at TransactionOptions.SpendTime
+	ErrNoMatureUxOuts = NewError(errors.New("no outputs to spend are mature at the spend time"))
+	// ErrInsufficientCoinHours is returned if the wallet has coins to spend but no coin hours to pay the transaction fee
+	ErrInsufficientCoinHours = NewError(errors.New("wallet has no coin hours to pay the transaction fee"))
+	// ErrMemoTooLong is returned if a transaction memo is longer than MaxMemoLength
+	ErrMemoTooLong = NewError(fmt.Errorf("memo must not be longer than %d bytes", MaxMemoLength))
+	// ErrDustOutput is returned if an output sends fewer coins than TransactionOptions.DustThreshold
+	ErrDustOutput = NewError(errors.New("output coins are below the dust threshold"))
+	// ErrOutputWithoutHours is returned if TransactionOptions.RequireOutputHours is set
+	// and an output of the created transaction has no coin hours
+	ErrOutputWithoutHours = NewError(errors.New("output of the transaction has no coin hours"))
+	// ErrNoTransactionsToCombine is returned if CombineSignatures is called without transactions
+	ErrNoTransactionsToCombine = NewError(errors.New("no transactions to combine"))
+	// ErrCombineTransactionMismatch is returned if the transactions passed to CombineSignatures
+	// do not have the same inputs and outputs
+	ErrCombineTransactionMismatch = NewError(errors.New("transactions to combine do not have the same inputs and outputs"))
 )
 
+// checkWalletCoin checks that the wallet can be used for the skycoin transactions created by this package.
+// Wallets without a coin type are treated as skycoin wallets.
+func checkWalletCoin(w Wallet) error {
+	if c := w.Coin(); c != "" && c != CoinTypeSkycoin {
+		return ErrWalletCoinMismatch
+	}
+	return nil
+}
+
 func validateSignIndexes(x []int, uxOuts []coin.UxOut) error {
 	if len(x) > len(uxOuts) {
 		return errors.New("Number of signature indexes exceeds number of inputs")
@@ -70,10 +130,14 @@ func copyTransaction(txn *coin.Transaction) *coin.Transaction {
 // Clients should avoid signing the same transaction multiple times.
 func SignTransaction(w Wallet, txn *coin.Transaction, signIndexes []int, uxOuts []coin.UxOut) (*coin.Transaction, error) {
 	switch w.Type() {
-	case WalletTypeXPub:
+	case WalletTypeXPub, WalletTypeWatchOnly:
 		return nil, ErrWalletCantSign
 	}
 
+	if err := checkWalletCoin(w); err != nil {
+		return nil, err
+	}
+
 	signedTxn := copyTransaction(txn)
 	txnInnerHash := signedTxn.HashInner()
 
@@ -184,6 +248,158 @@ func SignTransaction(w Wallet, txn *coin.Transaction, signIndexes []int, uxOuts
 	return signedTxn, nil
 }
 
+// CombineSignatures merges the signatures of partially signed copies of the same transaction,
+// e.g. the copies signed with SignTransaction by the wallets of the co-signers of a transaction
+// spending outputs owned by several parties. uxOuts are the outputs spent by the transaction inputs, in order.
+// Every signature is checked against the address of the output spent by its input, so that a co-signer
+// cannot slip in an invalid signature. The transactions are not modified.
+// The combined transaction is fully signed only if every input is signed in one of the transactions.
+func CombineSignatures(txns []coin.Transaction, uxOuts []coin.UxOut) (*coin.Transaction, error) {
+	if len(txns) == 0 {
+		return nil, ErrNoTransactionsToCombine
+	}
+
+	combinedTxn := copyTransaction(&txns[0])
+	if combinedTxn.InnerHash != combinedTxn.HashInner() {
+		return nil, NewError(errors.New("Transaction inner hash does not match computed inner hash"))
+	}
+	if len(combinedTxn.Sigs) != len(combinedTxn.In) {
+		return nil, NewError(errors.New("Number of signatures does not match number of inputs"))
+	}
+
+	if len(uxOuts) != len(combinedTxn.In) {
+		return nil, ErrTransactionInputsMismatch
+	}
+	for i, ux := range uxOuts {
+		if ux.Hash() != combinedTxn.In[i] {
+			return nil, ErrTransactionInputsMismatch
+		}
+	}
+
+	for i, txn := range txns {
+		if txn.InnerHash != combinedTxn.InnerHash || txn.HashInner() != combinedTxn.InnerHash || len(txn.Sigs) != len(txn.In) {
+			return nil, ErrCombineTransactionMismatch
+		}
+
+		if err := txn.VerifyPartialInputSignatures(uxOuts); err != nil {
+			return nil, NewError(fmt.Errorf("Transaction %d: %v", i, err))
+		}
+
+		// A signature is kept if another transaction has a different valid signature for the same input
+		for j, sig := range txn.Sigs {
+			if !sig.Null() && combinedTxn.Sigs[j].Null() {
+				combinedTxn.Sigs[j] = sig
+			}
+		}
+	}
+
+	if err := combinedTxn.UpdateHeader(); err != nil {
+		return nil, err
+	}
+
+	return combinedTxn, nil
+}
+
+// UnsignedTransaction is an unsigned transaction with the outputs spent by its inputs,
+// in the order of the transaction inputs. It holds everything an offline wallet needs
+// to sign the transaction, and is serialized to move it between machines.
+type UnsignedTransaction struct {
+	Txn    coin.Transaction
+	Inputs []transaction.UxBalance
+}
+
+// Serialize serializes the unsigned transaction to bytes
+func (ut UnsignedTransaction) Serialize() []byte {
+	return encoder.Serialize(ut)
+}
+
+// DeserializeUnsignedTransaction deserializes an unsigned transaction from bytes
+func DeserializeUnsignedTransaction(b []byte) (*UnsignedTransaction, error) {
+	var ut UnsignedTransaction
+	if err := encoder.DeserializeRawExact(b, &ut); err != nil {
+		return nil, err
+	}
+	return &ut, nil
+}
+
+// uxOutsFromBalances rebuilds the outputs spent by txn from inputs, checking that
+// each of them hashes to the transaction input at the same index
+func uxOutsFromBalances(txn *coin.Transaction, inputs []transaction.UxBalance) ([]coin.UxOut, error) {
+	if len(inputs) != len(txn.In) {
+		return nil, ErrTransactionInputsMismatch
+	}
+
+	uxOuts := make([]coin.UxOut, len(inputs))
+	for i, in := range inputs {
+		ux := coin.UxOut{
+			Head: coin.UxHead{
+				Time:  in.Time,
+				BkSeq: in.BkSeq,
+			},
+			Body: coin.UxBody{
+				SrcTransaction: in.SrcTransaction,
+				Address:        in.Address,
+				Coins:          in.Coins,
+				Hours:          in.InitialHours,
+			},
+		}
+
+		if ux.Hash() != txn.In[i] || in.Hash != txn.In[i] {
+			return nil, ErrTransactionInputsMismatch
+		}
+
+		uxOuts[i] = ux
+	}
+
+	return uxOuts, nil
+}
+
+// checkSignAddresses checks that the wallet has the addresses of the inputs at signIndexes,
+// or of all unsigned inputs if signIndexes is empty. Out of range indexes are left to SignTransaction to report.
+func checkSignAddresses(w Wallet, txn *coin.Transaction, signIndexes []int, uxOuts []coin.UxOut) error {
+	idxs := signIndexes
+	if len(idxs) == 0 {
+		for i := range uxOuts {
+			if i < len(txn.Sigs) && txn.Sigs[i].Null() {
+				idxs = append(idxs, i)
+			}
+		}
+	}
+
+	for _, i := range idxs {
+		if i < 0 || i >= len(uxOuts) {
+			continue
+		}
+
+		has, err := w.HasEntry(uxOuts[i].Body.Address)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return ErrUnknownAddress
+		}
+	}
+
+	return nil
+}
+
+// SelfSendOutputs returns the indexes of the outputs of p.To that send coins to an address of the wallet,
+// so that a user can be warned before sending coins to themselves.
+// For bip44 wallets, the addresses of the default account are checked.
+func SelfSendOutputs(w Wallet, p transaction.Params) ([]int, error) {
+	var idxs []int
+	for i, to := range p.To {
+		has, err := w.HasEntry(to.Address)
+		if err != nil {
+			return nil, err
+		}
+		if has {
+			idxs = append(idxs, i)
+		}
+	}
+	return idxs, nil
+}
+
 // CreateTransaction creates an unsigned transaction based upon transaction.Params.
 // Set the password as nil if the wallet is not encrypted, otherwise the password must be provided.
 // NOTE: Caller must ensure that auxs correspond to params.Wallet.Addresses and params.Wallet.UxOuts options
@@ -197,8 +413,13 @@ func SignTransaction(w Wallet, txn *coin.Transaction, signIndexes []int, uxOuts
 //     if the coinhour cost of adding that output is less than the coinhours that would be lost as change
 // If receiving hours are not explicitly specified, hours are allocated amongst the receiving outputs proportional to the number of coins being sent to them.
 // If the change address is not specified, the address whose bytes are lexically sorted first is chosen from the owners of the outputs being spent.
+// Returns ErrWalletCoinMismatch if the wallet is not a skycoin wallet.
 // WARNING: This method is not concurrent-safe if operating on the same wallet. Use Service.View or Service.ViewSecrets to lock the wallet, or use your own lock.
 func CreateTransaction(w Wallet, p transaction.Params, auxs coin.AddressUxOuts, headTime uint64) (*coin.Transaction, []transaction.UxBalance, error) {
+	if err := checkWalletCoin(w); err != nil {
+		return nil, nil, err
+	}
+
 	if err := p.Validate(); err != nil {
 		return nil, nil, err
 	}
@@ -228,6 +449,11 @@ func CreateTransaction(w Wallet, p transaction.Params, auxs coin.AddressUxOuts,
 // Set the password as nil if the wallet is not encrypted, otherwise the password must be provided.
 // Refer to CreateTransaction for information about transaction creation.
 func CreateTransactionSigned(w Wallet, p transaction.Params, auxs coin.AddressUxOuts, headTime uint64) (*coin.Transaction, []transaction.UxBalance, error) {
+	switch w.Type() {
+	case WalletTypeXPub, WalletTypeWatchOnly:
+		return nil, nil, ErrWalletCantSign
+	}
+
 	txn, uxb, err := CreateTransaction(w, p, auxs, headTime)
 	if err != nil {
 		return nil, nil, err
@@ -265,6 +491,551 @@ func CreateTransactionSigned(w Wallet, p transaction.Params, auxs coin.AddressUx
 	return txn, uxb, nil
 }
 
+// AddInputsToTransaction appends inputs owned by the wallet to an existing unsigned or partially signed transaction,
+// so that it spends additionalCoins more than its current inputs provide.
+// This allows multiple parties to fund a single transaction.
+// Outputs to spend are chosen from auxs, excluding outputs the transaction already spends.
+// The chosen coins in excess of additionalCoins are returned to a change output, along with the chosen
+// outputs' coin hours left after the fee burn. The change address is the first of the chosen outputs' owners,
+// sorted lexically by bytes. If there are no excess coins, the chosen outputs' hours are burned as fee.
+// Only the newly added inputs are signed. Changing the inputs invalidates any existing signatures,
+// so these are cleared and the other inputs must be signed again by their owners, e.g. with SignTransaction.
+// Returns the augmented transaction and the added inputs.
+// WARNING: This method is not concurrent-safe if operating on the same wallet. Use Service.AddInputsToTransaction,
+// Service.ViewSecrets to lock the wallet, or use your own lock.
+func AddInputsToTransaction(w Wallet, txn *coin.Transaction, additionalCoins uint64, auxs coin.AddressUxOuts, headTime uint64) (*coin.Transaction, []transaction.UxBalance, error) {
+	if w.IsEncrypted() {
+		return nil, nil, ErrWalletEncrypted
+	}
+
+	switch w.Type() {
+	case WalletTypeXPub, WalletTypeWatchOnly:
+		return nil, nil, ErrWalletCantSign
+	}
+
+	if txn.IsFullySigned() {
+		return nil, nil, ErrTransactionFullySigned
+	}
+
+	if err := txn.VerifyUnsigned(); err != nil {
+		return nil, nil, NewError(fmt.Errorf("invalid transaction: %v", err))
+	}
+
+	if txn.InnerHash != txn.HashInner() {
+		return nil, nil, NewError(errors.New("Transaction inner hash does not match computed inner hash"))
+	}
+
+	// Check that auxs does not contain addresses that are not known to this wallet
+	for a := range auxs {
+		has, err := w.HasEntry(a)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !has {
+			return nil, nil, fmt.Errorf("Address %s from auxs not found in wallet", a)
+		}
+	}
+
+	spending := make(map[cipher.SHA256]struct{}, len(txn.In))
+	for _, h := range txn.In {
+		spending[h] = struct{}{}
+	}
+
+	var uxa coin.UxArray
+	for _, uxs := range auxs {
+		for _, ux := range uxs {
+			if _, ok := spending[ux.Hash()]; !ok {
+				uxa = append(uxa, ux)
+			}
+		}
+	}
+
+	uxb, err := transaction.NewUxBalances(uxa, headTime)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	spends, err := transaction.ChooseSpendsMinimizeUxOuts(uxb, additionalCoins, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var addedCoins, addedHours uint64
+	for _, s := range spends {
+		addedCoins, err = mathutil.AddUint64(addedCoins, s.Coins)
+		if err != nil {
+			return nil, nil, err
+		}
+		addedHours, err = mathutil.AddUint64(addedHours, s.Hours)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	newTxn := copyTransaction(txn)
+	for _, s := range spends {
+		if err := newTxn.PushInput(s.Hash); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if changeCoins := addedCoins - additionalCoins; changeCoins > 0 {
+		changeAddress := spends[0].Address
+		for _, s := range spends[1:] {
+			if bytes.Compare(s.Address.Bytes(), changeAddress.Bytes()) < 0 {
+				changeAddress = s.Address
+			}
+		}
+
+		changeHours := fee.RemainingHours(addedHours, params.UserVerifyTxn.BurnFactor)
+		if err := newTxn.PushOutput(changeAddress, changeCoins, changeHours); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	newTxn.Sigs = make([]cipher.Sig, len(newTxn.In))
+	if err := newTxn.UpdateHeader(); err != nil {
+		return nil, nil, err
+	}
+
+	// Sign the added inputs
+	for i, s := range spends {
+		entry, err := w.GetEntry(s.Address)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := newTxn.SignInput(entry.Secret, len(txn.In)+i); err != nil {
+			logger.Critical().WithError(err).Errorf("AddInputsToTransaction SignInput(%d) failed", len(txn.In)+i)
+			return nil, nil, err
+		}
+	}
+
+	// Sanity check the augmented transaction
+	if err := newTxn.VerifyUnsigned(); err != nil {
+		logger.Critical().WithError(err).Error("AddInputsToTransaction created an invalid transaction")
+		return nil, nil, err
+	}
+
+	return newTxn, spends, nil
+}
+
+// EstimateFee creates an unsigned transaction like CreateTransaction, to preview its fee before signing it.
+// Returns a summary of the coins and coin hours spent by the transaction, and the chosen inputs.
+func EstimateFee(w Wallet, p transaction.Params, auxs coin.AddressUxOuts, headTime uint64) (*transaction.FeeEstimate, []transaction.UxBalance, error) {
+	txn, inputs, err := CreateTransaction(w, p, auxs, headTime)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	e, err := transaction.NewFeeEstimate(p, txn, inputs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return e, inputs, nil
+}
+
+// CreateTransactionsBatched creates and signs the transactions needed to make a spend
+// that would otherwise need more than maxInputsPerTxn inputs.
+// The outputs to spend are chosen like CreateTransaction does. If they don't fit in one transaction,
+// the smallest chosen outputs are merged by a consolidation transaction spending maxInputsPerTxn outputs
+// to a single output owned by the wallet, and the outputs are chosen again from the remaining and
+// consolidated outputs, so that the hours burned by the consolidation fees are accounted for.
+// The last transaction spends the chosen outputs and pays the requested outputs, along with any change.
+// The transactions must be injected in the returned order, since later transactions spend
+// the outputs of earlier ones. Returns the transactions and the inputs of each transaction.
+// WARNING: This method is not concurrent-safe if operating on the same wallet. Use Service.CreateTransactionsBatched,
+// Service.ViewSecrets to lock the wallet, or use your own lock.
+func CreateTransactionsBatched(w Wallet, p transaction.Params, auxs coin.AddressUxOuts, headTime uint64, maxInputsPerTxn int) ([]*coin.Transaction, [][]transaction.UxBalance, error) {
+	switch w.Type() {
+	case WalletTypeXPub, WalletTypeWatchOnly:
+		return nil, nil, ErrWalletCantSign
+	}
+
+	if maxInputsPerTxn < 2 {
+		return nil, nil, ErrInvalidMaxInputsPerTxn
+	}
+
+	pool := make(map[cipher.SHA256]coin.UxOut)
+	for _, uxs := range auxs {
+		for _, ux := range uxs {
+			pool[ux.Hash()] = ux
+		}
+	}
+
+	var txns []*coin.Transaction
+	var inputs [][]transaction.UxBalance
+	for {
+		poolAuxs := make(coin.AddressUxOuts)
+		for _, ux := range pool {
+			poolAuxs[ux.Body.Address] = append(poolAuxs[ux.Body.Address], ux)
+		}
+
+		// The unsigned transaction is enough to know which outputs the spend needs
+		txn, uxb, err := CreateTransaction(w, p, poolAuxs, headTime)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		chosen := make([]coin.UxOut, len(uxb))
+		for i, b := range uxb {
+			ux, ok := pool[b.Hash]
+			if !ok {
+				err := errors.New("chosen spend not found in auxs")
+				logger.Critical().WithError(err).Error("CreateTransactionsBatched")
+				return nil, nil, err
+			}
+			chosen[i] = ux
+		}
+
+		if len(uxb) <= maxInputsPerTxn {
+			signedTxn, err := SignTransaction(w, txn, nil, chosen)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if err := verifyCreatedSignedInvariants(p, signedTxn, uxb); err != nil {
+				return nil, nil, err
+			}
+
+			return append(txns, signedTxn), append(inputs, uxb), nil
+		}
+
+		// Consolidate the smallest chosen outputs, with the hash as a tie-breaker so the choice is deterministic
+		sort.Slice(chosen, func(i, j int) bool {
+			a, b := chosen[i].Body, chosen[j].Body
+			if a.Coins != b.Coins {
+				return a.Coins < b.Coins
+			}
+			if a.Hours != b.Hours {
+				return a.Hours < b.Hours
+			}
+			ha, hb := chosen[i].Hash(), chosen[j].Hash()
+			return bytes.Compare(ha[:], hb[:]) < 0
+		})
+
+		ctxn, cuxb, ux, err := createConsolidationTransaction(w, chosen[:maxInputsPerTxn], headTime)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		txns = append(txns, ctxn)
+		inputs = append(inputs, cuxb)
+
+		for _, b := range cuxb {
+			delete(pool, b.Hash)
+		}
+		pool[ux.Hash()] = ux
+	}
+}
+
+// CreateConsolidationTransaction creates and signs a transaction that merges up to maxInputs
+// of the wallet's smallest outputs into a single output, to reduce the number of inputs
+// later transactions need. Outputs are chosen from auxs by ascending coins, then ascending coin hours.
+// The output is sent to the address whose bytes are lexically sorted first among the owners of the
+// chosen outputs, with the coin hours left after the fee burn.
+// Returns ErrConsolidationNetLoss if the fee would burn all coin hours of the chosen outputs,
+// and fee.ErrTxnNoFee if they have no coin hours to pay the fee.
+// WARNING: This method is not concurrent-safe if operating on the same wallet. Use Service.CreateConsolidationTransaction,
+// Service.ViewSecrets to lock the wallet, or use your own lock.
+func CreateConsolidationTransaction(w Wallet, auxs coin.AddressUxOuts, headTime uint64, maxInputs int) (*coin.Transaction, []transaction.UxBalance, error) {
+	if w.IsEncrypted() {
+		return nil, nil, ErrWalletEncrypted
+	}
+
+	if err := checkWalletCoin(w); err != nil {
+		return nil, nil, err
+	}
+
+	switch w.Type() {
+	case WalletTypeXPub, WalletTypeWatchOnly:
+		return nil, nil, ErrWalletCantSign
+	}
+
+	if maxInputs < 2 {
+		return nil, nil, ErrInvalidMaxInputsPerTxn
+	}
+
+	// Check that auxs does not contain addresses that are not known to this wallet
+	for a := range auxs {
+		has, err := w.HasEntry(a)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !has {
+			return nil, nil, fmt.Errorf("Address %s from auxs not found in wallet", a)
+		}
+	}
+
+	uxOuts := auxs.Flatten()
+	if len(uxOuts) < 2 {
+		return nil, nil, ErrNothingToConsolidate
+	}
+
+	uxb, err := transaction.NewUxBalances(uxOuts, headTime)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Sort by ascending coins and hours, with the hash as a tie-breaker so the choice is deterministic
+	sort.Slice(uxb, func(i, j int) bool {
+		a, b := uxb[i], uxb[j]
+		if a.Coins != b.Coins {
+			return a.Coins < b.Coins
+		}
+		if a.Hours != b.Hours {
+			return a.Hours < b.Hours
+		}
+		return bytes.Compare(a.Hash[:], b.Hash[:]) < 0
+	})
+
+	if len(uxb) > maxInputs {
+		uxb = uxb[:maxInputs]
+	}
+
+	byHash := make(map[cipher.SHA256]coin.UxOut, len(uxOuts))
+	for _, ux := range uxOuts {
+		byHash[ux.Hash()] = ux
+	}
+
+	var hours uint64
+	chosen := make([]coin.UxOut, len(uxb))
+	for i, b := range uxb {
+		chosen[i] = byHash[b.Hash]
+		hours, err = mathutil.AddUint64(hours, b.Hours)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if hours > 0 && fee.RemainingHours(hours, params.UserVerifyTxn.BurnFactor) == 0 {
+		return nil, nil, ErrConsolidationNetLoss
+	}
+
+	txn, inputs, _, err := createConsolidationTransaction(w, chosen, headTime)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return txn, inputs, nil
+}
+
+// CreateSweepTransaction creates and signs a transaction that sends the entire balance of the outputs
+// in auxs to dest, in a single output. The output has all of the coins and the coin hours left after the fee burn.
+// Returns ErrNoSpendableOutputs if auxs has no outputs, and fee.ErrTxnNoFee if they have no coin hours to pay the fee.
+// WARNING: This method is not concurrent-safe if operating on the same wallet. Use Service.CreateSweepTransaction,
+// Service.ViewSecrets to lock the wallet, or use your own lock.
+func CreateSweepTransaction(w Wallet, dest cipher.Address, auxs coin.AddressUxOuts, headTime uint64) (*coin.Transaction, []transaction.UxBalance, error) {
+	if w.IsEncrypted() {
+		return nil, nil, ErrWalletEncrypted
+	}
+
+	if err := checkWalletCoin(w); err != nil {
+		return nil, nil, err
+	}
+
+	switch w.Type() {
+	case WalletTypeXPub, WalletTypeWatchOnly:
+		return nil, nil, ErrWalletCantSign
+	}
+
+	if dest.Null() {
+		return nil, nil, transaction.ErrNullAddressReceiver
+	}
+
+	// Check that auxs does not contain addresses that are not known to this wallet
+	for a := range auxs {
+		has, err := w.HasEntry(a)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !has {
+			return nil, nil, fmt.Errorf("Address %s from auxs not found in wallet", a)
+		}
+	}
+
+	uxOuts := auxs.Flatten()
+	if len(uxOuts) == 0 {
+		return nil, nil, ErrNoSpendableOutputs
+	}
+
+	uxb, err := transaction.NewUxBalances(uxOuts, headTime)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Sort the inputs so that the transaction does not depend on the map iteration order of auxs
+	sort.Slice(uxb, func(i, j int) bool {
+		return bytes.Compare(uxb[i].Hash[:], uxb[j].Hash[:]) < 0
+	})
+
+	txn, inputs, _, err := createSingleOutputTransaction(w, uxb, headTime, dest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return txn, inputs, nil
+}
+
+// maxSpendableCoins returns the coins of the outputs in uxb that can be sent in a single transaction
+// with one output, no larger than maxSize. The outputs are chosen coins highest, hours highest,
+// with the hash as a tiebreaker. If none of the chosen outputs have coin hours to pay the fee, the output
+// with the least coins is replaced by the output with the most coins that has coin hours.
+// Returns ErrInsufficientCoinHours if there are coins but no chosen output has coin hours.
+func maxSpendableCoins(uxb []transaction.UxBalance, maxSize uint32) (uint64, error) {
+	if len(uxb) == 0 {
+		return 0, nil
+	}
+
+	sort.Slice(uxb, func(i, j int) bool {
+		a, b := uxb[i], uxb[j]
+		if a.Coins != b.Coins {
+			return a.Coins > b.Coins
+		}
+		if a.Hours != b.Hours {
+			return a.Hours > b.Hours
+		}
+		return bytes.Compare(a.Hash[:], b.Hash[:]) < 0
+	})
+
+	n := len(uxb)
+	if maxSize != 0 {
+		for n > 0 && transaction.EstimateTransactionSize(n, 1) > int(maxSize) {
+			n--
+		}
+	}
+	if n == 0 {
+		return 0, transaction.ErrTransactionTooLarge
+	}
+
+	chosen := uxb[:n:n]
+
+	hasHours := false
+	for _, b := range chosen {
+		if b.Hours > 0 {
+			hasHours = true
+			break
+		}
+	}
+
+	if !hasHours {
+		for _, b := range uxb[n:] {
+			if b.Hours > 0 {
+				chosen = append(chosen[:n-1:n-1], b)
+				hasHours = true
+				break
+			}
+		}
+	}
+
+	if !hasHours {
+		return 0, ErrInsufficientCoinHours
+	}
+
+	var coins uint64
+	for _, b := range chosen {
+		var err error
+		coins, err = mathutil.AddUint64(coins, b.Coins)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return coins, nil
+}
+
+// createConsolidationTransaction creates a signed transaction spending uxOuts to a single output,
+// at the address whose bytes are lexically sorted first among the owners of uxOuts.
+// Returns the transaction, its inputs and the created output.
+func createConsolidationTransaction(w Wallet, uxOuts []coin.UxOut, headTime uint64) (*coin.Transaction, []transaction.UxBalance, coin.UxOut, error) {
+	uxb, err := transaction.NewUxBalances(uxOuts, headTime)
+	if err != nil {
+		return nil, nil, coin.UxOut{}, err
+	}
+
+	addr := uxb[0].Address
+	for _, b := range uxb[1:] {
+		if bytes.Compare(b.Address.Bytes(), addr.Bytes()) < 0 {
+			addr = b.Address
+		}
+	}
+
+	return createSingleOutputTransaction(w, uxb, headTime, addr)
+}
+
+// createSingleOutputTransaction creates a signed transaction spending all of uxb to a single output
+// at addr, with the coin hours left after the fee burn.
+// Returns the transaction, its inputs and the created output.
+func createSingleOutputTransaction(w Wallet, uxb []transaction.UxBalance, headTime uint64, addr cipher.Address) (*coin.Transaction, []transaction.UxBalance, coin.UxOut, error) {
+	txn := &coin.Transaction{}
+	var coins, hours, bkSeq uint64
+	var err error
+	for _, b := range uxb {
+		if b.BkSeq > bkSeq {
+			bkSeq = b.BkSeq
+		}
+
+		if err := txn.PushInput(b.Hash); err != nil {
+			return nil, nil, coin.UxOut{}, err
+		}
+
+		coins, err = mathutil.AddUint64(coins, b.Coins)
+		if err != nil {
+			return nil, nil, coin.UxOut{}, err
+		}
+		hours, err = mathutil.AddUint64(hours, b.Hours)
+		if err != nil {
+			return nil, nil, coin.UxOut{}, err
+		}
+	}
+
+	if hours == 0 {
+		return nil, nil, coin.UxOut{}, fee.ErrTxnNoFee
+	}
+
+	outHours := fee.RemainingHours(hours, params.UserVerifyTxn.BurnFactor)
+	if err := txn.PushOutput(addr, coins, outHours); err != nil {
+		return nil, nil, coin.UxOut{}, err
+	}
+
+	txn.Sigs = make([]cipher.Sig, len(txn.In))
+	if err := txn.UpdateHeader(); err != nil {
+		return nil, nil, coin.UxOut{}, err
+	}
+
+	for i, b := range uxb {
+		entry, err := w.GetEntry(b.Address)
+		if err != nil {
+			return nil, nil, coin.UxOut{}, err
+		}
+
+		if err := txn.SignInput(entry.Secret, i); err != nil {
+			return nil, nil, coin.UxOut{}, err
+		}
+	}
+
+	if err := txn.UpdateHeader(); err != nil {
+		return nil, nil, coin.UxOut{}, err
+	}
+
+	// The output is not in a block yet, it's assumed to be in the block after its newest input
+	ux := coin.UxOut{
+		Head: coin.UxHead{
+			Time:  headTime,
+			BkSeq: bkSeq + 1,
+		},
+		Body: coin.UxBody{
+			SrcTransaction: txn.Hash(),
+			Address:        addr,
+			Coins:          coins,
+			Hours:          outHours,
+		},
+	}
+
+	return txn, uxb, ux, nil
+}
+
 func verifyCreatedSignedInvariants(p transaction.Params, txn *coin.Transaction, inputs []transaction.UxBalance) error {
 	if !txn.IsFullySigned() {
 		return errors.New("Transaction is not fully signed")
@@ -15,6 +15,7 @@ import (
 
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/testutil"
 	"github.com/skycoin/skycoin/src/transaction"
 	"github.com/skycoin/skycoin/src/util/fee"
@@ -744,6 +745,283 @@ func TestWalletCreateTransaction(t *testing.T) {
 	}
 }
 
+func TestWalletAddInputsToTransaction(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+
+	w := &collection.Wallet{}
+	e := makeEntry()
+	require.NoError(t, w.AddEntry(e))
+
+	var uxouts []coin.UxOut
+	for i := 0; i < 2; i++ {
+		uxouts = append(uxouts, makeSpendableUxOut(t, e.Secret, 15e5, 100, headTime))
+	}
+	auxs := coin.AddressUxOuts{
+		e.SkycoinAddress(): uxouts,
+	}
+
+	// The transaction is partially funded by an input owned by another party
+	other := makeEntry()
+	otherUx := makeSpendableUxOut(t, other.Secret, 1e6, 100, headTime)
+	txn := coin.Transaction{}
+	require.NoError(t, txn.PushInput(otherUx.Hash()))
+	require.NoError(t, txn.PushOutput(makeAddress(), 3e6, 50))
+	txn.Sigs = make([]cipher.Sig, len(txn.In))
+	require.NoError(t, txn.UpdateHeader())
+
+	newTxn, inputs, err := wallet.AddInputsToTransaction(w, &txn, 2e6, auxs, headTime)
+	require.NoError(t, err)
+	require.Len(t, inputs, 2)
+
+	// The original transaction is not modified
+	require.Len(t, txn.In, 1)
+
+	require.Len(t, newTxn.In, 3)
+	require.Equal(t, otherUx.Hash(), newTxn.In[0])
+	require.Equal(t, txn.Out[0], newTxn.Out[0])
+	require.Equal(t, newTxn.HashInner(), newTxn.InnerHash)
+
+	// The excess coins and remaining hours are returned as change
+	require.Len(t, newTxn.Out, 2)
+	require.Equal(t, coin.TransactionOutput{
+		Address: e.SkycoinAddress(),
+		Coins:   1e6,
+		Hours:   fee.RemainingHours(200, params.UserVerifyTxn.BurnFactor),
+	}, newTxn.Out[1])
+
+	// Only the added inputs are signed
+	require.True(t, newTxn.Sigs[0].Null())
+	require.False(t, newTxn.Sigs[1].Null())
+	require.False(t, newTxn.Sigs[2].Null())
+	uxIn := coin.UxArray{otherUx}
+	for _, in := range inputs {
+		for _, ux := range uxouts {
+			if ux.Hash() == in.Hash {
+				uxIn = append(uxIn, ux)
+			}
+		}
+	}
+	require.NoError(t, newTxn.VerifyPartialInputSignatures(uxIn))
+
+	// Inputs can't be added to a fully signed transaction
+	signedTxn := *newTxn
+	signedTxn.Sigs = append([]cipher.Sig{}, newTxn.Sigs...)
+	require.NoError(t, signedTxn.SignInput(other.Secret, 0))
+	require.True(t, signedTxn.IsFullySigned())
+	_, _, err = wallet.AddInputsToTransaction(w, &signedTxn, 1e6, auxs, headTime)
+	require.Equal(t, wallet.ErrTransactionFullySigned, err)
+
+	// Insufficient balance
+	_, _, err = wallet.AddInputsToTransaction(w, &txn, 4e6, auxs, headTime)
+	require.Equal(t, transaction.ErrInsufficientBalance, err)
+
+	// Exact amount, no change output
+	exactTxn, _, err := wallet.AddInputsToTransaction(w, &txn, 15e5, auxs, headTime)
+	require.NoError(t, err)
+	require.Len(t, exactTxn.In, 2)
+	require.Len(t, exactTxn.Out, 1)
+
+	// Addresses in auxs must belong to the wallet
+	_, _, err = wallet.AddInputsToTransaction(w, &txn, 1e6, coin.AddressUxOuts{
+		makeAddress(): uxouts,
+	}, headTime)
+	require.Error(t, err)
+
+	// Malformed transaction
+	badTxn := coin.Transaction{}
+	_, _, err = wallet.AddInputsToTransaction(w, &badTxn, 1e6, auxs, headTime)
+	require.Error(t, err)
+}
+
+func TestWalletAddInputsToTransactionMultiParty(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+
+	// Parties A and B fund the transaction and party A signs its input first
+	a := makeEntry()
+	b := makeEntry()
+	uxA := makeSpendableUxOut(t, a.Secret, 1e6, 100, headTime)
+	uxB := makeSpendableUxOut(t, b.Secret, 1e6, 100, headTime)
+
+	txn := coin.Transaction{}
+	require.NoError(t, txn.PushInput(uxA.Hash()))
+	require.NoError(t, txn.PushInput(uxB.Hash()))
+	require.NoError(t, txn.PushOutput(makeAddress(), 3e6, 50))
+	txn.Sigs = make([]cipher.Sig, len(txn.In))
+	require.NoError(t, txn.UpdateHeader())
+	require.NoError(t, txn.SignInput(a.Secret, 0))
+
+	// Party C adds inputs to the partially signed transaction
+	w := &collection.Wallet{}
+	c := makeEntry()
+	require.NoError(t, w.AddEntry(c))
+	uxC := makeSpendableUxOut(t, c.Secret, 1e6, 100, headTime)
+	auxs := coin.AddressUxOuts{
+		c.SkycoinAddress(): []coin.UxOut{uxC},
+	}
+
+	newTxn, inputs, err := wallet.AddInputsToTransaction(w, &txn, 1e6, auxs, headTime)
+	require.NoError(t, err)
+	require.Len(t, inputs, 1)
+	require.Len(t, newTxn.In, 3)
+
+	// The original transaction is not modified
+	require.False(t, txn.Sigs[0].Null())
+
+	// Party A's signature was invalidated by the new input and is cleared
+	require.True(t, newTxn.Sigs[0].Null())
+	require.True(t, newTxn.Sigs[1].Null())
+	require.False(t, newTxn.Sigs[2].Null())
+
+	uxIn := coin.UxArray{uxA, uxB, uxC}
+	require.NoError(t, newTxn.VerifyPartialInputSignatures(uxIn))
+
+	// Parties A and B sign their inputs again
+	require.NoError(t, newTxn.SignInput(a.Secret, 0))
+	require.NoError(t, newTxn.SignInput(b.Secret, 1))
+	require.True(t, newTxn.IsFullySigned())
+	require.NoError(t, newTxn.Verify())
+	require.NoError(t, newTxn.VerifyInputSignatures(uxIn))
+}
+
+func TestWalletEstimateFee(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+
+	w := &collection.Wallet{}
+	e := makeEntry()
+	require.NoError(t, w.AddEntry(e))
+
+	var uxouts []coin.UxOut
+	for i := 0; i < 3; i++ {
+		uxout := makeUxOut(t, e.Secret, 1e6, 100)
+		uxout.Head.Time = headTime
+		uxout.Head.BkSeq = uint64(i + 1)
+		uxouts = append(uxouts, uxout)
+	}
+	auxs := coin.NewAddressUxOuts(uxouts)
+
+	p := transaction.Params{
+		HoursSelection: transaction.HoursSelection{
+			Type: transaction.HoursSelectionTypeManual,
+		},
+		To: []coin.TransactionOutput{
+			{
+				Address: makeAddress(),
+				Coins:   15e5,
+				Hours:   10,
+			},
+		},
+	}
+
+	est, inputs, err := wallet.EstimateFee(w, p, auxs, headTime)
+	require.NoError(t, err)
+
+	// The estimate matches the created transaction
+	txn, txnInputs, err := wallet.CreateTransaction(w, p, auxs, headTime)
+	require.NoError(t, err)
+	require.Equal(t, txnInputs, inputs)
+	require.Len(t, txn.Out, 2)
+
+	require.Equal(t, uint64(2e6), est.InputCoins)
+	require.Equal(t, uint64(200), est.InputHours)
+	require.Equal(t, est.InputCoins, est.OutputCoins)
+	require.Equal(t, uint64(5e5), est.ChangeCoins)
+	require.Equal(t, txn.Out[1].Hours, est.ChangeHours)
+	require.Equal(t, est.InputHours-txn.Out[0].Hours-txn.Out[1].Hours, est.Fee)
+
+	_, _, err = wallet.EstimateFee(w, p, coin.AddressUxOuts{}, headTime)
+	require.Equal(t, transaction.ErrNoUnspents, err)
+}
+
+func TestWalletCreateTransactionsBatched(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+
+	w := &collection.Wallet{}
+	e := makeEntry()
+	require.NoError(t, w.AddEntry(e))
+
+	var uxouts []coin.UxOut
+	for i := 0; i < 9; i++ {
+		uxout := makeUxOut(t, e.Secret, 1e6, 100)
+		uxout.Head.Time = headTime
+		uxout.Head.BkSeq = uint64(i + 1)
+		uxouts = append(uxouts, uxout)
+	}
+	auxs := coin.NewAddressUxOuts(uxouts)
+
+	to := makeAddress()
+	p := transaction.Params{
+		HoursSelection: transaction.HoursSelection{
+			Type: transaction.HoursSelectionTypeManual,
+		},
+		To: []coin.TransactionOutput{
+			{
+				Address: to,
+				Coins:   85e5,
+				Hours:   10,
+			},
+		},
+	}
+
+	_, _, err := wallet.CreateTransactionsBatched(w, p, auxs, headTime, 1)
+	require.Equal(t, wallet.ErrInvalidMaxInputsPerTxn, err)
+
+	// All inputs fit in one transaction
+	txns, inputs, err := wallet.CreateTransactionsBatched(w, p, auxs, headTime, 9)
+	require.NoError(t, err)
+	require.Len(t, txns, 1)
+	require.Len(t, inputs[0], 9)
+
+	// 9 inputs with up to 4 inputs per transaction requires two consolidation transactions
+	txns, inputs, err = wallet.CreateTransactionsBatched(w, p, auxs, headTime, 4)
+	require.NoError(t, err)
+	require.Len(t, txns, 3)
+	require.Len(t, inputs, 3)
+
+	// Outputs created by each transaction, to verify the transactions spending them
+	unspents := make(map[cipher.SHA256]coin.UxOut)
+	for _, ux := range uxouts {
+		unspents[ux.Hash()] = ux
+	}
+
+	for i, txn := range txns {
+		require.True(t, len(txn.In) <= 4)
+		require.Len(t, inputs[i], len(txn.In))
+		require.True(t, txn.IsFullySigned())
+		require.NoError(t, txn.Verify())
+
+		var uxIn coin.UxArray
+		var inCoins, outCoins, inHours uint64
+		for _, h := range txn.In {
+			ux, ok := unspents[h]
+			require.True(t, ok, "transaction %d spends an unknown output", i)
+			delete(unspents, h)
+			uxIn = append(uxIn, ux)
+			inCoins += ux.Body.Coins
+			hours, err := ux.CoinHours(headTime)
+			require.NoError(t, err)
+			inHours += hours
+		}
+		require.NoError(t, txn.VerifyInputSignatures(uxIn))
+
+		uxOut := coin.CreateUnspents(coin.BlockHeader{Time: headTime, BkSeq: uint64(i + 1)}, *txn)
+		for _, ux := range uxOut {
+			unspents[ux.Hash()] = ux
+			outCoins += ux.Body.Coins
+		}
+		require.Equal(t, inCoins, outCoins)
+
+		// Each transaction pays its own fee from the hours of its inputs
+		outHours, err := txn.OutputHours()
+		require.NoError(t, err)
+		require.True(t, inHours >= outHours)
+		require.NoError(t, fee.VerifyTransactionFee(txn, inHours-outHours, params.UserVerifyTxn.BurnFactor))
+	}
+
+	// The last transaction pays the requested output
+	last := txns[len(txns)-1]
+	require.Equal(t, p.To[0], last.Out[0])
+}
+
 func makeTransaction(t *testing.T, nInputs int) (coin.Transaction, []coin.UxOut, []cipher.SecKey) {
 	txn := coin.Transaction{}
 
@@ -768,6 +1046,57 @@ func makeTransaction(t *testing.T, nInputs int) (coin.Transaction, []coin.UxOut,
 	return txn, uxs, toSign
 }
 
+func TestDescribeTransaction(t *testing.T) {
+	_, s1 := cipher.GenerateKeyPair()
+	_, s2 := cipher.GenerateKeyPair()
+	ux1 := makeUxOut(t, s1, 2e6, 100)
+	ux2 := makeUxOut(t, s2, 1500000, 50)
+	addr1 := ux1.Body.Address
+	to := testutil.MakeAddress()
+
+	inputs := []transaction.UxBalance{
+		{Hash: ux1.Hash(), Address: addr1, Coins: 2e6, Hours: 100},
+		{Hash: ux2.Hash(), Address: ux2.Body.Address, Coins: 1500000, Hours: 50},
+	}
+
+	txn := &coin.Transaction{}
+	require.NoError(t, txn.PushInput(ux1.Hash()))
+	require.NoError(t, txn.PushInput(ux2.Hash()))
+	require.NoError(t, txn.PushOutput(to, 3e6, 60))
+	require.NoError(t, txn.PushOutput(addr1, 500000, 15))
+
+	s, err := wallet.DescribeTransaction(txn, inputs)
+	require.NoError(t, err)
+	require.Equal(t, wallet.TransactionSummary{
+		Inputs: []wallet.TransactionSummaryInput{
+			{Hash: ux1.Hash(), Address: addr1, Coins: "2.000000", Hours: 100},
+			{Hash: ux2.Hash(), Address: ux2.Body.Address, Coins: "1.500000", Hours: 50},
+		},
+		Outputs: []wallet.TransactionSummaryOutput{
+			{Address: to, Coins: "3.000000", Hours: 60},
+			{Address: addr1, Coins: "0.500000", Hours: 15, IsChange: true},
+		},
+		InputCoins:  "3.500000",
+		InputHours:  150,
+		OutputCoins: "3.500000",
+		OutputHours: 75,
+		Fee:         75,
+		ChangeCoins: "0.500000",
+		ChangeHours: 15,
+	}, s)
+
+	// The inputs must match the transaction inputs
+	_, err = wallet.DescribeTransaction(txn, inputs[:1])
+	require.Equal(t, wallet.ErrTransactionInputsMismatch, err)
+	_, err = wallet.DescribeTransaction(txn, []transaction.UxBalance{inputs[1], inputs[0]})
+	require.Equal(t, wallet.ErrTransactionInputsMismatch, err)
+
+	// The outputs can't spend more hours than the inputs
+	txn.Out[0].Hours = 200
+	_, err = wallet.DescribeTransaction(txn, inputs)
+	testutil.RequireError(t, err, "Transaction output hours exceed input hours")
+}
+
 func makeUxOut(t *testing.T, s cipher.SecKey, coins, hours uint64) coin.UxOut { //nolint:unparam
 	body := makeUxBody(t, s, coins, hours)
 	tm := rand.Int31n(1000)
@@ -781,6 +1110,18 @@ func makeUxOut(t *testing.T, s cipher.SecKey, coins, hours uint64) coin.UxOut {
 	}
 }
 
+// makeSpendableUxOut creates an unspent output with a fixed block time and a non-genesis block sequence,
+// so that its coin hours are deterministic
+func makeSpendableUxOut(t *testing.T, s cipher.SecKey, coins, hours, headTime uint64) coin.UxOut {
+	return coin.UxOut{
+		Head: coin.UxHead{
+			Time:  headTime,
+			BkSeq: 1,
+		},
+		Body: makeUxBody(t, s, coins, hours),
+	}
+}
+
 func makeUxBody(t *testing.T, s cipher.SecKey, coins, hours uint64) coin.UxBody {
 	p := cipher.MustPubKeyFromSecKey(s)
 	return coin.UxBody{
@@ -826,3 +1167,81 @@ func makeEntry() wallet.Entry {
 		Address: a,
 	}
 }
+
+func TestSelfSendOutputs(t *testing.T) {
+	w, err := wallet.NewWallet("t.wlt", "label", "seed", wallet.Options{
+		Type:      wallet.WalletTypeDeterministic,
+		GenerateN: 2,
+	})
+	require.NoError(t, err)
+	addrs, err := w.GetAddresses()
+	require.NoError(t, err)
+
+	p := transaction.Params{
+		To: []coin.TransactionOutput{
+			{Address: testutil.MakeAddress(), Coins: 1e6},
+			{Address: addrs[1].(cipher.Address), Coins: 1e6},
+			{Address: testutil.MakeAddress(), Coins: 1e6},
+			{Address: addrs[0].(cipher.Address), Coins: 1e6},
+		},
+	}
+
+	idxs, err := wallet.SelfSendOutputs(w, p)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 3}, idxs)
+
+	p.To = p.To[:1]
+	idxs, err = wallet.SelfSendOutputs(w, p)
+	require.NoError(t, err)
+	require.Empty(t, idxs)
+}
+
+func TestCombineSignatures(t *testing.T) {
+	txnSigned, uxs, _ := makeTransaction(t, 4)
+
+	partialTxn := func(idxs ...int) coin.Transaction {
+		txn := txnSigned
+		txn.Sigs = make([]cipher.Sig, len(txnSigned.Sigs))
+		for _, i := range idxs {
+			txn.Sigs[i] = txnSigned.Sigs[i]
+		}
+		return txn
+	}
+
+	txn1 := partialTxn(0, 3)
+	txn2 := partialTxn(1)
+	txn3 := partialTxn(1, 2)
+
+	// Partially combined
+	combinedTxn, err := wallet.CombineSignatures([]coin.Transaction{txn1, txn2}, uxs)
+	require.NoError(t, err)
+	require.False(t, combinedTxn.IsFullySigned())
+	require.Equal(t, partialTxn(0, 1, 3), *combinedTxn)
+	require.Equal(t, partialTxn(0, 3), txn1)
+
+	// Fully combined
+	combinedTxn, err = wallet.CombineSignatures([]coin.Transaction{txn1, txn2, txn3}, uxs)
+	require.NoError(t, err)
+	require.True(t, combinedTxn.IsFullySigned())
+	require.Equal(t, txnSigned, *combinedTxn)
+	err = combinedTxn.VerifyInputSignatures(uxs)
+	require.NoError(t, err)
+
+	_, err = wallet.CombineSignatures(nil, uxs)
+	require.Equal(t, wallet.ErrNoTransactionsToCombine, err)
+
+	// Another transaction
+	otherTxn, _, _ := makeTransaction(t, 4)
+	_, err = wallet.CombineSignatures([]coin.Transaction{txn1, otherTxn}, uxs)
+	require.Equal(t, wallet.ErrCombineTransactionMismatch, err)
+
+	// A signature that does not match the address of its input
+	badTxn := partialTxn()
+	badTxn.Sigs[2] = txnSigned.Sigs[1]
+	_, err = wallet.CombineSignatures([]coin.Transaction{txn1, badTxn}, uxs)
+	require.Equal(t, wallet.NewError(errors.New("Transaction 1: Signature not valid for output being spent")), err)
+
+	// Outputs that are not spent by the transaction
+	_, err = wallet.CombineSignatures([]coin.Transaction{txn1, txn2}, uxs[:3])
+	require.Equal(t, wallet.ErrTransactionInputsMismatch, err)
+}
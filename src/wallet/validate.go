@@ -0,0 +1,220 @@
+package wallet
+
+import (
+	"fmt"
+)
+
+// derivedWalletLabel is the label of the wallet derived from the seed by ValidateWallet, the wallet is discarded
+const derivedWalletLabel = "derived"
+
+// walletChains returns the options selecting each chain of entries of the wallet,
+// i.e. the external and change chains of every account of a bip44 wallet, or the single chain of the other wallets
+func walletChains(w Wallet) [][]Option {
+	if w.Type() != WalletTypeBip44 {
+		return [][]Option{nil}
+	}
+
+	var options [][]Option
+	for _, a := range w.Accounts() {
+		options = append(options,
+			[]Option{OptionAccount(a.Index), OptionExternal()},
+			[]Option{OptionAccount(a.Index), OptionChange()})
+	}
+	return options
+}
+
+// ValidateWallet checks that the entries of the wallet are consistent:
+//   - the address of every entry matches its public key
+//   - the child numbers of the entries of bip44 and xpub wallets are contiguous
+//   - the first address recorded by SetHeader, if any, is the address of the first entry
+//
+// If the wallet is not encrypted, it also checks that the secret key of every entry matches its public key,
+// and that the entries of deterministic and bip44 wallets are derived from the seed.
+// Encrypted wallets only get the public checks, use GuardView to check them decrypted.
+func ValidateWallet(w Wallet) error {
+	chains := walletChains(w)
+	for _, opts := range chains {
+		entries, err := w.GetEntries(opts...)
+		if err != nil {
+			return err
+		}
+
+		if err := validatePublicEntries(w.Type(), entries); err != nil {
+			return err
+		}
+	}
+
+	if err := validateHeader(w); err != nil {
+		return err
+	}
+
+	if w.IsEncrypted() {
+		return nil
+	}
+
+	switch w.Type() {
+	case WalletTypeWatchOnly, WalletTypeXPub:
+		return nil
+	}
+
+	for _, opts := range chains {
+		entries, err := w.GetEntries(opts...)
+		if err != nil {
+			return err
+		}
+
+		for i := range entries {
+			if err := entries[i].Verify(); err != nil {
+				return NewError(fmt.Errorf("invalid secret key of entry %d: %v", i, err))
+			}
+		}
+	}
+
+	switch w.Type() {
+	case WalletTypeDeterministic, WalletTypeBip44:
+		return validateDerivation(w, chains)
+	default:
+		return nil
+	}
+}
+
+// validatePublicEntries checks the public keys, addresses and child numbers of a chain of entries
+func validatePublicEntries(walletType string, entries Entries) error {
+	for i := range entries {
+		if err := entries[i].VerifyPublic(); err != nil {
+			return NewError(fmt.Errorf("invalid address of entry %d: %v", i, err))
+		}
+
+		switch walletType {
+		case WalletTypeBip44:
+			// bip44 chains derive the entries from consecutive child numbers, starting at 0
+			if entries[i].ChildNumber != uint32(i) {
+				return NewError(fmt.Errorf("entry %d has child number %d", i, entries[i].ChildNumber))
+			}
+		case WalletTypeXPub:
+			// xpub wallets skip the child numbers of invalid child keys
+			if i > 0 && entries[i].ChildNumber <= entries[i-1].ChildNumber {
+				return NewError(fmt.Errorf("entry %d has child number %d, after child number %d",
+					i, entries[i].ChildNumber, entries[i-1].ChildNumber))
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateHeader checks that the first address recorded by SetHeader is the address of the first entry
+func validateHeader(w Wallet) error {
+	hw, ok := w.(interface {
+		Header() (fingerprint, firstAddress string, ok bool)
+	})
+	if !ok {
+		return nil
+	}
+
+	_, headerAddr, ok := hw.Header()
+	if !ok {
+		return nil
+	}
+
+	addr, err := firstAddress(w)
+	if err != nil {
+		return err
+	}
+
+	if addr != headerAddr {
+		return NewError(fmt.Errorf("first address %q does not match the first entry address %q", headerAddr, addr))
+	}
+
+	return nil
+}
+
+// validateDerivation checks that the entries of an unencrypted deterministic or bip44 wallet
+// are the ones derived from its seed
+func validateDerivation(w Wallet, chains [][]Option) error {
+	if w.Seed() == "" {
+		return NewError(fmt.Errorf("seed missing in unencrypted %s wallet", w.Type()))
+	}
+
+	dw, err := NewWallet(w.Filename(), derivedWalletLabel, w.Seed(), Options{
+		Type:           w.Type(),
+		Coin:           w.Coin(),
+		Bip44Coin:      w.Bip44Coin(),
+		SeedPassphrase: w.SeedPassphrase(),
+	})
+	if err != nil {
+		return err
+	}
+	defer dw.Erase()
+
+	for _, a := range w.Accounts() {
+		for uint32(len(dw.Accounts())) <= a.Index {
+			if _, err := dw.(accountCreator).NewAccount(a.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, opts := range chains {
+		entries, err := w.GetEntries(opts...)
+		if err != nil {
+			return err
+		}
+
+		n, err := dw.EntriesLen(opts...)
+		if err != nil {
+			return err
+		}
+
+		if n < len(entries) {
+			genOpts := append([]Option{OptionGenerateN(uint64(len(entries) - n))}, opts...)
+			if _, err := dw.GenerateAddresses(genOpts...); err != nil {
+				return err
+			}
+		}
+
+		for i, e := range entries {
+			de, err := dw.GetEntryAt(i, opts...)
+			if err != nil {
+				return err
+			}
+
+			if de.Address != e.Address || de.Public != e.Public || de.Secret != e.Secret {
+				return NewError(fmt.Errorf("entry %d is not derived from the wallet seed", i))
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateWallet checks that the entries of the wallet are consistent, see ValidateWallet.
+// The entries of an encrypted wallet are also checked against its secrets if the password is provided,
+// otherwise only their public data is checked.
+// Returns ErrWalletNotEncrypted if a password is provided for an unencrypted wallet.
+func (serv *Service) ValidateWallet(wltID string, password []byte) error {
+	serv.RLock()
+	defer serv.RUnlock()
+	if err := serv.checkEnabled(); err != nil {
+		return err
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return err
+	}
+
+	if !w.IsEncrypted() {
+		if len(password) != 0 {
+			return ErrWalletNotEncrypted
+		}
+		return ValidateWallet(w)
+	}
+
+	if len(password) == 0 {
+		return ValidateWallet(w)
+	}
+
+	// The public checks are run on the decrypted wallet too
+	return serv.guardView(w, password, ValidateWallet)
+}
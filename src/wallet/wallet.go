@@ -14,7 +14,10 @@ initialization side effects.
 package wallet
 
 import (
+	"bytes"
+	"context"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -22,7 +25,9 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/cipher/bip44"
@@ -47,7 +52,7 @@ func NewError(err error) error {
 
 var (
 	// Version represents the current wallet version
-	Version = "0.4"
+	Version = "0.5"
 
 	logger = logging.MustGetLogger("wallet")
 
@@ -59,6 +64,10 @@ var (
 	ErrWalletNotEncrypted = NewError(errors.New("wallet is not encrypted"))
 	// ErrMissingPassword is returned when trying to create wallet with encryption, but password is not provided.
 	ErrMissingPassword = NewError(errors.New("missing password"))
+	// ErrWeakPassword is returned if the password is shorter than the minimum password length
+	ErrWeakPassword = NewError(errors.New("password is too short"))
+	// ErrWeakSeed is returned if the estimated entropy of a raw seed is below MinSeedEntropyBits
+	ErrWeakSeed = NewError(errors.New("seed is too weak"))
 	// ErrMissingEncrypt is returned when trying to create wallet with password, but options.Encrypt is not set.
 	ErrMissingEncrypt = NewError(errors.New("missing encrypt"))
 	// ErrInvalidPassword is returned if decrypts secrets failed
@@ -81,7 +90,15 @@ var (
 	ErrXPubKeyUsed = NewError(errors.New("a wallet already exists with this xpub key"))
 	// ErrWalletAPIDisabled is returned when trying to do wallet actions while the EnableWalletAPI option is false
 	ErrWalletAPIDisabled = NewError(errors.New("wallet api is disabled"))
-	// ErrSeedAPIDisabled is returned when trying to get seed of wallet while the EnableWalletAPI or EnableSeedAPI is false
+	// ErrServiceClosed is returned when trying to do wallet actions after the wallet service is closed
+	ErrServiceClosed = NewError(errors.New("wallet service is closed"))
+	// ErrWalletReadOnly is returned when trying to change a wallet or spend from it while the wallet service is read-only
+	ErrWalletReadOnly = NewError(errors.New("wallet service is read-only"))
+	// ErrReadOnlyWalletSource is returned when trying to save a wallet loaded from Config.WalletSource, which is read-only
+	ErrReadOnlyWalletSource = NewError(errors.New("wallet source is read-only"))
+	// ErrWalletLocked is returned when decrypting a wallet after too many wrong passwords, until the lockout ends
+	ErrWalletLocked = NewError(errors.New("too many wrong passwords, the wallet is temporarily locked"))
+	// ErrSeedAPIDisabled is returned when trying to get or enter the seed of a wallet while the EnableWalletAPI or EnableSeedAPI is false
 	ErrSeedAPIDisabled = NewError(errors.New("wallet seed api is disabled"))
 	// ErrWalletNameConflict represents the wallet name conflict error
 	ErrWalletNameConflict = NewError(errors.New("wallet name would conflict with existing wallet, renaming"))
@@ -89,18 +106,65 @@ var (
 	ErrWalletRecoverSeedWrong = NewError(errors.New("wallet recovery seed or seed passphrase is wrong"))
 	// ErrWalletSeedPassphrase is returned when using seed passphrase for none bip44 wallet
 	ErrWalletSeedPassphrase = NewError(errors.New("seedPassphrase is only used for \"bip44\" wallets"))
+	// ErrWalletNotBip44 is returned when adding accounts to a wallet that is not a bip44 wallet
+	ErrWalletNotBip44 = NewError(errors.New("accounts are only supported by \"bip44\" wallets"))
 	// ErrNilTransactionsFinder is returned if Options.ScanN > 0 but a nil TransactionsFinder was provided
 	ErrNilTransactionsFinder = NewError(errors.New("scan ahead requested but balance getter is nil"))
 	// ErrInvalidCoinType is returned for invalid coin types
 	ErrInvalidCoinType = NewError(errors.New("invalid coin type"))
 	// ErrInvalidWalletType is returned for invalid wallet types
 	ErrInvalidWalletType = NewError(errors.New("invalid wallet type"))
+	// ErrWalletNoSeedChain is returned by GetWalletSeedInfo if the wallet is not a deterministic wallet
+	ErrWalletNoSeedChain = NewError(errors.New("wallet does not have a deterministic seed chain"))
+	// ErrWalletIdentityChanged is returned by ReloadWallet if the wallet file now holds a different wallet
+	ErrWalletIdentityChanged = NewError(errors.New("wallet file holds a different wallet, its type or first address changed"))
+	// ErrMaxWalletsReached is returned when adding a wallet to a service that holds Config.MaxWallets wallets
+	ErrMaxWalletsReached = NewError(errors.New("maximum number of wallets reached"))
+	// ErrMergeSameWallet is returned by MergeWallets if the source and destination wallets are the same
+	ErrMergeSameWallet = NewError(errors.New("cannot merge a wallet into itself"))
+	// ErrMergeSeedMismatch is returned by MergeWallets if the wallets were not generated from the same seed
+	ErrMergeSeedMismatch = NewError(errors.New("wallets were not generated from the same seed"))
 	// ErrWalletTypeNotRecoverable is returned by RecoverWallet is the wallet type does not support recovery
 	ErrWalletTypeNotRecoverable = NewError(errors.New("wallet type is not recoverable"))
 	// ErrWalletPermission is returned when updating a wallet without writing permission
 	ErrWalletPermission = NewError(errors.New("saving wallet permission denied"))
 	// ErrInvalidPrivateKeys is returned when creating a collection wallet with invalid private keys
 	ErrInvalidPrivateKeys = NewError(errors.New("invalid private keys"))
+	// ErrInvalidMnemonic is returned if a mnemonic seed is not a valid bip39 mnemonic
+	ErrInvalidMnemonic = NewError(errors.New("invalid bip39 mnemonic seed"))
+	// ErrInvalidSeedType is returned for invalid seed types
+	ErrInvalidSeedType = NewError(errors.New("invalid seed type"))
+	// ErrInvalidAddressType is returned for invalid address types
+	ErrInvalidAddressType = NewError(errors.New("invalid address type"))
+	// ErrAddressTypeUnsupported is returned when setting the address type of a wallet that is not a deterministic wallet
+	ErrAddressTypeUnsupported = NewError(errors.New("address type is only supported by \"deterministic\" wallets"))
+	// ErrAddressTypeCoinMismatch is returned if the address type is not the format of the coin type's addresses
+	ErrAddressTypeCoinMismatch = NewError(errors.New("address type does not match the coin type"))
+	// ErrInvalidEntropyBits is returned if the mnemonic entropy size is not 128 or 256 bits
+	ErrInvalidEntropyBits = NewError(errors.New("entropy bits must be 128 or 256"))
+	// ErrInvalidWalletSignature is returned if a wallet signature was not produced by the given public key
+	ErrInvalidWalletSignature = NewError(errors.New("wallet signature is invalid"))
+	// ErrWatchOnlyWallet is returned when accessing the secrets of a watch-only wallet
+	ErrWatchOnlyWallet = NewError(errors.New("watch-only wallet has no secrets"))
+	// ErrWalletNoSeed is returned when requesting the seed of a wallet that does not have one
+	ErrWalletNoSeed = NewError(errors.New("wallet has no seed"))
+	// ErrExportFileExists is returned when exporting a wallet to an existing file without overwriting it
+	ErrExportFileExists = NewError(errors.New("export destination file already exists"))
+	// ErrInvalidWalletFilename is returned if a wallet filename does not have the WalletExt extension
+	ErrInvalidWalletFilename = NewError(fmt.Errorf("wallet filename must have a .%s extension", WalletExt))
+	// ErrGapLimitTooLarge is returned if Options.GapLimit exceeds MaxGapLimit
+	ErrGapLimitTooLarge = NewError(fmt.Errorf("gap limit must not exceed %d", MaxGapLimit))
+	// ErrScanChunkSizeTooLarge is returned if Options.ScanChunkSize exceeds MaxScanChunkSize
+	ErrScanChunkSizeTooLarge = NewError(fmt.Errorf("scan chunk size must not exceed %d", MaxScanChunkSize))
+	// ErrWalletChangedDuringScan is returned if a wallet is changed by another operation while its addresses are scanned
+	ErrWalletChangedDuringScan = NewError(errors.New("wallet was changed during the address scan"))
+	// ErrInvalidScryptParams is returned if the scrypt parameters are outside the safe bounds
+	ErrInvalidScryptParams = NewError(fmt.Errorf("invalid scrypt parameters, N must be a power of 2 between %d and %d, r between 1 and %d, p between 1 and %d and 128*N*r at most %d bytes",
+		ScryptMinN, ScryptMaxN, ScryptMaxR, ScryptMaxP, ScryptMaxMemory))
+	// ErrInvalidSortField is returned if the wallets are sorted by an unknown field
+	ErrInvalidSortField = NewError(errors.New("invalid wallet sort field"))
+	// ErrInvalidPagination is returned if a page offset is negative or its limit is not positive
+	ErrInvalidPagination = NewError(errors.New("offset must not be negative and limit must be positive"))
 
 	// ErrEntryNotFound is returned by GetEntry is the wallet does not contains the entry
 	ErrEntryNotFound = errors.New("entry not found")
@@ -115,11 +179,31 @@ const (
 	// WalletTimestampFormat wallet timestamp layout
 	WalletTimestampFormat = "2006_01_02"
 
+	// BackupExt is the extension of the wallet backup files created before a wallet file is overwritten
+	BackupExt = "bak"
+
+	// BackupTimestampFormat is the layout of the timestamp in the backup file names, e.g. t.wlt.2006-01-02T15-04-05.000000000Z.bak.
+	// It sorts chronologically and is a valid file name on all platforms
+	BackupTimestampFormat = "2006-01-02T15-04-05.000000000Z"
+
+	// MaxGapLimit is the maximum number of consecutive unused addresses
+	// that may be scanned ahead when creating a wallet
+	MaxGapLimit = 1000
+
+	// MaxScanChunkSize is the maximum number of addresses whose activity
+	// is queried at once when scanning with a gap limit
+	MaxScanChunkSize = 10000
+
 	// CoinTypeSkycoin skycoin type
 	CoinTypeSkycoin CoinType = "skycoin"
 	// CoinTypeBitcoin bitcoin type
 	CoinTypeBitcoin CoinType = "bitcoin"
 
+	// AddressTypeSkycoin skycoin address format, see cipher.Address
+	AddressTypeSkycoin AddressType = "skycoin"
+	// AddressTypeBitcoin bitcoin address format, see cipher.BitcoinAddress
+	AddressTypeBitcoin AddressType = "bitcoin"
+
 	// WalletTypeDeterministic deterministic wallet type.
 	// Uses the original Skycoin deterministic key generator.
 	WalletTypeDeterministic = "deterministic"
@@ -132,16 +216,51 @@ const (
 	// WalletTypeXPub xpub HD wallet type.
 	// Allows generating addresses without a secret key
 	WalletTypeXPub = "xpub"
+	// WalletTypeWatchOnly watch-only wallet type.
+	// Holds public keys only; can't sign transactions
+	WalletTypeWatchOnly = "watchonly"
 )
 
 // CoinType represents the wallet coin type, which refers to the pubkey2addr method used
 type CoinType string
 
+// AddressType represents the format of the addresses a wallet creates from its public keys
+type AddressType string
+
+// addressTypeCoins maps the address types to the coin type whose addresses have that format
+var addressTypeCoins = map[AddressType]CoinType{
+	AddressTypeSkycoin: CoinTypeSkycoin,
+	AddressTypeBitcoin: CoinTypeBitcoin,
+}
+
+// IsValidAddressType returns true if an address type is recognized
+func IsValidAddressType(t AddressType) bool {
+	_, ok := addressTypeCoins[t]
+	return ok
+}
+
+// DefaultAddressType returns the address type of the wallets of a coin type that were created
+// without an address type. Returns an empty address type for an unknown coin type
+func DefaultAddressType(coin CoinType) AddressType {
+	switch coin {
+	case CoinTypeSkycoin:
+		return AddressTypeSkycoin
+	case CoinTypeBitcoin:
+		return AddressTypeBitcoin
+	default:
+		return ""
+	}
+}
+
 // NewWalletFilename generates a filename from the current time and random bytes
 func NewWalletFilename() string {
 	timestamp := time.Now().Format(WalletTimestampFormat)
 	// should read in wallet files and make sure does not exist
-	padding := hex.EncodeToString(cipher.RandByte(2))
+	b, err := randBytes(2)
+	if err != nil {
+		logger.WithError(err).Panic("NewWalletFilename: randBytes failed")
+	}
+	padding := hex.EncodeToString(b)
 	return fmt.Sprintf("%s_%s.%s", timestamp, padding, WalletExt)
 }
 
@@ -149,27 +268,113 @@ func NewWalletFilename() string {
 type Options struct {
 	Version               string
 	Type                  string            // wallet type: deterministic, collection. Refers to which key generation mechanism is used.
-	Coin                  CoinType          // coin type: skycoin, bitcoin, etc. Refers to which pubkey2addr method is used.
+	Coin                  CoinType          // coin type: skycoin, bitcoin, etc. Refers to which pubkey2addr method is used, stored in the wallet meta data.
+	AddressType           AddressType       // address format of the generated addresses, which must be the format of the coin type's addresses. Stored in the wallet meta data [deterministic wallets]
 	Bip44Coin             *bip44.CoinType   // bip44 path coin type
 	Label                 string            // wallet label
 	Seed                  string            // wallet seed
+	SeedType              string            // seed type: raw or mnemonic. A mnemonic seed must pass the bip39 checksum verification. Recorded in deterministic wallets, so a recovery seed is verified the same way
 	SeedPassphrase        string            // wallet seed passphrase (bip44 wallets only)
 	Encrypt               bool              // whether the wallet need to be encrypted.
 	Password              []byte            // password that would be used for encryption, and would only be used when 'Encrypt' is true.
 	CryptoType            crypto.CryptoType // wallet encryption type, scrypt-chacha20poly1305 or sha256-xor.
 	ScanN                 uint64            // number of addresses that're going to be scanned for a balance. The highest address with a balance will be used.
+	GapLimit              uint64            // if set, scanning continues until this many consecutive addresses without activity are found.
+	ScanChunkSize         uint64            // number of addresses queried at once when scanning with GapLimit, GapLimit is used if smaller.
 	GenerateN             uint64            // number of addresses to generate, regardless of balance
+	NoDefaultAddresses    bool              // if set, no address is generated when GenerateN is 0, instead of one address (and one change address for bip44 wallets). The wallet may stay empty
 	XPub                  string            // xpub key (xpub wallets only)
 	Decoder               Decoder
 	TF                    TransactionsFinder
+	Context               context.Context // if set, scanning stops with the context error once it is done, e.g. to bound the scan of an untrusted request
 	Temp                  bool            // whether the wallet is created temporary in memory.
 	CollectionPrivateKeys []cipher.SecKey // private keys for collection wallet
+	WatchOnlyPublicKeys   []cipher.PubKey // public keys for watch-only wallet
+	ScryptParams          *ScryptParams   // scrypt parameters for the scrypt-chacha20poly1305 crypto types, the defaults are used if nil
+	RejectWeakSeed        bool            // if set, raw seeds of deterministic and bip44 wallets are rejected with ErrWeakSeed if their estimated entropy is below MinSeedEntropyBits
 }
 
+// Validate validates the options
 func (opts Options) Validate() error {
 	if opts.Type == WalletTypeDeterministic && opts.SeedPassphrase != "" {
 		return ErrWalletSeedPassphrase
 	}
+
+	if !IsValidSeedType(opts.SeedType) {
+		return ErrInvalidSeedType
+	}
+
+	// The coin type selects the address format, an unknown one would
+	// silently fall back to the skycoin address format
+	if opts.Coin != "" && !IsRegisteredCoinType(opts.Coin) {
+		return ErrInvalidCoinType
+	}
+
+	if opts.AddressType != "" {
+		if !IsValidAddressType(opts.AddressType) {
+			return ErrInvalidAddressType
+		}
+
+		if opts.Type != WalletTypeDeterministic {
+			return ErrAddressTypeUnsupported
+		}
+
+		coin := opts.Coin
+		if coin == "" {
+			coin = CoinTypeSkycoin
+		}
+		if addressTypeCoins[opts.AddressType] != coin {
+			return ErrAddressTypeCoinMismatch
+		}
+	}
+
+	if opts.SeedType == SeedTypeMnemonic {
+		if err := ValidateMnemonic(opts.Seed); err != nil {
+			return err
+		}
+	}
+
+	if opts.RejectWeakSeed && opts.SeedType != SeedTypeMnemonic {
+		switch opts.Type {
+		case WalletTypeDeterministic, WalletTypeBip44:
+			if err := CheckSeedStrength(opts.Seed); err != nil {
+				return err
+			}
+		}
+	}
+
+	if opts.GapLimit > MaxGapLimit {
+		return ErrGapLimitTooLarge
+	}
+
+	if opts.GapLimit > 0 && opts.TF == nil {
+		return ErrNilTransactionsFinder
+	}
+
+	if opts.ScanChunkSize > MaxScanChunkSize {
+		return ErrScanChunkSizeTooLarge
+	}
+
+	if opts.ScryptParams != nil {
+		if err := opts.ScryptParams.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckPasswordStrength returns ErrWeakPassword if the password, once trimmed of
+// surrounding whitespace, is shorter than minLength characters.
+// The check is disabled if minLength is 0. An empty password is not checked,
+// so that callers can report it as ErrMissingPassword.
+func CheckPasswordStrength(password []byte, minLength int) error {
+	if minLength <= 0 || len(password) == 0 {
+		return nil
+	}
+
+	if utf8.RuneCount(bytes.TrimSpace(password)) < minLength {
+		return ErrWeakPassword
+	}
 	return nil
 }
 
@@ -179,6 +384,8 @@ func (opts Options) Validate() error {
 type Wallet interface {
 	Seed() string
 	LastSeed() string
+	// LastSeedIndex returns the number of keys derived from the seed chain [deterministic wallets]
+	LastSeedIndex() uint64
 	SeedPassphrase() string
 	Timestamp() int64
 	SetTimestamp(int64)
@@ -191,8 +398,18 @@ type Wallet interface {
 	SetBip44Coin(ct bip44.CoinType)
 	Label() string
 	SetLabel(string)
+	// Notes returns the free-form notes of the wallet, which are stored in cleartext
+	Notes() string
+	SetNotes(string)
+	// TransactionMemo returns the memo recorded for the transaction of given inner hash, which is stored in cleartext
+	TransactionMemo(innerHash cipher.SHA256) []byte
+	SetTransactionMemo(innerHash cipher.SHA256, memo []byte)
+	// SetHeader records the fingerprint and first address of the wallet in its meta data
+	SetHeader(fingerprint, firstAddress string)
 	Filename() string
 	SetFilename(string)
+	// IsEncrypted returns whether the wallet secrets are encrypted.
+	// Addresses, public keys and labels are never encrypted.
 	IsEncrypted() bool
 	// CryptoType returns the crypto type for encrypting/decrypting the wallet
 	CryptoType() crypto.CryptoType
@@ -205,7 +422,8 @@ type Wallet interface {
 	Secrets() string
 	// XPub returns the xpub key of a xpub wallet
 	XPub() string
-	// Lock encrypts the wallet
+	// Lock encrypts the seeds and secret keys of the wallet,
+	// leaving the public data of its entries in cleartext
 	Lock(password []byte) error
 	// Unlock decrypts the wallets, returns an copy of the decrypted wallet
 	Unlock(password []byte) (Wallet, error)
@@ -244,6 +462,10 @@ type Wallet interface {
 	// for bip44 wallet, if no options are specified, it will check the external chain of account
 	// of index 0.
 	HasEntry(addr cipher.Addresser, options ...Option) (bool, error)
+	// SetEntryLabel sets the label of the entry with given address,
+	// for bip44 wallet, if no options are specified, it will search the account
+	// of index 0.
+	SetEntryLabel(addr cipher.Addresser, label string, options ...Option) error
 	// EntriesLen returns the entries length
 	// for bip44 wallet, if no options are specified, the length of the entries on external chain of account
 	// with index 0 will be returned.
@@ -288,6 +510,7 @@ type Bip44Account struct {
 }
 
 // GuardUpdate executes a function within the context of a read-write managed decrypted wallet.
+// The secrets of the decrypted wallet are wiped when fn returns, so fn must not keep a reference to it.
 // Returns ErrWalletNotEncrypted if wallet is not encrypted.
 func GuardUpdate(w Wallet, password []byte, fn func(w Wallet) error) error {
 	if !w.IsEncrypted() {
@@ -321,6 +544,7 @@ func GuardUpdate(w Wallet, password []byte, fn func(w Wallet) error) error {
 }
 
 // GuardView executes a function within the context of a read-only managed decrypted wallet.
+// The secrets of the decrypted wallet are wiped when f returns, so f must not keep a reference to it.
 // Returns ErrWalletNotEncrypted if wallet is not encrypted.
 func GuardView(w Wallet, password []byte, f func(w Wallet) error) error {
 	if !w.IsEncrypted() {
@@ -336,9 +560,8 @@ func GuardView(w Wallet, password []byte, f func(w Wallet) error) error {
 		return err
 	}
 
-	// TODO: consider to catch panic and erase sensitive data in recovery function
-	// in case the f(wlt) function get panic
-
+	// Wipes the decrypted secrets as soon as f returns, deferred so that
+	// they are wiped even if f panics
 	defer wlt.Erase()
 
 	return f(wlt)
@@ -352,7 +575,12 @@ type walletLoadMeta struct {
 }
 
 // Save saves the wallet to a directory. The wallet's filename is read from its metadata.
+// The file is replaced atomically, so a crash during Save does not leave a truncated wallet file.
 func Save(w Wallet, dir string) error {
+	return saveWithPerm(w, dir, DefaultFilePerm)
+}
+
+func saveWithPerm(w Wallet, dir string, perm os.FileMode) error {
 	if w.IsTemp() {
 		return nil
 	}
@@ -361,7 +589,7 @@ func Save(w Wallet, dir string) error {
 	if err != nil {
 		return err
 	}
-	return file.SaveBinary(filepath.Join(dir, w.Filename()), data, 0600)
+	return file.SaveBinary(filepath.Join(dir, w.Filename()), data, perm)
 }
 
 // Load loads wallet from a file
@@ -370,18 +598,37 @@ func Load(filename string) (Wallet, error) {
 		return nil, fmt.Errorf("wallet %q doesn't exist", filename)
 	}
 
-	// Load the wallet meta type field from JSON
-	m, err := loadWalletMeta(filename)
+	data, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
 
-	if m.Meta.Type == "" {
-		err := errors.New("missing meta.type field")
-		logger.WithError(err).WithField("filename", filename)
+	w, err := loadWalletData(data)
+	if err != nil {
+		logger.WithError(err).WithField("filename", filename).Error("Load: loadWalletData failed")
+		return nil, err
+	}
+	if w == nil {
+		return nil, nil
+	}
+
+	w.SetFilename(filepath.Base(filename))
+	return w, nil
+}
+
+// loadWalletData loads a wallet from its serialized data, with the loader of the wallet type in its metadata.
+// Returns nil if there is no loader for the wallet type.
+func loadWalletData(data []byte) (Wallet, error) {
+	// Load the wallet meta type field from JSON
+	var m walletLoadMeta
+	if err := json.Unmarshal(data, &m); err != nil {
 		return nil, err
 	}
 
+	if m.Meta.Type == "" {
+		return nil, errors.New("missing meta.type field")
+	}
+
 	// Depending on the wallet type in the wallet metadata header, load the full wallet data
 	l, ok := getLoader(m.Meta.Type)
 	if !ok {
@@ -389,18 +636,58 @@ func Load(filename string) (Wallet, error) {
 		return nil, nil
 	}
 
-	data, err := ioutil.ReadFile(filename)
+	// Upgrades wallets of older versions in memory, the file is rewritten on the next save
+	data, err := migrateWalletData(data)
 	if err != nil {
 		return nil, err
 	}
 
-	w, err := l.Load(data)
+	return l.Load(data)
+}
+
+// recoverTempFiles resolves the *.wlt.tmp.* files left in the given directory by a Save that was interrupted.
+// If the wallet file is missing or unreadable and the temp file holds a complete wallet, the rename is completed.
+// Otherwise the wallet file is intact and the temp file, whose save never completed, is discarded.
+func recoverTempFiles(dir string) error {
+	files, err := ioutil.ReadDir(dir)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	w.SetFilename(filepath.Base(filename))
-	return w, nil
+	tmpSep := file.TempFilePrefix(".wlt")
+	for _, f := range files {
+		i := strings.Index(f.Name(), tmpSep)
+		if f.IsDir() || i == -1 {
+			continue
+		}
+
+		tmpFile := filepath.Join(dir, f.Name())
+		wltFile := filepath.Join(dir, f.Name()[:i+len(".wlt")])
+
+		if !isCompleteWalletFile(wltFile) && isCompleteWalletFile(tmpFile) {
+			logger.Warningf("Completing interrupted save of wallet %s from %s", wltFile, tmpFile)
+			if err := os.Rename(tmpFile, wltFile); err != nil {
+				return err
+			}
+			continue
+		}
+
+		logger.Warningf("Discarding interrupted save of wallet %s in %s", wltFile, tmpFile)
+		if err := os.Remove(tmpFile); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isCompleteWalletFile returns true if the file exists and holds a parseable wallet with a type
+func isCompleteWalletFile(filename string) bool {
+	var m walletLoadMeta
+	if err := file.LoadJSON(filename, &m); err != nil {
+		return false
+	}
+	return m.Meta.Type != ""
 }
 
 // removeBackupFiles removes any *.wlt.bak files whom have version 0.1 and *.wlt matched in the given directory
@@ -443,6 +730,72 @@ func removeBackupFiles(dir string) error {
 	return nil
 }
 
+// backupWalletFile copies the wallet file, if it exists, to a new backup file named with the current time
+func backupWalletFile(dir, wltID string, perm os.FileMode) error {
+	data, err := ioutil.ReadFile(filepath.Join(dir, wltID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	name := fmt.Sprintf("%s.%s.%s", wltID, time.Now().UTC().Format(BackupTimestampFormat), BackupExt)
+	return file.SaveBinary(filepath.Join(dir, name), data, perm)
+}
+
+// listBackupFiles returns the paths of the timestamped backup files in the directory,
+// grouped by wallet filename and sorted from oldest to newest.
+// The legacy *.wlt.bak files are not included.
+func listBackupFiles(dir string) (map[string][]string, error) {
+	bakFs, err := filterDir(dir, "."+BackupExt)
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make(map[string][]string)
+	for _, bf := range bakFs {
+		// The timestamp has a fixed length and contains a dot, so the name is split by length
+		name := strings.TrimSuffix(filepath.Base(bf), "."+BackupExt)
+		i := len(name) - len(BackupTimestampFormat) - 1
+		if i < 0 || name[i] != '.' || !strings.HasSuffix(name[:i], "."+WalletExt) {
+			continue
+		}
+		if _, err := time.Parse(BackupTimestampFormat, name[i+1:]); err != nil {
+			continue
+		}
+
+		// filterDir returns the files sorted by name, so the backups of each wallet are sorted by time
+		backups[name[:i]] = append(backups[name[:i]], bf)
+	}
+
+	return backups, nil
+}
+
+// pruneBackupFiles removes the oldest timestamped backup files of each wallet in the directory,
+// keeping at most maxBackups of them. If wltID is not empty, only the backups of that wallet are pruned.
+func pruneBackupFiles(dir, wltID string, maxBackups int) error {
+	backups, err := listBackupFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	for id, bfs := range backups {
+		if wltID != "" && id != wltID {
+			continue
+		}
+
+		for len(bfs) > maxBackups {
+			if err := os.Remove(bfs[0]); err != nil {
+				return err
+			}
+			bfs = bfs[1:]
+		}
+	}
+
+	return nil
+}
+
 func loadWalletMeta(filename string) (*walletLoadMeta, error) {
 	var m walletLoadMeta
 	if err := file.LoadJSON(filename, &m); err != nil {
@@ -473,26 +826,28 @@ func IsValidWalletType(t string) bool {
 	case WalletTypeDeterministic,
 		WalletTypeCollection,
 		WalletTypeBip44,
-		WalletTypeXPub:
+		WalletTypeXPub,
+		WalletTypeWatchOnly:
 		return true
 	default:
 		return false
 	}
 }
 
-// AddressConstructor returns a function to create a cipher.Addresser from a cipher.PubKey
+// AddressConstructor returns a function to create a cipher.Addresser from a cipher.PubKey,
+// in the address format of the wallet, see Meta.AddressType
 func AddressConstructor(m Meta) func(cipher.PubKey) cipher.Addresser {
-	switch m.Coin() {
-	case CoinTypeSkycoin:
+	switch m.AddressType() {
+	case AddressTypeSkycoin:
 		return func(pk cipher.PubKey) cipher.Addresser {
 			return cipher.AddressFromPubKey(pk)
 		}
-	case CoinTypeBitcoin:
+	case AddressTypeBitcoin:
 		return func(pk cipher.PubKey) cipher.Addresser {
 			return cipher.BitcoinAddressFromPubKey(pk)
 		}
 	default:
-		logger.Panicf("Invalid wallet coin type %q", m.Coin())
+		logger.Panicf("Invalid wallet address type %q of coin type %q", m.AddressType(), m.Coin())
 		return nil
 	}
 }
@@ -518,6 +873,16 @@ func ValidateMeta(m Meta) error {
 		return errors.New("coin field not set")
 	}
 
+	if addrType, ok := m[MetaAddressType]; ok {
+		coin, known := addressTypeCoins[AddressType(addrType)]
+		if !known {
+			return errors.New("invalid address type")
+		}
+		if coin != m.Coin() {
+			return errors.New("address type does not match the coin type")
+		}
+	}
+
 	var isEncrypted bool
 	if encStr, ok := m[MetaEncrypted]; ok {
 		// validate the encrypted value
@@ -552,6 +917,10 @@ func ValidateMetaCryptoType(m Meta) error {
 		return errors.New("unknown crypto type")
 	}
 
+	if _, err := m.ScryptParams(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -569,6 +938,143 @@ func ValidateMetaSeed(m Meta) error {
 	return nil
 }
 
+// ScanAddressesGapLimit scans ahead addresses until gapLimit consecutive addresses without
+// activity are found. This allows recovering funds on addresses that are separated by up to
+// gapLimit-1 unused addresses. The activity of chunkSize addresses is queried at once,
+// chunkSize is raised to gapLimit if smaller, so that a window always holds a whole gap.
+// Returns the scanned addresses that were kept.
+func ScanAddressesGapLimit(w Wallet, gapLimit, chunkSize uint64, tf TransactionsFinder) ([]cipher.Addresser, error) {
+	if gapLimit == 0 {
+		return nil, nil
+	}
+
+	if gapLimit > MaxGapLimit {
+		return nil, ErrGapLimitTooLarge
+	}
+
+	if chunkSize > MaxScanChunkSize {
+		return nil, ErrScanChunkSizeTooLarge
+	}
+
+	if tf == nil {
+		return nil, ErrNilTransactionsFinder
+	}
+
+	if chunkSize < gapLimit {
+		chunkSize = gapLimit
+	}
+
+	gtf := gapLimitTransactionsFinder{
+		tf:       tf,
+		gapLimit: gapLimit,
+	}
+
+	var addrs []cipher.Addresser
+	for {
+		// ScanAddresses keeps the addresses up to the last active one of the window,
+		// so the next window starts right after it and a gap that crosses the end of
+		// this window is scanned again whole.
+		as, err := w.ScanAddresses(chunkSize, gtf)
+		if err != nil {
+			return nil, err
+		}
+
+		addrs = append(addrs, as...)
+
+		// The window ends with a whole gap
+		if chunkSize-uint64(len(as)) >= gapLimit {
+			return addrs, nil
+		}
+	}
+}
+
+// gapLimitTransactionsFinder wraps a TransactionsFinder, reporting no activity for
+// the addresses that follow the first gapLimit consecutive addresses without activity
+type gapLimitTransactionsFinder struct {
+	tf       TransactionsFinder
+	gapLimit uint64
+}
+
+func (g gapLimitTransactionsFinder) AddressesActivity(addrs []cipher.Addresser) ([]bool, error) {
+	active, err := g.tf.AddressesActivity(addrs)
+	if err != nil {
+		return nil, err
+	}
+
+	active = append([]bool{}, active...)
+
+	var empties uint64
+	for i, a := range active {
+		if a {
+			empties = 0
+			continue
+		}
+
+		empties++
+		if empties == g.gapLimit {
+			for j := i + 1; j < len(active); j++ {
+				active[j] = false
+			}
+			break
+		}
+	}
+
+	return active, nil
+}
+
+// rateLimitedTransactionsFinder wraps a TransactionsFinder, spacing its queries at least interval
+// after the time in last, which the finders of all scans share, and returning the context error once ctx is done.
+// last is guarded by mu, since the scans may run concurrently
+type rateLimitedTransactionsFinder struct {
+	tf       TransactionsFinder
+	ctx      context.Context
+	interval time.Duration
+	mu       *sync.Mutex
+	last     *time.Time
+}
+
+func (r *rateLimitedTransactionsFinder) AddressesActivity(addrs []cipher.Addresser) ([]bool, error) {
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	now := time.Now()
+	next := now
+	if !r.last.IsZero() && r.last.Add(r.interval).After(now) {
+		next = r.last.Add(r.interval)
+	}
+
+	// Don't start or wait for a query that would start after the deadline
+	if deadline, ok := r.ctx.Deadline(); ok && !next.Before(deadline) {
+		r.mu.Unlock()
+		return nil, context.DeadlineExceeded
+	}
+
+	// Reserve the time of the query, so that the queries of concurrent scans are spaced too
+	*r.last = next
+	r.mu.Unlock()
+
+	if wait := next.Sub(now); wait > 0 {
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-r.ctx.Done():
+			t.Stop()
+			return nil, r.ctx.Err()
+		}
+	}
+
+	// The query may start after its reserved time, so space the next queries from its actual start
+	r.mu.Lock()
+	if start := time.Now(); start.After(*r.last) {
+		*r.last = start
+	}
+	r.mu.Unlock()
+
+	return r.tf.AddressesActivity(addrs)
+}
+
 // SkycoinAddresses converts the addresses to skycoin addresses
 func SkycoinAddresses(addrs []cipher.Addresser) []cipher.Address {
 	skyAddrs := make([]cipher.Address, len(addrs))
@@ -618,3 +1124,8 @@ func GetGenerateNFromOptions(options ...Option) uint64 {
 func GetPrivateKeysFromOptions(options ...Option) []cipher.SecKey {
 	return applyAdvancedOptions(options...).PrivateKeys
 }
+
+// GetPublicKeysFromOptions gets public keys from options
+func GetPublicKeysFromOptions(options ...Option) []cipher.PubKey {
+	return applyAdvancedOptions(options...).PublicKeys
+}
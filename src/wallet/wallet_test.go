@@ -84,8 +84,11 @@ func TestWalletGuard(t *testing.T) {
 				encrypted: tc.encrypted,
 			}
 
+			var unlocked Wallet
 			err := GuardView(w, tc.pwd, func(wlt Wallet) error {
 				require.False(t, wlt.IsEncrypted())
+				require.Equal(t, seed, wlt.Seed())
+				unlocked = wlt
 				return nil
 			})
 
@@ -94,10 +97,31 @@ func TestWalletGuard(t *testing.T) {
 				return
 			}
 			require.True(t, w.IsEncrypted())
+			// The decrypted wallet is wiped once the guarded function returns
+			require.Empty(t, unlocked.Seed())
 		})
 	}
 }
 
+func TestWalletGuardViewPanic(t *testing.T) {
+	w := &fakeWalletForGuardView{
+		seed:      bip39.MustNewDefaultMnemonic(),
+		label:     "label",
+		encrypted: true,
+	}
+
+	var unlocked Wallet
+	require.Panics(t, func() {
+		_ = GuardView(w, []byte("pwd"), func(wlt Wallet) error {
+			unlocked = wlt
+			panic("guarded function panics")
+		})
+	})
+
+	require.NotNil(t, unlocked)
+	require.Empty(t, unlocked.Seed())
+}
+
 func TestRemoveBackupFiles(t *testing.T) {
 	type wltInfo struct {
 		wltName string
@@ -415,3 +439,89 @@ func prepareWltDir() string {
 
 	return dir
 }
+
+func TestScryptParamsValidate(t *testing.T) {
+	tt := []struct {
+		name string
+		p    ScryptParams
+		err  error
+	}{
+		{"ok", ScryptParams{N: 1 << 14, R: 8, P: 1}, nil},
+		{"ok max", ScryptParams{N: 1 << 20, R: 8, P: ScryptMaxP}, nil},
+		{"N too small", ScryptParams{N: 1 << 13, R: 8, P: 1}, ErrInvalidScryptParams},
+		{"N too large", ScryptParams{N: 1 << 23, R: 8, P: 1}, ErrInvalidScryptParams},
+		{"N not power of 2", ScryptParams{N: 1<<14 + 1, R: 8, P: 1}, ErrInvalidScryptParams},
+		{"r zero", ScryptParams{N: 1 << 14, R: 0, P: 1}, ErrInvalidScryptParams},
+		{"r too large", ScryptParams{N: 1 << 14, R: ScryptMaxR + 1, P: 1}, ErrInvalidScryptParams},
+		{"p zero", ScryptParams{N: 1 << 14, R: 8, P: 0}, ErrInvalidScryptParams},
+		{"p too large", ScryptParams{N: 1 << 14, R: 8, P: ScryptMaxP + 1}, ErrInvalidScryptParams},
+		{"too much memory", ScryptParams{N: 1 << 22, R: 8, P: 1}, ErrInvalidScryptParams},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.err, tc.p.Validate())
+		})
+	}
+}
+
+func TestMetaScryptParams(t *testing.T) {
+	m := Meta{}
+	p, err := m.ScryptParams()
+	require.NoError(t, err)
+	require.Nil(t, p)
+
+	m.SetScryptParams(ScryptParams{N: 1 << 14, R: 4, P: 2})
+	p, err = m.ScryptParams()
+	require.NoError(t, err)
+	require.Equal(t, &ScryptParams{N: 1 << 14, R: 4, P: 2}, p)
+
+	m[MetaScryptR] = "x"
+	_, err = m.ScryptParams()
+	require.Equal(t, ErrInvalidScryptParams, err)
+
+	m[MetaScryptR] = "64"
+	_, err = m.ScryptParams()
+	require.Equal(t, ErrInvalidScryptParams, err)
+}
+
+func TestOptionsValidateAddressType(t *testing.T) {
+	tt := []struct {
+		name string
+		opts Options
+		err  error
+	}{
+		{"not set", Options{Type: WalletTypeDeterministic}, nil},
+		{"skycoin", Options{Type: WalletTypeDeterministic, AddressType: AddressTypeSkycoin}, nil},
+		{"skycoin coin", Options{Type: WalletTypeDeterministic, Coin: CoinTypeSkycoin, AddressType: AddressTypeSkycoin}, nil},
+		{"bitcoin", Options{Type: WalletTypeDeterministic, Coin: CoinTypeBitcoin, AddressType: AddressTypeBitcoin}, nil},
+		{"invalid", Options{Type: WalletTypeDeterministic, AddressType: "foo"}, ErrInvalidAddressType},
+		{"coin mismatch", Options{Type: WalletTypeDeterministic, AddressType: AddressTypeBitcoin}, ErrAddressTypeCoinMismatch},
+		{"not deterministic", Options{Type: WalletTypeBip44, AddressType: AddressTypeSkycoin}, ErrAddressTypeUnsupported},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.err, tc.opts.Validate())
+		})
+	}
+}
+
+func TestMetaAddressType(t *testing.T) {
+	m := Meta{MetaFilename: "t.wlt", MetaType: WalletTypeDeterministic, MetaCoin: string(CoinTypeBitcoin)}
+
+	// The address type of a wallet created without one is the format of its coin type's addresses
+	require.Equal(t, AddressTypeBitcoin, m.AddressType())
+	require.NoError(t, ValidateMeta(m))
+
+	m.SetAddressType(AddressTypeBitcoin)
+	require.Equal(t, "bitcoin", m[MetaAddressType])
+	require.Equal(t, AddressTypeBitcoin, m.AddressType())
+	require.NoError(t, ValidateMeta(m))
+
+	m.SetAddressType(AddressTypeSkycoin)
+	require.Error(t, ValidateMeta(m))
+
+	m.SetAddressType("foo")
+	require.Error(t, ValidateMeta(m))
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -34,6 +35,11 @@ func loadWallets(dir string, loader Loader) (Wallets, error) {
 			if err != nil {
 				return nil, err
 			}
+			data, err = migrateWalletData(data)
+			if err != nil {
+				logger.WithError(err).WithField("filename", fullpath).Error("loadWallets: migrateWalletData failed")
+				return nil, err
+			}
 			w, err := loader.Load(data)
 			if err != nil {
 				logger.WithError(err).WithField("filename", fullpath).Error("loadWallets: loadWallet failed")
@@ -88,6 +94,17 @@ func (wlts Wallets) set(w Wallet) {
 	wlts[w.Filename()] = w.Clone()
 }
 
+// Filter returns the wallets for which f returns true
+func (wlts Wallets) Filter(f func(Wallet) bool) Wallets {
+	fwlts := make(Wallets)
+	for id, w := range wlts {
+		if f(w) {
+			fwlts[id] = w
+		}
+	}
+	return fwlts
+}
+
 // containsDuplicate returns true if there is a duplicate wallet identified by
 // the wallet's fingerprint. This is to detect duplicate generative wallets;
 // wallets with no defined generation method do not have a concept of being
@@ -110,20 +127,137 @@ func (wlts Wallets) containsDuplicate() (string, string, bool) {
 	return "", "", false
 }
 
+// removeInvalid removes the duplicate and empty wallets and returns their errors.
+// Of the wallets sharing a fingerprint, the first one by wallet ID is kept.
+func (wlts Wallets) removeInvalid() []error {
+	ids := make([]string, 0, len(wlts))
+	for wltID := range wlts {
+		ids = append(ids, wltID)
+	}
+	sort.Strings(ids)
+
+	var errs []error
+	fps := make(map[string]struct{}, len(wlts))
+	for _, wltID := range ids {
+		if fp := wlts[wltID].Fingerprint(); fp != "" {
+			if _, ok := fps[fp]; ok {
+				errs = append(errs, DuplicateWalletError{WalletID: wltID, Fingerprint: fp})
+				delete(wlts, wltID)
+				continue
+			}
+			fps[fp] = struct{}{}
+		}
+
+		if _, empty := (Wallets{wltID: wlts[wltID]}).containsEmpty(); empty {
+			errs = append(errs, EmptyWalletError{WalletID: wltID})
+			delete(wlts, wltID)
+		}
+	}
+
+	for _, err := range errs {
+		logger.WithError(err).Warning("Skipping invalid wallet")
+	}
+
+	return errs
+}
+
+// verify checks the consistency of the wallets and returns the problems found, in wallet ID order.
+// It checks that every wallet is stored under its filename, that the wallets that must have
+// entries are not empty, and that no two wallets share a fingerprint or a first address.
+func (wlts Wallets) verify() []error {
+	ids := make([]string, 0, len(wlts))
+	for wltID := range wlts {
+		ids = append(ids, wltID)
+	}
+	sort.Strings(ids)
+
+	var errs []error
+	fps := make(map[string]struct{}, len(wlts))
+	firstAddrs := make(map[string]string, len(wlts))
+	for _, wltID := range ids {
+		w := wlts[wltID]
+		if w == nil {
+			errs = append(errs, fmt.Errorf("wallet %q is nil", wltID))
+			continue
+		}
+
+		if w.Filename() != wltID {
+			errs = append(errs, fmt.Errorf("wallet %q is stored under the ID %q", w.Filename(), wltID))
+		}
+
+		if fp := w.Fingerprint(); fp != "" {
+			if _, ok := fps[fp]; ok {
+				errs = append(errs, DuplicateWalletError{WalletID: wltID, Fingerprint: fp})
+			}
+			fps[fp] = struct{}{}
+		}
+
+		l, err := w.EntriesLen()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("wallet %q: %v", wltID, err))
+			continue
+		}
+
+		if l == 0 {
+			if _, empty := (Wallets{wltID: w}).containsEmpty(); empty {
+				errs = append(errs, EmptyWalletError{WalletID: wltID})
+			}
+			continue
+		}
+
+		e, err := w.GetEntryAt(0)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("wallet %q: %v", wltID, err))
+			continue
+		}
+
+		addr := e.Address.String()
+		if otherID, ok := firstAddrs[addr]; ok {
+			errs = append(errs, SharedAddressError{
+				WalletID:      wltID,
+				OtherWalletID: otherID,
+				Address:       addr,
+			})
+			continue
+		}
+		firstAddrs[addr] = wltID
+	}
+
+	return errs
+}
+
 // containsEmpty returns true there is an empty wallet and the ID of that wallet if true.
-// Does not apply to collection wallets
+// A wallet whose entries can't be counted is treated as empty.
+// Does not apply to collection and watch-only wallets
 func (wlts Wallets) containsEmpty() (string, bool) {
 	for wltID, wlt := range wlts {
 		switch wlt.Type() {
-		case WalletTypeCollection:
+		case WalletTypeCollection, WalletTypeWatchOnly:
+			continue
+		}
+
+		// The wallet was created empty on purpose, see Options.NoDefaultAddresses
+		if aw, ok := wlt.(interface{ AllowEmpty() bool }); ok && aw.AllowEmpty() {
+			continue
+		}
+
+		// Checks the header of the wallets that are not loaded yet
+		if lw, ok := wlt.(*lazyWallet); ok {
+			if lw.isEmpty() {
+				return wltID, true
+			}
 			continue
+		}
+
+		switch wlt.Type() {
 		case WalletTypeBip44:
 			var l int
 			// gets the external entries length
 			for _, a := range wlt.Accounts() {
 				el, err := wlt.EntriesLen(OptionAccount(a.Index))
 				if err != nil {
-					panic(err)
+					logger.WithError(err).WithField("wltID", wltID).Error("containsEmpty: EntriesLen failed")
+					return wltID, true
 				}
 				l += el
 			}
@@ -134,7 +268,8 @@ func (wlts Wallets) containsEmpty() (string, bool) {
 		default:
 			l, err := wlt.EntriesLen()
 			if err != nil {
-				panic(err)
+				logger.WithError(err).WithField("wltID", wltID).Error("containsEmpty: EntriesLen failed")
+				return wltID, true
 			}
 
 			if l == 0 {
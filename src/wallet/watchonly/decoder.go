@@ -0,0 +1,123 @@
+package watchonly
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// JSONDecoder implements the Decoder interface for watch-only wallet
+type JSONDecoder struct{}
+
+// Encode encodes the watch-only wallet to []byte, and error if any
+func (d JSONDecoder) Encode(w wallet.Wallet) ([]byte, error) {
+	rw := newReadableWallet(w.(*Wallet))
+	return json.MarshalIndent(rw, "", "    ")
+}
+
+// Decode decodes the []byte to a watch-only wallet, and error if any
+func (d JSONDecoder) Decode(b []byte) (wallet.Wallet, error) {
+	var rw readableWallet
+	if err := json.Unmarshal(b, &rw); err != nil {
+		return nil, err
+	}
+
+	return rw.toWallet()
+}
+
+// readableEntry wallet entry with json tags
+type readableEntry struct {
+	Address string `json:"address"`
+	Public  string `json:"public_key"`
+	Label   string `json:"label,omitempty"`
+}
+
+// readableEntries array of readableEntry
+type readableEntries []readableEntry
+
+func newReadableEntries(entries wallet.Entries) readableEntries {
+	re := make(readableEntries, len(entries))
+	for i, e := range entries {
+		re[i] = readableEntry{
+			Address: e.Address.String(),
+			Public:  e.Public.Hex(),
+			Label:   e.Label,
+		}
+	}
+	return re
+}
+
+// toWalletEntries converts readable entries to entries
+func (res readableEntries) toWalletEntries() (wallet.Entries, error) {
+	entries := make(wallet.Entries, len(res))
+	for i, re := range res {
+		a, err := cipher.DecodeBase58Address(re.Address)
+		if err != nil {
+			return nil, err
+		}
+
+		p, err := cipher.PubKeyFromHex(re.Public)
+		if err != nil {
+			return nil, err
+		}
+
+		e := wallet.Entry{
+			Address: a,
+			Public:  p,
+			Label:   re.Label,
+		}
+		if err := e.VerifyPublic(); err != nil {
+			return nil, err
+		}
+
+		entries[i] = e
+	}
+	return entries, nil
+}
+
+// readableWallet used for [de]serialization of a watch-only wallet
+type readableWallet struct {
+	wallet.Meta `json:"meta"`
+	Entries     readableEntries `json:"entries"`
+}
+
+// newReadableWallet creates readable wallet
+func newReadableWallet(w *Wallet) *readableWallet {
+	return &readableWallet{
+		Meta:    w.Meta.Clone(),
+		Entries: newReadableEntries(w.entries),
+	}
+}
+
+// toWallet converts readable wallet to Wallet
+func (rw *readableWallet) toWallet() (wallet.Wallet, error) {
+	w := &Wallet{
+		Meta: rw.Meta.Clone(),
+	}
+
+	ct, err := wallet.ResolveCoinType(string(w.Meta.Coin()))
+	if err != nil {
+		return nil, err
+	}
+
+	if ct != wallet.CoinTypeSkycoin {
+		return nil, fmt.Errorf("invalid wallet %q: %q wallets only support skycoin", w.Filename(), WalletType)
+	}
+
+	w.SetCoin(ct)
+
+	if err := w.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid wallet %q: %v", w.Filename(), err)
+	}
+
+	entries, err := rw.Entries.toWalletEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	w.entries = entries
+
+	return w, nil
+}
@@ -0,0 +1,342 @@
+package watchonly
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+const (
+	// WalletType represents the watch-only wallet type
+	WalletType = "watchonly"
+)
+
+var defaultWalletDecoder = &JSONDecoder{}
+
+func init() {
+	if err := wallet.RegisterCreator(WalletType, &Creator{}); err != nil {
+		panic(err)
+	}
+
+	if err := wallet.RegisterLoader(WalletType, &Loader{}); err != nil {
+		panic(err)
+	}
+}
+
+// Wallet holds an arbitrary collection of public keys and their addresses.
+// Watch-only wallets can track balances and create unsigned transactions,
+// but can't sign transactions because they hold no secret keys.
+// This wallet does not use seeds, and does not support encryption.
+type Wallet struct {
+	wallet.Meta
+	entries wallet.Entries
+	decoder wallet.Decoder
+}
+
+// NewWallet creates a watch-only wallet
+func NewWallet(filename, label string, options ...wallet.Option) (*Wallet, error) {
+	if label == "" {
+		return nil, wallet.ErrMissingLabel
+	}
+
+	wlt := &Wallet{
+		Meta: wallet.Meta{
+			wallet.MetaFilename:  filename,
+			wallet.MetaLabel:     label,
+			wallet.MetaEncrypted: "false",
+			wallet.MetaType:      WalletType,
+			wallet.MetaVersion:   wallet.Version,
+			wallet.MetaCoin:      string(wallet.CoinTypeSkycoin),
+			wallet.MetaTimestamp: strconv.FormatInt(time.Now().Unix(), 10),
+		},
+		entries: wallet.Entries{},
+		decoder: defaultWalletDecoder,
+	}
+
+	advOpts := &wallet.AdvancedOptions{}
+	for _, opt := range options {
+		opt(wlt)
+		opt(advOpts)
+	}
+
+	if err := validateMeta(wlt.Meta); err != nil {
+		return nil, err
+	}
+
+	if wlt.Coin() != wallet.CoinTypeSkycoin {
+		return nil, wallet.ErrInvalidCoinType
+	}
+
+	if advOpts.GenerateN != 0 || advOpts.ScanN != 0 || advOpts.GapLimit != 0 {
+		return nil, wallet.NewError(fmt.Errorf("wallet scanning is not defined for %q wallet", WalletType))
+	}
+
+	if advOpts.Encrypt || len(advOpts.Password) > 0 {
+		return nil, wallet.ErrWatchOnlyWallet
+	}
+
+	for _, pk := range advOpts.PublicKeys {
+		if err := wlt.AddEntry(wallet.Entry{
+			Address: cipher.AddressFromPubKey(pk),
+			Public:  pk,
+		}); err != nil {
+			return nil, wallet.NewError(fmt.Errorf("invalid wallet public key: %v", err))
+		}
+	}
+
+	return wlt, nil
+}
+
+func validateMeta(m wallet.Meta) error {
+	if m[wallet.MetaType] != WalletType {
+		return wallet.ErrInvalidWalletType
+	}
+
+	if m[wallet.MetaSeed] != "" {
+		return wallet.NewError(fmt.Errorf("seed should not be provided for %q wallets", WalletType))
+	}
+
+	if m.IsEncrypted() {
+		return wallet.NewError(fmt.Errorf("%q wallets can't be encrypted", WalletType))
+	}
+
+	return wallet.ValidateMeta(m)
+}
+
+// SetDecoder sets the decoder
+func (w *Wallet) SetDecoder(d wallet.Decoder) {
+	w.decoder = d
+}
+
+// Serialize encode the wallet to byte slice
+func (w Wallet) Serialize() ([]byte, error) {
+	if w.decoder == nil {
+		w.decoder = defaultWalletDecoder
+	}
+
+	return w.decoder.Encode(&w)
+}
+
+// Deserialize decodes wallet from byte slice
+func (w *Wallet) Deserialize(data []byte) error {
+	if w.decoder == nil {
+		w.decoder = defaultWalletDecoder
+	}
+
+	wlt, err := w.decoder.Decode(data)
+	if err != nil {
+		return err
+	}
+
+	w2 := wlt.(*Wallet)
+	w2.decoder = w.decoder
+	*w = *w2
+	return nil
+}
+
+// IsEncrypted always returns false, watch-only wallets have no secrets to encrypt
+func (w *Wallet) IsEncrypted() bool {
+	return false
+}
+
+// Lock is not supported by watch-only wallets
+func (w *Wallet) Lock(_ []byte) error {
+	return wallet.ErrWatchOnlyWallet
+}
+
+// Unlock is not supported by watch-only wallets
+func (w *Wallet) Unlock(_ []byte) (wallet.Wallet, error) {
+	return nil, wallet.ErrWatchOnlyWallet
+}
+
+// Fingerprint returns an empty string; fingerprints are only defined for
+// wallets with a seed
+func (w *Wallet) Fingerprint() string {
+	return ""
+}
+
+// Clone clones the wallet a new wallet object
+func (w *Wallet) Clone() wallet.Wallet {
+	return &Wallet{
+		Meta:    w.Meta.Clone(),
+		entries: w.entries.Clone(),
+		decoder: w.decoder,
+	}
+}
+
+// CopyFromRef copies the src wallet with a pointer dereference
+func (w *Wallet) CopyFromRef(src wallet.Wallet) {
+	*w = *(src.(*Wallet))
+}
+
+// Accounts is not defined for watch-only wallet
+func (w *Wallet) Accounts() []wallet.Bip44Account {
+	return nil
+}
+
+// Erase is a no-op, watch-only wallets have no secrets
+func (w *Wallet) Erase() {}
+
+// Validate validates the wallet
+func (w *Wallet) Validate() error {
+	if err := w.Meta.Validate(); err != nil {
+		return err
+	}
+
+	if w.Type() != WalletType {
+		return wallet.ErrInvalidWalletType
+	}
+
+	if s := w.Meta[wallet.MetaSeed]; s != "" {
+		return errors.New("seed should not be in watch-only wallets")
+	}
+
+	if s := w.Meta[wallet.MetaLastSeed]; s != "" {
+		return errors.New("lastSeed should not be in watch-only wallets")
+	}
+
+	if w.Meta.IsEncrypted() {
+		return errors.New("watch-only wallets can't be encrypted")
+	}
+	return nil
+}
+
+// ScanAddresses is not defined for "watchonly" wallets
+func (w *Wallet) ScanAddresses(scanN uint64, tf wallet.TransactionsFinder) ([]cipher.Addresser, error) {
+	return nil, wallet.NewError(errors.New("A watch-only wallet does not implement ScanAddresses"))
+}
+
+// GenerateAddresses adds the addresses of the public keys parsed from options.
+// Addresses can't be derived without a seed, ErrWatchOnlyWallet is returned if no public keys are provided.
+func (w *Wallet) GenerateAddresses(options ...wallet.Option) ([]cipher.Addresser, error) {
+	pubKeys := wallet.GetPublicKeysFromOptions(options...)
+	if len(pubKeys) == 0 {
+		return nil, wallet.ErrWatchOnlyWallet
+	}
+
+	addrs := make([]cipher.Addresser, 0, len(pubKeys))
+	for _, pk := range pubKeys {
+		addr := cipher.AddressFromPubKey(pk)
+		if err := w.AddEntry(wallet.Entry{
+			Address: addr,
+			Public:  pk,
+		}); err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// GetAddresses returns all addresses in wallet
+func (w *Wallet) GetAddresses(_ ...wallet.Option) ([]cipher.Addresser, error) {
+	return w.entries.GetAddresses(), nil
+}
+
+// GetEntries returns a copy of all entries held by the wallet
+func (w *Wallet) GetEntries(_ ...wallet.Option) (wallet.Entries, error) {
+	return w.entries.Clone(), nil
+}
+
+// GetEntryAt returns entry at a given index in the entries array
+func (w *Wallet) GetEntryAt(i int, _ ...wallet.Option) (wallet.Entry, error) {
+	if i < 0 || i >= len(w.entries) {
+		return wallet.Entry{}, fmt.Errorf("entry index %d is out of range", i)
+	}
+	return w.entries[i], nil
+}
+
+// GetEntry returns entry of given address
+func (w *Wallet) GetEntry(a cipher.Addresser, _ ...wallet.Option) (wallet.Entry, error) {
+	e, ok := w.entries.Get(a)
+	if !ok {
+		return wallet.Entry{}, wallet.ErrEntryNotFound
+	}
+	return e, nil
+}
+
+// HasEntry returns true if the wallet has an entry.Entry with a given cipher.Address.
+func (w *Wallet) HasEntry(a cipher.Addresser, _ ...wallet.Option) (bool, error) {
+	return w.entries.Has(a), nil
+}
+
+// SetEntryLabel sets the label of the entry with given address
+func (w *Wallet) SetEntryLabel(a cipher.Addresser, label string, _ ...wallet.Option) error {
+	if !w.entries.SetLabel(a, label) {
+		return wallet.ErrEntryNotFound
+	}
+	return nil
+}
+
+// EntriesLen returns the number of entries in the wallet
+func (w *Wallet) EntriesLen(_ ...wallet.Option) (int, error) {
+	return len(w.entries), nil
+}
+
+// AddEntry adds a new entry to the wallet. The entry must not have a secret key.
+func (w *Wallet) AddEntry(e wallet.Entry) error {
+	if !e.Secret.Null() {
+		return wallet.NewError(errors.New("watch-only wallet entries must not have a secret key"))
+	}
+
+	if err := e.VerifyPublic(); err != nil {
+		return err
+	}
+
+	if w.entries.Has(e.Address) {
+		return errors.New("wallet already contains entry with this address")
+	}
+
+	w.entries = append(w.entries, e)
+	return nil
+}
+
+// Loader implements the wallet.Loader interface
+type Loader struct{}
+
+// Load loads wallet from byte slice
+func (l Loader) Load(data []byte) (wallet.Wallet, error) {
+	w := &Wallet{}
+	if err := w.Deserialize(data); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Creator implements the wallet.Creator interface
+type Creator struct{}
+
+// Create implements the wallet.Creator interface
+func (c Creator) Create(filename, label, _ string, options wallet.Options) (wallet.Wallet, error) {
+	return NewWallet(filename, label, convertOptions(options)...)
+}
+
+func convertOptions(options wallet.Options) []wallet.Option {
+	var opts []wallet.Option
+	if options.Coin != "" {
+		opts = append(opts, wallet.OptionCoinType(options.Coin))
+	}
+
+	if options.Decoder != nil {
+		opts = append(opts, wallet.OptionDecoder(options.Decoder))
+	}
+
+	if options.Encrypt {
+		opts = append(opts, wallet.OptionEncrypt(true))
+		opts = append(opts, wallet.OptionPassword(options.Password))
+	}
+
+	if options.Temp {
+		opts = append(opts, wallet.OptionTemp(true))
+	}
+
+	if len(options.WatchOnlyPublicKeys) > 0 {
+		opts = append(opts, wallet.OptionWatchOnlyPublicKeys(options.WatchOnlyPublicKeys))
+	}
+
+	return opts
+}
@@ -0,0 +1,132 @@
+package watchonly
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func makePubKeys(n int) []cipher.PubKey {
+	pks := make([]cipher.PubKey, n)
+	for i := range pks {
+		pks[i], _ = cipher.GenerateKeyPair()
+	}
+	return pks
+}
+
+func TestNewWallet(t *testing.T) {
+	pks := makePubKeys(2)
+
+	tt := []struct {
+		name    string
+		label   string
+		options []wallet.Option
+		err     error
+	}{
+		{
+			name:    "ok",
+			label:   "watch",
+			options: []wallet.Option{wallet.OptionWatchOnlyPublicKeys(pks)},
+		},
+		{
+			name:  "ok no public keys",
+			label: "watch",
+		},
+		{
+			name:    "missing label",
+			options: []wallet.Option{wallet.OptionWatchOnlyPublicKeys(pks)},
+			err:     wallet.ErrMissingLabel,
+		},
+		{
+			name:    "encrypt",
+			label:   "watch",
+			options: []wallet.Option{wallet.OptionEncrypt(true), wallet.OptionPassword([]byte("pwd"))},
+			err:     wallet.ErrWatchOnlyWallet,
+		},
+		{
+			name:    "bitcoin",
+			label:   "watch",
+			options: []wallet.Option{wallet.OptionCoinType(wallet.CoinTypeBitcoin)},
+			err:     wallet.ErrInvalidCoinType,
+		},
+		{
+			name:    "generate addresses",
+			label:   "watch",
+			options: []wallet.Option{wallet.OptionGenerateN(1)},
+			err:     wallet.NewError(fmt.Errorf("wallet scanning is not defined for %q wallet", WalletType)),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			w, err := NewWallet("t.wlt", tc.label, tc.options...)
+			require.Equal(t, tc.err, err)
+			if err != nil {
+				return
+			}
+
+			require.Equal(t, WalletType, w.Type())
+			require.False(t, w.IsEncrypted())
+			require.Empty(t, w.Fingerprint())
+			require.Empty(t, w.Seed())
+
+			entries, err := w.GetEntries()
+			require.NoError(t, err)
+			for i, e := range entries {
+				require.Equal(t, pks[i], e.Public)
+				require.Equal(t, cipher.AddressFromPubKey(pks[i]), e.Address)
+				require.True(t, e.Secret.Null())
+			}
+		})
+	}
+}
+
+func TestWalletSerialize(t *testing.T) {
+	w, err := NewWallet("t.wlt", "watch", wallet.OptionWatchOnlyPublicKeys(makePubKeys(3)))
+	require.NoError(t, err)
+
+	b, err := w.Serialize()
+	require.NoError(t, err)
+
+	w2 := &Wallet{}
+	require.NoError(t, w2.Deserialize(b))
+	require.Equal(t, w.Meta, w2.Meta)
+	require.Equal(t, w.entries, w2.entries)
+}
+
+func TestWalletSecrets(t *testing.T) {
+	w, err := NewWallet("t.wlt", "watch")
+	require.NoError(t, err)
+
+	require.Equal(t, wallet.ErrWatchOnlyWallet, w.Lock([]byte("pwd")))
+	_, err = w.Unlock([]byte("pwd"))
+	require.Equal(t, wallet.ErrWatchOnlyWallet, err)
+
+	// Addresses can't be generated without public keys
+	_, err = w.GenerateAddresses(wallet.OptionGenerateN(1))
+	require.Equal(t, wallet.ErrWatchOnlyWallet, err)
+
+	pks := makePubKeys(1)
+	addrs, err := w.GenerateAddresses(wallet.OptionWatchOnlyPublicKeys(pks))
+	require.NoError(t, err)
+	require.Equal(t, []cipher.Addresser{cipher.AddressFromPubKey(pks[0])}, addrs)
+
+	// Entries with secret keys are rejected
+	pk, sk := cipher.GenerateKeyPair()
+	err = w.AddEntry(wallet.Entry{
+		Address: cipher.AddressFromPubKey(pk),
+		Public:  pk,
+		Secret:  sk,
+	})
+	require.Error(t, err)
+
+	// Transactions can't be signed
+	txn := &coin.Transaction{}
+	_, err = wallet.SignTransaction(w, txn, nil, nil)
+	require.Equal(t, wallet.ErrWalletCantSign, err)
+}
@@ -85,6 +85,7 @@ func (es readableXPubEntries) toXPubEntries(ad wallet.AddressDecoder) (wallet.En
 			Address:     addr,
 			Public:      p,
 			ChildNumber: e.ChildNumber,
+			Label:       e.Label,
 		}
 	}
 
@@ -99,6 +100,7 @@ func newReadableEntries(entries wallet.Entries) readableXPubEntries {
 			Address:     e.Address.String(),
 			Public:      e.Public.Hex(),
 			ChildNumber: e.ChildNumber,
+			Label:       e.Label,
 		}
 	}
 
@@ -109,4 +111,5 @@ type readableXPubEntry struct {
 	Address     string `json:"address"`
 	Public      string `json:"public"`
 	ChildNumber uint32 `json:"child_number"` // For bip32/bip44
+	Label       string `json:"label,omitempty"`
 }
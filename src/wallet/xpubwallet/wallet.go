@@ -100,6 +100,12 @@ func NewWallet(filename, label, xPub string, options ...wallet.Option) (*Wallet,
 		}
 	}
 
+	if advOpts.GapLimit > 0 {
+		if _, err := wallet.ScanAddressesGapLimit(wlt, advOpts.GapLimit, advOpts.ScanChunkSize, advOpts.TF); err != nil {
+			return nil, err
+		}
+	}
+
 	return wlt, nil
 }
 
@@ -396,6 +402,14 @@ func (w *Wallet) HasEntry(addr cipher.Addresser, _ ...wallet.Option) (bool, erro
 	return w.entries.Has(addr), nil
 }
 
+// SetEntryLabel sets the label of the entry with given address
+func (w *Wallet) SetEntryLabel(addr cipher.Addresser, label string, _ ...wallet.Option) error {
+	if !w.entries.SetLabel(addr, label) {
+		return wallet.ErrEntryNotFound
+	}
+	return nil
+}
+
 // EntriesLen returns the number of entries in the wallet
 func (w *Wallet) EntriesLen(_ ...wallet.Option) (int, error) {
 	return len(w.entries), nil
@@ -458,11 +472,21 @@ func convertOptions(options wallet.Options) []wallet.Option {
 		opts = append(opts, wallet.OptionGenerateN(options.GenerateN))
 	}
 
+	if options.NoDefaultAddresses {
+		opts = append(opts, wallet.OptionNoDefaultAddresses(true))
+	}
+
 	if options.ScanN > 0 {
 		opts = append(opts, wallet.OptionScanN(options.ScanN))
 		opts = append(opts, wallet.OptionTransactionsFinder(options.TF))
 	}
 
+	if options.GapLimit > 0 {
+		opts = append(opts, wallet.OptionGapLimit(options.GapLimit))
+		opts = append(opts, wallet.OptionScanChunkSize(options.ScanChunkSize))
+		opts = append(opts, wallet.OptionTransactionsFinder(options.TF))
+	}
+
 	if options.Temp {
 		opts = append(opts, wallet.OptionTemp(true))
 	}